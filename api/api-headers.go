@@ -19,8 +19,10 @@ package api
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	. "github.com/journeymidnight/yig/api/datatype"
 	meta "github.com/journeymidnight/yig/meta/types"
@@ -65,6 +67,20 @@ func SetObjectHeaders(w http.ResponseWriter, object *meta.Object, contentRange *
 	if object.Type == meta.ObjectTypeAppendable {
 		w.Header().Set("X-Amz-Next-Append-Position", strconv.FormatInt(object.Size, 10))
 	}
+	if len(object.Tags) != 0 {
+		w.Header().Set("X-Amz-Tagging-Count", strconv.Itoa(len(object.Tags)))
+	}
+	if object.ObjectLock.Mode != "" {
+		w.Header().Set("X-Amz-Object-Lock-Mode", object.ObjectLock.Mode)
+		w.Header().Set("X-Amz-Object-Lock-Retain-Until-Date",
+			object.ObjectLock.RetainUntilDate.UTC().Format(time.RFC3339))
+	}
+	if object.ObjectLock.LegalHold {
+		w.Header().Set("X-Amz-Object-Lock-Legal-Hold", LegalHoldOn)
+	}
+	if object.ReplicationStatus != "" {
+		w.Header().Set("X-Amz-Replication-Status", object.ReplicationStatus)
+	}
 
 	// for providing ranged content
 	if contentRange != nil && contentRange.OffsetBegin > -1 {
@@ -76,3 +92,31 @@ func SetObjectHeaders(w http.ResponseWriter, object *meta.Object, contentRange *
 
 	w.WriteHeader(statusCode)
 }
+
+// Set x-amz-expiration header if the object would be expired by lifecycle of the bucket
+func SetExpirationHeader(w http.ResponseWriter, bucket *meta.Bucket, object *meta.Object) {
+	if bucket == nil || len(bucket.Lifecycle.Rule) == 0 {
+		return
+	}
+	// the earliest expiration of all matching rules wins
+	var expiryTime time.Time
+	var ruleId string
+	var found bool
+	for _, rule := range bucket.Lifecycle.MatchRules(object.Name, object.Size, object.Tags) {
+		if rule.Expiration == nil {
+			continue
+		}
+		t, ok := rule.Expiration.ExpiryTime(object.LastModifiedTime)
+		if !ok {
+			continue
+		}
+		if !found || t.Before(expiryTime) {
+			expiryTime, ruleId, found = t, rule.ID, true
+		}
+	}
+	if !found {
+		return
+	}
+	w.Header().Set("X-Amz-Expiration", fmt.Sprintf("expiry-date=\"%s\", rule-id=\"%s\"",
+		expiryTime.Format(http.TimeFormat), ruleId))
+}
@@ -78,6 +78,27 @@ func RegisterAPIRouter(mux *router.Router, api ObjectAPIHandlers) {
 		// GetObjectAcl
 		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectAclHandler).
 			Queries("acl", "")
+		// PutObjectTagging
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectTaggingHandler).
+			Queries("tagging", "")
+		// GetObjectTagging
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectTaggingHandler).
+			Queries("tagging", "")
+		// DeleteObjectTagging
+		bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectTaggingHandler).
+			Queries("tagging", "")
+		// PutObjectRetention
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectRetentionHandler).
+			Queries("retention", "")
+		// GetObjectRetention
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectRetentionHandler).
+			Queries("retention", "")
+		// PutObjectLegalHold
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectLegalHoldHandler).
+			Queries("legal-hold", "")
+		// GetObjectLegalHold
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectLegalHoldHandler).
+			Queries("legal-hold", "")
 
 		// AppendObject
 		bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.AppendObjectHandler).Queries("append", "")
@@ -143,6 +164,26 @@ func RegisterAPIRouter(mux *router.Router, api ObjectAPIHandlers) {
 		bucket.Methods("GET").HandlerFunc(api.GetBucketEncryption).Queries("encryption", "")
 		//
 		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketEncryption).Queries("encryption", "")
+		// PutBucketTagging
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketTaggingHandler).Queries("tagging", "")
+		// GetBucketTagging
+		bucket.Methods("GET").HandlerFunc(api.GetBucketTaggingHandler).Queries("tagging", "")
+		// DeleteBucketTagging
+		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketTaggingHandler).Queries("tagging", "")
+		// PutBucketObjectLock
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketObjectLockHandler).Queries("object-lock", "")
+		// GetBucketObjectLock
+		bucket.Methods("GET").HandlerFunc(api.GetBucketObjectLockHandler).Queries("object-lock", "")
+		// PutBucketNotification
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketNotificationHandler).Queries("notification", "")
+		// GetBucketNotification
+		bucket.Methods("GET").HandlerFunc(api.GetBucketNotificationHandler).Queries("notification", "")
+		// PutBucketReplication
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketReplicationHandler).Queries("replication", "")
+		// GetBucketReplication
+		bucket.Methods("GET").HandlerFunc(api.GetBucketReplicationHandler).Queries("replication", "")
+		// DeleteBucketReplication
+		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketReplicationHandler).Queries("replication", "")
 
 		// HeadBucket
 		bucket.Methods("HEAD").HandlerFunc(api.HeadBucketHandler)
@@ -75,6 +75,29 @@ func IsBucketPolicyAllowed(userId string, bucket *meta.Bucket, r *http.Request,
 
 }
 
+// isBypassGovernanceAllowed checks `x-amz-bypass-governance-retention` header of request.
+// Bucket owner could always bypass GOVERNANCE retention, other users need
+// s3:BypassGovernanceRetention permission granted by bucket policy.
+func isBypassGovernanceAllowed(r *http.Request, credential common.Credential,
+	bucket *meta.Bucket, objectName string) (bool, error) {
+
+	if strings.ToLower(r.Header.Get("X-Amz-Bypass-Governance-Retention")) != "true" {
+		return false, nil
+	}
+	if bucket == nil {
+		return false, ErrNoSuchBucket
+	}
+	if bucket.OwnerId == credential.UserId {
+		return true, nil
+	}
+	isAllow, err := IsBucketPolicyAllowed(credential.UserId, bucket, r,
+		policy.BypassGovernanceRetentionAction, objectName)
+	if err != nil || !isAllow {
+		return false, ErrAccessDenied
+	}
+	return true, nil
+}
+
 func getConditionValues(request *http.Request, locationConstraint string) map[string][]string {
 	args := make(map[string][]string)
 
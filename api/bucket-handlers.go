@@ -315,8 +315,13 @@ func (api ObjectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 	var deletedObjects []ObjectIdentifier
 	// Loop through all the objects and delete them sequentially.
 	for _, object := range deleteObjects.Objects {
-		result, err := api.ObjectAPI.DeleteObject(bucket, object.ObjectName,
-			object.VersionId, credential)
+		bypassGovernance, err := isBypassGovernanceAllowed(r, credential,
+			getRequestContext(r).BucketInfo, object.ObjectName)
+		var result DeleteObjectResult
+		if err == nil {
+			result, err = api.ObjectAPI.DeleteObject(bucket, object.ObjectName,
+				object.VersionId, credential, bypassGovernance)
+		}
 		if err == nil {
 			deletedObjects = append(deletedObjects, ObjectIdentifier{
 				ObjectName:   object.ObjectName,
@@ -325,6 +330,9 @@ func (api ObjectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 				DeleteMarkerVersionId: helper.Ternary(result.DeleteMarker,
 					result.VersionId, "").(string),
 			})
+			sendObjectEvent(r, credential,
+				helper.Ternary(result.DeleteMarker, ObjectRemovedDeleteMarkerCreated, ObjectRemovedDelete).(string),
+				EventObject{Key: object.ObjectName, VersionId: result.VersionId})
 		} else {
 			logger.Error("Unable to delete object:", err)
 			apiErrorCode, ok := err.(ApiErrorCode)
@@ -384,10 +392,12 @@ func (api ObjectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	objectLockEnabled := strings.ToLower(r.Header.Get("X-Amz-Bucket-Object-Lock-Enabled")) == "true"
+
 	// TODO:the location value in the request body should match the Region in serverConfig.
 
 	// Make bucket.
-	err = api.ObjectAPI.MakeBucket(bucketName, acl, credential)
+	err = api.ObjectAPI.MakeBucket(bucketName, acl, credential, objectLockEnabled)
 	if err != nil {
 		logger.Error("Unable to create bucket", bucketName, "error:", err)
 		WriteErrorResponse(w, r, err)
@@ -527,6 +537,12 @@ func (api ObjectAPIHandlers) PutBucketLifeCycleHandler(w http.ResponseWriter, r
 		WriteErrorResponse(w, r, ErrInternalError)
 		return
 	}
+	err = lc.Validate()
+	if err != nil {
+		logger.Error("Invalid lifecycle configuration:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
 
 	logger.Info("Setting lifecycle:", lc)
 	err = api.ObjectAPI.SetBucketLifecycle(bucket, lc, credential)
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam/common"
+	bus "github.com/journeymidnight/yig/mq"
+	"github.com/journeymidnight/yig/signature"
+)
+
+func (api ObjectAPIHandlers) PutBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+	// Error out if Content-Length is missing.
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	config, err := datatype.ParseNotificationConfig(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	err = config.ValidateDestinations(helper.CONFIG.Region, bus.MsgSenderName)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.ObjectAPI.SetBucketNotification(ctx.BucketInfo, *config)
+	if err != nil {
+		logger.Error("Unable to set notification for bucket:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutBucketNotification"
+	WriteSuccessResponse(w, nil)
+}
+
+func (api ObjectAPIHandlers) GetBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+
+	config, err := api.ObjectAPI.GetBucketNotification(ctx.BucketName)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	config.Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	encodedSuccessResponse, err := xmlFormat(config)
+	if err != nil {
+		logger.Error("Failed to marshal NotificationConfiguration XML for bucket", ctx.BucketName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	setXmlHeader(w)
+	//ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetBucketNotification"
+	// Write to client.
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
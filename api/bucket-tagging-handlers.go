@@ -0,0 +1,148 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/iam/common"
+	"github.com/journeymidnight/yig/signature"
+)
+
+func (api ObjectAPIHandlers) PutBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+	// Error out if Content-Length is missing.
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	tags, err := datatype.ParseTagging(io.LimitReader(r.Body, r.ContentLength), datatype.MaxBucketTagsCount)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.ObjectAPI.SetBucketTagging(ctx.BucketInfo, tags)
+	if err != nil {
+		logger.Error("Unable to set tagging for bucket:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutBucketTagging"
+	WriteSuccessNoContent(w)
+}
+
+func (api ObjectAPIHandlers) GetBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+
+	tags, err := api.ObjectAPI.GetBucketTagging(ctx.BucketName)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	encodedSuccessResponse, err := xmlFormat(datatype.TaggingFromMap(tags))
+	if err != nil {
+		logger.Error("Failed to marshal Tagging XML for bucket", ctx.BucketName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	setXmlHeader(w)
+	//ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetBucketTagging"
+	// Write to client.
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
+
+func (api ObjectAPIHandlers) DeleteBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+
+	if err := api.ObjectAPI.DeleteBucketTagging(ctx.BucketInfo); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "DeleteBucketTagging"
+	// Success.
+	WriteSuccessNoContent(w)
+}
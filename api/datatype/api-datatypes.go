@@ -314,6 +314,10 @@ type PutObjectResult struct {
 	Md5          string
 	VersionId    string
 	LastModified time.Time
+	// size of the object stored, the request size is -1 for chunked uploads
+	Size int64
+	// KMS key the data key is sealed with, for SSE-KMS
+	SseAwsKmsKeyId string
 }
 
 type RenameObjectResult struct {
@@ -323,6 +327,8 @@ type RenameObjectResult struct {
 type AppendObjectResult struct {
 	PutObjectResult
 	NextPosition int64
+	// encryption of the object, which is decided by the first append
+	SseType string
 }
 
 type DeleteObjectResult struct {
@@ -333,7 +339,7 @@ type DeleteObjectResult struct {
 type PutObjectPartResult struct {
 	ETag                    string
 	SseType                 string
-	SseAwsKmsKeyIdBase64    string
+	SseAwsKmsKeyId          string
 	SseCustomerAlgorithm    string
 	SseCustomerKeyMd5Base64 string
 }
@@ -342,19 +348,19 @@ type CompleteMultipartResult struct {
 	ETag                    string
 	VersionId               string
 	SseType                 string
-	SseAwsKmsKeyIdBase64    string
+	SseAwsKmsKeyId          string
 	SseCustomerAlgorithm    string
 	SseCustomerKeyMd5Base64 string
 }
 
 type SseRequest struct {
-	// type of Server Side Encryption, could be "SSE-KMS", "SSE-S3", "SSE-C"(custom), or ""(none),
-	// KMS is not implemented yet
+	// type of Server Side Encryption, could be "SSE-KMS", "SSE-S3", "SSE-C"(custom), or ""(none)
 	Type string
 
 	// AWS-managed specific(KMS and S3)
 	SseAwsKmsKeyId string
-	SseContext     string
+	// encryption context of SSE-KMS in JSON
+	SseContext string
 
 	// customer-provided specific(SSE-C)
 	SseCustomerAlgorithm string
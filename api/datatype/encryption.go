@@ -3,6 +3,7 @@ package datatype
 import (
 	"encoding/xml"
 	"github.com/dustin/go-humanize"
+	"github.com/journeymidnight/yig/crypto"
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	"io"
@@ -50,6 +51,9 @@ func (e *EncryptionConfiguration) Validate() (error error) {
 					return ErrMissingSSEAlgorithmOrKMSMasterKeyIDInEncryptionRule
 				}
 			}
+			if masterKeyID != "" && !crypto.IsValidKeyID(masterKeyID) {
+				return ErrMalformedEncryptionConfiguration
+			}
 		}
 	} else {
 		return ErrMissingRuleInEncryption
@@ -1,19 +1,357 @@
 package datatype
 
 import (
+	"encoding/json"
 	"encoding/xml"
-	//	. "github.com/journeymidnight/yig/error"
-	//	"github.com/journeymidnight/yig/helper"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/journeymidnight/yig/error"
+)
+
+const (
+	MaxLifecycleRulesCount = 1000
+
+	LifecycleStatusEnabled  = "Enabled"
+	LifecycleStatusDisabled = "Disabled"
 )
 
 type LifecycleRule struct {
-	ID         string `xml:"ID"`
-	Prefix     string `xml:"Prefix"`
-	Status     string `xml:"Status"`
-	Expiration string `xml:"Expiration>Days"`
+	ID                             string                          `xml:"ID"`
+	Prefix                         string                          `xml:"Prefix"`
+	Filter                         *LifecycleFilter                `xml:"Filter,omitempty"`
+	Status                         string                          `xml:"Status"`
+	Expiration                     *LifecycleExpiration            `xml:"Expiration,omitempty"`
+	Transitions                    []LifecycleTransition           `xml:"Transition"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:"NoncurrentVersionExpiration,omitempty"`
+	NoncurrentVersionTransitions   []NoncurrentVersionTransition   `xml:"NoncurrentVersionTransition"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+// LifecycleExpiration expires objects after Days since their last modification,
+// or at Date. ExpiredObjectDeleteMarker removes delete markers without noncurrent
+// versions in versioned buckets.
+type LifecycleExpiration struct {
+	Days                      int    `xml:"Days,omitempty"`
+	Date                      string `xml:"Date,omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:"ExpiredObjectDeleteMarker,omitempty"`
+}
+
+// Expiration was saved as a string of days before, keep compatible with it
+func (e *LifecycleExpiration) UnmarshalJSON(data []byte) (err error) {
+	var days string
+	if json.Unmarshal(data, &days) == nil {
+		if days != "" {
+			e.Days, err = strconv.Atoi(days)
+		}
+		return err
+	}
+	type expiration LifecycleExpiration
+	return json.Unmarshal(data, (*expiration)(e))
+}
+
+// ExpiryTime returns when an object last modified at modTime expires,
+// days are rounded up to the next midnight UTC as AWS does.
+func (e *LifecycleExpiration) ExpiryTime(modTime time.Time) (time.Time, bool) {
+	if e.Date != "" {
+		date, err := ParseLifecycleDate(e.Date)
+		return date, err == nil
+	}
+	if e.Days > 0 {
+		expiry := modTime.UTC().Add(time.Duration(e.Days) * 24 * time.Hour)
+		midnight := expiry.Truncate(24 * time.Hour)
+		if midnight.Before(expiry) {
+			midnight = midnight.Add(24 * time.Hour)
+		}
+		return midnight, true
+	}
+	return time.Time{}, false
+}
+
+// LifecycleTransition moves objects to StorageClass after Days since
+// their last modification, or at Date.
+type LifecycleTransition struct {
+	Days         int    `xml:"Days,omitempty"`
+	Date         string `xml:"Date,omitempty"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// NoncurrentVersionExpiration removes noncurrent versions NoncurrentDays
+// after they became noncurrent, only works in versioned buckets.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays int `xml:"NoncurrentDays"`
+}
+
+// NoncurrentVersionTransition moves noncurrent versions to StorageClass
+// NoncurrentDays after they became noncurrent.
+type NoncurrentVersionTransition struct {
+	NoncurrentDays int    `xml:"NoncurrentDays"`
+	StorageClass   string `xml:"StorageClass"`
+}
+
+// AbortIncompleteMultipartUpload aborts multipart uploads which are not completed
+// DaysAfterInitiation days after they were initiated.
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// LifecycleFilter selects objects a rule applies to, at most one of
+// Prefix, Tag, ObjectSizeGreaterThan, ObjectSizeLessThan and And could be set.
+// An empty filter selects all objects.
+type LifecycleFilter struct {
+	Prefix                string              `xml:"Prefix,omitempty"`
+	Tag                   *Tag                `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan int64               `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    int64               `xml:"ObjectSizeLessThan,omitempty"`
+	And                   *LifecycleFilterAnd `xml:"And,omitempty"`
+}
+
+// LifecycleFilterAnd selects objects matching all of its conditions
+type LifecycleFilterAnd struct {
+	Prefix                string `xml:"Prefix,omitempty"`
+	Tags                  []Tag  `xml:"Tag"`
+	ObjectSizeGreaterThan int64  `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    int64  `xml:"ObjectSizeLessThan,omitempty"`
 }
 
 type Lifecycle struct {
 	XMLName xml.Name        `xml:"LifecycleConfiguration"`
 	Rule    []LifecycleRule `xml:"Rule"`
 }
+
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/intro-lifecycle-rules.html
+func (lc *Lifecycle) Validate() error {
+	if len(lc.Rule) == 0 || len(lc.Rule) > MaxLifecycleRulesCount {
+		return ErrInvalidLc
+	}
+	for _, rule := range lc.Rule {
+		if rule.Status != LifecycleStatusEnabled && rule.Status != LifecycleStatusDisabled {
+			return ErrInvalidLc
+		}
+		if rule.Expiration == nil && len(rule.Transitions) == 0 &&
+			rule.NoncurrentVersionExpiration == nil && len(rule.NoncurrentVersionTransitions) == 0 &&
+			rule.AbortIncompleteMultipartUpload == nil {
+			return ErrInvalidLc
+		}
+		if rule.Filter != nil {
+			if rule.Prefix != "" {
+				return ErrInvalidLc
+			}
+			err := rule.Filter.Validate()
+			if err != nil {
+				return err
+			}
+			// delete markers and multipart uploads could not be selected by tags
+			if rule.Filter.hasTags() && ((rule.Expiration != nil && rule.Expiration.ExpiredObjectDeleteMarker) ||
+				rule.AbortIncompleteMultipartUpload != nil) {
+				return ErrInvalidLc
+			}
+		}
+		if rule.Expiration != nil {
+			err := rule.Expiration.Validate()
+			if err != nil {
+				return err
+			}
+		}
+		storageClasses := make(map[string]bool)
+		for _, transition := range rule.Transitions {
+			err := transition.Validate()
+			if err != nil {
+				return err
+			}
+			if storageClasses[transition.StorageClass] {
+				return ErrInvalidLc
+			}
+			storageClasses[transition.StorageClass] = true
+		}
+		if rule.NoncurrentVersionExpiration != nil && rule.NoncurrentVersionExpiration.NoncurrentDays <= 0 {
+			return ErrInvalidLc
+		}
+		if rule.AbortIncompleteMultipartUpload != nil && rule.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+			return ErrInvalidLc
+		}
+		storageClasses = make(map[string]bool)
+		for _, transition := range rule.NoncurrentVersionTransitions {
+			if transition.NoncurrentDays <= 0 {
+				return ErrInvalidLc
+			}
+			err := validateTransitionStorageClass(transition.StorageClass)
+			if err != nil {
+				return err
+			}
+			if storageClasses[transition.StorageClass] {
+				return ErrInvalidLc
+			}
+			storageClasses[transition.StorageClass] = true
+		}
+	}
+	return nil
+}
+
+// Validate checks that exactly one of Days, Date and ExpiredObjectDeleteMarker is set
+func (e *LifecycleExpiration) Validate() error {
+	conditions := 0
+	for _, set := range []bool{e.Days != 0, e.Date != "", e.ExpiredObjectDeleteMarker} {
+		if set {
+			conditions++
+		}
+	}
+	if e.Days < 0 || conditions != 1 {
+		return ErrInvalidLc
+	}
+	if e.Date != "" {
+		return validateLifecycleDate(e.Date)
+	}
+	return nil
+}
+
+// Validate checks that exactly one of Days and Date is set, and objects
+// are only moved to STANDARD_IA or GLACIER
+func (t LifecycleTransition) Validate() error {
+	if t.Days < 0 || (t.Days == 0) == (t.Date == "") {
+		return ErrInvalidLc
+	}
+	if t.Date != "" {
+		err := validateLifecycleDate(t.Date)
+		if err != nil {
+			return err
+		}
+	}
+	return validateTransitionStorageClass(t.StorageClass)
+}
+
+// AWS only accepts dates at midnight UTC
+func validateLifecycleDate(date string) error {
+	t, err := ParseLifecycleDate(date)
+	if err != nil {
+		return ErrInvalidLc
+	}
+	if !t.Equal(t.Truncate(24 * time.Hour)) {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+func validateTransitionStorageClass(storageClass string) error {
+	switch storageClass {
+	case "STANDARD_IA", "GLACIER":
+		return nil
+	default:
+		return ErrInvalidStorageClass
+	}
+}
+
+// ParseLifecycleDate parses date in ISO 8601 format, e.g. "2020-01-01T00:00:00.000Z"
+func ParseLifecycleDate(date string) (time.Time, error) {
+	return time.Parse(time.RFC3339, date)
+}
+
+func (f *LifecycleFilter) Validate() error {
+	conditions := 0
+	for _, set := range []bool{f.Prefix != "", f.Tag != nil, f.ObjectSizeGreaterThan != 0,
+		f.ObjectSizeLessThan != 0, f.And != nil} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions > 1 {
+		return ErrInvalidLc
+	}
+	if f.Tag != nil {
+		if err := validateTag(f.Tag.Key, f.Tag.Value); err != nil {
+			return err
+		}
+	}
+	if f.And != nil {
+		keys := make(map[string]bool)
+		for _, tag := range f.And.Tags {
+			if err := validateTag(tag.Key, tag.Value); err != nil {
+				return err
+			}
+			if keys[tag.Key] {
+				return ErrInvalidTag
+			}
+			keys[tag.Key] = true
+		}
+		return validateObjectSizeRange(f.And.ObjectSizeGreaterThan, f.And.ObjectSizeLessThan)
+	}
+	return validateObjectSizeRange(f.ObjectSizeGreaterThan, f.ObjectSizeLessThan)
+}
+
+func validateObjectSizeRange(greaterThan, lessThan int64) error {
+	if greaterThan < 0 || lessThan < 0 {
+		return ErrInvalidLc
+	}
+	if greaterThan != 0 && lessThan != 0 && lessThan <= greaterThan {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+func (f *LifecycleFilter) hasTags() bool {
+	return f.Tag != nil || (f.And != nil && len(f.And.Tags) != 0)
+}
+
+// Match reports whether the object is selected by the filter.
+// Negative size means the size is unknown, e.g. for multipart uploads,
+// and the size conditions are ignored.
+func (f *LifecycleFilter) Match(objectName string, size int64, tags map[string]string) bool {
+	if f.And != nil {
+		return matchConditions(f.And.Prefix, f.And.Tags, f.And.ObjectSizeGreaterThan, f.And.ObjectSizeLessThan,
+			objectName, size, tags)
+	}
+	var filterTags []Tag
+	if f.Tag != nil {
+		filterTags = []Tag{*f.Tag}
+	}
+	return matchConditions(f.Prefix, filterTags, f.ObjectSizeGreaterThan, f.ObjectSizeLessThan,
+		objectName, size, tags)
+}
+
+func matchConditions(prefix string, filterTags []Tag, greaterThan, lessThan int64,
+	objectName string, size int64, tags map[string]string) bool {
+
+	if !strings.HasPrefix(objectName, prefix) {
+		return false
+	}
+	for _, tag := range filterTags {
+		if value, ok := tags[tag.Key]; !ok || value != tag.Value {
+			return false
+		}
+	}
+	if size >= 0 {
+		if greaterThan != 0 && size <= greaterThan {
+			return false
+		}
+		if lessThan != 0 && size >= lessThan {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether the rule applies to the object
+func (r *LifecycleRule) Match(objectName string, size int64, tags map[string]string) bool {
+	if !strings.HasPrefix(objectName, r.Prefix) {
+		return false
+	}
+	if r.Filter == nil {
+		return true
+	}
+	return r.Filter.Match(objectName, size, tags)
+}
+
+// MatchRules returns all enabled rules which apply to the object, the actions of
+// all of them are taken: expiration wins over transitions if any is due.
+func (lc *Lifecycle) MatchRules(objectName string, size int64, tags map[string]string) (rules []LifecycleRule) {
+	for _, r := range lc.Rule {
+		if r.Status == LifecycleStatusDisabled {
+			continue
+		}
+		if r.Match(objectName, size, tags) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,184 @@
+package datatype
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var lifecycleFilterMatchTests = []struct {
+	Filter   LifecycleFilter
+	Name     string
+	Size     int64
+	Tags     map[string]string
+	Expected bool
+}{
+	{Filter: LifecycleFilter{}, Name: "a", Size: 1, Expected: true},                                               // 0
+	{Filter: LifecycleFilter{Prefix: "log/"}, Name: "log/a", Size: 1, Expected: true},                             // 1
+	{Filter: LifecycleFilter{Prefix: "log/"}, Name: "data/a", Size: 1, Expected: false},                           // 2
+	{Filter: LifecycleFilter{Tag: &Tag{"k", "v"}}, Name: "a", Tags: map[string]string{"k": "v"}, Expected: true},  // 3
+	{Filter: LifecycleFilter{Tag: &Tag{"k", "v"}}, Name: "a", Tags: map[string]string{"k": "x"}, Expected: false}, // 4
+	{Filter: LifecycleFilter{Tag: &Tag{"k", "v"}}, Name: "a", Expected: false},                                    // 5
+	{Filter: LifecycleFilter{ObjectSizeGreaterThan: 10}, Name: "a", Size: 10, Expected: false},                    // 6
+	{Filter: LifecycleFilter{ObjectSizeGreaterThan: 10}, Name: "a", Size: 11, Expected: true},                     // 7
+	{Filter: LifecycleFilter{ObjectSizeLessThan: 10}, Name: "a", Size: 10, Expected: false},                       // 8
+	{Filter: LifecycleFilter{ObjectSizeLessThan: 10}, Name: "a", Size: 9, Expected: true},                         // 9
+	{Filter: LifecycleFilter{ObjectSizeLessThan: 10}, Name: "a", Size: -1, Expected: true},                        // 10
+	{
+		Filter: LifecycleFilter{And: &LifecycleFilterAnd{
+			Prefix: "log/",
+			Tags:   []Tag{{"k1", "v1"}, {"k2", "v2"}},
+		}},
+		Name:     "log/a",
+		Tags:     map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"},
+		Expected: true,
+	}, // 11
+	{
+		Filter: LifecycleFilter{And: &LifecycleFilterAnd{
+			Prefix: "log/",
+			Tags:   []Tag{{"k1", "v1"}, {"k2", "v2"}},
+		}},
+		Name:     "log/a",
+		Tags:     map[string]string{"k1": "v1"},
+		Expected: false,
+	}, // 12
+	{
+		Filter: LifecycleFilter{And: &LifecycleFilterAnd{
+			Prefix:                "log/",
+			ObjectSizeGreaterThan: 10,
+			ObjectSizeLessThan:    20,
+		}},
+		Name:     "log/a",
+		Size:     15,
+		Expected: true,
+	}, // 13
+	{
+		Filter: LifecycleFilter{And: &LifecycleFilterAnd{
+			Prefix:                "log/",
+			ObjectSizeGreaterThan: 10,
+			ObjectSizeLessThan:    20,
+		}},
+		Name:     "log/a",
+		Size:     20,
+		Expected: false,
+	}, // 14
+}
+
+func TestLifecycleFilterMatch(t *testing.T) {
+	for i, test := range lifecycleFilterMatchTests {
+		if got := test.Filter.Match(test.Name, test.Size, test.Tags); got != test.Expected {
+			t.Errorf("Test %d: Wanted %v but got %v", i, test.Expected, got)
+		}
+	}
+}
+
+var lifecycleValidateTests = []struct {
+	Body     string
+	Expected bool
+}{
+	{Body: `<Rule><Prefix></Prefix><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: true},                                                                                                               // 0
+	{Body: `<Rule><Prefix></Prefix><Status>Enabled</Status></Rule>`, Expected: false},                                                                                                                                                     // 1
+	{Body: `<Rule><Filter><Prefix>log/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: true},                                                                                          // 2
+	{Body: `<Rule><Prefix>log/</Prefix><Filter><Prefix>log/</Prefix></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},                                                                    // 3
+	{Body: `<Rule><Filter><Prefix>log/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},                                                  // 4
+	{Body: `<Rule><Filter><And><Prefix>log/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></And></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: true},                                        // 5
+	{Body: `<Rule><Filter><And><Tag><Key>k</Key><Value>v</Value></Tag><Tag><Key>k</Key><Value>v</Value></Tag></And></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},                     // 6
+	{Body: `<Rule><Filter><And><ObjectSizeGreaterThan>20</ObjectSizeGreaterThan><ObjectSizeLessThan>10</ObjectSizeLessThan></And></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},       // 7
+	{Body: `<Rule><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>1</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule>`, Expected: false}, // 8
+	{Body: `<Rule><Filter><Tag><Key></Key><Value>v</Value></Tag></Filter><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},                                                                        // 9
+	{Body: `<Rule><Prefix></Prefix><Status>Enabled</Status><Expiration><Date>2020-01-01T00:00:00.000Z</Date></Expiration></Rule>`, Expected: true},                                                                                        // 10
+	{Body: `<Rule><Prefix></Prefix><Status>Enabled</Status><Expiration><Date>2020-01-01T08:00:00.000Z</Date></Expiration></Rule>`, Expected: false},                                                                                       // 11
+	{Body: `<Rule><Prefix></Prefix><Status>Enabled</Status><Expiration><Days>1</Days><Date>2020-01-01T00:00:00.000Z</Date></Expiration></Rule>`, Expected: false},                                                                         // 12
+	{Body: `<Rule><Prefix></Prefix><Status>Disabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: true},                                                                                                              // 13
+	{Body: `<Rule><Prefix></Prefix><Status>enabled</Status><Expiration><Days>1</Days></Expiration></Rule>`, Expected: false},                                                                                                              // 14
+}
+
+func TestLifecycleValidate(t *testing.T) {
+	for i, test := range lifecycleValidateTests {
+		var lc Lifecycle
+		err := xml.Unmarshal([]byte("<LifecycleConfiguration>"+test.Body+"</LifecycleConfiguration>"), &lc)
+		if err != nil {
+			t.Fatalf("Test %d: Unable to parse lifecycle: %v", i, err)
+		}
+		if err = lc.Validate(); (err == nil) != test.Expected {
+			t.Errorf("Test %d: Wanted valid %v but got error %v", i, test.Expected, err)
+		}
+	}
+}
+
+func TestLifecycleMatchRules(t *testing.T) {
+	lc := Lifecycle{
+		Rule: []LifecycleRule{
+			{ID: "default", Status: "Enabled", Expiration: &LifecycleExpiration{Days: 30}},
+			{ID: "log", Status: "Enabled", Prefix: "log/", Expiration: &LifecycleExpiration{Days: 7}},
+			{ID: "tmp", Status: "Enabled", Filter: &LifecycleFilter{Tag: &Tag{"tmp", "true"}},
+				Expiration: &LifecycleExpiration{Days: 1}},
+			{ID: "disabled", Status: "Disabled", Prefix: "log/", Expiration: &LifecycleExpiration{Days: 1}},
+		},
+	}
+	var testcase = [...]struct {
+		name string
+		tags map[string]string
+		ids  string
+	}{
+		{"a", nil, "default"},
+		{"log/a", nil, "default,log"},
+		{"a", map[string]string{"tmp": "true"}, "default,tmp"},
+		{"log/a", map[string]string{"tmp": "true"}, "default,log,tmp"},
+	}
+	for _, v := range testcase {
+		var ids []string
+		for _, rule := range lc.MatchRules(v.name, 1, v.tags) {
+			ids = append(ids, rule.ID)
+		}
+		if strings.Join(ids, ",") != v.ids {
+			t.Errorf("MatchRules for %s %v failed, expected %s, got %v\n", v.name, v.tags, v.ids, ids)
+		}
+	}
+
+	lc.Rule = lc.Rule[1:]
+	if rules := lc.MatchRules("a", 1, nil); len(rules) != 0 {
+		t.Errorf("MatchRules for a without default rule, expected no rule, got %v\n", rules)
+	}
+}
+
+func TestLifecycleExpiryTime(t *testing.T) {
+	modTime := time.Date(2019, 12, 20, 10, 30, 0, 0, time.UTC)
+	var testcase = [...]struct {
+		expiration LifecycleExpiration
+		expiry     time.Time
+		ok         bool
+	}{
+		{LifecycleExpiration{Days: 1}, time.Date(2019, 12, 22, 0, 0, 0, 0, time.UTC), true},
+		{LifecycleExpiration{Days: 30}, time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC), true},
+		{LifecycleExpiration{Date: "2020-01-01T00:00:00.000Z"}, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{LifecycleExpiration{ExpiredObjectDeleteMarker: true}, time.Time{}, false},
+	}
+	for i, v := range testcase {
+		expiry, ok := v.expiration.ExpiryTime(modTime)
+		if ok != v.ok || !expiry.Equal(v.expiry) {
+			t.Errorf("Test %d: expected %v %v, got %v %v\n", i, v.expiry, v.ok, expiry, ok)
+		}
+	}
+}
+
+func TestLifecycleExpirationUnmarshalJSON(t *testing.T) {
+	var testcase = [...]struct {
+		data       string
+		expiration LifecycleExpiration
+	}{
+		{`"30"`, LifecycleExpiration{Days: 30}},
+		{`""`, LifecycleExpiration{}},
+		{`{"Days":7}`, LifecycleExpiration{Days: 7}},
+		{`{"Date":"2020-01-01T00:00:00Z"}`, LifecycleExpiration{Date: "2020-01-01T00:00:00Z"}},
+	}
+	for i, v := range testcase {
+		var expiration LifecycleExpiration
+		err := json.Unmarshal([]byte(v.data), &expiration)
+		if err != nil || expiration != v.expiration {
+			t.Errorf("Test %d: expected %+v, got %+v, err %v\n", i, v.expiration, expiration, err)
+		}
+	}
+}
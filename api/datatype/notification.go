@@ -0,0 +1,290 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxNotificationConfigurationSize = 20 * humanize.KiByte
+
+	ObjectCreatedAll                     = "s3:ObjectCreated:*"
+	ObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	ObjectCreatedPost                    = "s3:ObjectCreated:Post"
+	ObjectCreatedCopy                    = "s3:ObjectCreated:Copy"
+	ObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	ObjectRemovedAll                     = "s3:ObjectRemoved:*"
+	ObjectRemovedDelete                  = "s3:ObjectRemoved:Delete"
+	ObjectRemovedDeleteMarkerCreated     = "s3:ObjectRemoved:DeleteMarkerCreated"
+	ObjectRestoreAll                     = "s3:ObjectRestore:*"
+	ObjectRestorePost                    = "s3:ObjectRestore:Post"
+)
+
+var supportedNotificationEvents = map[string]bool{
+	ObjectCreatedAll:                     true,
+	ObjectCreatedPut:                     true,
+	ObjectCreatedPost:                    true,
+	ObjectCreatedCopy:                    true,
+	ObjectCreatedCompleteMultipartUpload: true,
+	ObjectRemovedAll:                     true,
+	ObjectRemovedDelete:                  true,
+	ObjectRemovedDeleteMarkerCreated:     true,
+	ObjectRestoreAll:                     true,
+	ObjectRestorePost:                    true,
+}
+
+// NotificationConfiguration is the bucket notification setting.
+// Events of both queue and topic configurations are delivered through the message queue,
+// the ARNs of them must name the message queue plugin, see ValidateDestinations.
+type NotificationConfiguration struct {
+	XMLName             xml.Name             `xml:"NotificationConfiguration"`
+	Xmlns               string               `xml:"xmlns,attr,omitempty"`
+	QueueConfigurations []QueueConfiguration `xml:"QueueConfiguration,omitempty"`
+	TopicConfigurations []TopicConfiguration `xml:"TopicConfiguration,omitempty"`
+}
+
+type NotificationRule struct {
+	Id     string              `xml:"Id,omitempty"`
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type QueueConfiguration struct {
+	NotificationRule
+	Queue string `xml:"Queue"`
+}
+
+type TopicConfiguration struct {
+	NotificationRule
+	Topic string `xml:"Topic"`
+}
+
+type NotificationFilter struct {
+	S3Key S3KeyFilter `xml:"S3Key"`
+}
+
+type S3KeyFilter struct {
+	FilterRules []FilterRule `xml:"FilterRule"`
+}
+
+type FilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// Reference:https://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+func (n *NotificationConfiguration) Validate() error {
+	for _, q := range n.QueueConfigurations {
+		if q.Queue == "" {
+			return ErrMalformedXML
+		}
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, t := range n.TopicConfigurations {
+		if t.Topic == "" {
+			return ErrMalformedXML
+		}
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *NotificationRule) Validate() error {
+	if len(r.Events) == 0 {
+		return ErrMalformedXML
+	}
+	for _, event := range r.Events {
+		if !supportedNotificationEvents[event] {
+			return ErrInvalidNotificationEvent
+		}
+	}
+	if r.Filter == nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, rule := range r.Filter.S3Key.FilterRules {
+		name := strings.ToLower(rule.Name)
+		if name != "prefix" && name != "suffix" {
+			return ErrInvalidNotificationFilter
+		}
+		if names[name] {
+			return ErrInvalidNotificationFilter
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// ValidateDestinations checks the queue and topic ARNs in format
+// "arn:aws:sqs:<region>:<account>:<name>" and "arn:aws:sns:<region>:<account>:<name>",
+// events are delivered by the message queue plugin, so name must be the plugin name.
+// Region and account could be empty.
+func (n *NotificationConfiguration) ValidateDestinations(region string, name string) error {
+	for _, q := range n.QueueConfigurations {
+		if !validNotificationArn(q.Queue, "sqs", region, name) {
+			return ErrInvalidNotificationDestination
+		}
+	}
+	for _, t := range n.TopicConfigurations {
+		if !validNotificationArn(t.Topic, "sns", region, name) {
+			return ErrInvalidNotificationDestination
+		}
+	}
+	return nil
+}
+
+func validNotificationArn(arn string, service string, region string, name string) bool {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[1] != "aws" || parts[2] != service {
+		return false
+	}
+	if parts[3] != "" && parts[3] != region {
+		return false
+	}
+	return name != "" && parts[5] == name
+}
+
+// Match reports whether the event of object with key `objectName` should be sent
+func (r *NotificationRule) Match(eventName string, objectName string) bool {
+	if r.Filter != nil {
+		for _, rule := range r.Filter.S3Key.FilterRules {
+			switch strings.ToLower(rule.Name) {
+			case "prefix":
+				if !strings.HasPrefix(objectName, rule.Value) {
+					return false
+				}
+			case "suffix":
+				if !strings.HasSuffix(objectName, rule.Value) {
+					return false
+				}
+			}
+		}
+	}
+	for _, event := range r.Events {
+		if event == eventName {
+			return true
+		}
+		if strings.HasSuffix(event, "*") && strings.HasPrefix(eventName, strings.TrimSuffix(event, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchRules returns ids of configurations the event matches
+func (n *NotificationConfiguration) MatchRules(eventName string, objectName string) (ids []string) {
+	for _, q := range n.QueueConfigurations {
+		if q.Match(eventName, objectName) {
+			ids = append(ids, q.Id)
+		}
+	}
+	for _, t := range n.TopicConfigurations {
+		if t.Match(eventName, objectName) {
+			ids = append(ids, t.Id)
+		}
+	}
+	return
+}
+
+func (n *NotificationConfiguration) IsEmpty() bool {
+	return len(n.QueueConfigurations) == 0 && len(n.TopicConfigurations) == 0
+}
+
+func ParseNotificationConfig(reader io.Reader) (*NotificationConfiguration, error) {
+	config := new(NotificationConfiguration)
+	configBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read notification config body:", err)
+		return nil, err
+	}
+	if len(configBuffer) > MaxNotificationConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(configBuffer, config)
+	if err != nil {
+		helper.Logger.Error("Unable to parse notification config XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Event message in S3 format, see
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/notification-content-structure.html
+type Event struct {
+	Records []EventRecord `json:"Records"`
+}
+
+type EventRecord struct {
+	EventVersion      string            `json:"eventVersion"`
+	EventSource       string            `json:"eventSource"`
+	AwsRegion         string            `json:"awsRegion"`
+	EventTime         string            `json:"eventTime"`
+	EventName         string            `json:"eventName"`
+	UserIdentity      EventIdentity     `json:"userIdentity"`
+	RequestParameters map[string]string `json:"requestParameters"`
+	ResponseElements  map[string]string `json:"responseElements"`
+	S3                EventS3           `json:"s3"`
+}
+
+type EventIdentity struct {
+	PrincipalId string `json:"principalId"`
+}
+
+type EventS3 struct {
+	SchemaVersion   string      `json:"s3SchemaVersion"`
+	ConfigurationId string      `json:"configurationId"`
+	Bucket          EventBucket `json:"bucket"`
+	Object          EventObject `json:"object"`
+}
+
+type EventBucket struct {
+	Name          string        `json:"name"`
+	OwnerIdentity EventIdentity `json:"ownerIdentity"`
+	Arn           string        `json:"arn"`
+}
+
+type EventObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionId string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// NewEventRecord builds the record of event `eventName`, e.g. "s3:ObjectCreated:Put",
+// the "s3:" prefix is trimmed as S3 does.
+func NewEventRecord(eventName string, eventTime time.Time, region string,
+	bucketName string, bucketOwner string, object EventObject) EventRecord {
+
+	return EventRecord{
+		EventVersion: "2.1",
+		EventSource:  "aws:s3",
+		AwsRegion:    region,
+		EventTime:    eventTime.UTC().Format(time.RFC3339Nano),
+		EventName:    strings.TrimPrefix(eventName, "s3:"),
+		S3: EventS3{
+			SchemaVersion: "1.0",
+			Bucket: EventBucket{
+				Name:          bucketName,
+				OwnerIdentity: EventIdentity{PrincipalId: bucketOwner},
+				Arn:           "arn:aws:s3:::" + bucketName,
+			},
+			Object: object,
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package datatype
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var notificationConfigTests = []struct {
+	Body     string
+	Expected bool
+}{
+	{Body: `<NotificationConfiguration></NotificationConfiguration>`, Expected: true},                                                                                                                                                                                                                                                                         // 0
+	{Body: `<NotificationConfiguration><QueueConfiguration><Id>1</Id><Queue>arn:aws:sqs:r:1:q</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`, Expected: true},                                                                                                                                                     // 1
+	{Body: `<NotificationConfiguration><TopicConfiguration><Topic>arn:aws:sns:r:1:t</Topic><Event>s3:ObjectRemoved:Delete</Event><Event>s3:ObjectRestore:Post</Event></TopicConfiguration></NotificationConfiguration>`, Expected: true},                                                                                                                      // 2
+	{Body: `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:r:1:q</Queue></QueueConfiguration></NotificationConfiguration>`, Expected: false},                                                                                                                                                                                               // 3
+	{Body: `<NotificationConfiguration><QueueConfiguration><Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`, Expected: false},                                                                                                                                                                                              // 4
+	{Body: `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:r:1:q</Queue><Event>s3:ReducedRedundancyLostObject</Event></QueueConfiguration></NotificationConfiguration>`, Expected: false},                                                                                                                                                  // 5
+	{Body: `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:r:1:q</Queue><Event>s3:ObjectCreated:*</Event><Filter><S3Key><FilterRule><Name>prefix</Name><Value>log/</Value></FilterRule><FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule></S3Key></Filter></QueueConfiguration></NotificationConfiguration>`, Expected: true}, // 6
+	{Body: `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:r:1:q</Queue><Event>s3:ObjectCreated:*</Event><Filter><S3Key><FilterRule><Name>prefix</Name><Value>a</Value></FilterRule><FilterRule><Name>prefix</Name><Value>b</Value></FilterRule></S3Key></Filter></QueueConfiguration></NotificationConfiguration>`, Expected: false},      // 7
+	{Body: `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:r:1:q</Queue><Event>s3:ObjectCreated:*</Event><Filter><S3Key><FilterRule><Name>size</Name><Value>1</Value></FilterRule></S3Key></Filter></QueueConfiguration></NotificationConfiguration>`, Expected: false},                                                                    // 8
+}
+
+func TestParseNotificationConfig(t *testing.T) {
+	for i, test := range notificationConfigTests {
+		_, err := ParseNotificationConfig(strings.NewReader(test.Body))
+		if (err == nil) != test.Expected {
+			t.Errorf("Test %d: Wanted valid %v but got error %v", i, test.Expected, err)
+		}
+	}
+}
+
+func TestNotificationMatchRules(t *testing.T) {
+	config := NotificationConfiguration{
+		QueueConfigurations: []QueueConfiguration{
+			{NotificationRule: NotificationRule{Id: "created", Events: []string{ObjectCreatedAll}}, Queue: "q"},
+			{NotificationRule: NotificationRule{Id: "images", Events: []string{ObjectCreatedPut},
+				Filter: &NotificationFilter{S3Key: S3KeyFilter{FilterRules: []FilterRule{
+					{Name: "prefix", Value: "images/"}, {Name: "Suffix", Value: ".jpg"},
+				}}}}, Queue: "q"},
+		},
+		TopicConfigurations: []TopicConfiguration{
+			{NotificationRule: NotificationRule{Id: "removed", Events: []string{ObjectRemovedDelete}}, Topic: "t"},
+		},
+	}
+	var testcase = [...]struct {
+		event string
+		key   string
+		ids   string
+	}{
+		{ObjectCreatedPut, "a", "created"},
+		{ObjectCreatedCopy, "images/a.jpg", "created"},
+		{ObjectCreatedPut, "images/a.jpg", "created,images"},
+		{ObjectCreatedPut, "images/a.png", "created"},
+		{ObjectRemovedDelete, "a", "removed"},
+		{ObjectRemovedDeleteMarkerCreated, "a", ""},
+		{ObjectRestorePost, "a", ""},
+	}
+	for i, v := range testcase {
+		ids := strings.Join(config.MatchRules(v.event, v.key), ",")
+		if ids != v.ids {
+			t.Errorf("Test %d: expected %s, got %s\n", i, v.ids, ids)
+		}
+	}
+}
+
+func TestNotificationValidateDestinations(t *testing.T) {
+	var testcase = [...]struct {
+		queue string
+		topic string
+		valid bool
+	}{
+		{"arn:aws:sqs:cn-bj-1:1:kafka", "arn:aws:sns:cn-bj-1:1:kafka", true},
+		{"arn:aws:sqs:::kafka", "", true},
+		{"", "arn:aws:sns:::kafka", true},
+		{"arn:aws:sqs:cn-bj-1:1:webhook", "", false},
+		{"arn:aws:sqs:cn-bj-2:1:kafka", "", false},
+		{"arn:aws:sns:cn-bj-1:1:kafka", "", false},
+		{"", "arn:aws:sqs:cn-bj-1:1:kafka", false},
+		{"kafka", "", false},
+	}
+	for i, v := range testcase {
+		var config NotificationConfiguration
+		if v.queue != "" {
+			config.QueueConfigurations = []QueueConfiguration{{Queue: v.queue}}
+		}
+		if v.topic != "" {
+			config.TopicConfigurations = []TopicConfiguration{{Topic: v.topic}}
+		}
+		err := config.ValidateDestinations("cn-bj-1", "kafka")
+		if (err == nil) != v.valid {
+			t.Errorf("Test %d: Wanted valid %v but got error %v", i, v.valid, err)
+		}
+	}
+	var config NotificationConfiguration
+	config.QueueConfigurations = []QueueConfiguration{{Queue: "arn:aws:sqs:::"}}
+	if err := config.ValidateDestinations("cn-bj-1", ""); err == nil {
+		t.Errorf("Destination is valid without message queue plugin")
+	}
+}
+
+func TestNewEventRecord(t *testing.T) {
+	eventTime := time.Date(2019, 12, 20, 10, 30, 0, 0, time.UTC)
+	record := NewEventRecord(ObjectCreatedPut, eventTime, "cn-bj-1", "bucket", "owner",
+		EventObject{Key: "a", Size: 1, ETag: "etag"})
+	data, err := json.Marshal(Event{Records: []EventRecord{record}})
+	if err != nil {
+		t.Fatalf("Marshal event failed: %v", err)
+	}
+	var event map[string][]map[string]interface{}
+	err = json.Unmarshal(data, &event)
+	if err != nil || len(event["Records"]) != 1 {
+		t.Fatalf("Unmarshal event failed: %s %v", string(data), err)
+	}
+	r := event["Records"][0]
+	if r["eventName"] != "ObjectCreated:Put" || r["eventSource"] != "aws:s3" ||
+		r["eventTime"] != "2019-12-20T10:30:00Z" || r["awsRegion"] != "cn-bj-1" {
+		t.Errorf("Unexpected event record: %s\n", string(data))
+	}
+	s3 := r["s3"].(map[string]interface{})
+	if s3["bucket"].(map[string]interface{})["arn"] != "arn:aws:s3:::bucket" ||
+		s3["object"].(map[string]interface{})["key"] != "a" {
+		t.Errorf("Unexpected s3 entity: %s\n", string(data))
+	}
+}
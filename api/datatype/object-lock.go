@@ -0,0 +1,285 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	ObjectLockEnabled = "Enabled"
+
+	RetentionModeGovernance = "GOVERNANCE"
+	RetentionModeCompliance = "COMPLIANCE"
+
+	LegalHoldOn  = "ON"
+	LegalHoldOff = "OFF"
+
+	MaxObjectLockConfigurationSize = 20 * humanize.KiByte
+)
+
+// ObjectLockConfiguration is the bucket level object lock setting,
+// set by `x-amz-bucket-object-lock-enabled` header or `?object-lock` API
+type ObjectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	Xmlns             string          `xml:"xmlns,attr,omitempty"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled,omitempty"`
+	Rule              *ObjectLockRule `xml:"Rule,omitempty"`
+}
+
+type ObjectLockRule struct {
+	DefaultRetention DefaultRetention `xml:"DefaultRetention"`
+}
+
+type DefaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+type ObjectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	Xmlns           string   `xml:"xmlns,attr,omitempty"`
+	Mode            string   `xml:"Mode,omitempty"`
+	RetainUntilDate string   `xml:"RetainUntilDate,omitempty"`
+}
+
+type ObjectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status"`
+}
+
+// ObjectLock is the lock state of an object version
+type ObjectLock struct {
+	Mode            string
+	RetainUntilDate time.Time
+	LegalHold       bool
+}
+
+func (c *ObjectLockConfiguration) IsEnabled() bool {
+	return c.ObjectLockEnabled == ObjectLockEnabled
+}
+
+// Reference:https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLockConfiguration.html
+func (c *ObjectLockConfiguration) Validate() error {
+	if c.ObjectLockEnabled != ObjectLockEnabled {
+		return ErrMalformedXML
+	}
+	if c.Rule == nil {
+		return nil
+	}
+	retention := c.Rule.DefaultRetention
+	if !isValidRetentionMode(retention.Mode) {
+		return ErrMalformedXML
+	}
+	if (retention.Days == 0) == (retention.Years == 0) ||
+		retention.Days < 0 || retention.Years < 0 {
+		return ErrMalformedXML
+	}
+	return nil
+}
+
+// LockForNewObject returns the lock of a new object version created at `now`.
+// Retention specified by request headers takes precedence over the default
+// retention of bucket.
+func (c *ObjectLockConfiguration) LockForNewObject(lock ObjectLock, now time.Time) (ObjectLock, error) {
+	if !c.IsEnabled() {
+		if lock.IsSet() {
+			return lock, ErrInvalidBucketObjectLockConfiguration
+		}
+		return lock, nil
+	}
+	if lock.Mode == "" && c.Rule != nil {
+		retention := c.Rule.DefaultRetention
+		lock.Mode = retention.Mode
+		lock.RetainUntilDate = now.AddDate(retention.Years, 0, retention.Days).UTC()
+	}
+	return lock, nil
+}
+
+func ParseObjectLockConfig(reader io.Reader) (*ObjectLockConfiguration, error) {
+	config := new(ObjectLockConfiguration)
+	configBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read object lock config body:", err)
+		return nil, err
+	}
+	if len(configBuffer) > MaxObjectLockConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(configBuffer, config)
+	if err != nil {
+		helper.Logger.Error("Unable to parse object lock config XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func ParseObjectRetention(reader io.Reader) (lock ObjectLock, err error) {
+	retention := new(ObjectRetention)
+	retentionBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read retention body:", err)
+		return
+	}
+	if len(retentionBuffer) > MaxObjectLockConfigurationSize {
+		return lock, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(retentionBuffer, retention)
+	if err != nil {
+		helper.Logger.Error("Unable to parse retention XML body:", err)
+		return lock, ErrMalformedXML
+	}
+	// an empty retention removes the GOVERNANCE retention
+	if retention.Mode == "" && retention.RetainUntilDate == "" {
+		return lock, nil
+	}
+	if !isValidRetentionMode(retention.Mode) {
+		return lock, ErrMalformedXML
+	}
+	lock.Mode = retention.Mode
+	lock.RetainUntilDate, err = parseRetainUntilDate(retention.RetainUntilDate)
+	if err != nil {
+		return lock, ErrMalformedXML
+	}
+	if !lock.RetainUntilDate.After(time.Now()) {
+		return lock, ErrPastObjectLockRetainDate
+	}
+	return lock, nil
+}
+
+func ParseObjectLegalHold(reader io.Reader) (bool, error) {
+	legalHold := new(ObjectLegalHold)
+	legalHoldBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read legal hold body:", err)
+		return false, err
+	}
+	if len(legalHoldBuffer) > MaxObjectLockConfigurationSize {
+		return false, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(legalHoldBuffer, legalHold)
+	if err != nil {
+		helper.Logger.Error("Unable to parse legal hold XML body:", err)
+		return false, ErrMalformedXML
+	}
+	switch legalHold.Status {
+	case LegalHoldOn:
+		return true, nil
+	case LegalHoldOff:
+		return false, nil
+	default:
+		return false, ErrMalformedXML
+	}
+}
+
+// ParseObjectLockHeaders parses `x-amz-object-lock-mode`, `x-amz-object-lock-retain-until-date`
+// and `x-amz-object-lock-legal-hold` headers of PUT object requests.
+// Mode and date must be supplied together.
+func ParseObjectLockHeaders(header http.Header) (lock ObjectLock, err error) {
+	mode := header.Get("X-Amz-Object-Lock-Mode")
+	date := header.Get("X-Amz-Object-Lock-Retain-Until-Date")
+	legalHold := header.Get("X-Amz-Object-Lock-Legal-Hold")
+	if (mode == "") != (date == "") {
+		return lock, ErrInvalidObjectLockHeaders
+	}
+	if mode != "" {
+		if !isValidRetentionMode(mode) {
+			return lock, ErrInvalidObjectLockHeaders
+		}
+		lock.Mode = mode
+		lock.RetainUntilDate, err = parseRetainUntilDate(date)
+		if err != nil {
+			return lock, ErrInvalidObjectLockHeaders
+		}
+		if !lock.RetainUntilDate.After(time.Now()) {
+			return lock, ErrPastObjectLockRetainDate
+		}
+	}
+	switch legalHold {
+	case "":
+	case LegalHoldOn:
+		lock.LegalHold = true
+	case LegalHoldOff:
+		lock.LegalHold = false
+	default:
+		return lock, ErrInvalidObjectLockHeaders
+	}
+	return lock, nil
+}
+
+// IsSet reports whether any retention or legal hold is specified
+func (l ObjectLock) IsSet() bool {
+	return l.Mode != "" || l.LegalHold
+}
+
+// IsLocked reports whether the object version is protected from deletion
+// or overwrite. GOVERNANCE retention could be bypassed with
+// `x-amz-bypass-governance-retention` header and corresponding permission.
+func (l ObjectLock) IsLocked(bypassGovernance bool) bool {
+	if l.LegalHold {
+		return true
+	}
+	if l.Mode == "" || !l.RetainUntilDate.After(time.Now()) {
+		return false
+	}
+	return l.Mode == RetentionModeCompliance || !bypassGovernance
+}
+
+// CanChangeRetention reports whether the retention of object version could be
+// replaced by `retention`. COMPLIANCE retention could only be extended.
+func (l ObjectLock) CanChangeRetention(retention ObjectLock, bypassGovernance bool) bool {
+	if l.Mode == "" || !l.RetainUntilDate.After(time.Now()) {
+		return true
+	}
+	switch l.Mode {
+	case RetentionModeCompliance:
+		return retention.Mode == RetentionModeCompliance &&
+			!retention.RetainUntilDate.Before(l.RetainUntilDate)
+	default:
+		if bypassGovernance {
+			return true
+		}
+		return retention.Mode != "" && !retention.RetainUntilDate.Before(l.RetainUntilDate)
+	}
+}
+
+func (l ObjectLock) ToRetention() ObjectRetention {
+	retention := ObjectRetention{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	if l.Mode != "" {
+		retention.Mode = l.Mode
+		retention.RetainUntilDate = l.RetainUntilDate.UTC().Format(time.RFC3339)
+	}
+	return retention
+}
+
+func (l ObjectLock) ToLegalHold() ObjectLegalHold {
+	return ObjectLegalHold{
+		Xmlns:  "http://s3.amazonaws.com/doc/2006-03-01/",
+		Status: helper.Ternary(l.LegalHold, LegalHoldOn, LegalHoldOff).(string),
+	}
+}
+
+func isValidRetentionMode(mode string) bool {
+	return mode == RetentionModeGovernance || mode == RetentionModeCompliance
+}
+
+func parseRetainUntilDate(date string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(date))
+	if err != nil {
+		return t, err
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,152 @@
+package datatype
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var objectLockConfigTests = []struct {
+	Body     string
+	Expected bool
+}{
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`, Expected: true},                                                                                                         // 0
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Disabled</ObjectLockEnabled></ObjectLockConfiguration>`, Expected: false},                                                                                                       // 1
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: true},                  // 2
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: true},                // 3
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: false}, // 4
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: false},                               // 5
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>governance</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: false},                 // 6
+	{Body: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>-1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, Expected: false},                // 7
+}
+
+func TestParseObjectLockConfig(t *testing.T) {
+	for i, test := range objectLockConfigTests {
+		_, err := ParseObjectLockConfig(strings.NewReader(test.Body))
+		if (err == nil) != test.Expected {
+			t.Errorf("Test %d: Wanted valid %v but got error %v", i, test.Expected, err)
+		}
+	}
+}
+
+func TestParseObjectLockHeaders(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	var testcase = [...]struct {
+		mode      string
+		date      string
+		legalHold string
+		ok        bool
+	}{
+		{"", "", "", true},
+		{"GOVERNANCE", future, "", true},
+		{"COMPLIANCE", future, "ON", true},
+		{"", "", "OFF", true},
+		{"GOVERNANCE", "", "", false},
+		{"", future, "", false},
+		{"GOVERNANCE", past, "", false},
+		{"LOCKED", future, "", false},
+		{"GOVERNANCE", "tomorrow", "", false},
+		{"", "", "on", false},
+	}
+	for i, v := range testcase {
+		header := http.Header{}
+		if v.mode != "" {
+			header.Set("X-Amz-Object-Lock-Mode", v.mode)
+		}
+		if v.date != "" {
+			header.Set("X-Amz-Object-Lock-Retain-Until-Date", v.date)
+		}
+		if v.legalHold != "" {
+			header.Set("X-Amz-Object-Lock-Legal-Hold", v.legalHold)
+		}
+		lock, err := ParseObjectLockHeaders(header)
+		if (err == nil) != v.ok {
+			t.Errorf("Test %d: expected ok %v, got error %v\n", i, v.ok, err)
+			continue
+		}
+		if err == nil && (lock.Mode != v.mode || lock.LegalHold != (v.legalHold == LegalHoldOn)) {
+			t.Errorf("Test %d: unexpected lock %+v\n", i, lock)
+		}
+	}
+}
+
+func TestObjectLockIsLocked(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	var testcase = [...]struct {
+		lock             ObjectLock
+		bypassGovernance bool
+		locked           bool
+	}{
+		{ObjectLock{}, false, false},
+		{ObjectLock{LegalHold: true}, true, true},
+		{ObjectLock{Mode: RetentionModeGovernance, RetainUntilDate: future}, false, true},
+		{ObjectLock{Mode: RetentionModeGovernance, RetainUntilDate: future}, true, false},
+		{ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: future}, true, true},
+		{ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: past}, false, false},
+	}
+	for i, v := range testcase {
+		if locked := v.lock.IsLocked(v.bypassGovernance); locked != v.locked {
+			t.Errorf("Test %d: expected locked %v, got %v\n", i, v.locked, locked)
+		}
+	}
+}
+
+func TestObjectLockCanChangeRetention(t *testing.T) {
+	now := time.Now()
+	governance := ObjectLock{Mode: RetentionModeGovernance, RetainUntilDate: now.Add(time.Hour)}
+	compliance := ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: now.Add(time.Hour)}
+	var testcase = [...]struct {
+		lock             ObjectLock
+		retention        ObjectLock
+		bypassGovernance bool
+		ok               bool
+	}{
+		{ObjectLock{}, compliance, false, true},
+		{governance, ObjectLock{Mode: RetentionModeGovernance, RetainUntilDate: now.Add(2 * time.Hour)}, false, true},
+		{governance, ObjectLock{Mode: RetentionModeGovernance, RetainUntilDate: now.Add(time.Minute)}, false, false},
+		{governance, ObjectLock{}, false, false},
+		{governance, ObjectLock{}, true, true},
+		{governance, compliance, false, true},
+		{compliance, ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: now.Add(2 * time.Hour)}, false, true},
+		{compliance, ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: now.Add(time.Minute)}, true, false},
+		{compliance, governance, true, false},
+		{compliance, ObjectLock{}, true, false},
+	}
+	for i, v := range testcase {
+		if ok := v.lock.CanChangeRetention(v.retention, v.bypassGovernance); ok != v.ok {
+			t.Errorf("Test %d: expected %v, got %v\n", i, v.ok, ok)
+		}
+	}
+}
+
+func TestLockForNewObject(t *testing.T) {
+	now := time.Date(2019, 12, 20, 10, 30, 0, 0, time.UTC)
+	config := ObjectLockConfiguration{
+		ObjectLockEnabled: ObjectLockEnabled,
+		Rule: &ObjectLockRule{
+			DefaultRetention: DefaultRetention{Mode: RetentionModeGovernance, Days: 30},
+		},
+	}
+	lock, err := config.LockForNewObject(ObjectLock{LegalHold: true}, now)
+	if err != nil || lock.Mode != RetentionModeGovernance || !lock.LegalHold ||
+		!lock.RetainUntilDate.Equal(now.AddDate(0, 0, 30)) {
+		t.Errorf("Default retention expected, got %+v, err %v\n", lock, err)
+	}
+
+	specified := ObjectLock{Mode: RetentionModeCompliance, RetainUntilDate: now.AddDate(1, 0, 0)}
+	lock, err = config.LockForNewObject(specified, now)
+	if err != nil || lock != specified {
+		t.Errorf("Specified retention expected, got %+v, err %v\n", lock, err)
+	}
+
+	var disabled ObjectLockConfiguration
+	if _, err = disabled.LockForNewObject(specified, now); err == nil {
+		t.Errorf("Object lock on bucket without object lock configuration, expected error\n")
+	}
+	if lock, err = disabled.LockForNewObject(ObjectLock{}, now); err != nil || lock.IsSet() {
+		t.Errorf("No object lock expected, got %+v, err %v\n", lock, err)
+	}
+}
@@ -83,6 +83,31 @@ const (
 
 	// PutObjectAction - PutObject Rest API action.
 	PutObjectAction = "s3:PutObject"
+
+	// PutObjectRetentionAction - PutObjectRetention Rest API action.
+	PutObjectRetentionAction = "s3:PutObjectRetention"
+
+	// GetObjectRetentionAction - GetObjectRetention Rest API action.
+	GetObjectRetentionAction = "s3:GetObjectRetention"
+
+	// PutObjectLegalHoldAction - PutObjectLegalHold Rest API action.
+	PutObjectLegalHoldAction = "s3:PutObjectLegalHold"
+
+	// GetObjectLegalHoldAction - GetObjectLegalHold Rest API action.
+	GetObjectLegalHoldAction = "s3:GetObjectLegalHold"
+
+	// PutObjectTaggingAction - PutObjectTagging Rest API action.
+	PutObjectTaggingAction = "s3:PutObjectTagging"
+
+	// GetObjectTaggingAction - GetObjectTagging Rest API action.
+	GetObjectTaggingAction = "s3:GetObjectTagging"
+
+	// DeleteObjectTaggingAction - DeleteObjectTagging Rest API action.
+	DeleteObjectTaggingAction = "s3:DeleteObjectTagging"
+
+	// BypassGovernanceRetentionAction - allows deleting objects or changing retention
+	// protected by GOVERNANCE mode, with `x-amz-bypass-governance-retention` header.
+	BypassGovernanceRetentionAction = "s3:BypassGovernanceRetention"
 )
 
 // isObjectAction - returns whether action is object type or not.
@@ -91,6 +116,12 @@ func (action Action) isObjectAction() bool {
 	case AbortMultipartUploadAction, DeleteObjectAction, GetObjectAction:
 		fallthrough
 	case ListMultipartUploadPartsAction, PutObjectAction:
+		fallthrough
+	case PutObjectRetentionAction, GetObjectRetentionAction:
+		fallthrough
+	case PutObjectLegalHoldAction, GetObjectLegalHoldAction, BypassGovernanceRetentionAction:
+		fallthrough
+	case PutObjectTaggingAction, GetObjectTaggingAction, DeleteObjectTaggingAction:
 		return true
 	}
 
@@ -113,6 +144,12 @@ func (action Action) IsValid() bool {
 	case ListMultipartUploadPartsAction, PutBucketNotificationAction:
 		fallthrough
 	case PutBucketPolicyAction, PutObjectAction:
+		fallthrough
+	case PutObjectRetentionAction, GetObjectRetentionAction:
+		fallthrough
+	case PutObjectLegalHoldAction, GetObjectLegalHoldAction, BypassGovernanceRetentionAction:
+		fallthrough
+	case PutObjectTaggingAction, GetObjectTaggingAction, DeleteObjectTaggingAction:
 		return true
 	}
 
@@ -253,4 +290,44 @@ var actionConditionKeyMap = map[Action]condition.KeySet{
 		condition.AWSReferer,
 		condition.AWSSourceIP,
 	),
+
+	PutObjectRetentionAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectRetentionAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	PutObjectLegalHoldAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectLegalHoldAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	BypassGovernanceRetentionAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	PutObjectTaggingAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectTaggingAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	DeleteObjectTaggingAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
 }
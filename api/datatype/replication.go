@@ -0,0 +1,208 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxReplicationConfigurationSize = 20 * humanize.KiByte
+	MaxReplicationRules             = 1000
+
+	ReplicationRuleEnabled  = "Enabled"
+	ReplicationRuleDisabled = "Disabled"
+
+	// replication status of objects, returned in `x-amz-replication-status` header
+	ReplicationStatusPending   = "PENDING"
+	ReplicationStatusCompleted = "COMPLETED"
+	ReplicationStatusFailed    = "FAILED"
+
+	bucketArnPrefix = "arn:aws:s3:::"
+)
+
+// ReplicationConfiguration is the bucket replication setting,
+// new object versions matched by rules are copied to destination bucket
+// on the remote endpoint by tools/replication
+type ReplicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Xmlns   string            `xml:"xmlns,attr,omitempty"`
+	Role    string            `xml:"Role,omitempty"`
+	Rules   []ReplicationRule `xml:"Rule"`
+}
+
+type ReplicationRule struct {
+	ID          string                 `xml:"ID,omitempty"`
+	Priority    int                    `xml:"Priority,omitempty"`
+	Status      string                 `xml:"Status"`
+	Prefix      string                 `xml:"Prefix,omitempty"`
+	Filter      *ReplicationFilter     `xml:"Filter,omitempty"`
+	Destination ReplicationDestination `xml:"Destination"`
+}
+
+type ReplicationFilter struct {
+	Prefix string                `xml:"Prefix,omitempty"`
+	Tag    *Tag                  `xml:"Tag,omitempty"`
+	And    *ReplicationFilterAnd `xml:"And,omitempty"`
+}
+
+type ReplicationFilterAnd struct {
+	Prefix string `xml:"Prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag,omitempty"`
+}
+
+type ReplicationDestination struct {
+	// in format "arn:aws:s3:::bucketname"
+	Bucket       string `xml:"Bucket"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+func (c *ReplicationConfiguration) IsEmpty() bool {
+	return len(c.Rules) == 0
+}
+
+// Reference:https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
+func (c *ReplicationConfiguration) Validate() error {
+	if len(c.Rules) == 0 || len(c.Rules) > MaxReplicationRules {
+		return ErrMalformedXML
+	}
+	ids := make(map[string]bool)
+	priorities := make(map[int]bool)
+	for _, rule := range c.Rules {
+		if rule.ID != "" {
+			if ids[rule.ID] {
+				return ErrMalformedXML
+			}
+			ids[rule.ID] = true
+		}
+		if rule.Filter != nil {
+			if priorities[rule.Priority] {
+				return ErrMalformedXML
+			}
+			priorities[rule.Priority] = true
+		}
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ReplicationRule) Validate() error {
+	if r.Status != ReplicationRuleEnabled && r.Status != ReplicationRuleDisabled {
+		return ErrMalformedXML
+	}
+	if r.Prefix != "" && r.Filter != nil {
+		return ErrMalformedXML
+	}
+	if r.DestinationBucket() == "" {
+		return ErrMalformedXML
+	}
+	switch r.Destination.StorageClass {
+	case "", "STANDARD", "STANDARD_IA", "GLACIER":
+	default:
+		return ErrInvalidStorageClass
+	}
+	if r.Filter == nil {
+		return nil
+	}
+	f := r.Filter
+	conditions := 0
+	for _, set := range []bool{f.Prefix != "", f.Tag != nil, f.And != nil} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions > 1 {
+		return ErrMalformedXML
+	}
+	if f.Tag != nil {
+		if err := validateTag(f.Tag.Key, f.Tag.Value); err != nil {
+			return err
+		}
+	}
+	if f.And != nil {
+		keys := make(map[string]bool)
+		for _, tag := range f.And.Tags {
+			if err := validateTag(tag.Key, tag.Value); err != nil {
+				return err
+			}
+			if keys[tag.Key] {
+				return ErrInvalidTag
+			}
+			keys[tag.Key] = true
+		}
+	}
+	return nil
+}
+
+// DestinationBucket returns the bucket name in destination ARN, empty if malformed
+func (r *ReplicationRule) DestinationBucket() string {
+	if !strings.HasPrefix(r.Destination.Bucket, bucketArnPrefix) {
+		return ""
+	}
+	name := strings.TrimPrefix(r.Destination.Bucket, bucketArnPrefix)
+	if strings.Contains(name, "/") {
+		return ""
+	}
+	return name
+}
+
+func (r *ReplicationRule) Match(objectName string, tags map[string]string) bool {
+	if r.Status != ReplicationRuleEnabled {
+		return false
+	}
+	if r.Filter == nil {
+		return strings.HasPrefix(objectName, r.Prefix)
+	}
+	if r.Filter.And != nil {
+		return matchConditions(r.Filter.And.Prefix, r.Filter.And.Tags, 0, 0, objectName, -1, tags)
+	}
+	var filterTags []Tag
+	if r.Filter.Tag != nil {
+		filterTags = []Tag{*r.Filter.Tag}
+	}
+	return matchConditions(r.Filter.Prefix, filterTags, 0, 0, objectName, -1, tags)
+}
+
+// MatchRule returns the enabled rule for object, rule with higher priority wins
+// if more than one rules match.
+func (c *ReplicationConfiguration) MatchRule(objectName string, tags map[string]string) (rule ReplicationRule, ok bool) {
+	for _, r := range c.Rules {
+		if !r.Match(objectName, tags) {
+			continue
+		}
+		if !ok || r.Priority > rule.Priority {
+			rule = r
+			ok = true
+		}
+	}
+	return
+}
+
+func ParseReplicationConfig(reader io.Reader) (*ReplicationConfiguration, error) {
+	config := new(ReplicationConfiguration)
+	configBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read replication config body:", err)
+		return nil, err
+	}
+	if len(configBuffer) > MaxReplicationConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(configBuffer, config)
+	if err != nil {
+		helper.Logger.Error("Unable to parse replication config XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
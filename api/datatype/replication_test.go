@@ -0,0 +1,71 @@
+package datatype
+
+import (
+	"strings"
+	"testing"
+)
+
+var replicationConfigTests = []struct {
+	Body     string
+	Expected bool
+}{
+	{Body: `<ReplicationConfiguration></ReplicationConfiguration>`, Expected: false},                                                                                                                                                                                                                                                                       // 0
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Prefix>log/</Prefix><Destination><Bucket>arn:aws:s3:::dest</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: true},                                                                                                                                               // 1
+	{Body: `<ReplicationConfiguration><Rule><Status>On</Status><Destination><Bucket>arn:aws:s3:::dest</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                                                                                                                                         // 2
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Destination><Bucket>dest</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                                                                                                                                                  // 3
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::dest</Bucket><StorageClass>REDUCED_REDUNDANCY</StorageClass></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                                                                                      // 4
+	{Body: `<ReplicationConfiguration><Rule><ID>1</ID><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::a</Bucket></Destination></Rule><Rule><ID>1</ID><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::b</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                    // 5
+	{Body: `<ReplicationConfiguration><Rule><Priority>1</Priority><Status>Enabled</Status><Filter><Prefix>a</Prefix></Filter><Destination><Bucket>arn:aws:s3:::a</Bucket></Destination></Rule><Rule><Priority>1</Priority><Status>Enabled</Status><Filter><Prefix>b</Prefix></Filter><Destination><Bucket>arn:aws:s3:::b</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false}, // 6
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Filter><And><Prefix>a</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></And></Filter><Destination><Bucket>arn:aws:s3:::dest</Bucket><StorageClass>STANDARD_IA</StorageClass></Destination></Rule></ReplicationConfiguration>`, Expected: true},                                         // 7
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>a</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></Filter><Destination><Bucket>arn:aws:s3:::dest</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                                                          // 8
+	{Body: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Prefix>a</Prefix><Filter><Prefix>a</Prefix></Filter><Destination><Bucket>arn:aws:s3:::dest</Bucket></Destination></Rule></ReplicationConfiguration>`, Expected: false},                                                                                                               // 9
+}
+
+func TestParseReplicationConfig(t *testing.T) {
+	for i, test := range replicationConfigTests {
+		_, err := ParseReplicationConfig(strings.NewReader(test.Body))
+		if (err == nil) != test.Expected {
+			t.Errorf("Test %d: Wanted valid %v but got error %v", i, test.Expected, err)
+		}
+	}
+}
+
+func TestReplicationMatchRule(t *testing.T) {
+	config := ReplicationConfiguration{
+		Rules: []ReplicationRule{
+			{ID: "all", Priority: 1, Status: ReplicationRuleEnabled, Filter: &ReplicationFilter{},
+				Destination: ReplicationDestination{Bucket: "arn:aws:s3:::all"}},
+			{ID: "logs", Priority: 2, Status: ReplicationRuleEnabled, Filter: &ReplicationFilter{Prefix: "log/"},
+				Destination: ReplicationDestination{Bucket: "arn:aws:s3:::logs"}},
+			{ID: "tagged", Priority: 3, Status: ReplicationRuleEnabled,
+				Filter: &ReplicationFilter{And: &ReplicationFilterAnd{Prefix: "log/", Tags: []Tag{{Key: "k", Value: "v"}}}},
+				Destination: ReplicationDestination{Bucket: "arn:aws:s3:::tagged"}},
+			{ID: "disabled", Priority: 4, Status: ReplicationRuleDisabled, Filter: &ReplicationFilter{},
+				Destination: ReplicationDestination{Bucket: "arn:aws:s3:::disabled"}},
+		},
+	}
+	var testcase = [...]struct {
+		name string
+		tags map[string]string
+		id   string
+	}{
+		{"a", nil, "all"},
+		{"log/a", nil, "logs"},
+		{"log/a", map[string]string{"k": "v"}, "tagged"},
+		{"log/a", map[string]string{"k": "x"}, "logs"},
+	}
+	for i, v := range testcase {
+		rule, ok := config.MatchRule(v.name, v.tags)
+		if !ok || rule.ID != v.id {
+			t.Errorf("Test %d: Expected rule %s, got %s", i, v.id, rule.ID)
+		}
+	}
+	if rule, _ := config.MatchRule("log/a", nil); rule.DestinationBucket() != "logs" {
+		t.Errorf("Expected destination bucket logs, got %s", rule.DestinationBucket())
+	}
+
+	config.Rules = config.Rules[1:2]
+	if _, ok := config.MatchRule("a", nil); ok {
+		t.Errorf("Expected no rule matches")
+	}
+}
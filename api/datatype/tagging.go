@@ -0,0 +1,129 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxObjectTagsCount          = 10
+	MaxBucketTagsCount          = 50
+	MaxTagKeyLength             = 128
+	MaxTagValueLength           = 256
+	MaxTaggingConfigurationSize = 20 * humanize.KiByte
+)
+
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  TagSet   `xml:"TagSet"`
+}
+
+type TagSet struct {
+	Tags []Tag `xml:"Tag"`
+}
+
+type Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// Reference:https://docs.aws.amazon.com/AmazonS3/latest/dev/object-tagging.html
+func (t *Tagging) Validate(maxTagsCount int) error {
+	if len(t.TagSet.Tags) > maxTagsCount {
+		return ErrTooManyTags
+	}
+	keys := make(map[string]bool)
+	for _, tag := range t.TagSet.Tags {
+		if err := validateTag(tag.Key, tag.Value); err != nil {
+			return err
+		}
+		if keys[tag.Key] {
+			return ErrInvalidTag
+		}
+		keys[tag.Key] = true
+	}
+	return nil
+}
+
+func (t *Tagging) ToMap() map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range t.TagSet.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+func validateTag(key, value string) error {
+	if key == "" || utf8.RuneCountInString(key) > MaxTagKeyLength {
+		return ErrInvalidTag
+	}
+	if utf8.RuneCountInString(value) > MaxTagValueLength {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+func TaggingFromMap(tags map[string]string) Tagging {
+	tagging := Tagging{Xmlns: "http://s3.amazonaws.com/doc/2006-03-01/"}
+	tagging.TagSet.Tags = make([]Tag, 0, len(tags))
+	for k, v := range tags {
+		tagging.TagSet.Tags = append(tagging.TagSet.Tags, Tag{Key: k, Value: v})
+	}
+	return tagging
+}
+
+func ParseTagging(reader io.Reader, maxTagsCount int) (map[string]string, error) {
+	tagging := new(Tagging)
+	taggingBuffer, err := ioutil.ReadAll(reader)
+	if err != nil {
+		helper.Logger.Error("Unable to read tagging body:", err)
+		return nil, err
+	}
+	if len(taggingBuffer) > MaxTaggingConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(taggingBuffer, tagging)
+	if err != nil {
+		helper.Logger.Error("Unable to parse tagging XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = tagging.Validate(maxTagsCount)
+	if err != nil {
+		return nil, err
+	}
+	return tagging.ToMap(), nil
+}
+
+// ParseTaggingHeader parses the URL query encoded "x-amz-tagging" header,
+// e.g. "key1=value1&key2=value2"
+func ParseTaggingHeader(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, ErrInvalidTag
+	}
+	if len(values) > MaxObjectTagsCount {
+		return nil, ErrTooManyTags
+	}
+	tags := make(map[string]string)
+	for key, value := range values {
+		if len(value) != 1 {
+			return nil, ErrInvalidTag
+		}
+		if err := validateTag(key, value[0]); err != nil {
+			return nil, err
+		}
+		tags[key] = value[0]
+	}
+	return tags, nil
+}
@@ -170,9 +170,6 @@ func ignoreNotImplementedObjectResources(req *http.Request) bool {
 
 // List of not implemented bucket queries
 var notImplementedBucketResourceNames = map[string]bool{
-	"notification":   true,
-	"replication":    true,
-	"tagging":        true,
 	"requestPayment": true,
 }
 
@@ -19,6 +19,7 @@ package api
 import (
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
@@ -91,15 +92,14 @@ func extractMetadataFromHeader(header http.Header) map[string]string {
 
 func parseSseHeader(header http.Header) (request SseRequest, err error) {
 	// sse three options are mutually exclusive
-	if crypto.S3.IsRequested(header) && crypto.SSEC.IsRequested(header) {
+	if (crypto.S3.IsRequested(header) || crypto.S3KMS.IsRequested(header)) && crypto.SSEC.IsRequested(header) {
 		return request, ErrIncompatibleEncryptionMethod
 	}
 
 	if sse := header.Get(crypto.SSEHeader); sse != "" {
 		switch sse {
 		case crypto.SSEAlgorithmKMS:
-			err = ErrNotImplemented
-			return request, err
+			request.Type = crypto.S3KMS.String()
 		case crypto.SSEAlgorithmAES256:
 			request.Type = crypto.S3.String()
 		default:
@@ -117,26 +117,15 @@ func parseSseHeader(header http.Header) (request SseRequest, err error) {
 		}
 	}
 
-	switch request.Type {
-	case crypto.S3KMS.String():
-		// Not implemented yet
-		return request, ErrNotImplemented
-	case crypto.S3.String():
-		// encrypt key will retrieve from kms now
-		return request, nil
-	case crypto.SSEC.String():
-		// validate ssec header
-		key, err := crypto.SSEC.ParseHTTP(header)
-		if err != nil {
-			return request, err
-		}
-		request.SseCustomerAlgorithm = crypto.SSEAlgorithmAES256
-		request.SseCustomerKey = key[:]
-		return request, nil
+	// key ID and encryption context are only for SSE-KMS
+	if request.Type != crypto.S3KMS.String() &&
+		(header.Get(crypto.SSEKmsID) != "" || header.Get(crypto.SSEKmsContext) != "") {
+		return request, ErrInvalidSseHeader
 	}
 
-	// SSECCopy not support now.
-	if sse := header.Get(crypto.SSECopyAlgorithm); sse != "" {
+	// customer key to decrypt the source of copy, which is independent of encryption of the target
+	if crypto.SSECopy.IsRequested(header) {
+		sse := header.Get(crypto.SSECopyAlgorithm)
 		if sse != crypto.SSEAlgorithmAES256 {
 			err = ErrInvalidSseHeader
 			return
@@ -170,9 +159,51 @@ func parseSseHeader(header http.Header) (request SseRequest, err error) {
 			return
 		}
 	}
+
+	switch request.Type {
+	case crypto.S3KMS.String():
+		// default key of KMS is used if key ID is not specified
+		request.SseAwsKmsKeyId = header.Get(crypto.SSEKmsID)
+		if request.SseAwsKmsKeyId != "" && !crypto.IsValidKeyID(request.SseAwsKmsKeyId) {
+			return request, ErrInvalidSseHeader
+		}
+		request.SseContext, err = parseSseContext(header.Get(crypto.SSEKmsContext))
+		return request, err
+	case crypto.S3.String():
+		// encrypt key will retrieve from kms now
+		return request, nil
+	case crypto.SSEC.String():
+		// validate ssec header
+		key, err := crypto.SSEC.ParseHTTP(header)
+		if err != nil {
+			return request, err
+		}
+		request.SseCustomerAlgorithm = crypto.SSEAlgorithmAES256
+		request.SseCustomerKey = key[:]
+		return request, nil
+	}
+
 	return
 }
 
+// parseSseContext validates encryption context of SSE-KMS, which is base64 encoded JSON
+// of string pairs, and returns the JSON decoded
+func parseSseContext(encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSseHeader
+	}
+	var context crypto.Context
+	err = json.Unmarshal(decoded, &context)
+	if err != nil {
+		return "", ErrInvalidSseHeader
+	}
+	return string(decoded), nil
+}
+
 // Suffix matcher string matches suffix in a platform specific way.
 // For example on windows since its case insensitive we are supposed
 // to do case insensitive checks.
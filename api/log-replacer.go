@@ -16,6 +16,7 @@ package api
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -29,7 +30,7 @@ const (
 		"{object_size} {requester_id} {project_id} {remote_addr} {http_x_real_ip} {request_length} {server_cost} " +
 		"{request_time} {http_status} {error_code} {body_bytes_sent} {http_referer} {http_user_agent}"
 
-	BillingLogFormat = "{is_private_subnet} {storage_class} {target_storage_class} {bucket_logging} {cdn_request}"
+	BillingLogFormat = "{is_private_subnet} {storage_class} {target_storage_class} {bucket_logging} {cdn_request} {bucket_tags}"
 )
 
 // Replacer is a type which can replace placeholder
@@ -285,6 +286,16 @@ func (r *replacer) getSubstitution(key string) string {
 			}
 		}
 		return strconv.FormatBool(false)
+	case "{bucket_tags}":
+		bucketInfo := getRequestContext(r.request).BucketInfo
+		if bucketInfo == nil || len(bucketInfo.Tags) == 0 {
+			return "-"
+		}
+		tags := url.Values{}
+		for k, v := range bucketInfo.Tags {
+			tags.Set(k, v)
+		}
+		return tags.Encode()
 	case "{cdn_request}":
 		// TODO: change to go plugin
 		var judgeFunc JudgeCdnRequest
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam/common"
+	bus "github.com/journeymidnight/yig/mq"
+)
+
+// sendObjectEvent sends the S3 format event of object to message queue
+// for each notification configuration of bucket it matches.
+func sendObjectEvent(r *http.Request, credential common.Credential,
+	eventName string, object datatype.EventObject) {
+
+	ctx := getRequestContext(r)
+	bucket := ctx.BucketInfo
+	if bucket == nil || bucket.Notification.IsEmpty() {
+		return
+	}
+	ids := bucket.Notification.MatchRules(eventName, object.Key)
+	if len(ids) == 0 {
+		return
+	}
+
+	now := time.Now()
+	object.Sequencer = fmt.Sprintf("%016X", now.UnixNano())
+	record := datatype.NewEventRecord(eventName, now, helper.CONFIG.Region,
+		bucket.Name, bucket.OwnerId, object)
+	record.UserIdentity.PrincipalId = credential.UserId
+	record.RequestParameters = map[string]string{"sourceIPAddress": GetSourceIP(r)}
+	record.ResponseElements = map[string]string{"x-amz-request-id": ctx.RequestID}
+	for _, id := range ids {
+		record.S3.ConfigurationId = id
+		val, err := json.Marshal(datatype.Event{Records: []datatype.EventRecord{record}})
+		if err != nil {
+			ctx.Logger.Error("Failed to marshal event", eventName, "of", object.Key, "err:", err)
+			return
+		}
+		err = bus.MsgSender.AsyncSend(val)
+		if err != nil {
+			ctx.Logger.Error(
+				fmt.Sprintf("Failed to send event [%s] to message queue, err: %v",
+					string(val), err))
+		}
+	}
+}
@@ -17,8 +17,10 @@
 package api
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/xml"
+	"fmt"
 	"github.com/gorilla/mux"
 	. "github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/api/datatype/policy"
@@ -56,6 +58,16 @@ func setGetRespHeaders(w http.ResponseWriter, reqParams url.Values) {
 	}
 }
 
+// setRestoreHeader sets x-amz-restore of GLACIER objects requested to restore
+func setRestoreHeader(w http.ResponseWriter, freezer *meta.Freezer) {
+	if freezer.Status == meta.ObjectHasRestored {
+		w.Header().Set("x-amz-restore", fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`,
+			freezer.ExpiryDate().UTC().Format(http.TimeFormat)))
+	} else {
+		w.Header().Set("x-amz-restore", `ongoing-request="true"`)
+	}
+}
+
 func getStorageClassFromHeader(r *http.Request) (meta.StorageClass, error) {
 	storageClassStr := r.Header.Get("X-Amz-Storage-Class")
 
@@ -142,6 +154,54 @@ func (api ObjectAPIHandlers) errAllowableObjectNotFound(w http.ResponseWriter, r
 	WriteErrorResponse(w, r, err)
 }
 
+// applyBucketEncryption sets default encryption of bucket to sseRequest if no encryption is requested
+func (api ObjectAPIHandlers) applyBucketEncryption(sseRequest *SseRequest, bucketName string) {
+	if sseRequest.Type != "" {
+		return
+	}
+	configuration, ok := api.ObjectAPI.CheckBucketEncryption(bucketName)
+	if !ok {
+		return
+	}
+	switch configuration.SSEAlgorithm {
+	case crypto.SSEAlgorithmAES256:
+		sseRequest.Type = crypto.S3.String()
+	case crypto.SSEAlgorithmKMS:
+		sseRequest.Type = crypto.S3KMS.String()
+		sseRequest.SseAwsKmsKeyId = configuration.KMSMasterKeyID
+	}
+}
+
+// checkCopySourceEncryption validates the customer key to decrypt the source of copy,
+// which is required if and only if the source object is encrypted with SSE-C
+func checkCopySourceEncryption(sseRequest SseRequest, sourceObject *meta.Object) error {
+	if sourceObject.SseType == crypto.SSEC.String() {
+		if len(sseRequest.CopySourceSseCustomerKey) == 0 {
+			return ErrSSEEncryptedObject
+		}
+		return nil
+	}
+	if len(sseRequest.CopySourceSseCustomerKey) != 0 {
+		return ErrInvalidSseHeader
+	}
+	return nil
+}
+
+// isEncryptionChanged returns true if the target of copy is encrypted differently from the source,
+// so the data should be encrypted again
+func isEncryptionChanged(sseRequest SseRequest, sourceObject *meta.Object) bool {
+	if sseRequest.Type != sourceObject.SseType {
+		return true
+	}
+	switch sseRequest.Type {
+	case crypto.SSEC.String():
+		return !bytes.Equal(sseRequest.SseCustomerKey, sseRequest.CopySourceSseCustomerKey)
+	case crypto.S3KMS.String():
+		return sseRequest.SseAwsKmsKeyId != "" && sseRequest.SseAwsKmsKeyId != sourceObject.SseKmsKeyId
+	}
+	return false
+}
+
 type GetObjectResponseWriter struct {
 	dataWritten bool
 	w           http.ResponseWriter
@@ -234,6 +294,7 @@ func (api ObjectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 			WriteErrorResponse(w, r, ErrInvalidGlacierObject)
 			return
 		}
+		setRestoreHeader(w, freezer)
 		object.Etag = freezer.Etag
 		object.Size = freezer.Size
 		object.Parts = freezer.Parts
@@ -293,6 +354,10 @@ func (api ObjectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		length = hrange.GetLength()
 	}
 
+	if version == "" {
+		SetExpirationHeader(w, ctx.BucketInfo, object)
+	}
+
 	// io.Writer type which keeps track if any data was written.
 	writer := newGetObjectResponseWriter(w, r, object, hrange, http.StatusOK, version)
 
@@ -301,7 +366,7 @@ func (api ObjectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		break
 	case crypto.S3KMS.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
-		// TODO: not implemented yet
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", object.SseKmsKeyId)
 	case crypto.S3.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
 	case crypto.SSEC.String():
@@ -361,16 +426,15 @@ func (api ObjectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	if object.StorageClass == meta.ObjectStorageClassGlacier {
-		freezer, err := api.ObjectAPI.GetFreezerStatus(object.BucketName, object.Name, version)
+		freezer, err := api.ObjectAPI.GetFreezer(object.BucketName, object.Name, version)
 		if err != nil && err != ErrNoSuchKey {
 			logger.Error("Unable to get restore object status", object.BucketName, object.Name, version,
 				"error:", err)
 			WriteErrorResponse(w, r, err)
+			return
 		}
-		if freezer.Status == meta.ObjectHasRestored {
-			w.Header().Set("x-amz-restore", "ongoing-request='true'")
-		} else {
-			w.Header().Set("x-amz-restore", "ongoing-request='false'")
+		if err == nil {
+			setRestoreHeader(w, freezer)
 		}
 	}
 
@@ -423,7 +487,7 @@ func (api ObjectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 		break
 	case crypto.S3KMS.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
-		// TODO not implemented yet
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", object.SseKmsKeyId)
 	case crypto.S3.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
 	case crypto.SSEC.String():
@@ -432,6 +496,10 @@ func (api ObjectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 			r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
 	}
 
+	if version == "" {
+		SetExpirationHeader(w, ctx.BucketInfo, object)
+	}
+
 	//ResponseRecorder
 	w.(*ResponseRecorder).operationName = "HeadObject"
 
@@ -518,16 +586,15 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		WriteErrorResponse(w, r, err)
 		return
 	}
-	if sseRequest.Type == "" {
-		if configuration, ok := api.ObjectAPI.CheckBucketEncryption(targetBucketName); ok {
-			if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 {
-				sseRequest.Type = crypto.S3.String()
-			}
-			//TODO:add kms
-		}
+	if err = checkCopySourceEncryption(sseRequest, sourceObject); err != nil {
+		WriteErrorResponseWithResource(w, r, err, copySource)
+		return
 	}
-	if sseRequest.Type == "" {
+	api.applyBucketEncryption(&sseRequest, targetBucketName)
+	// customer key is not kept, so copy of SSE-C object is encrypted only if requested
+	if sseRequest.Type == "" && sourceObject.SseType != crypto.SSEC.String() {
 		sseRequest.Type = sourceObject.SseType
+		sseRequest.SseAwsKmsKeyId = sourceObject.SseKmsKeyId
 	}
 
 	// Verify before x-amz-copy-source preconditions before continuing with CopyObject.
@@ -637,11 +704,33 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	taggingDirective := r.Header.Get("X-Amz-Tagging-Directive")
+	if taggingDirective == "COPY" || taggingDirective == "" {
+		targetObject.Tags = sourceObject.Tags
+	} else if taggingDirective == "REPLACE" {
+		targetObject.Tags, err = ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+		if err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		WriteErrorResponse(w, r, ErrInvalidCopyRequest)
+		return
+	}
+
+	targetObject.ObjectLock, err = ParseObjectLockHeaders(r.Header)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	var isMetadataOnly bool
 	isMetadataOnly = false
 	if sourceBucketName == targetBucketName && sourceObjectName == targetObjectName {
 		if sourceObject.StorageClass != meta.ObjectStorageClassGlacier && targetObject.StorageClass == meta.ObjectStorageClassGlacier {
 			isMetadataOnly = false
+		} else if isEncryptionChanged(sseRequest, sourceObject) {
+			isMetadataOnly = false
 		} else {
 			isMetadataOnly = true
 		}
@@ -678,6 +767,21 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 			w.Header().Set(headerName, header)
 		}
 	}
+	if sseRequest.Type == crypto.S3.String() {
+		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
+	}
+	// key ID is set by default if not requested
+	if result.SseAwsKmsKeyId != "" {
+		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", result.SseAwsKmsKeyId)
+	}
+
+	sendObjectEvent(r, credential, ObjectCreatedCopy, EventObject{
+		Key:       targetObjectName,
+		Size:      sourceObject.Size,
+		ETag:      result.Md5,
+		VersionId: result.VersionId,
+	})
 
 	//ResponseRecorder
 	w.(*ResponseRecorder).operationName = "CopyObject"
@@ -868,7 +972,7 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Parse SSE related headers
-	// Support SSE-S3 and SSE-C now
+	// Support SSE-S3, SSE-KMS and SSE-C now
 	var sseRequest SseRequest
 
 	if hasServerSideEncryptionHeader(r.Header) && !hasSuffix(objectName, "/") { // handle SSE requests
@@ -877,11 +981,8 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			WriteErrorResponse(w, r, err)
 			return
 		}
-	} else if configuration, ok := api.ObjectAPI.CheckBucketEncryption(bucketName); ok {
-		if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 {
-			sseRequest.Type = crypto.S3.String()
-		}
-		//TODO:add kms
+	} else {
+		api.applyBucketEncryption(&sseRequest, bucketName)
 	}
 
 	acl, err := getAclFromHeader(r.Header)
@@ -890,6 +991,18 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	tags, err := ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	objectLock, err := ParseObjectLockHeaders(r.Header)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	credential, dataReadCloser, err := signature.VerifyUpload(r)
 	if err != nil {
 		WriteErrorResponse(w, r, err)
@@ -898,7 +1011,7 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 
 	var result PutObjectResult
 	result, err = api.ObjectAPI.PutObject(bucketName, objectName, credential, size, dataReadCloser,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, tags, objectLock)
 	if err != nil {
 		logger.Error("Unable to create object", objectName, "error:", err)
 		WriteErrorResponse(w, r, err)
@@ -911,6 +1024,12 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	if result.VersionId != "" {
 		w.Header().Set("x-amz-version-id", result.VersionId)
 	}
+	SetExpirationHeader(w, getRequestContext(r).BucketInfo, &meta.Object{
+		Name:             objectName,
+		Size:             result.Size,
+		Tags:             tags,
+		LastModifiedTime: result.LastModified,
+	})
 	// Set SSE related headers
 	for _, headerName := range []string{
 		"X-Amz-Server-Side-Encryption",
@@ -922,6 +1041,18 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			w.Header().Set(headerName, header)
 		}
 	}
+	// key ID is set by default if not requested
+	if result.SseAwsKmsKeyId != "" {
+		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", result.SseAwsKmsKeyId)
+	}
+
+	sendObjectEvent(r, credential, ObjectCreatedPut, EventObject{
+		Key:       objectName,
+		Size:      size,
+		ETag:      result.Md5,
+		VersionId: result.VersionId,
+	})
 
 	// ResponseRecorder
 	w.(*ResponseRecorder).operationName = "PutObject"
@@ -951,7 +1082,7 @@ func (api ObjectAPIHandlers) AppendObjectHandler(w http.ResponseWriter, r *http.
 
 	pos := r.URL.Query().Get("position")
 	// Parse SSE related headers
-	// Support SSE-S3 and SSE-C now
+	// Support SSE-S3 and SSE-KMS now
 	var sseRequest SseRequest
 	var position uint64
 	var acl Acl
@@ -1087,6 +1218,14 @@ func (api ObjectAPIHandlers) AppendObjectHandler(w http.ResponseWriter, r *http.
 			WriteErrorResponse(w, r, ErrNotImplemented)
 			return
 		}
+	} else if objInfo == nil {
+		api.applyBucketEncryption(&sseRequest, bucketName)
+	}
+	// data appended is encrypted the same way as the object
+	if objInfo != nil && sseRequest.Type != "" && (sseRequest.Type != objInfo.SseType ||
+		(sseRequest.SseAwsKmsKeyId != "" && sseRequest.SseAwsKmsKeyId != objInfo.SseKmsKeyId)) {
+		WriteErrorResponse(w, r, ErrInvalidSseHeader)
+		return
 	}
 
 	var result AppendObjectResult
@@ -1106,12 +1245,12 @@ func (api ObjectAPIHandlers) AppendObjectHandler(w http.ResponseWriter, r *http.
 	}
 
 	// Set SSE related headers
-	for _, headerName := range []string{
-		"X-Amz-Server-Side-Encryption",
-	} {
-		if header := r.Header.Get(headerName); header != "" {
-			w.Header().Set(headerName, header)
-		}
+	switch result.SseType {
+	case crypto.S3KMS.String():
+		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", result.SseAwsKmsKeyId)
+	case crypto.S3.String():
+		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
 	}
 
 	// Set next position
@@ -1306,14 +1445,22 @@ func (api ObjectAPIHandlers) RestoreObjectHandler(w http.ResponseWriter, r *http
 		if err != nil {
 			logger.Error("Unable to create freezer:", err)
 			WriteErrorResponse(w, r, ErrCreateRestoreObject)
+			return
 		}
 		logger.Info("Submit thaw request successfully")
+		sendObjectEvent(r, credential, ObjectRestorePost, EventObject{
+			Key:       object.Name,
+			Size:      object.Size,
+			ETag:      object.Etag,
+			VersionId: object.VersionId,
+		})
 
 		// ResponseRecorder
 		w.WriteHeader(http.StatusAccepted)
 		w.(*ResponseRecorder).operationName = "RestoreObject"
 
 		WriteSuccessResponseWithStatus(w, nil, http.StatusAccepted)
+		return
 	}
 	if freezer.Status == meta.ObjectHasRestored {
 		err = api.ObjectAPI.UpdateFreezerDate(freezer, info.Days, true)
@@ -1389,6 +1536,281 @@ func (api ObjectAPIHandlers) GetObjectAclHandler(w http.ResponseWriter, r *http.
 	WriteSuccessResponse(w, aclBuffer)
 }
 
+func (api ObjectAPIHandlers) PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.PutObjectTaggingAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	if r.ContentLength > MaxTaggingConfigurationSize {
+		WriteErrorResponse(w, r, ErrEntityTooLarge)
+		return
+	}
+
+	tags, err := ParseTagging(io.LimitReader(r.Body, r.ContentLength), MaxObjectTagsCount)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.SetObjectTagging(ctx.BucketName, ctx.ObjectName, version, tags, credential)
+	if err != nil {
+		logger.Error("Unable to set tagging for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectTagging"
+
+	WriteSuccessResponse(w, nil)
+}
+
+func (api ObjectAPIHandlers) GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.GetObjectTaggingAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	tags, err := api.ObjectAPI.GetObjectTagging(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to fetch object tagging:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	taggingBuffer, err := xmlFormat(TaggingFromMap(tags))
+	if err != nil {
+		logger.Error("Failed to marshal tagging XML for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	setXmlHeader(w)
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectTagging"
+	WriteSuccessResponse(w, taggingBuffer)
+}
+
+func (api ObjectAPIHandlers) DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.DeleteObjectTaggingAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.DeleteObjectTagging(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to delete tagging for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "DeleteObjectTagging"
+
+	WriteSuccessNoContent(w)
+}
+
+func (api ObjectAPIHandlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.PutObjectRetentionAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	retention, err := ParseObjectRetention(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	bypassGovernance, err := isBypassGovernanceAllowed(r, credential, ctx.BucketInfo, ctx.ObjectName)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.SetObjectRetention(ctx.BucketName, ctx.ObjectName, version, retention,
+		bypassGovernance, credential)
+	if err != nil {
+		logger.Error("Unable to set retention for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectRetention"
+
+	WriteSuccessResponse(w, nil)
+}
+
+func (api ObjectAPIHandlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.GetObjectRetentionAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	retention, err := api.ObjectAPI.GetObjectRetention(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to fetch object retention:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	retentionBuffer, err := xmlFormat(retention.ToRetention())
+	if err != nil {
+		logger.Error("Failed to marshal retention XML for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	setXmlHeader(w)
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectRetention"
+	WriteSuccessResponse(w, retentionBuffer)
+}
+
+func (api ObjectAPIHandlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.PutObjectLegalHoldAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	legalHold, err := ParseObjectLegalHold(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.SetObjectLegalHold(ctx.BucketName, ctx.ObjectName, version, legalHold, credential)
+	if err != nil {
+		logger.Error("Unable to set legal hold for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectLegalHold"
+
+	WriteSuccessResponse(w, nil)
+}
+
+func (api ObjectAPIHandlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	if credential, err = checkRequestAuth(r, policy.GetObjectLegalHoldAction); err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	legalHold, err := api.ObjectAPI.GetObjectLegalHold(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to fetch object legal hold:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	legalHoldBuffer, err := xmlFormat(ObjectLock{LegalHold: legalHold}.ToLegalHold())
+	if err != nil {
+		logger.Error("Failed to marshal legal hold XML for object", ctx.ObjectName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	setXmlHeader(w)
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectLegalHold"
+	WriteSuccessResponse(w, legalHoldBuffer)
+}
+
 // Multipart objectAPIHandlers
 
 // NewMultipartUploadHandler - New multipart upload
@@ -1436,11 +1858,8 @@ func (api ObjectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 			WriteErrorResponse(w, r, err)
 			return
 		}
-	} else if configuration, ok := api.ObjectAPI.CheckBucketEncryption(bucketName); ok {
-		if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 {
-			sseRequest.Type = crypto.S3.String()
-		}
-		//TODO:add kms
+	} else {
+		api.applyBucketEncryption(&sseRequest, bucketName)
 	}
 
 	storageClass, err := getStorageClassFromHeader(r)
@@ -1449,8 +1868,20 @@ func (api ObjectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 		return
 	}
 
+	tags, err := ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	objectLock, err := ParseObjectLockHeaders(r.Header)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	uploadID, err := api.ObjectAPI.NewMultipartUpload(credential, bucketName, objectName,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, tags, objectLock)
 	if err != nil {
 		logger.Error("Unable to initiate new multipart upload id:", err)
 		WriteErrorResponse(w, r, err)
@@ -1569,7 +2000,7 @@ func (api ObjectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 	case crypto.S3KMS.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
 		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
-			result.SseAwsKmsKeyIdBase64)
+			result.SseAwsKmsKeyId)
 	case crypto.S3.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
 	case crypto.SSEC.String():
@@ -1709,6 +2140,10 @@ func (api ObjectAPIHandlers) CopyObjectPartHandler(w http.ResponseWriter, r *htt
 		WriteErrorResponseWithResource(w, r, err, copySource)
 		return
 	}
+	if err = checkCopySourceEncryption(sseRequest, sourceObject); err != nil {
+		WriteErrorResponseWithResource(w, r, err, copySource)
+		return
+	}
 
 	// Verify before x-amz-copy-source preconditions before continuing with CopyObject.
 	if err = checkObjectPreconditions(w, r, sourceObject); err != nil {
@@ -1972,7 +2407,7 @@ func (api ObjectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 	case crypto.S3KMS.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
 		w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
-			result.SseAwsKmsKeyIdBase64)
+			result.SseAwsKmsKeyId)
 	case crypto.S3.String():
 		w.Header().Set("X-Amz-Server-Side-Encryption", "AES256")
 	case crypto.SSEC.String():
@@ -1983,6 +2418,12 @@ func (api ObjectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 
 	setXmlHeader(w)
 
+	sendObjectEvent(r, credential, ObjectCreatedCompleteMultipartUpload, EventObject{
+		Key:       objectName,
+		ETag:      result.ETag,
+		VersionId: result.VersionId,
+	})
+
 	//ResponseRecorder
 	w.(*ResponseRecorder).operationName = "CompleteMultipartUpload"
 	// write success response.
@@ -2013,10 +2454,16 @@ func (api ObjectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 			return
 		}
 	}
+	bypassGovernance, err := isBypassGovernanceAllowed(r, credential,
+		getRequestContext(r).BucketInfo, objectName)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
 	version := r.URL.Query().Get("versionId")
 	// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETE.html
 	// Ignore delete object errors, since we are supposed to reply only 204.
-	result, err := api.ObjectAPI.DeleteObject(bucketName, objectName, version, credential)
+	result, err := api.ObjectAPI.DeleteObject(bucketName, objectName, version, credential, bypassGovernance)
 	if err != nil {
 		WriteErrorResponse(w, r, err)
 		return
@@ -2029,6 +2476,9 @@ func (api ObjectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 	if result.VersionId != "" {
 		w.Header().Set("x-amz-version-id", result.VersionId)
 	}
+	sendObjectEvent(r, credential,
+		helper.Ternary(result.DeleteMarker, ObjectRemovedDeleteMarkerCreated, ObjectRemovedDelete).(string),
+		EventObject{Key: objectName, VersionId: result.VersionId})
 	// ResponseRecorder
 	w.(*ResponseRecorder).operationName = "DeleteObject"
 	WriteSuccessNoContent(w)
@@ -2127,14 +2577,7 @@ func (api ObjectAPIHandlers) PostObjectHandler(w http.ResponseWriter, r *http.Re
 		WriteErrorResponse(w, r, err)
 		return
 	}
-	if sseRequest.Type == "" {
-		if configuration, ok := api.ObjectAPI.CheckBucketEncryption(bucketName); ok {
-			if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 {
-				sseRequest.Type = crypto.S3.String()
-			}
-			//TODO:add kms
-		}
-	}
+	api.applyBucketEncryption(&sseRequest, bucketName)
 
 	storageClass, err := getStorageClassFromHeader(r)
 	if err != nil {
@@ -2143,7 +2586,7 @@ func (api ObjectAPIHandlers) PostObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	result, err := api.ObjectAPI.PutObject(bucketName, objectName, credential, -1, fileBody,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, nil, ObjectLock{})
 	if err != nil {
 		logger.Error("Unable to create object", objectName, "error:", err)
 		WriteErrorResponse(w, r, err)
@@ -2152,6 +2595,11 @@ func (api ObjectAPIHandlers) PostObjectHandler(w http.ResponseWriter, r *http.Re
 	if result.Md5 != "" {
 		w.Header().Set("ETag", "\""+result.Md5+"\"")
 	}
+	sendObjectEvent(r, credential, ObjectCreatedPost, EventObject{
+		Key:       objectName,
+		ETag:      result.Md5,
+		VersionId: result.VersionId,
+	})
 
 	var redirect string
 	redirect, _ = formValues["Success_action_redirect"]
@@ -28,7 +28,7 @@ import (
 // ObjectLayer implements primitives for object API layer.
 type ObjectLayer interface {
 	// Bucket operations.
-	MakeBucket(bucket string, acl datatype.Acl, credential common.Credential) error
+	MakeBucket(bucket string, acl datatype.Acl, credential common.Credential, objectLockEnabled bool) error
 	SetBucketLogging(bucket string, config datatype.BucketLoggingStatus) error
 	GetBucketLogging(bucket string) (datatype.BucketLoggingStatus, error)
 	SetBucketLifecycle(bucket string, config datatype.Lifecycle,
@@ -69,6 +69,24 @@ type ObjectLayer interface {
 	DeleteBucketEncryption(bucket *meta.Bucket) error
 	CheckBucketEncryption(bucket string) (*datatype.ApplyServerSideEncryptionByDefault, bool)
 
+	// Object lock operations
+	SetBucketObjectLock(bucket *meta.Bucket, config datatype.ObjectLockConfiguration) error
+	GetBucketObjectLock(bucket string) (datatype.ObjectLockConfiguration, error)
+
+	// Notification operations
+	SetBucketNotification(bucket *meta.Bucket, config datatype.NotificationConfiguration) error
+	GetBucketNotification(bucket string) (datatype.NotificationConfiguration, error)
+
+	// Replication operations
+	SetBucketReplication(bucket *meta.Bucket, config datatype.ReplicationConfiguration) error
+	GetBucketReplication(bucket string) (datatype.ReplicationConfiguration, error)
+	DeleteBucketReplication(bucket *meta.Bucket) error
+
+	// Tagging operations
+	SetBucketTagging(bucket *meta.Bucket, tags map[string]string) error
+	GetBucketTagging(bucket string) (map[string]string, error)
+	DeleteBucketTagging(bucket *meta.Bucket) error
+
 	// Object operations.
 	GetObject(object *meta.Object, startOffset int64, length int64, writer io.Writer,
 		sse datatype.SseRequest) (err error)
@@ -76,7 +94,8 @@ type ObjectLayer interface {
 	GetObjectInfoByCtx(ctx RequestContext, version string, credential common.Credential) (objInfo *meta.Object, err error)
 	PutObject(bucket, object string, credential common.Credential, size int64, data io.ReadCloser,
 		metadata map[string]string, acl datatype.Acl,
-		sse datatype.SseRequest, storageClass meta.StorageClass) (result datatype.PutObjectResult, err error)
+		sse datatype.SseRequest, storageClass meta.StorageClass,
+		tags map[string]string, objectLock datatype.ObjectLock) (result datatype.PutObjectResult, err error)
 	AppendObject(bucket, object string, credential common.Credential, offset uint64, size int64, data io.ReadCloser,
 		metadata map[string]string, acl datatype.Acl,
 		sse datatype.SseRequest, storageClass meta.StorageClass, objInfo *meta.Object) (result datatype.AppendObjectResult, err error)
@@ -89,15 +108,29 @@ type ObjectLayer interface {
 		acl datatype.Acl, credential common.Credential) error
 	GetObjectAcl(bucket string, object string, version string, credential common.Credential) (
 		policy datatype.AccessControlPolicyResponse, err error)
-	DeleteObject(bucket, object, version string, credential common.Credential) (datatype.DeleteObjectResult,
-		error)
+	SetObjectTagging(bucket string, object string, version string, tags map[string]string,
+		credential common.Credential) error
+	GetObjectTagging(bucket string, object string, version string, credential common.Credential) (
+		tags map[string]string, err error)
+	DeleteObjectTagging(bucket string, object string, version string, credential common.Credential) error
+	SetObjectRetention(bucket string, object string, version string, retention datatype.ObjectLock,
+		bypassGovernance bool, credential common.Credential) error
+	GetObjectRetention(bucket string, object string, version string, credential common.Credential) (
+		retention datatype.ObjectLock, err error)
+	SetObjectLegalHold(bucket string, object string, version string, legalHold bool,
+		credential common.Credential) error
+	GetObjectLegalHold(bucket string, object string, version string, credential common.Credential) (
+		legalHold bool, err error)
+	DeleteObject(bucket, object, version string, credential common.Credential,
+		bypassGovernance bool) (datatype.DeleteObjectResult, error)
 
 	// Multipart operations.
 	ListMultipartUploads(credential common.Credential, bucket string,
 		request datatype.ListUploadsRequest) (result datatype.ListMultipartUploadsResponse, err error)
 	NewMultipartUpload(credential common.Credential, bucket, object string,
 		metadata map[string]string, acl datatype.Acl,
-		sse datatype.SseRequest, storageClass meta.StorageClass) (uploadID string, err error)
+		sse datatype.SseRequest, storageClass meta.StorageClass,
+		tags map[string]string, objectLock datatype.ObjectLock) (uploadID string, err error)
 	PutObjectPart(bucket, object string, credential common.Credential, uploadID string, partID int,
 		size int64, data io.ReadCloser, md5Hex string,
 		sse datatype.SseRequest) (result datatype.PutObjectPartResult, err error)
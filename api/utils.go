@@ -133,5 +133,5 @@ func setXmlHeader(w http.ResponseWriter) {
 // hasServerSideEncryptionHeader returns true if the given HTTP header
 // contains server-side-encryption.
 func hasServerSideEncryptionHeader(header http.Header) bool {
-	return crypto.S3.IsRequested(header) || crypto.SSEC.IsRequested(header)
+	return crypto.S3.IsRequested(header) || crypto.S3KMS.IsRequested(header) || crypto.SSEC.IsRequested(header)
 }
@@ -34,6 +34,13 @@ type Cluster interface {
 	Remove(poolName, objectName string) error
 }
 
+// Clusters which could list objects in a pool implement Lister,
+// tools/fsck uses it to find objects not referenced by metadata
+type Lister interface {
+	// call fn with name of every object in pool, stop on the first error of fn
+	List(poolName string, fn func(objectName string) error) error
+}
+
 // Backend plugins should implement this interface
 type Plugin interface {
 	// initialize backend cluster handlers,
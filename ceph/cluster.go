@@ -51,6 +51,7 @@ type CephCluster struct {
 	Name       string
 	Conn       RadosConn
 	InstanceId uint64
+	ConfigFile string
 	counter    uint64
 }
 
@@ -87,6 +88,7 @@ func NewCephStorage(configFile string) *CephCluster {
 		Conn:       radosConn{conn},
 		Name:       name,
 		InstanceId: id,
+		ConfigFile: configFile,
 	}
 
 	helper.Logger.Info("Ceph Cluster", name, "is ready, InstanceId is", name, id)
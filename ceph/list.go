@@ -0,0 +1,64 @@
+package ceph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/journeymidnight/yig/backend"
+)
+
+var (
+	// name of objects put by yig, see getUniqUploadName
+	oidPattern = regexp.MustCompile(`^[0-9]+:[0-9]+$`)
+	// libradosstriper saves object oid in RADOS objects oid.<index in 16 hex digits>
+	firstStripeSuffix = fmt.Sprintf(".%016x", 0)
+)
+
+// List calls fn with every object put by yig in the pool.
+// radoshttpd has no binding to list objects, so it runs `rados ls` with the config file of cluster,
+// which must be installed where List is called.
+func (cluster *CephCluster) List(poolName string, fn func(objectName string) error) error {
+	cmd := exec.Command("rados", "-c", cluster.ConfigFile, "-p", poolName, "ls")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	err = listObjects(stdout, poolName, fn)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+// listObjects calls fn with objects put by yig in output of `rados ls`,
+// a striped object is listed once by its first RADOS object
+func listObjects(r io.Reader, poolName string, fn func(objectName string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		oid := scanner.Text()
+		if poolName != backend.SMALL_FILE_POOLNAME {
+			if !strings.HasSuffix(oid, firstStripeSuffix) {
+				continue
+			}
+			oid = strings.TrimSuffix(oid, firstStripeSuffix)
+		}
+		if !oidPattern.MatchString(oid) {
+			continue
+		}
+		err := fn(oid)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,208 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeyRewrapper is implemented by KMS whose master keys could be rotated.
+// RewrapKey seals the data key in sealedKey again under the newest master key,
+// the data key itself is unchanged, so objects encrypted by it are untouched.
+// rewrapped is false if sealedKey is sealed by the newest master key already.
+type KeyRewrapper interface {
+	RewrapKey(keyID string, sealedKey []byte, context Context) (newSealedKey []byte, rewrapped bool, err error)
+}
+
+// MasterKey is a version of master keys in Keyring, the ID is stored along with every key it seals
+type MasterKey struct {
+	ID  string
+	Key [32]byte
+}
+
+// ParseMasterKey parses master key in the form of `<ID>:<base64 of 32 bytes>`
+func ParseMasterKey(s string) (masterKey MasterKey, err error) {
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return masterKey, errors.New("master key should be in the form of <ID>:<base64 of 32 bytes>")
+	}
+	masterKey.ID = s[:i]
+	key, err := base64.StdEncoding.DecodeString(s[i+1:])
+	if err != nil {
+		return masterKey, fmt.Errorf("master key %s is not base64 encoded: %v", masterKey.ID, err)
+	}
+	if len(key) != len(masterKey.Key) {
+		return masterKey, fmt.Errorf("master key %s has %d bytes, expected %d", masterKey.ID, len(key), len(masterKey.Key))
+	}
+	copy(masterKey.Key[:], key)
+	return masterKey, nil
+}
+
+// Keyring is a KMS with versions of master keys configured locally.
+// Data keys are sealed by the newest master key with AES-256-GCM, binding the key ID and context:
+//
+//	sealedKey := len(ID) || ID || nonce || AES-256-GCM(MasterKey[ID], nonce, dataKey, ID || keyID || context)
+//
+// Older master keys are kept to unseal keys sealed before rotation, until they are rewrapped,
+// see KeyRewrapper and tools/rekey.
+//
+// Keys sealed by another KMS, e.g. Vault before switching to Keyring, have no master key ID
+// of Keyring, they're unsealed by the fallback KMS if it's set, and rewrapped under the newest
+// master key like keys sealed by older ones. Without fallback, such keys could not be unsealed.
+type Keyring struct {
+	keyID    string
+	keys     map[string][32]byte
+	current  string
+	fallback KMS
+}
+
+// NewKeyring returns Keyring with master keys from the oldest to the newest,
+// keyID is returned by GetKeyID as the default key
+func NewKeyring(keyID string, masterKeys ...MasterKey) (*Keyring, error) {
+	if len(masterKeys) == 0 {
+		return nil, errors.New("no master key in keyring")
+	}
+	k := &Keyring{
+		keyID: keyID,
+		keys:  make(map[string][32]byte),
+	}
+	for _, masterKey := range masterKeys {
+		if masterKey.ID == "" || len(masterKey.ID) > 255 {
+			return nil, fmt.Errorf("invalid master key ID %q", masterKey.ID)
+		}
+		if _, ok := k.keys[masterKey.ID]; ok {
+			return nil, fmt.Errorf("duplicated master key ID %q", masterKey.ID)
+		}
+		k.keys[masterKey.ID] = masterKey.Key
+		k.current = masterKey.ID
+	}
+	return k, nil
+}
+
+// SetFallback sets the KMS to unseal keys not sealed by Keyring, which is the KMS used before
+func (k *Keyring) SetFallback(fallback KMS) {
+	k.fallback = fallback
+}
+
+func (k *Keyring) GetKeyID() string {
+	return k.keyID
+}
+
+func (k *Keyring) GenerateKey(keyID string, context Context) (key [32]byte, sealedKey []byte, err error) {
+	if _, err = io.ReadFull(rand.Reader, key[:]); err != nil {
+		return key, nil, errOutOfEntropy
+	}
+	sealedKey, err = k.seal(k.current, key, keyID, context)
+	return key, sealedKey, err
+}
+
+func (k *Keyring) UnsealKey(keyID string, sealedKey []byte, context Context) (key [32]byte, err error) {
+	if k.isFallback(sealedKey) {
+		return k.fallback.UnsealKey(keyID, sealedKey, context)
+	}
+	id, nonce, ciphertext, err := parseSealedKey(sealedKey)
+	if err != nil {
+		return
+	}
+	aead, err := k.aead(id)
+	if err != nil {
+		return
+	}
+	additionalData, err := sealingData(id, keyID, context)
+	if err != nil {
+		return
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil || len(plaintext) != len(key) {
+		return key, ErrSecretKeyMismatch
+	}
+	copy(key[:], plaintext)
+	return key, nil
+}
+
+func (k *Keyring) RewrapKey(keyID string, sealedKey []byte, context Context) (newSealedKey []byte, rewrapped bool, err error) {
+	if !k.isFallback(sealedKey) {
+		id, _, _, err := parseSealedKey(sealedKey)
+		if err != nil {
+			return nil, false, err
+		}
+		if id == k.current {
+			return sealedKey, false, nil
+		}
+	}
+	key, err := k.UnsealKey(keyID, sealedKey, context)
+	if err != nil {
+		return
+	}
+	newSealedKey, err = k.seal(k.current, key, keyID, context)
+	return newSealedKey, err == nil, err
+}
+
+func (k *Keyring) seal(id string, key [32]byte, keyID string, context Context) ([]byte, error) {
+	aead, err := k.aead(id)
+	if err != nil {
+		return nil, err
+	}
+	additionalData, err := sealingData(id, keyID, context)
+	if err != nil {
+		return nil, err
+	}
+	sealedKey := make([]byte, 1+len(id)+aead.NonceSize(), 1+len(id)+aead.NonceSize()+len(key)+aead.Overhead())
+	sealedKey[0] = byte(len(id))
+	copy(sealedKey[1:], id)
+	nonce := sealedKey[1+len(id):]
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errOutOfEntropy
+	}
+	return aead.Seal(sealedKey, nonce, key[:], additionalData), nil
+}
+
+func (k *Keyring) aead(id string) (cipher.AEAD, error) {
+	masterKey, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("master key %q is not in keyring", id)
+	}
+	block, err := aes.NewCipher(masterKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isFallback reports whether sealedKey is not sealed by Keyring and should be unsealed by fallback
+func (k *Keyring) isFallback(sealedKey []byte) bool {
+	if k.fallback == nil {
+		return false
+	}
+	id, _, _, err := parseSealedKey(sealedKey)
+	if err != nil {
+		return true
+	}
+	_, ok := k.keys[id]
+	return !ok
+}
+
+// parseSealedKey splits sealedKey into the master key ID, nonce and ciphertext
+func parseSealedKey(sealedKey []byte) (id string, nonce, ciphertext []byte, err error) {
+	// nonce size of GCM is 12 bytes
+	if len(sealedKey) == 0 || len(sealedKey) < 1+int(sealedKey[0])+12 {
+		return "", nil, nil, errInvalidSealedKey
+	}
+	n := 1 + int(sealedKey[0])
+	return string(sealedKey[1:n]), sealedKey[n : n+12], sealedKey[n+12:], nil
+}
+
+// sealingData is the additional data of AES-256-GCM, which binds the master key ID, key ID and context
+func sealingData(id, keyID string, context Context) ([]byte, error) {
+	var encodedContext strings.Builder
+	if _, err := context.WriteTo(&encodedContext); err != nil {
+		return nil, err
+	}
+	return json.Marshal([]string{id, keyID, encodedContext.String()})
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func masterKey(t *testing.T, id string, b byte) MasterKey {
+	k, err := ParseMasterKey(id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)))
+	if err != nil {
+		t.Fatal("ParseMasterKey error:", err)
+	}
+	return k
+}
+
+func TestParseMasterKey(t *testing.T) {
+	for _, s := range []string{"", "no-id", ":" + base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		"1:not base64", "1:" + base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParseMasterKey(s); err == nil {
+			t.Errorf("ParseMasterKey %q, expected error", s)
+		}
+	}
+	if k := masterKey(t, "v1", 1); k.ID != "v1" || k.Key != [32]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1} {
+		t.Errorf("ParseMasterKey returns %v", k)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	if _, err := NewKeyring("yig"); err == nil {
+		t.Fatal("NewKeyring without master keys, expected error")
+	}
+	if _, err := NewKeyring("yig", masterKey(t, "v1", 1), masterKey(t, "v1", 2)); err == nil {
+		t.Fatal("NewKeyring with duplicated IDs, expected error")
+	}
+	old, err := NewKeyring("yig", masterKey(t, "v1", 1))
+	if err != nil {
+		t.Fatal("NewKeyring error:", err)
+	}
+	if old.GetKeyID() != "yig" {
+		t.Fatal("GetKeyID returns", old.GetKeyID())
+	}
+	context := Context{"bucket": "bucket/object"}
+	key, sealedKey, err := old.GenerateKey("yig", context)
+	if err != nil {
+		t.Fatal("GenerateKey error:", err)
+	}
+	unsealed, err := old.UnsealKey("yig", sealedKey, context)
+	if err != nil || unsealed != key {
+		t.Fatal("UnsealKey returns", unsealed, err)
+	}
+	if _, err = old.UnsealKey("other", sealedKey, context); err != ErrSecretKeyMismatch {
+		t.Fatal("UnsealKey with another key ID returns", err)
+	}
+	if _, err = old.UnsealKey("yig", sealedKey, Context{"bucket": "bucket/other"}); err != ErrSecretKeyMismatch {
+		t.Fatal("UnsealKey with another context returns", err)
+	}
+	if _, err = old.UnsealKey("yig", sealedKey[:10], context); err != errInvalidSealedKey {
+		t.Fatal("UnsealKey with truncated key returns", err)
+	}
+
+	// rotated, keys sealed by v1 are unsealed until rewrapped
+	rotated, err := NewKeyring("yig", masterKey(t, "v1", 1), masterKey(t, "v2", 2))
+	if err != nil {
+		t.Fatal("NewKeyring error:", err)
+	}
+	if unsealed, err = rotated.UnsealKey("yig", sealedKey, context); err != nil || unsealed != key {
+		t.Fatal("UnsealKey by rotated keyring returns", unsealed, err)
+	}
+	if _, _, err = rotated.RewrapKey("yig", sealedKey, Context{}); err != ErrSecretKeyMismatch {
+		t.Fatal("RewrapKey with another context returns", err)
+	}
+	rewrappedKey, rewrapped, err := rotated.RewrapKey("yig", sealedKey, context)
+	if err != nil || !rewrapped || bytes.Equal(rewrappedKey, sealedKey) {
+		t.Fatal("RewrapKey returns", rewrapped, err)
+	}
+	if unsealed, err = rotated.UnsealKey("yig", rewrappedKey, context); err != nil || unsealed != key {
+		t.Fatal("UnsealKey rewrapped key returns", unsealed, err)
+	}
+	if _, err = old.UnsealKey("yig", rewrappedKey, context); err == nil {
+		t.Fatal("UnsealKey rewrapped key without the newest master key, expected error")
+	}
+	again, rewrapped, err := rotated.RewrapKey("yig", rewrappedKey, context)
+	if err != nil || rewrapped || !bytes.Equal(again, rewrappedKey) {
+		t.Fatal("RewrapKey sealed by the newest master key returns", rewrapped, err)
+	}
+}
+
+// plainKMS seals keys as "plain:" || key, standing for the KMS used before keyring
+type plainKMS struct{}
+
+func (plainKMS) GenerateKey(keyID string, context Context) (key [32]byte, sealedKey []byte, err error) {
+	key[0] = 1
+	return key, append([]byte("plain:"), key[:]...), nil
+}
+
+func (plainKMS) UnsealKey(keyID string, sealedKey []byte, context Context) (key [32]byte, err error) {
+	if !bytes.HasPrefix(sealedKey, []byte("plain:")) || len(sealedKey) != 6+len(key) {
+		return key, ErrSecretKeyMismatch
+	}
+	copy(key[:], sealedKey[6:])
+	return key, nil
+}
+
+func (plainKMS) GetKeyID() string {
+	return "yig"
+}
+
+func TestKeyringFallback(t *testing.T) {
+	context := Context{"bucket": "bucket/object"}
+	key, sealedKey, _ := plainKMS{}.GenerateKey("yig", context)
+	k, err := NewKeyring("yig", masterKey(t, "v1", 1))
+	if err != nil {
+		t.Fatal("NewKeyring error:", err)
+	}
+	if _, err = k.UnsealKey("yig", sealedKey, context); err == nil {
+		t.Fatal("UnsealKey sealed by another KMS without fallback, expected error")
+	}
+	if _, _, err = k.RewrapKey("yig", sealedKey, context); err == nil {
+		t.Fatal("RewrapKey sealed by another KMS without fallback, expected error")
+	}
+
+	k.SetFallback(plainKMS{})
+	if unsealed, err := k.UnsealKey("yig", sealedKey, context); err != nil || unsealed != key {
+		t.Fatal("UnsealKey by fallback returns", unsealed, err)
+	}
+	rewrappedKey, rewrapped, err := k.RewrapKey("yig", sealedKey, context)
+	if err != nil || !rewrapped {
+		t.Fatal("RewrapKey sealed by fallback returns", rewrapped, err)
+	}
+	if unsealed, err := k.UnsealKey("yig", rewrappedKey, context); err != nil || unsealed != key {
+		t.Fatal("UnsealKey rewrapped key returns", unsealed, err)
+	}
+	// keys sealed by keyring are not passed to fallback
+	if _, rewrapped, err = k.RewrapKey("yig", rewrappedKey, context); err != nil || rewrapped {
+		t.Fatal("RewrapKey sealed by the newest master key returns", rewrapped, err)
+	}
+	if _, err = k.UnsealKey("yig", rewrappedKey, Context{}); err != ErrSecretKeyMismatch {
+		t.Fatal("UnsealKey with another context returns", err)
+	}
+}
@@ -17,9 +17,19 @@ package crypto
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
 )
 
+// key IDs are used in URL paths of KMS APIs, so only safe characters are allowed
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// IsValidKeyID reports whether keyID consists of letters, digits, '-', '_' and '.',
+// starts with a letter or digit, and is at most 128 characters long.
+func IsValidKeyID(keyID string) bool {
+	return keyIDPattern.MatchString(keyID)
+}
+
 // Context is a list of key-value pairs cryptographically
 // associated with a certain object.
 type Context map[string]string
@@ -42,3 +42,27 @@ func TestContextWriteTo(t *testing.T) {
 		}
 	}
 }
+
+func TestIsValidKeyID(t *testing.T) {
+	var testcase = [...]struct {
+		keyID string
+		valid bool
+	}{
+		{"yig", true},
+		{"1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"key_v1.2", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"", false},
+		{".key", false},
+		{"alias/key", false},
+		{"../sys", false},
+		{"key?x=1", false},
+		{"key%2F", false},
+	}
+	for i, test := range testcase {
+		if IsValidKeyID(test.keyID) != test.valid {
+			t.Errorf("Test %d: IsValidKeyID(%q) expected %v", i, test.keyID, test.valid)
+		}
+	}
+}
@@ -15,7 +15,6 @@
 package crypto
 
 import (
-	"net/http"
 	"testing"
 )
 
@@ -0,0 +1,360 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const DefaultRequestTimeout = 5 * time.Second
+
+var (
+	// tokens are renewed when half of the TTL has passed, but at least minRenewInterval apart
+	minRenewInterval = 10 * time.Second
+	// interval to try again after renewal or login failed
+	retryInterval = 10 * time.Second
+)
+
+type Config struct {
+	// address of Vault, e.g. http://127.0.0.1:8200
+	Endpoint string
+	// transit key used if no key is specified by requests
+	KeyName string
+	// version of transit keys to generate data keys with, 0 for the latest
+	KeyVersion int
+	// AppRole to login with, Token is used directly if RoleId is not set
+	RoleId   string
+	SecretId string
+	Token    string
+	// path AppRole auth method is enabled at, "approle" by default
+	AppRolePath    string
+	RequestTimeout time.Duration
+}
+
+// Vault implements crypto.KMS with the transit secrets engine of HashiCorp Vault.
+// Data keys are generated by `transit/datakey/plaintext` and unsealed by `transit/decrypt`,
+// with crypto.Context as the derivation context, so transit keys should be created with `derived=true`.
+// The token is renewed in background before it expires, and logged in again with AppRole
+// if renewal fails.
+type Vault struct {
+	config Config
+	client *http.Client
+	token  string
+	lock   sync.RWMutex
+	// closed to stop renewing token
+	stop chan struct{}
+	once sync.Once
+}
+
+// response of Vault APIs, only fields used are listed
+type response struct {
+	Errors []string `json:"errors"`
+	Auth   *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Data struct {
+		TTL        int64  `json:"ttl"`
+		Renewable  bool   `json:"renewable"`
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+}
+
+// lease of the token, renewed after ttl/2 if renewable
+type lease struct {
+	ttl       time.Duration
+	renewable bool
+}
+
+func NewVault(config Config) (*Vault, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("vault endpoint is not configured")
+	}
+	if config.KeyName == "" {
+		return nil, errors.New("vault transit key name is not configured")
+	}
+	if !crypto.IsValidKeyID(config.KeyName) {
+		return nil, fmt.Errorf("invalid vault transit key name %q", config.KeyName)
+	}
+	if config.RoleId == "" && config.Token == "" {
+		return nil, errors.New("neither vault AppRole nor token is configured")
+	}
+	if config.AppRolePath == "" {
+		config.AppRolePath = "approle"
+	}
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	v := &Vault{
+		config: config,
+		client: &http.Client{Timeout: config.RequestTimeout},
+		token:  config.Token,
+		stop:   make(chan struct{}),
+	}
+	var l lease
+	var err error
+	if config.RoleId != "" {
+		l, err = v.login()
+	} else {
+		l, err = v.lookupToken()
+	}
+	if err != nil {
+		return nil, err
+	}
+	go v.renewToken(l)
+	return v, nil
+}
+
+// Close stops renewing the token
+func (v *Vault) Close() {
+	v.once.Do(func() {
+		close(v.stop)
+	})
+}
+
+func (v *Vault) GetKeyID() string {
+	return v.config.KeyName
+}
+
+// keyPath returns the path of transit API for keyID, which is validated again
+// as requests with invalid key IDs should have been rejected
+func keyPath(api string, keyID string) (string, error) {
+	if !crypto.IsValidKeyID(keyID) {
+		return "", fmt.Errorf("invalid vault transit key name %q", keyID)
+	}
+	return "/v1/transit/" + api + "/" + url.PathEscape(keyID), nil
+}
+
+func (v *Vault) GenerateKey(keyID string, context crypto.Context) (key [32]byte, sealedKey []byte, err error) {
+	path, err := keyPath("datakey/plaintext", keyID)
+	if err != nil {
+		return
+	}
+	encodedContext, err := encodeContext(context)
+	if err != nil {
+		return
+	}
+	body := map[string]interface{}{
+		"context": encodedContext,
+		"bits":    256,
+	}
+	if v.config.KeyVersion > 0 {
+		body["key_version"] = v.config.KeyVersion
+	}
+	resp, err := v.request("POST", path, body)
+	if err != nil {
+		return
+	}
+	key, err = decodeKey(resp.Data.Plaintext)
+	if err != nil {
+		return
+	}
+	if resp.Data.Ciphertext == "" {
+		return key, nil, errors.New("vault returns no ciphertext of data key")
+	}
+	return key, []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *Vault) UnsealKey(keyID string, sealedKey []byte, context crypto.Context) (key [32]byte, err error) {
+	path, err := keyPath("decrypt", keyID)
+	if err != nil {
+		return
+	}
+	encodedContext, err := encodeContext(context)
+	if err != nil {
+		return
+	}
+	resp, err := v.request("POST", path, map[string]interface{}{
+		"ciphertext": string(sealedKey),
+		"context":    encodedContext,
+	})
+	if err != nil {
+		return
+	}
+	return decodeKey(resp.Data.Plaintext)
+}
+
+// encodeContext returns the context as base64 of canonical JSON
+func encodeContext(context crypto.Context) (string, error) {
+	var buffer bytes.Buffer
+	_, err := context.WriteTo(&buffer)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+func decodeKey(plaintext string) (key [32]byte, err error) {
+	decoded, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return key, fmt.Errorf("vault returns invalid data key: %v", err)
+	}
+	if len(decoded) != len(key) {
+		return key, fmt.Errorf("vault returns data key of %d bytes, expected %d", len(decoded), len(key))
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
+
+func (v *Vault) getToken() string {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.token
+}
+
+// request calls Vault with the token, and logs in again then retries once
+// if the token is rejected, e.g. it expired while Vault was unreachable
+func (v *Vault) request(method, path string, body interface{}) (*response, error) {
+	resp, status, err := v.do(method, path, v.getToken(), body)
+	if status == http.StatusForbidden && v.config.RoleId != "" {
+		_, err = v.login()
+		if err != nil {
+			return nil, err
+		}
+		resp, _, err = v.do(method, path, v.getToken(), body)
+	}
+	return resp, err
+}
+
+func (v *Vault) do(method, path, token string, body interface{}) (resp *response, status int, err error) {
+	var reqBody []byte
+	if body != nil {
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return
+		}
+	}
+	req, err := http.NewRequest(method, v.config.Endpoint+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := v.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, httpResp.StatusCode, err
+	}
+	resp = new(response)
+	if len(respBody) != 0 {
+		err = json.Unmarshal(respBody, resp)
+	}
+	if httpResp.StatusCode/100 != 2 {
+		return nil, httpResp.StatusCode, fmt.Errorf("vault %s %s returns %d: %s", method, path,
+			httpResp.StatusCode, strings.Join(resp.Errors, "; "))
+	}
+	if err != nil {
+		return nil, httpResp.StatusCode, fmt.Errorf("vault %s %s returns invalid response: %v", method, path, err)
+	}
+	return resp, httpResp.StatusCode, nil
+}
+
+// login with AppRole and replaces the token
+func (v *Vault) login() (l lease, err error) {
+	resp, _, err := v.do("POST", "/v1/auth/"+v.config.AppRolePath+"/login", "", map[string]string{
+		"role_id":   v.config.RoleId,
+		"secret_id": v.config.SecretId,
+	})
+	if err != nil {
+		return
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return l, errors.New("vault AppRole login returns no token")
+	}
+	v.lock.Lock()
+	v.token = resp.Auth.ClientToken
+	v.lock.Unlock()
+	return lease{
+		ttl:       time.Duration(resp.Auth.LeaseDuration) * time.Second,
+		renewable: resp.Auth.Renewable,
+	}, nil
+}
+
+// lookupToken returns the lease of the configured token
+func (v *Vault) lookupToken() (l lease, err error) {
+	resp, _, err := v.do("GET", "/v1/auth/token/lookup-self", v.getToken(), nil)
+	if err != nil {
+		return
+	}
+	return lease{
+		ttl:       time.Duration(resp.Data.TTL) * time.Second,
+		renewable: resp.Data.Renewable,
+	}, nil
+}
+
+func (v *Vault) renewSelf() (l lease, err error) {
+	resp, _, err := v.do("POST", "/v1/auth/token/renew-self", v.getToken(), nil)
+	if err != nil {
+		return
+	}
+	if resp.Auth == nil {
+		return l, errors.New("vault token renewal returns no auth")
+	}
+	return lease{
+		ttl:       time.Duration(resp.Auth.LeaseDuration) * time.Second,
+		renewable: resp.Auth.Renewable,
+	}, nil
+}
+
+// renewToken keeps the token valid until Close. Tokens without TTL, like root tokens,
+// never expire and are not renewed. Tokens which are not renewable, or failed to renew,
+// are replaced by logging in again if AppRole is configured.
+func (v *Vault) renewToken(l lease) {
+	for {
+		if l.ttl == 0 {
+			return
+		}
+		if !l.renewable && v.config.RoleId == "" {
+			helper.Logger.Warn("Vault token is not renewable, it expires in", l.ttl)
+			return
+		}
+		wait := l.ttl / 2
+		if wait < minRenewInterval {
+			wait = minRenewInterval
+		}
+		select {
+		case <-v.stop:
+			return
+		case <-time.After(wait):
+		}
+		if l.renewable {
+			renewed, err := v.renewSelf()
+			if err == nil {
+				l = renewed
+				continue
+			}
+			helper.Logger.Warn("Renew vault token failed:", err)
+			if v.config.RoleId == "" {
+				// try again before the token expires
+				l.ttl = 2 * retryInterval
+				continue
+			}
+		}
+		var err error
+		l, err = v.login()
+		if err != nil {
+			helper.Logger.Error("Login vault with AppRole failed:", err)
+			l = lease{ttl: 2 * retryInterval}
+		}
+	}
+}
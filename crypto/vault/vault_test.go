@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	minRenewInterval = 10 * time.Millisecond
+	retryInterval = 10 * time.Millisecond
+	os.Exit(m.Run())
+}
+
+// server stands in for Vault with transit keys "yig" and "other",
+// ciphertext is the context and data key, which is rejected if the context differs
+type server struct {
+	sync.Mutex
+	tokens        map[string]bool
+	leaseDuration int64
+	renewable     bool
+	failRenew     bool
+	logins        int
+	renewals      int
+}
+
+func newServer() *server {
+	return &server{tokens: map[string]bool{"root": true}, leaseDuration: 3600, renewable: true}
+}
+
+func (s *server) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *server) fail(w http.ResponseWriter, status int, message string) {
+	s.reply(w, status, map[string][]string{"errors": {message}})
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	if r.URL.Path == "/v1/auth/approle/login" {
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			s.fail(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		s.logins += 1
+		token := fmt.Sprintf("token-%d", s.logins)
+		s.tokens[token] = true
+		s.reply(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": token, "lease_duration": s.leaseDuration, "renewable": s.renewable}})
+		return
+	}
+	if !s.tokens[r.Header.Get("X-Vault-Token")] {
+		s.fail(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	switch {
+	case r.URL.Path == "/v1/auth/token/lookup-self":
+		s.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"ttl": 0, "renewable": false}})
+	case r.URL.Path == "/v1/auth/token/renew-self":
+		if s.failRenew {
+			s.fail(w, http.StatusBadRequest, "lease is not renewable")
+			return
+		}
+		s.renewals += 1
+		s.reply(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token": r.Header.Get("X-Vault-Token"), "lease_duration": s.leaseDuration,
+			"renewable": s.renewable}})
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/datakey/plaintext/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/transit/datakey/plaintext/")
+		context, _ := body["context"].(string)
+		if !s.validKey(w, name, context) {
+			return
+		}
+		key := make([]byte, 32)
+		rand.Read(key)
+		plaintext := base64.StdEncoding.EncodeToString(key)
+		s.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"plaintext": plaintext, "ciphertext": "vault:v1:" + name + ":" + context + ":" + plaintext}})
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/transit/decrypt/")
+		context, _ := body["context"].(string)
+		if !s.validKey(w, name, context) {
+			return
+		}
+		ciphertext, _ := body["ciphertext"].(string)
+		prefix := "vault:v1:" + name + ":" + context + ":"
+		if !strings.HasPrefix(ciphertext, prefix) {
+			s.fail(w, http.StatusBadRequest, "cipher: message authentication failed")
+			return
+		}
+		s.reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"plaintext": strings.TrimPrefix(ciphertext, prefix)}})
+	default:
+		s.fail(w, http.StatusNotFound, "unsupported path")
+	}
+}
+
+func (s *server) validKey(w http.ResponseWriter, name, context string) bool {
+	if name != "yig" && name != "other" {
+		s.fail(w, http.StatusBadRequest, "encryption key not found")
+		return false
+	}
+	if context == "" {
+		s.fail(w, http.StatusBadRequest, "missing 'context' for key derivation")
+		return false
+	}
+	return true
+}
+
+func (s *server) count() (logins, renewals int) {
+	s.Lock()
+	defer s.Unlock()
+	return s.logins, s.renewals
+}
+
+// newVault returns vault logged in with AppRole and the function to close it
+func newVault(t *testing.T, s *server) (*Vault, func()) {
+	endpoint := httptest.NewServer(s)
+	v, err := NewVault(Config{Endpoint: endpoint.URL + "/", KeyName: "yig", RoleId: "role", SecretId: "secret"})
+	if err != nil {
+		endpoint.Close()
+		t.Fatal("NewVault error:", err)
+	}
+	return v, func() {
+		v.Close()
+		endpoint.Close()
+	}
+}
+
+func TestVault_GenerateAndUnsealKey(t *testing.T) {
+	v, closeVault := newVault(t, newServer())
+	defer closeVault()
+	if v.GetKeyID() != "yig" {
+		t.Fatal("GetKeyID returns", v.GetKeyID())
+	}
+	context := crypto.Context{"bucket": "bucket/object"}
+	key, sealedKey, err := v.GenerateKey("yig", context)
+	if err != nil {
+		t.Fatal("GenerateKey error:", err)
+	}
+	unsealed, err := v.UnsealKey("yig", sealedKey, crypto.Context{"bucket": "bucket/object"})
+	if err != nil {
+		t.Fatal("UnsealKey error:", err)
+	}
+	if unsealed != key {
+		t.Fatal("UnsealKey returns another key")
+	}
+	if _, err = v.UnsealKey("yig", sealedKey, crypto.Context{"bucket": "bucket/another"}); err == nil {
+		t.Fatal("UnsealKey with another context, expected error")
+	}
+	if _, err = v.UnsealKey("other", sealedKey, context); err == nil {
+		t.Fatal("UnsealKey with another key, expected error")
+	}
+	if _, _, err = v.GenerateKey("missing", context); err == nil ||
+		!strings.Contains(err.Error(), "encryption key not found") {
+		t.Fatal("GenerateKey with missing key returns", err)
+	}
+	// key IDs never reach other APIs of Vault
+	for _, keyID := range []string{"../../sys/seal", "yig/../../sys/seal", "yig?x=1", "yig%2F"} {
+		if _, _, err = v.GenerateKey(keyID, context); err == nil ||
+			!strings.Contains(err.Error(), "invalid vault transit key name") {
+			t.Fatal("GenerateKey with key", keyID, "returns", err)
+		}
+		if _, err = v.UnsealKey(keyID, sealedKey, context); err == nil ||
+			!strings.Contains(err.Error(), "invalid vault transit key name") {
+			t.Fatal("UnsealKey with key", keyID, "returns", err)
+		}
+	}
+}
+
+func TestVault_LoginAgain(t *testing.T) {
+	s := newServer()
+	v, closeVault := newVault(t, s)
+	defer closeVault()
+	// token revoked or expired
+	s.Lock()
+	s.tokens = map[string]bool{}
+	s.Unlock()
+	if _, _, err := v.GenerateKey("yig", crypto.Context{"a": "b"}); err != nil {
+		t.Fatal("GenerateKey error:", err)
+	}
+	if logins, _ := s.count(); logins != 2 {
+		t.Fatal("Logged in", logins, "times, expected 2")
+	}
+}
+
+func TestVault_RenewToken(t *testing.T) {
+	s := newServer()
+	s.leaseDuration = 1
+	_, closeVault := newVault(t, s)
+	defer closeVault()
+	time.Sleep(1200 * time.Millisecond)
+	logins, renewals := s.count()
+	if logins != 1 || renewals == 0 {
+		t.Fatal("Logged in", logins, "times and renewed", renewals, "times")
+	}
+
+	// login again if renewal fails
+	s.Lock()
+	s.failRenew = true
+	s.Unlock()
+	time.Sleep(1200 * time.Millisecond)
+	if logins, _ = s.count(); logins < 2 {
+		t.Fatal("Logged in", logins, "times after renewal failed")
+	}
+}
+
+func TestVault_Token(t *testing.T) {
+	s := newServer()
+	endpoint := httptest.NewServer(s)
+	defer endpoint.Close()
+	if _, err := NewVault(Config{Endpoint: endpoint.URL, KeyName: "yig", Token: "invalid"}); err == nil {
+		t.Fatal("NewVault with invalid token, expected error")
+	}
+	if _, err := NewVault(Config{Endpoint: endpoint.URL, KeyName: "yig", RoleId: "role", SecretId: "bad"}); err == nil {
+		t.Fatal("NewVault with invalid secret ID, expected error")
+	}
+	v, err := NewVault(Config{Endpoint: endpoint.URL, KeyName: "yig", Token: "root"})
+	if err != nil {
+		t.Fatal("NewVault error:", err)
+	}
+	defer v.Close()
+	if _, _, err = v.GenerateKey("yig", crypto.Context{"a": "b"}); err != nil {
+		t.Fatal("GenerateKey error:", err)
+	}
+	if logins, renewals := s.count(); logins != 0 || renewals != 0 {
+		t.Fatal("Logged in", logins, "times and renewed", renewals, "times with root token")
+	}
+}
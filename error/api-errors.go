@@ -177,6 +177,20 @@ const (
 	ErrInvalidRestoreInfo
 	ErrCreateRestoreObject
 	ErrInvalidGlacierObject
+	ErrInvalidTag
+	ErrTooManyTags
+	ErrNoSuchTagSet
+	ErrObjectLockConfigurationNotFound
+	ErrNoSuchObjectLockConfiguration
+	ErrInvalidBucketObjectLockConfiguration
+	ErrInvalidObjectLockHeaders
+	ErrPastObjectLockRetainDate
+	ErrObjectLocked
+	ErrInvalidBucketState
+	ErrInvalidNotificationEvent
+	ErrInvalidNotificationFilter
+	ErrInvalidNotificationDestination
+	ErrReplicationConfigurationNotFound
 )
 
 // error code to APIError structure, these fields carry respective
@@ -828,6 +842,76 @@ var ErrorCodeResponse = map[ApiErrorCode]ApiErrorStruct{
 		Description:    "Create object thaw operation failed",
 		HttpStatusCode: http.StatusInternalServerError,
 	},
+	ErrInvalidTag: {
+		AwsErrorCode:   "InvalidTag",
+		Description:    "The tag provided was not a valid tag.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrTooManyTags: {
+		AwsErrorCode:   "BadRequest",
+		Description:    "The number of tags exceeds the limit.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchTagSet: {
+		AwsErrorCode:   "NoSuchTagSet",
+		Description:    "The TagSet does not exist.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrObjectLockConfigurationNotFound: {
+		AwsErrorCode:   "ObjectLockConfigurationNotFoundError",
+		Description:    "Object Lock configuration does not exist for this bucket.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		AwsErrorCode:   "NoSuchObjectLockConfiguration",
+		Description:    "The specified object does not have a ObjectLock configuration.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBucketObjectLockConfiguration: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "Bucket is missing ObjectLockConfiguration.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidObjectLockHeaders: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "x-amz-object-lock-retain-until-date and x-amz-object-lock-mode must both be supplied.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrPastObjectLockRetainDate: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "The retain until date must be in the future.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLocked: {
+		AwsErrorCode:   "AccessDenied",
+		Description:    "Access Denied because object protected by object lock.",
+		HttpStatusCode: http.StatusForbidden,
+	},
+	ErrInvalidBucketState: {
+		AwsErrorCode:   "InvalidBucketState",
+		Description:    "The request is not valid with the current state of the bucket.",
+		HttpStatusCode: http.StatusConflict,
+	},
+	ErrInvalidNotificationEvent: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The event is not supported for notifications.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidNotificationFilter: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The filter rule name must be either prefix or suffix and appear only once.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidNotificationDestination: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Unable to validate the destination configuration, the ARN does not name the message queue of the service.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrReplicationConfigurationNotFound: {
+		AwsErrorCode:   "ReplicationConfigurationNotFoundError",
+		Description:    "The replication configuration was not found.",
+		HttpStatusCode: http.StatusNotFound,
+	},
 }
 
 func (e ApiErrorCode) AwsErrorCode() string {
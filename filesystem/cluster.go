@@ -0,0 +1,290 @@
+package filesystem
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	FSID_FILE_NAME = "fsid"
+	BUFFER_SIZE    = 1 << 20 // 1M
+)
+
+var pools = []string{
+	backend.SMALL_FILE_POOLNAME,
+	backend.BIG_FILE_POOLNAME,
+	backend.GLACIER_FILE_POOLNAME,
+}
+
+// Initialize opens every directory in `filesystem_paths` as a cluster,
+// panic on errors as ceph.Initialize does
+func Initialize(config helper.Config) map[string]backend.Cluster {
+	if len(config.FileSystemPaths) == 0 {
+		panic("No filesystem path found")
+	}
+	clusters := make(map[string]backend.Cluster)
+	for _, path := range config.FileSystemPaths {
+		c, err := NewFileSystemCluster(path)
+		if err != nil {
+			panic("Failed to open filesystem cluster " + path + ": " + err.Error())
+		}
+		clusters[c.Name] = c
+	}
+	return clusters
+}
+
+// FileSystemCluster stores objects as files under a local directory,
+// every pool is a sub directory:
+//
+//	<root>/fsid                       cluster ID, generated on first start
+//	<root>/<pool>/<xx>/<oid>          xx is the first byte of md5(oid) in hex
+type FileSystemCluster struct {
+	Name       string
+	Root       string
+	instanceId string
+	counter    uint64
+}
+
+func NewFileSystemCluster(root string) (*FileSystemCluster, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		err = os.MkdirAll(filepath.Join(root, pool), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	name, err := loadFsid(root)
+	if err != nil {
+		return nil, err
+	}
+	cluster := &FileSystemCluster{
+		Name: name,
+		Root: root,
+		// unique among processes sharing the same directory, e.g. yig and tools/lc
+		instanceId: fmt.Sprintf("%x%x", os.Getpid(), time.Now().UnixNano()),
+	}
+	helper.Logger.Info("Filesystem Cluster", name, "is ready, root is", root)
+	return cluster, nil
+}
+
+func loadFsid(root string) (string, error) {
+	fsidFile := filepath.Join(root, FSID_FILE_NAME)
+	fsid, err := ioutil.ReadFile(fsidFile)
+	if err == nil {
+		return strings.TrimSpace(string(fsid)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	sum := md5.Sum([]byte(fmt.Sprintf("%s%d", root, time.Now().UnixNano())))
+	id := hex.EncodeToString(sum[:])
+	id = strings.Join([]string{id[:8], id[8:12], id[12:16], id[16:20], id[20:]}, "-")
+	tmpFile := fsidFile + ".tmp"
+	err = ioutil.WriteFile(tmpFile, []byte(id+"\n"), 0644)
+	if err != nil {
+		return "", err
+	}
+	return id, os.Rename(tmpFile, fsidFile)
+}
+
+func (cluster *FileSystemCluster) ID() string {
+	return cluster.Name
+}
+
+func (cluster *FileSystemCluster) getUniqUploadName() string {
+	v := atomic.AddUint64(&cluster.counter, 1)
+	return fmt.Sprintf("%s:%d", cluster.instanceId, v)
+}
+
+func (cluster *FileSystemCluster) objectPath(poolName, oid string) (string, error) {
+	validPool := false
+	for _, pool := range pools {
+		if pool == poolName {
+			validPool = true
+		}
+	}
+	if !validPool {
+		return "", fmt.Errorf("Bad poolname %s", poolName)
+	}
+	if oid == "" || strings.ContainsAny(oid, "/\\") || oid == "." || oid == ".." {
+		return "", fmt.Errorf("Bad oid %s", oid)
+	}
+	sum := md5.Sum([]byte(oid))
+	return filepath.Join(cluster.Root, poolName, hex.EncodeToString(sum[:1]), oid), nil
+}
+
+func (cluster *FileSystemCluster) GetUsage() (usage backend.Usage, err error) {
+	var stat syscall.Statfs_t
+	err = syscall.Statfs(cluster.Root, &stat)
+	if err != nil {
+		return usage, err
+	}
+	if stat.Blocks == 0 {
+		return usage, nil
+	}
+	usage.UsedSpacePercent = int((stat.Blocks - stat.Bfree) * uint64(100) / stat.Blocks)
+	return
+}
+
+func (cluster *FileSystemCluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	oid = cluster.getUniqUploadName()
+	path, err := cluster.objectPath(poolname, oid)
+	if err != nil {
+		return oid, 0, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return oid, 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return oid, 0, err
+	}
+	size, err = writeAndSync(f, data)
+	if err != nil {
+		os.Remove(path)
+		return oid, 0, fmt.Errorf("Write failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	return oid, size, nil
+}
+
+func (cluster *FileSystemCluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	oid = existName
+	if len(oid) == 0 {
+		oid = cluster.getUniqUploadName()
+	}
+	if poolname != backend.BIG_FILE_POOLNAME {
+		return oid, 0,
+			errors.New("specified pool must be used for storing big file.")
+	}
+	path, err := cluster.objectPath(poolname, oid)
+	if err != nil {
+		return oid, 0, err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return oid, 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return oid, 0, err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return oid, 0, err
+	}
+	size, err = writeAndSync(f, data)
+	if err != nil {
+		return oid, 0, fmt.Errorf("Write failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	return oid, size, nil
+}
+
+// writeAndSync copies data to f and closes it, data is flushed to disk before return
+func writeAndSync(f *os.File, data io.Reader) (size uint64, err error) {
+	buffer := make([]byte, BUFFER_SIZE)
+	n, err := io.CopyBuffer(f, data, buffer)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return uint64(n), err
+}
+
+type fileReader struct {
+	io.Reader
+	f *os.File
+}
+
+func (r *fileReader) Close() error {
+	return r.f.Close()
+}
+
+func (cluster *FileSystemCluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	path, err := cluster.objectPath(poolName, oid)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(startOffset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if length == 0 {
+		return f, nil
+	}
+	return &fileReader{Reader: io.LimitReader(f, int64(length)), f: f}, nil
+}
+
+// Remove succeeds if the object does not exist, so removing garbage is idempotent
+func (cluster *FileSystemCluster) Remove(poolname string, oid string) error {
+	path, err := cluster.objectPath(poolname, oid)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List calls fn with every object in the pool, files being written are listed as well
+func (cluster *FileSystemCluster) List(poolName string, fn func(objectName string) error) error {
+	if _, err := cluster.objectPath(poolName, "oid"); err != nil {
+		return err
+	}
+	dirs, err := ioutil.ReadDir(filepath.Join(cluster.Root, poolName))
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(cluster.Root, poolName, dir.Name()))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			err = fn(file.Name())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package filesystem
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+func setupCluster(t *testing.T) (*FileSystemCluster, func()) {
+	root, err := ioutil.TempDir("", "yig-filesystem")
+	if err != nil {
+		t.Fatal("TempDir error:", err)
+	}
+	cluster, err := NewFileSystemCluster(root)
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal("NewFileSystemCluster error:", err)
+	}
+	return cluster, func() { os.RemoveAll(root) }
+}
+
+func readAll(t *testing.T, cluster *FileSystemCluster, pool, oid string, offset int64, length uint64) []byte {
+	reader, err := cluster.GetReader(pool, oid, offset, length)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Read error:", err)
+	}
+	return data
+}
+
+func TestFileSystemCluster_PutAndGet(t *testing.T) {
+	cluster, cleanup := setupCluster(t)
+	defer cleanup()
+
+	data := []byte("hello filesystem backend")
+	for _, pool := range pools {
+		oid, size, err := cluster.Put(pool, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal("Put error:", err)
+		}
+		if size != uint64(len(data)) {
+			t.Fatalf("Put size %d, expected %d", size, len(data))
+		}
+		if got := readAll(t, cluster, pool, oid, 0, uint64(len(data))); !bytes.Equal(got, data) {
+			t.Fatalf("Read %q, expected %q", got, data)
+		}
+		if got := readAll(t, cluster, pool, oid, 6, 4); string(got) != "file" {
+			t.Fatalf("Read range %q, expected %q", got, "file")
+		}
+		if got := readAll(t, cluster, pool, oid, 6, 0); !bytes.Equal(got, data[6:]) {
+			t.Fatalf("Read to end %q, expected %q", got, data[6:])
+		}
+		if err = cluster.Remove(pool, oid); err != nil {
+			t.Fatal("Remove error:", err)
+		}
+		if _, err = cluster.GetReader(pool, oid, 0, 0); err == nil {
+			t.Fatal("GetReader removed object, expected error")
+		}
+		if err = cluster.Remove(pool, oid); err != nil {
+			t.Fatal("Remove removed object error:", err)
+		}
+	}
+
+	if _, _, err := cluster.Put("unknown", bytes.NewReader(data)); err == nil {
+		t.Fatal("Put to unknown pool, expected error")
+	}
+	if _, err := cluster.GetReader(backend.BIG_FILE_POOLNAME, "../fsid", 0, 0); err == nil {
+		t.Fatal("GetReader with bad oid, expected error")
+	}
+}
+
+func TestFileSystemCluster_Append(t *testing.T) {
+	cluster, cleanup := setupCluster(t)
+	defer cleanup()
+
+	if _, _, err := cluster.Append(backend.SMALL_FILE_POOLNAME, "", bytes.NewReader([]byte("a")), 0); err == nil {
+		t.Fatal("Append to small file pool, expected error")
+	}
+	oid, size, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("hello")), 0)
+	if err != nil || size != 5 {
+		t.Fatal("Append error:", err, size)
+	}
+	_, size, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte(" world")), 5)
+	if err != nil || size != 6 {
+		t.Fatal("Append error:", err, size)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 11); string(got) != "hello world" {
+		t.Fatalf("Read %q, expected %q", got, "hello world")
+	}
+}
+
+func TestFileSystemCluster_List(t *testing.T) {
+	cluster, cleanup := setupCluster(t)
+	defer cleanup()
+
+	put := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		oid, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader([]byte("a")))
+		if err != nil {
+			t.Fatal("Put error:", err)
+		}
+		put[oid] = true
+	}
+	listed := make(map[string]bool)
+	err := cluster.List(backend.BIG_FILE_POOLNAME, func(oid string) error {
+		listed[oid] = true
+		return nil
+	})
+	if err != nil || len(listed) != len(put) {
+		t.Fatal("List returns", listed, err)
+	}
+	for oid := range put {
+		if !listed[oid] {
+			t.Fatal("Object", oid, "not listed")
+		}
+	}
+	err = cluster.List(backend.SMALL_FILE_POOLNAME, func(oid string) error {
+		t.Fatal("Object", oid, "listed in empty pool")
+		return nil
+	})
+	if err != nil {
+		t.Fatal("List error:", err)
+	}
+	if err = cluster.List("unknown", func(string) error { return nil }); err == nil {
+		t.Fatal("List unknown pool, expected error")
+	}
+}
+
+func TestFileSystemCluster_ID(t *testing.T) {
+	cluster, cleanup := setupCluster(t)
+	defer cleanup()
+
+	if cluster.ID() == "" {
+		t.Fatal("Empty cluster ID")
+	}
+	reopened, err := NewFileSystemCluster(cluster.Root)
+	if err != nil {
+		t.Fatal("NewFileSystemCluster error:", err)
+	}
+	if reopened.ID() != cluster.ID() {
+		t.Fatalf("Cluster ID changed after reopen, %s and %s", cluster.ID(), reopened.ID())
+	}
+	if _, err = cluster.GetUsage(); err != nil {
+		t.Fatal("GetUsage error:", err)
+	}
+	clusters := Initialize(helper.Config{FileSystemPaths: []string{cluster.Root}})
+	if _, ok := clusters[cluster.ID()]; !ok {
+		t.Fatal("Initialize returns", clusters)
+	}
+}
@@ -30,19 +30,24 @@ type Config struct {
 
 	InstanceId             string // if empty, generated one at server startup
 	ConcurrentRequestLimit int
-	DebugMode              bool   `toml:"debug_mode"`
-	EnablePProf            bool   `toml:"enable_pprof"`
-	BindPProfAddress       string `toml:"pprof_listener"`
-	AdminKey               string `toml:"admin_key"` //used for tools/admin to communicate with yig
-	GcThread               int    `toml:"gc_thread"`
-	LcThread               int    //used for tools/lc only, set worker numbers to do lc
-	LogLevel               string `toml:"log_level"` // "info", "warn", "error"
-	CephConfigPattern      string `toml:"ceph_config_pattern"`
-	ReservedOrigins        string `toml:"reserved_origins"` // www.ccc.com,www.bbb.com,127.0.0.1
-	MetaStore              string `toml:"meta_store"`
-	TidbInfo               string `toml:"tidb_info"`
-	KeepAlive              bool   `toml:"keepalive"`
-	EnableCompression      bool   `toml:"enable_compression"`
+	DebugMode              bool     `toml:"debug_mode"`
+	EnablePProf            bool     `toml:"enable_pprof"`
+	BindPProfAddress       string   `toml:"pprof_listener"`
+	AdminKey               string   `toml:"admin_key"` //used for tools/admin to communicate with yig
+	GcThread               int      `toml:"gc_thread"`
+	LcThread               int      //used for tools/lc only, set worker numbers to do lc
+	LogLevel               string   `toml:"log_level"` // "info", "warn", "error"
+	CephConfigPattern      string   `toml:"ceph_config_pattern"`
+	DataStore              string   `toml:"data_store"`       // "ceph" or "filesystem"
+	FileSystemPaths        []string `toml:"filesystem_paths"` // used if data_store is "filesystem", one cluster per path
+	ReservedOrigins        string   `toml:"reserved_origins"` // www.ccc.com,www.bbb.com,127.0.0.1
+	MetaStore              string   `toml:"meta_store"`
+	TidbInfo               string   `toml:"tidb_info"`
+	MysqlInfo              string   `toml:"mysql_info"`         // used if meta_store is "mysql"
+	PostgresInfo           string   `toml:"postgres_info"`      // used if meta_store is "postgres"
+	EmbeddedMetaPath       string   `toml:"embedded_meta_path"` // used if meta_store is "embedded", a local directory
+	KeepAlive              bool     `toml:"keepalive"`
+	EnableCompression      bool     `toml:"enable_compression"`
 
 	//About cache
 	EnableUsagePush       bool   `toml:"enable_usage_push"`
@@ -78,6 +83,31 @@ type Config struct {
 	DownloadBufPoolSize int64 `toml:"download_buf_pool_size"`
 	UploadMinChunkSize  int64 `toml:"upload_min_chunk_size"`
 	UploadMaxChunkSize  int64 `toml:"upload_max_chunk_size"`
+
+	// used for tools/replication only, the remote yig/S3 endpoint to replicate objects to
+	ReplicationThread        int    `toml:"replication_thread"`
+	ReplicationEndpoint      string `toml:"replication_endpoint"`
+	ReplicationRegion        string `toml:"replication_region"`
+	ReplicationAccessKey     string `toml:"replication_access_key"`
+	ReplicationSecretKey     string `toml:"replication_secret_key"`
+	ReplicationMaxTriedTimes int    `toml:"replication_max_tried_times"`
+
+	// used for tools/restore only, set worker numbers to restore GLACIER objects
+	RestoreThread int `toml:"restore_thread"`
+
+	// extra clusters in other S3-compatible services, gateway name -> config
+	S3Gateways map[string]S3GatewayConfig `toml:"s3_gateways"`
+}
+
+// Objects of StorageClasses are stored in Bucket of the S3-compatible service at Endpoint
+type S3GatewayConfig struct {
+	Endpoint       string   `toml:"endpoint"`
+	Region         string   `toml:"region"` // use region of yig if empty
+	AccessKey      string   `toml:"access_key"`
+	SecretKey      string   `toml:"secret_key"`
+	Bucket         string   `toml:"bucket"`
+	Prefix         string   `toml:"prefix"`
+	StorageClasses []string `toml:"storage_classes"` // e.g. ["GLACIER"]
 }
 
 type PluginConfig struct {
@@ -124,8 +154,14 @@ func MarshalTOMLConfig() error {
 	CONFIG.BindPProfAddress = c.BindPProfAddress
 	CONFIG.AdminKey = c.AdminKey
 	CONFIG.CephConfigPattern = c.CephConfigPattern
+	CONFIG.DataStore = Ternary(c.DataStore == "", "ceph", c.DataStore).(string)
+	CONFIG.FileSystemPaths = c.FileSystemPaths
+	CONFIG.S3Gateways = c.S3Gateways
 	CONFIG.ReservedOrigins = c.ReservedOrigins
 	CONFIG.TidbInfo = c.TidbInfo
+	CONFIG.MysqlInfo = c.MysqlInfo
+	CONFIG.PostgresInfo = c.PostgresInfo
+	CONFIG.EmbeddedMetaPath = Ternary(c.EmbeddedMetaPath == "", "/var/lib/yig/meta", c.EmbeddedMetaPath).(string)
 	CONFIG.KeepAlive = c.KeepAlive
 	CONFIG.EnableCompression = c.EnableCompression
 	CONFIG.InstanceId = Ternary(c.InstanceId == "",
@@ -168,5 +204,14 @@ func MarshalTOMLConfig() error {
 	CONFIG.UploadMinChunkSize = Ternary(c.UploadMinChunkSize < MIN_BUFFER_SIZE || c.UploadMinChunkSize > MAX_BUFEER_SIZE, MIN_BUFFER_SIZE, c.UploadMinChunkSize).(int64)
 	CONFIG.UploadMaxChunkSize = Ternary(c.UploadMaxChunkSize < CONFIG.UploadMinChunkSize || c.UploadMaxChunkSize > MAX_BUFEER_SIZE, MAX_BUFEER_SIZE, c.UploadMaxChunkSize).(int64)
 
+	CONFIG.ReplicationThread = Ternary(c.ReplicationThread <= 0, 1, c.ReplicationThread).(int)
+	CONFIG.ReplicationEndpoint = c.ReplicationEndpoint
+	CONFIG.ReplicationRegion = Ternary(c.ReplicationRegion == "", c.Region, c.ReplicationRegion).(string)
+	CONFIG.ReplicationAccessKey = c.ReplicationAccessKey
+	CONFIG.ReplicationSecretKey = c.ReplicationSecretKey
+	CONFIG.ReplicationMaxTriedTimes = Ternary(c.ReplicationMaxTriedTimes <= 0, 3, c.ReplicationMaxTriedTimes).(int)
+
+	CONFIG.RestoreThread = Ternary(c.RestoreThread <= 0, 1, c.RestoreThread).(int)
+
 	return nil
 }
@@ -1,3 +1,6 @@
+-- Deprecated: schema changes are migrations of meta/migrate now, applied by `yig_migrate up`.
+-- Kept for reference of how old tables were upgraded by hand.
+
 -- rename table
 
 ALTER TABLE `multiparts`
@@ -50,4 +53,40 @@ CREATE TABLE `objects` (
    UNIQUE KEY `rowkey` (`bucketname`,`name`,`version`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
-INSERT INTO `objects` SELECT * FROM `objects_bak`;
\ No newline at end of file
+INSERT INTO `objects` SELECT * FROM `objects_bak`;
+-- object tagging
+
+ALTER TABLE `objects` ADD COLUMN `tags` JSON DEFAULT NULL;
+ALTER TABLE `multiparts` ADD COLUMN `tags` JSON DEFAULT NULL;
+
+-- bucket tagging
+
+ALTER TABLE `buckets` ADD COLUMN `tags` JSON DEFAULT NULL;
+
+-- object lock
+
+ALTER TABLE `buckets` ADD COLUMN `objectlock` JSON DEFAULT NULL;
+ALTER TABLE `objects` ADD COLUMN `objectlock` JSON DEFAULT NULL;
+ALTER TABLE `multiparts` ADD COLUMN `objectlock` JSON DEFAULT NULL;
+
+-- bucket notification
+
+ALTER TABLE `buckets` ADD COLUMN `notification` JSON DEFAULT NULL;
+
+-- bucket replication
+
+ALTER TABLE `buckets` ADD COLUMN `replication` JSON DEFAULT NULL;
+ALTER TABLE `objects` ADD COLUMN `replicationstatus` varchar(255) DEFAULT NULL;
+CREATE TABLE `replication` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `mtime` datetime DEFAULT NULL,
+  `triedtimes` int(11) DEFAULT NULL,
+   UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- sse-kms
+
+ALTER TABLE `objects` ADD COLUMN `ssekmskeyid` varchar(255) DEFAULT NULL;
+ALTER TABLE `objects` ADD COLUMN `ssecontext` JSON DEFAULT NULL;
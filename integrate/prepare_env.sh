@@ -17,7 +17,7 @@ function prepare_tidb(){
 function prepare_vault(){
     echo "start init vault transit..."
     docker exec vault vault secrets enable transit
-    docker exec vault vault write -f transit/keys/yig
+    docker exec vault vault write transit/keys/yig derived=true
 }
 
 echo "creating Ceph pool..."
@@ -0,0 +1,15 @@
+BASEDIR=$(dirname $(pwd))
+echo ${BASEDIR}
+WORKDIR=$1
+sudo docker rm --force replication
+if [ -x "$BASEDIR/replication" ]; then 
+    sudo docker run -d --name replication \
+			 -v ${BASEDIR}/integrate/cephconf:/etc/ceph/ \
+			 -v ${BASEDIR}/integrate/yigconf:/etc/yig/ \
+			 -v ${BASEDIR}:/var/log/yig \
+			 -v ${BASEDIR}:${WORKDIR} \
+                         --net=integrate_vpcbr \
+                         --ip 10.5.0.23 \
+			 journeymidnight/yig /work/replication
+    echo "started replication from local dir"
+fi
@@ -35,6 +35,10 @@ CREATE TABLE `buckets` (
   `createtime` datetime DEFAULT NULL,
   `usages` bigint(20) DEFAULT NULL,
   `versioning` varchar(255) DEFAULT NULL,
+  `tags` JSON DEFAULT NULL,
+  `objectlock` JSON DEFAULT NULL,
+  `notification` JSON DEFAULT NULL,
+  `replication` JSON DEFAULT NULL,
   PRIMARY KEY (`bucketname`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
@@ -76,6 +80,23 @@ CREATE TABLE `gc` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
+--
+-- Table structure for table `replication`
+--
+
+DROP TABLE IF EXISTS `replication`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE `replication` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `mtime` datetime DEFAULT NULL,
+  `triedtimes` int(11) DEFAULT NULL,
+   UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
 --
 -- Table structure for table `gcpart`
 --
@@ -142,6 +163,8 @@ CREATE TABLE `multiparts` (
   `cipher` blob DEFAULT NULL,
   `attrs` JSON DEFAULT NULL,
   `storageclass` tinyint(1) DEFAULT 0,
+  `tags` JSON DEFAULT NULL,
+  `objectlock` JSON DEFAULT NULL,
   UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`uploadtime`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
@@ -197,6 +220,11 @@ CREATE TABLE `objects` (
   `initializationvector` blob DEFAULT NULL,
   `type` tinyint(1) DEFAULT 0,
   `storageclass` tinyint(1) DEFAULT 0,
+  `tags` JSON DEFAULT NULL,
+  `objectlock` JSON DEFAULT NULL,
+  `replicationstatus` varchar(255) DEFAULT NULL,
+  `ssekmskeyid` varchar(255) DEFAULT NULL,
+  `ssecontext` JSON DEFAULT NULL,
    UNIQUE KEY `rowkey` (`bucketname`,`name`,`version`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
@@ -291,4 +319,14 @@ DROP TABLE IF EXISTS `lifecycle`;
 CREATE TABLE `lifecycle` (
                        `bucketname` varchar(255) DEFAULT NULL,
                        `status` varchar(255) DEFAULT NULL
-) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+-- schema version of tables above, upgrade with `yig_migrate up` instead of editing tables by hand
+
+DROP TABLE IF EXISTS `schema_version`;
+CREATE TABLE `schema_version` (
+  `version` int(11) NOT NULL,
+  `description` varchar(255) DEFAULT NULL,
+  `appliedtime` datetime DEFAULT NULL,
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+INSERT INTO `schema_version` VALUES (1,'tables of yig',NOW()),(2,'tagging, object lock, notification and replication',NOW()),(3,'sse-kms key id and encryption context',NOW());
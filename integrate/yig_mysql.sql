@@ -0,0 +1,219 @@
+-- Schema of yig for MySQL 8, used if meta_store is "mysql".
+--
+-- Tables are the same as integrate/yig.sql for TiDB, except:
+--   * JSON columns are `text`, MySQL refuses to build JSON values from binary strings,
+--     which is how go-sql-driver sends []byte arguments.
+--   * strings are utf8mb4 and compared in binary, as Go compares them.
+
+SET NAMES utf8mb4;
+
+DROP TABLE IF EXISTS `buckets`;
+CREATE TABLE `buckets` (
+  `bucketname` varchar(255) NOT NULL DEFAULT '',
+  `acl` text DEFAULT NULL,
+  `cors` text DEFAULT NULL,
+  `logging` text DEFAULT NULL,
+  `lc` text DEFAULT NULL,
+  `uid` varchar(255) DEFAULT NULL,
+  `policy` text DEFAULT NULL,
+  `website` text DEFAULT NULL,
+  `encryption` text DEFAULT NULL,
+  `createtime` datetime DEFAULT NULL,
+  `usages` bigint(20) DEFAULT NULL,
+  `versioning` varchar(255) DEFAULT NULL,
+  `tags` text DEFAULT NULL,
+  `objectlock` text DEFAULT NULL,
+  `notification` text DEFAULT NULL,
+  `replication` text DEFAULT NULL,
+  PRIMARY KEY (`bucketname`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `cluster`;
+CREATE TABLE `cluster` (
+  `fsid` varchar(255) DEFAULT NULL,
+  `pool` varchar(255) DEFAULT NULL,
+  `weight` int(11) DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`fsid`,`pool`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `gc`;
+CREATE TABLE `gc` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL,
+  `pool` varchar(255) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `status` varchar(255) DEFAULT NULL,
+  `mtime` datetime DEFAULT NULL,
+  `part` tinyint(1) DEFAULT NULL,
+  `triedtimes` int(11) DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `replication`;
+CREATE TABLE `replication` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `mtime` datetime DEFAULT NULL,
+  `triedtimes` int(11) DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `gcpart`;
+CREATE TABLE `gcpart` (
+  `partnumber` int(11) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `offset` bigint(20) DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  `lastmodified` datetime DEFAULT NULL,
+  `initializationvector` blob DEFAULT NULL,
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `multipartpart`;
+CREATE TABLE `multipartpart` (
+  `partnumber` int(11) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `offset` bigint(20) DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  `lastmodified` datetime DEFAULT NULL,
+  `initializationvector` blob DEFAULT NULL,
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `uploadtime` bigint(20) UNSIGNED DEFAULT NULL,
+  KEY `rowkey` (`bucketname`,`objectname`,`uploadtime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `multiparts`;
+CREATE TABLE `multiparts` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `uploadtime` bigint(20) UNSIGNED DEFAULT NULL,
+  `initiatorid` varchar(255) DEFAULT NULL,
+  `ownerid` varchar(255) DEFAULT NULL,
+  `contenttype` varchar(255) DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL,
+  `pool` varchar(255) DEFAULT NULL,
+  `acl` text DEFAULT NULL,
+  `sserequest` text DEFAULT NULL,
+  `encryption` blob DEFAULT NULL,
+  `cipher` blob DEFAULT NULL,
+  `attrs` text DEFAULT NULL,
+  `storageclass` tinyint(1) DEFAULT 0,
+  `tags` text DEFAULT NULL,
+  `objectlock` text DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`uploadtime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `objectpart`;
+CREATE TABLE `objectpart` (
+  `partnumber` int(11) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `offset` bigint(20) DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  `lastmodified` datetime DEFAULT NULL,
+  `initializationvector` blob DEFAULT NULL,
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` varchar(255) DEFAULT NULL,
+  KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `objects`;
+CREATE TABLE `objects` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `name` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL,
+  `pool` varchar(255) DEFAULT NULL,
+  `ownerid` varchar(255) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `lastmodifiedtime` datetime DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  `contenttype` varchar(255) DEFAULT NULL,
+  `customattributes` text DEFAULT NULL,
+  `acl` text DEFAULT NULL,
+  `nullversion` tinyint(1) DEFAULT NULL,
+  `deletemarker` tinyint(1) DEFAULT NULL,
+  `ssetype` varchar(255) DEFAULT NULL,
+  `encryptionkey` blob DEFAULT NULL,
+  `initializationvector` blob DEFAULT NULL,
+  `type` tinyint(1) DEFAULT 0,
+  `storageclass` tinyint(1) DEFAULT 0,
+  `tags` text DEFAULT NULL,
+  `objectlock` text DEFAULT NULL,
+  `replicationstatus` varchar(255) DEFAULT NULL,
+  `ssekmskeyid` varchar(255) DEFAULT NULL,
+  `ssecontext` text DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`name`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `restoreobjectpart`;
+CREATE TABLE `restoreobjectpart` (
+  `partnumber` int(11) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `offset` bigint(20) DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  `lastmodified` datetime DEFAULT NULL,
+  `initializationvector` blob DEFAULT NULL,
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `restoreobjects`;
+CREATE TABLE `restoreobjects` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `version` bigint(20) UNSIGNED DEFAULT NULL,
+  `status` tinyint(1) DEFAULT '0',
+  `lifetime` tinyint(2) DEFAULT '1',
+  `lastmodifiedtime` datetime DEFAULT NULL,
+  `location` varchar(255) DEFAULT NULL,
+  `pool` varchar(255) DEFAULT NULL,
+  `ownerid` varchar(255) DEFAULT NULL,
+  `size` bigint(20) DEFAULT NULL,
+  `objectid` varchar(255) DEFAULT NULL,
+  `etag` varchar(255) DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `objmap`;
+CREATE TABLE `objmap` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `objectname` varchar(255) DEFAULT NULL,
+  `nullvernum` bigint(20) DEFAULT NULL,
+  UNIQUE KEY `objmap` (`bucketname`,`objectname`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `users`;
+CREATE TABLE `users` (
+  `userid` varchar(255) DEFAULT NULL,
+  `bucketname` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `lifecycle`;
+CREATE TABLE `lifecycle` (
+  `bucketname` varchar(255) DEFAULT NULL,
+  `status` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+DROP TABLE IF EXISTS `schema_version`;
+CREATE TABLE `schema_version` (
+  `version` int(11) NOT NULL,
+  `description` varchar(255) DEFAULT NULL,
+  `appliedtime` datetime DEFAULT NULL,
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+INSERT INTO `schema_version` VALUES (1,'tables of yig',NOW()),(2,'tagging, object lock, notification and replication',NOW()),(3,'sse-kms key id and encryption context',NOW());
@@ -0,0 +1,217 @@
+-- Schema of yig for PostgreSQL, used if meta_store is "postgres".
+--
+-- Tables are the same as integrate/yig.sql for TiDB, except:
+--   * versions and upload times are numeric(20), there is no unsigned bigint.
+--   * names are collated in "C", i.e. compared in bytes as Go compares them.
+--   * lastmodified of parts is stored as the string yig writes.
+
+DROP TABLE IF EXISTS buckets;
+CREATE TABLE buckets (
+  bucketname varchar(255) COLLATE "C" NOT NULL DEFAULT '',
+  acl json DEFAULT NULL,
+  cors json DEFAULT NULL,
+  logging json DEFAULT NULL,
+  lc json DEFAULT NULL,
+  uid varchar(255) DEFAULT NULL,
+  policy json DEFAULT NULL,
+  website json DEFAULT NULL,
+  encryption json DEFAULT NULL,
+  createtime timestamp DEFAULT NULL,
+  usages bigint DEFAULT NULL,
+  versioning varchar(255) DEFAULT NULL,
+  tags json DEFAULT NULL,
+  objectlock json DEFAULT NULL,
+  notification json DEFAULT NULL,
+  replication json DEFAULT NULL,
+  PRIMARY KEY (bucketname)
+);
+
+DROP TABLE IF EXISTS cluster;
+CREATE TABLE cluster (
+  fsid varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  weight integer DEFAULT NULL,
+  UNIQUE (fsid, pool)
+);
+
+DROP TABLE IF EXISTS gc;
+CREATE TABLE gc (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  status varchar(255) DEFAULT NULL,
+  mtime timestamp DEFAULT NULL,
+  part boolean DEFAULT NULL,
+  triedtimes integer DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+);
+
+DROP TABLE IF EXISTS replication;
+CREATE TABLE replication (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  mtime timestamp DEFAULT NULL,
+  triedtimes integer DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+);
+
+DROP TABLE IF EXISTS gcpart;
+CREATE TABLE gcpart (
+  partnumber integer DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  "offset" bigint DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified varchar(255) DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL
+);
+CREATE INDEX gcpart_rowkey ON gcpart (bucketname, objectname, version);
+
+DROP TABLE IF EXISTS multipartpart;
+CREATE TABLE multipartpart (
+  partnumber integer DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  "offset" bigint DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified varchar(255) DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  uploadtime numeric(20) DEFAULT NULL
+);
+CREATE INDEX multipartpart_rowkey ON multipartpart (bucketname, objectname, uploadtime);
+
+DROP TABLE IF EXISTS multiparts;
+CREATE TABLE multiparts (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  uploadtime numeric(20) DEFAULT NULL,
+  initiatorid varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  acl json DEFAULT NULL,
+  sserequest json DEFAULT NULL,
+  encryption bytea DEFAULT NULL,
+  cipher bytea DEFAULT NULL,
+  attrs json DEFAULT NULL,
+  storageclass smallint DEFAULT 0,
+  tags json DEFAULT NULL,
+  objectlock json DEFAULT NULL,
+  UNIQUE (bucketname, objectname, uploadtime)
+);
+
+DROP TABLE IF EXISTS objectpart;
+CREATE TABLE objectpart (
+  partnumber integer DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  "offset" bigint DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified varchar(255) DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL
+);
+CREATE INDEX objectpart_rowkey ON objectpart (bucketname, objectname, version);
+
+DROP TABLE IF EXISTS objects;
+CREATE TABLE objects (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  name varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  lastmodifiedtime timestamp DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  customattributes json DEFAULT NULL,
+  acl json DEFAULT NULL,
+  nullversion boolean DEFAULT NULL,
+  deletemarker boolean DEFAULT NULL,
+  ssetype varchar(255) DEFAULT NULL,
+  encryptionkey bytea DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  type smallint DEFAULT 0,
+  storageclass smallint DEFAULT 0,
+  tags json DEFAULT NULL,
+  objectlock json DEFAULT NULL,
+  replicationstatus varchar(255) DEFAULT NULL,
+  ssekmskeyid varchar(255) DEFAULT NULL,
+  ssecontext json DEFAULT NULL,
+  UNIQUE (bucketname, name, version)
+);
+
+DROP TABLE IF EXISTS restoreobjectpart;
+CREATE TABLE restoreobjectpart (
+  partnumber integer DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  "offset" bigint DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified varchar(255) DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL
+);
+CREATE INDEX restoreobjectpart_rowkey ON restoreobjectpart (bucketname, objectname, version);
+
+DROP TABLE IF EXISTS restoreobjects;
+CREATE TABLE restoreobjects (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  status smallint DEFAULT 0,
+  lifetime smallint DEFAULT 1,
+  lastmodifiedtime timestamp DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+);
+
+DROP TABLE IF EXISTS objmap;
+CREATE TABLE objmap (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  nullvernum numeric(20) DEFAULT NULL,
+  UNIQUE (bucketname, objectname)
+);
+
+DROP TABLE IF EXISTS users;
+CREATE TABLE users (
+  userid varchar(255) DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL
+);
+
+DROP TABLE IF EXISTS lifecycle;
+CREATE TABLE lifecycle (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  status varchar(255) DEFAULT NULL
+);
+
+DROP TABLE IF EXISTS schema_version;
+CREATE TABLE schema_version (
+  version integer NOT NULL,
+  description varchar(255) DEFAULT NULL,
+  appliedtime timestamp DEFAULT NULL,
+  PRIMARY KEY (version)
+);
+INSERT INTO schema_version VALUES (1,'tables of yig',NOW()),(2,'tagging, object lock, notification and replication',NOW()),(3,'sse-kms key id and encryption context',NOW());
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const CLUSTER_NAME = "memory"
+
+var pools = []string{
+	backend.SMALL_FILE_POOLNAME,
+	backend.BIG_FILE_POOLNAME,
+	backend.GLACIER_FILE_POOLNAME,
+}
+
+// Initialize returns a single empty cluster named CLUSTER_NAME
+func Initialize(config helper.Config) map[string]backend.Cluster {
+	c := NewMemoryCluster(CLUSTER_NAME)
+	return map[string]backend.Cluster{c.Name: c}
+}
+
+// MemoryCluster keeps objects in memory, it is meant for unit tests.
+// Put and Remove failures could be injected by SetPutHook and SetRemoveHook.
+type MemoryCluster struct {
+	Name    string
+	counter uint64
+
+	lock    sync.RWMutex
+	objects map[string]map[string][]byte // pool -> oid -> data
+
+	putHook    func(poolName string) error
+	removeHook func(poolName, oid string) error
+}
+
+func NewMemoryCluster(name string) *MemoryCluster {
+	cluster := &MemoryCluster{
+		Name:    name,
+		objects: make(map[string]map[string][]byte),
+	}
+	for _, pool := range pools {
+		cluster.objects[pool] = make(map[string][]byte)
+	}
+	return cluster
+}
+
+// SetPutHook sets a function called before every Put and Append, the operation
+// fails with the returned error if it's not nil and nothing is written.
+// nil clears the hook.
+func (cluster *MemoryCluster) SetPutHook(hook func(poolName string) error) {
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	cluster.putHook = hook
+}
+
+// SetRemoveHook sets a function called before every Remove, the operation
+// fails with the returned error if it's not nil and the object is kept.
+// nil clears the hook.
+func (cluster *MemoryCluster) SetRemoveHook(hook func(poolName, oid string) error) {
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	cluster.removeHook = hook
+}
+
+// Objects returns sorted oids of all objects in the pool
+func (cluster *MemoryCluster) Objects(poolName string) (oids []string) {
+	cluster.lock.RLock()
+	defer cluster.lock.RUnlock()
+	for oid := range cluster.objects[poolName] {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+	return
+}
+
+// List calls fn with every object in the pool
+func (cluster *MemoryCluster) List(poolName string, fn func(objectName string) error) error {
+	if _, ok := cluster.objects[poolName]; !ok {
+		return fmt.Errorf("Bad poolname %s", poolName)
+	}
+	for _, oid := range cluster.Objects(poolName) {
+		err := fn(oid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cluster *MemoryCluster) ID() string {
+	return cluster.Name
+}
+
+func (cluster *MemoryCluster) getUniqUploadName() string {
+	v := atomic.AddUint64(&cluster.counter, 1)
+	return fmt.Sprintf("%s:%d", cluster.Name, v)
+}
+
+func (cluster *MemoryCluster) GetUsage() (usage backend.Usage, err error) {
+	return usage, nil
+}
+
+func (cluster *MemoryCluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	oid = cluster.getUniqUploadName()
+	if err = cluster.callPutHook(poolname); err != nil {
+		return oid, 0, err
+	}
+	buffer, err := ioutil.ReadAll(data)
+	if err != nil {
+		return oid, 0, fmt.Errorf("Write failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	pool, ok := cluster.objects[poolname]
+	if !ok {
+		return oid, 0, fmt.Errorf("Bad poolname %s", poolname)
+	}
+	pool[oid] = buffer
+	return oid, uint64(len(buffer)), nil
+}
+
+func (cluster *MemoryCluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	oid = existName
+	if len(oid) == 0 {
+		oid = cluster.getUniqUploadName()
+	}
+	if poolname != backend.BIG_FILE_POOLNAME {
+		return oid, 0,
+			errors.New("specified pool must be used for storing big file.")
+	}
+	if err = cluster.callPutHook(poolname); err != nil {
+		return oid, 0, err
+	}
+	chunk, err := ioutil.ReadAll(data)
+	if err != nil {
+		return oid, 0, fmt.Errorf("Write failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	pool := cluster.objects[poolname]
+	// copy on write, buffers returned by GetReader are never modified
+	buffer := make([]byte, offset, offset+int64(len(chunk)))
+	copy(buffer, pool[oid])
+	pool[oid] = append(buffer, chunk...)
+	return oid, uint64(len(chunk)), nil
+}
+
+func (cluster *MemoryCluster) callPutHook(poolname string) error {
+	cluster.lock.RLock()
+	hook := cluster.putHook
+	cluster.lock.RUnlock()
+	if hook == nil {
+		return nil
+	}
+	return hook(poolname)
+}
+
+func (cluster *MemoryCluster) callRemoveHook(poolname, oid string) error {
+	cluster.lock.RLock()
+	hook := cluster.removeHook
+	cluster.lock.RUnlock()
+	if hook == nil {
+		return nil
+	}
+	return hook(poolname, oid)
+}
+
+func (cluster *MemoryCluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	cluster.lock.RLock()
+	defer cluster.lock.RUnlock()
+	buffer, ok := cluster.objects[poolName][oid]
+	if !ok {
+		return nil, fmt.Errorf("No such object. pool:%s oid:%s", poolName, oid)
+	}
+	if startOffset < 0 || startOffset > int64(len(buffer)) {
+		return nil, fmt.Errorf("Bad offset %d. pool:%s oid:%s", startOffset, poolName, oid)
+	}
+	buffer = buffer[startOffset:]
+	if length != 0 && length < uint64(len(buffer)) {
+		buffer = buffer[:length]
+	}
+	return ioutil.NopCloser(bytes.NewReader(buffer)), nil
+}
+
+// Remove succeeds if the object does not exist, so removing garbage is idempotent
+func (cluster *MemoryCluster) Remove(poolname string, oid string) error {
+	if err := cluster.callRemoveHook(poolname, oid); err != nil {
+		return err
+	}
+	cluster.lock.Lock()
+	defer cluster.lock.Unlock()
+	delete(cluster.objects[poolname], oid)
+	return nil
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/journeymidnight/yig/backend"
+)
+
+func readAll(t *testing.T, cluster *MemoryCluster, pool, oid string, offset int64, length uint64) []byte {
+	reader, err := cluster.GetReader(pool, oid, offset, length)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Read error:", err)
+	}
+	return data
+}
+
+func TestMemoryCluster_PutAndGet(t *testing.T) {
+	cluster := NewMemoryCluster("test")
+
+	data := []byte("hello memory backend")
+	for _, pool := range pools {
+		oid, size, err := cluster.Put(pool, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal("Put error:", err)
+		}
+		if size != uint64(len(data)) {
+			t.Fatalf("Put size %d, expected %d", size, len(data))
+		}
+		if got := readAll(t, cluster, pool, oid, 0, uint64(len(data))); !bytes.Equal(got, data) {
+			t.Fatalf("Read %q, expected %q", got, data)
+		}
+		if got := readAll(t, cluster, pool, oid, 6, 6); string(got) != "memory" {
+			t.Fatalf("Read range %q, expected %q", got, "memory")
+		}
+		if got := readAll(t, cluster, pool, oid, 6, 0); !bytes.Equal(got, data[6:]) {
+			t.Fatalf("Read to end %q, expected %q", got, data[6:])
+		}
+		if oids := cluster.Objects(pool); len(oids) != 1 || oids[0] != oid {
+			t.Fatalf("Objects %v, expected [%s]", oids, oid)
+		}
+		if err = cluster.Remove(pool, oid); err != nil {
+			t.Fatal("Remove error:", err)
+		}
+		if _, err = cluster.GetReader(pool, oid, 0, 0); err == nil {
+			t.Fatal("GetReader removed object, expected error")
+		}
+		if err = cluster.Remove(pool, oid); err != nil {
+			t.Fatal("Remove removed object error:", err)
+		}
+	}
+
+	if _, _, err := cluster.Put("unknown", bytes.NewReader(data)); err == nil {
+		t.Fatal("Put to unknown pool, expected error")
+	}
+}
+
+func TestMemoryCluster_Append(t *testing.T) {
+	cluster := NewMemoryCluster("test")
+
+	if _, _, err := cluster.Append(backend.SMALL_FILE_POOLNAME, "", bytes.NewReader([]byte("a")), 0); err == nil {
+		t.Fatal("Append to small file pool, expected error")
+	}
+	oid, size, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("hello")), 0)
+	if err != nil || size != 5 {
+		t.Fatal("Append error:", err, size)
+	}
+	reader, err := cluster.GetReader(backend.BIG_FILE_POOLNAME, oid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	_, size, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte(" world")), 5)
+	if err != nil || size != 6 {
+		t.Fatal("Append error:", err, size)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 0); string(got) != "hello world" {
+		t.Fatalf("Read %q, expected %q", got, "hello world")
+	}
+	// readers opened before are not affected
+	if got, _ := ioutil.ReadAll(reader); string(got) != "hello" {
+		t.Fatalf("Read %q, expected %q", got, "hello")
+	}
+}
+
+func TestMemoryCluster_Hooks(t *testing.T) {
+	cluster := NewMemoryCluster("test")
+	injected := errors.New("injected")
+
+	cluster.SetPutHook(func(poolName string) error {
+		if poolName == backend.BIG_FILE_POOLNAME {
+			return injected
+		}
+		return nil
+	})
+	if _, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader([]byte("a"))); err != injected {
+		t.Fatal("Put with hook, expected injected error, got", err)
+	}
+	if _, _, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("a")), 0); err != injected {
+		t.Fatal("Append with hook, expected injected error, got", err)
+	}
+	if oids := cluster.Objects(backend.BIG_FILE_POOLNAME); len(oids) != 0 {
+		t.Fatal("Objects written with failed put:", oids)
+	}
+	oid, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("a")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+
+	var removed []string
+	cluster.SetRemoveHook(func(poolName, oid string) error {
+		removed = append(removed, oid)
+		return injected
+	})
+	if err = cluster.Remove(backend.SMALL_FILE_POOLNAME, oid); err != injected {
+		t.Fatal("Remove with hook, expected injected error, got", err)
+	}
+	if len(removed) != 1 || removed[0] != oid {
+		t.Fatal("Remove hook called with", removed)
+	}
+	if oids := cluster.Objects(backend.SMALL_FILE_POOLNAME); len(oids) != 1 {
+		t.Fatal("Object removed with failed remove:", oids)
+	}
+
+	cluster.SetRemoveHook(nil)
+	if err = cluster.Remove(backend.SMALL_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	if oids := cluster.Objects(backend.SMALL_FILE_POOLNAME); len(oids) != 0 {
+		t.Fatal("Object not removed:", oids)
+	}
+}
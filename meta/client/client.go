@@ -22,8 +22,13 @@ type Client interface {
 	ReplaceObjectMetas(object *Object, tx DB) (err error)
 	DeleteObject(object *Object, tx DB) error
 	UpdateObject(object *Object, tx DB) (err error)
+	// TransitObject updates data location of object as UpdateObject, only if it's still located
+	// as sourceObject, transited is false if it's changed since read
+	TransitObject(object, sourceObject *Object, tx DB) (transited bool, err error)
 	UpdateObjectAcl(object *Object) error
 	UpdateObjectAttrs(object *Object) error
+	UpdateObjectTags(object *Object) error
+	UpdateObjectLock(object *Object) error
 	//bucket
 	GetBucket(bucketName string) (bucket *Bucket, err error)
 	GetBuckets() (buckets []Bucket, err error)
@@ -58,10 +63,18 @@ type Client interface {
 	PutFreezerToGarbageCollection(object *Freezer, tx DB) (err error)
 	ScanGarbageCollection(limit int, startRowKey string) ([]GarbageCollection, error)
 	RemoveGarbageCollection(garbage GarbageCollection) error
+	//replication
+	PutObjectToReplication(object *Object, tx DB) error
+	ScanReplication(limit int, startRowKey string) ([]Replication, error)
+	UpdateReplicationTriedTimes(replication Replication) error
+	RemoveReplication(replication Replication, status string) error
 	//freezer
 	CreateFreezer(freezer *Freezer) (err error)
 	GetFreezer(bucketName, objectName, version string) (freezer *Freezer, err error)
 	GetFreezerStatus(bucketName, objectName, version string) (freezer *Freezer, err error)
 	UploadFreezerDate(bucketName, objectName string, lifetime int) (err error)
 	DeleteFreezer(bucketName, objectName string, tx DB) (err error)
+	ScanFreezers(limit int, startRowKey string, status Status) (freezers []Freezer, err error)
+	UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo Status) (updated bool, err error)
+	PutFreezer(freezer *Freezer, status Status, tx DB) (err error)
 }
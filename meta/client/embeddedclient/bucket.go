@@ -0,0 +1,61 @@
+package embeddedclient
+
+import (
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (e *EmbeddedClient) PutBucket(bucket Bucket) error {
+	return e.change("PutBucket", func() error {
+		return e.MemoryClient.PutBucket(bucket)
+	}, bucket)
+}
+
+// CheckAndPutBucket journals the bucket only if created
+func (e *EmbeddedClient) CheckAndPutBucket(bucket Bucket) (created bool, err error) {
+	err = e.change("CheckAndPutBucket", func() (err error) {
+		created, err = e.MemoryClient.CheckAndPutBucket(bucket)
+		if err == nil && !created {
+			return errUnchanged
+		}
+		return err
+	}, bucket)
+	return created, err
+}
+
+func (e *EmbeddedClient) DeleteBucket(bucket Bucket) error {
+	return e.change("DeleteBucket", func() error {
+		return e.MemoryClient.DeleteBucket(bucket)
+	}, bucket)
+}
+
+func (e *EmbeddedClient) UpdateUsage(bucketName string, size int64, tx DB) error {
+	return e.change("UpdateUsage", func() error {
+		return e.MemoryClient.UpdateUsage(bucketName, size, tx)
+	}, bucketName, size)
+}
+
+//lc
+func (e *EmbeddedClient) PutBucketToLifeCycle(lifeCycle LifeCycle) error {
+	return e.change("PutBucketToLifeCycle", func() error {
+		return e.MemoryClient.PutBucketToLifeCycle(lifeCycle)
+	}, lifeCycle)
+}
+
+func (e *EmbeddedClient) RemoveBucketFromLifeCycle(bucket Bucket) error {
+	return e.change("RemoveBucketFromLifeCycle", func() error {
+		return e.MemoryClient.RemoveBucketFromLifeCycle(bucket)
+	}, bucket)
+}
+
+//user
+func (e *EmbeddedClient) AddBucketForUser(bucketName, userId string) error {
+	return e.change("AddBucketForUser", func() error {
+		return e.MemoryClient.AddBucketForUser(bucketName, userId)
+	}, bucketName, userId)
+}
+
+func (e *EmbeddedClient) RemoveBucketForUser(bucketName string, userId string) error {
+	return e.change("RemoveBucketForUser", func() error {
+		return e.MemoryClient.RemoveBucketForUser(bucketName, userId)
+	}, bucketName, userId)
+}
@@ -0,0 +1,214 @@
+package embeddedclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/client/memoryclient"
+)
+
+const (
+	lockFile     = "LOCK"
+	snapshotFile = "snapshot.json"
+	journalFile  = "journal"
+
+	// the journal is merged into snapshot after so many records
+	compactRecords = 100000
+)
+
+// EmbeddedClient keeps metadata of a single node deployment in a local directory,
+// no database is needed.
+//
+// All metadata is held in a MemoryClient, changes are appended to a journal
+// and synced before applied to memory. The journal is merged into a snapshot of all tables
+// on start and every compactRecords changes. Changes are serialized, reads are
+// served from memory as MemoryClient does.
+//
+// Transactions are not supported, as MemoryClient: every change in a transaction is
+// journaled on its own, and nothing is rolled back on AbortTrans. So a crash could leave
+// part of a multi-step change, e.g. an object put without its bucket usage updated.
+//
+// The directory is locked, so only one process could open it at a time.
+type EmbeddedClient struct {
+	*memoryclient.MemoryClient
+
+	dir      string
+	lock     sync.Mutex // serializes changes, so they are journaled in the order applied
+	lockFile *os.File
+	journal  *os.File
+	seq      uint64 // sequence number of the last journaled change
+	records  int    // records in journal
+	size     int64  // bytes of records in journal
+	failed   error  // set if journal could not be rolled back, changes are refused then
+}
+
+func NewEmbeddedClient() *EmbeddedClient {
+	e, err := Open(helper.CONFIG.EmbeddedMetaPath)
+	if err != nil {
+		helper.Logger.Error("Failed to open embedded meta store:", helper.CONFIG.EmbeddedMetaPath, err)
+		os.Exit(1)
+	}
+	return e
+}
+
+// Open loads metadata from dir, which is created if not exists
+func Open(dir string) (e *EmbeddedClient, err error) {
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return
+	}
+	e = &EmbeddedClient{
+		MemoryClient: memoryclient.NewMemoryClient(),
+		dir:          dir,
+	}
+	e.lockFile, err = os.OpenFile(filepath.Join(dir, lockFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(e.lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		e.lockFile.Close()
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			e.Close()
+			e = nil
+		}
+	}()
+
+	err = e.loadSnapshot()
+	if err != nil {
+		return
+	}
+	err = e.replay()
+	if err != nil {
+		return
+	}
+	e.journal, err = os.OpenFile(filepath.Join(dir, journalFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	err = e.compact()
+	return
+}
+
+// Close releases the directory, metadata is not accessible after Close
+func (e *EmbeddedClient) Close() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.journal != nil {
+		e.journal.Close()
+		e.journal = nil
+	}
+	return e.lockFile.Close()
+}
+
+// snapshotHeader is the first line of snapshot file, followed by tables dumped by MemoryClient
+type snapshotHeader struct {
+	Seq uint64 // changes up to Seq are in the snapshot
+}
+
+func (e *EmbeddedClient) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(e.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	var header snapshotHeader
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		return err
+	}
+	e.seq = header.Seq
+	return e.MemoryClient.Load(reader)
+}
+
+// compact saves all tables to a new snapshot and empties the journal
+func (e *EmbeddedClient) compact() error {
+	path := filepath.Join(e.dir, snapshotFile)
+	f, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	err = json.NewEncoder(writer).Encode(snapshotHeader{Seq: e.seq})
+	if err == nil {
+		err = e.MemoryClient.Dump(writer)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return err
+	}
+	err = syncDir(e.dir)
+	if err != nil {
+		return err
+	}
+	// records left in journal when crashed here are skipped by sequence number on load
+	err = e.journal.Truncate(0)
+	if err != nil {
+		return err
+	}
+	e.records = 0
+	e.size = 0
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// readRecords calls fn for every record in the journal, a torn record at the end,
+// written partially when crashed, is ignored
+func readRecords(r io.Reader, fn func(r record) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) != 0 {
+				helper.Logger.Warn("Ignore torn record at the end of embedded meta journal")
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var rec record
+		err = json.Unmarshal(line, &rec)
+		if err != nil {
+			return err
+		}
+		err = fn(rec)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package embeddedclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	helper.CONFIG.PiggybackUpdateUsage = true
+	os.Exit(m.Run())
+}
+
+func open(t *testing.T, dir string) *EmbeddedClient {
+	e, err := Open(dir)
+	if err != nil {
+		t.Fatal("Open error:", err)
+	}
+	return e
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "embedded")
+	if err != nil {
+		t.Fatal("TempDir error:", err)
+	}
+	return dir
+}
+
+func TestEmbeddedClient_Reopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	e := open(t, dir)
+	now := time.Now().UTC()
+	if created, err := e.CheckAndPutBucket(Bucket{Name: "bucket", OwnerId: "user", CreateTime: now}); !created {
+		t.Fatal("CheckAndPutBucket returns", created, err)
+	}
+	if err := e.AddBucketForUser("bucket", "user"); err != nil {
+		t.Fatal("AddBucketForUser error:", err)
+	}
+	v1 := &Object{BucketName: "bucket", Name: "a", Size: 1, LastModifiedTime: now}
+	v2 := &Object{BucketName: "bucket", Name: "a", Size: 2, LastModifiedTime: now.Add(time.Second)}
+	for _, o := range []*Object{v1, v2} {
+		if err := e.PutObject(o, nil); err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+	if err := e.UpdateUsage("bucket", 3, nil); err != nil {
+		t.Fatal("UpdateUsage error:", err)
+	}
+	if err := e.DeleteObject(v1, nil); err != nil {
+		t.Fatal("DeleteObject error:", err)
+	}
+	if err := e.PutObjectToGarbageCollection(v1, nil); err != nil {
+		t.Fatal("PutObjectToGarbageCollection error:", err)
+	}
+	moved, stale := *v2, *v2
+	moved.Location = "cold"
+	stale.ObjectId = "stale"
+	if transited, err := e.TransitObject(&moved, &stale, nil); transited || err != nil {
+		t.Fatal("TransitObject from stale object returns", transited, err)
+	}
+	if transited, err := e.TransitObject(&moved, v2, nil); !transited || err != nil {
+		t.Fatal("TransitObject returns", transited, err)
+	}
+	multipart := Multipart{BucketName: "bucket", ObjectName: "m", InitialTime: now,
+		Parts: make(map[int]*Part)}
+	if err := e.CreateMultipart(multipart); err != nil {
+		t.Fatal("CreateMultipart error:", err)
+	}
+	part := &Part{PartNumber: 1, Size: 5, ObjectId: "oid", LastModified: now.Format(CREATE_TIME_LAYOUT)}
+	if err := e.PutObjectPart(&multipart, part, nil); err != nil {
+		t.Fatal("PutObjectPart error:", err)
+	}
+	if err := e.CreateFreezer(&Freezer{BucketName: "bucket", Name: "a", LifeTime: 1}); err != nil {
+		t.Fatal("CreateFreezer error:", err)
+	}
+	if err := e.UploadFreezerDate("bucket", "a", 7); err != nil {
+		t.Fatal("UploadFreezerDate error:", err)
+	}
+	e.Close()
+
+	check := func(e *EmbeddedClient) {
+		bucket, err := e.GetBucket("bucket")
+		if err != nil || bucket.Usage != 3 || bucket.OwnerId != "user" {
+			t.Fatal("GetBucket returns", bucket, err)
+		}
+		buckets, err := e.GetUserBuckets("user")
+		if err != nil || len(buckets) != 1 {
+			t.Fatal("GetUserBuckets returns", buckets, err)
+		}
+		all, err := e.GetAllObject("bucket", "a", "")
+		if err != nil || len(all) != 1 || all[0].Size != 2 || all[0].Location != "cold" {
+			t.Fatal("GetAllObject returns", all, err)
+		}
+		gcs, err := e.ScanGarbageCollection(10, "")
+		if err != nil || len(gcs) != 1 || gcs[0].ObjectName != "a" {
+			t.Fatal("ScanGarbageCollection returns", gcs, err)
+		}
+		uploads, _, _, _, _, err := e.ListMultipartUploads("bucket", "", "", "", "", "", 10)
+		if err != nil || len(uploads) != 1 {
+			t.Fatal("ListMultipartUploads returns", uploads, err)
+		}
+		got, err := e.GetMultipart("bucket", "m", uploads[0].UploadId)
+		if err != nil || len(got.Parts) != 1 || got.Parts[1].ObjectId != "oid" {
+			t.Fatal("GetMultipart returns", got, err)
+		}
+		freezer, err := e.GetFreezer("bucket", "a", "")
+		if err != nil || freezer.LifeTime != 7 {
+			t.Fatal("GetFreezer returns", freezer, err)
+		}
+	}
+	// loaded from journal
+	e = open(t, dir)
+	check(e)
+	e.Close()
+	// loaded from snapshot
+	e = open(t, dir)
+	check(e)
+	e.Close()
+}
+
+func TestEmbeddedClient_Lock(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	e := open(t, dir)
+	if _, err := Open(dir); err == nil {
+		t.Fatal("Open locked directory, expected error")
+	}
+	e.Close()
+	e = open(t, dir)
+	e.Close()
+}
+
+func TestEmbeddedClient_Recover(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	journal := filepath.Join(dir, journalFile)
+
+	e := open(t, dir)
+	if _, err := e.CheckAndPutBucket(Bucket{Name: "bucket"}); err != nil {
+		t.Fatal("CheckAndPutBucket error:", err)
+	}
+	if err := e.UpdateUsage("bucket", 10, nil); err != nil {
+		t.Fatal("UpdateUsage error:", err)
+	}
+	e.Close()
+	records, err := ioutil.ReadFile(journal)
+	if err != nil {
+		t.Fatal("ReadFile error:", err)
+	}
+
+	// journal merged into snapshot, but not truncated as crashed
+	e = open(t, dir)
+	e.Close()
+	// with a record written partially
+	err = ioutil.WriteFile(journal, append(records, []byte(`{"Seq":3,"Op":"UpdateU`)...), 0600)
+	if err != nil {
+		t.Fatal("WriteFile error:", err)
+	}
+	e = open(t, dir)
+	defer e.Close()
+	bucket, err := e.GetBucket("bucket")
+	if err != nil || bucket.Usage != 10 {
+		t.Fatal("GetBucket returns", bucket, err)
+	}
+	if err = e.UpdateUsage("bucket", 1, nil); err != nil {
+		t.Fatal("UpdateUsage error:", err)
+	}
+	if e.seq != 3 {
+		t.Fatal("Sequence number", e.seq, "expected 3")
+	}
+}
+
+func TestEmbeddedClient_FailedChange(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	journal := filepath.Join(dir, journalFile)
+
+	e := open(t, dir)
+	object := &Object{BucketName: "bucket", Name: "a", LastModifiedTime: time.Now().UTC()}
+	if err := e.PutObject(object, nil); err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	records, err := ioutil.ReadFile(journal)
+	if err != nil {
+		t.Fatal("ReadFile error:", err)
+	}
+	// records of failed or no changes are cut off
+	if err = e.PutObject(object, nil); err == nil {
+		t.Fatal("PutObject duplicate object, expected error")
+	}
+	if created, err := e.CheckAndPutBucket(Bucket{Name: "bucket"}); !created || err != nil {
+		t.Fatal("CheckAndPutBucket returns", created, err)
+	}
+	if created, err := e.CheckAndPutBucket(Bucket{Name: "bucket"}); created || err != nil {
+		t.Fatal("CheckAndPutBucket existing bucket returns", created, err)
+	}
+	got, err := ioutil.ReadFile(journal)
+	if err != nil || len(got) <= len(records) || string(got[:len(records)]) != string(records) {
+		t.Fatalf("Journal is %q, error %v", got, err)
+	}
+	if e.seq != 2 {
+		t.Fatal("Sequence number", e.seq, "expected 2")
+	}
+	e.Close()
+
+	// the last record failed to apply is left as crashed before cut off
+	failed, err := encode(2, "PutObject", object)
+	if err != nil {
+		t.Fatal("encode error:", err)
+	}
+	err = ioutil.WriteFile(journal, append(records, failed...), 0600)
+	if err != nil {
+		t.Fatal("WriteFile error:", err)
+	}
+	os.Remove(filepath.Join(dir, snapshotFile))
+	e = open(t, dir)
+	if e.seq != 1 {
+		t.Fatal("Sequence number", e.seq, "expected 1")
+	}
+	e.Close()
+
+	// but not in the middle of journal
+	os.Remove(filepath.Join(dir, snapshotFile))
+	next, err := encode(3, "UpdateUsage", "bucket", 1)
+	if err != nil {
+		t.Fatal("encode error:", err)
+	}
+	err = ioutil.WriteFile(journal, append(append(records, failed...), next...), 0600)
+	if err != nil {
+		t.Fatal("WriteFile error:", err)
+	}
+	if e, err = Open(dir); err == nil {
+		e.Close()
+		t.Fatal("Open journal with a failed record in the middle, expected error")
+	}
+}
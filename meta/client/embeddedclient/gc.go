@@ -0,0 +1,79 @@
+package embeddedclient
+
+import (
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// Entries of gc and replication are queued with current time as mtime,
+// which changes to the time of replay when loaded from journal.
+
+//gc
+func (e *EmbeddedClient) PutObjectToGarbageCollection(object *Object, tx DB) error {
+	return e.change("PutObjectToGarbageCollection", func() error {
+		return e.MemoryClient.PutObjectToGarbageCollection(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) PutFreezerToGarbageCollection(object *Freezer, tx DB) error {
+	return e.change("PutFreezerToGarbageCollection", func() error {
+		return e.MemoryClient.PutFreezerToGarbageCollection(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) RemoveGarbageCollection(garbage GarbageCollection) error {
+	return e.change("RemoveGarbageCollection", func() error {
+		return e.MemoryClient.RemoveGarbageCollection(garbage)
+	}, garbage)
+}
+
+//replication
+func (e *EmbeddedClient) PutObjectToReplication(object *Object, tx DB) error {
+	return e.change("PutObjectToReplication", func() error {
+		return e.MemoryClient.PutObjectToReplication(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateReplicationTriedTimes(replication Replication) error {
+	return e.change("UpdateReplicationTriedTimes", func() error {
+		return e.MemoryClient.UpdateReplicationTriedTimes(replication)
+	}, replication)
+}
+
+func (e *EmbeddedClient) RemoveReplication(replication Replication, status string) error {
+	return e.change("RemoveReplication", func() error {
+		return e.MemoryClient.RemoveReplication(replication, status)
+	}, replication, status)
+}
+
+//freezer
+func (e *EmbeddedClient) CreateFreezer(freezer *Freezer) error {
+	return e.change("CreateFreezer", func() error {
+		return e.MemoryClient.CreateFreezer(freezer)
+	}, freezer)
+}
+
+func (e *EmbeddedClient) UploadFreezerDate(bucketName, objectName string, lifetime int) error {
+	return e.change("UploadFreezerDate", func() error {
+		return e.MemoryClient.UploadFreezerDate(bucketName, objectName, lifetime)
+	}, bucketName, objectName, lifetime)
+}
+
+func (e *EmbeddedClient) DeleteFreezer(bucketName, objectName string, tx DB) error {
+	return e.change("DeleteFreezer", func() error {
+		return e.MemoryClient.DeleteFreezer(bucketName, objectName, tx)
+	}, bucketName, objectName)
+}
+
+func (e *EmbeddedClient) UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo Status) (updated bool, err error) {
+	err = e.change("UpdateFreezerStatus", func() error {
+		updated, err = e.MemoryClient.UpdateFreezerStatus(bucketName, objectName, status, statusSetTo)
+		return err
+	}, bucketName, objectName, status, statusSetTo)
+	return
+}
+
+func (e *EmbeddedClient) PutFreezer(freezer *Freezer, status Status, tx DB) error {
+	return e.change("PutFreezer", func() error {
+		return e.MemoryClient.PutFreezer(freezer, status, tx)
+	}, freezer, status)
+}
@@ -0,0 +1,308 @@
+package embeddedclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/journeymidnight/yig/helper"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// record is a change to metadata, one line of json in the journal.
+// Op is the name of the Client method called, with Args in json.
+type record struct {
+	Seq  uint64
+	Op   string
+	Args []json.RawMessage
+}
+
+// errUnchanged is returned by fn of change if nothing is changed, the record is dropped then
+var errUnchanged = errors.New("unchanged")
+
+// change journals op with args, then applies fn to memory. The record is synced
+// before applied, so memory never holds a change which is not durable. The record
+// is cut off from the journal if fn fails, and if that fails too, all later changes
+// are refused, since memory and journal would differ.
+func (e *EmbeddedClient) change(op string, fn func() error, args ...interface{}) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.failed != nil {
+		return e.failed
+	}
+	line, err := encode(e.seq+1, op, args...)
+	if err != nil {
+		return err
+	}
+	_, err = e.journal.Write(line)
+	if err == nil {
+		err = e.journal.Sync()
+	}
+	if err != nil {
+		helper.Logger.Error("Failed to write embedded meta journal:", err)
+		e.rollback()
+		return err
+	}
+	err = fn()
+	if err != nil {
+		e.rollback()
+		if err == errUnchanged {
+			return nil
+		}
+		return err
+	}
+	e.seq += 1
+	e.size += int64(len(line))
+	e.records += 1
+	if e.records >= compactRecords {
+		err = e.compact()
+		if err != nil {
+			// the change is journaled, compact later
+			helper.Logger.Error("Failed to compact embedded meta journal:", err)
+		}
+	}
+	return nil
+}
+
+// encode returns the record of op with args as a line of json
+func encode(seq uint64, op string, args ...interface{}) ([]byte, error) {
+	rec := record{Seq: seq, Op: op}
+	for _, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		rec.Args = append(rec.Args, data)
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// rollback cuts off the record written last from the journal
+func (e *EmbeddedClient) rollback() {
+	err := e.journal.Truncate(e.size)
+	if err == nil {
+		err = e.journal.Sync()
+	}
+	if err != nil {
+		helper.Logger.Error("Failed to roll back embedded meta journal, refuse changes:", err)
+		e.failed = fmt.Errorf("embedded meta journal failed: %v", err)
+	}
+}
+
+func (e *EmbeddedClient) replay() error {
+	f, err := os.Open(filepath.Join(e.dir, journalFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	// the last record failed to apply, and left as crashed before cut off, see change
+	var failed error
+	err = readRecords(f, func(rec record) error {
+		if failed != nil {
+			return failed
+		}
+		if rec.Seq <= e.seq {
+			// in snapshot already
+			return nil
+		}
+		err := e.apply(rec)
+		if err != nil {
+			failed = fmt.Errorf("replay %s of seq %d: %v", rec.Op, rec.Seq, err)
+			return nil
+		}
+		e.seq = rec.Seq
+		e.records += 1
+		return nil
+	})
+	if err == nil && failed != nil {
+		helper.Logger.Warn("Ignore the last record of embedded meta journal:", failed)
+	}
+	return err
+}
+
+func decode(rec record, values ...interface{}) error {
+	if len(rec.Args) != len(values) {
+		return fmt.Errorf("%d arguments, expected %d", len(rec.Args), len(values))
+	}
+	for i, value := range values {
+		err := json.Unmarshal(rec.Args[i], value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply calls the method of MemoryClient journaled in rec
+func (e *EmbeddedClient) apply(rec record) (err error) {
+	m := e.MemoryClient
+	var object Object
+	var bucket Bucket
+	var multipart Multipart
+	var part Part
+	var objMap ObjMap
+	var freezer Freezer
+	var bucketName, objectName, name string
+	switch rec.Op {
+	case "PutObject":
+		if err = decode(rec, &object); err == nil {
+			err = m.PutObject(&object, nil)
+		}
+	case "UpdateAppendObject":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateAppendObject(&object, nil)
+		}
+	case "RenameObject":
+		if err = decode(rec, &object, &name); err == nil {
+			err = m.RenameObject(&object, name, nil)
+		}
+	case "ReplaceObjectMetas":
+		if err = decode(rec, &object); err == nil {
+			err = m.ReplaceObjectMetas(&object, nil)
+		}
+	case "DeleteObject":
+		if err = decode(rec, &object); err == nil {
+			err = m.DeleteObject(&object, nil)
+		}
+	case "UpdateObject":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateObject(&object, nil)
+		}
+	case "TransitObject":
+		var sourceObject Object
+		if err = decode(rec, &object, &sourceObject); err == nil {
+			_, err = m.TransitObject(&object, &sourceObject, nil)
+		}
+	case "UpdateObjectAcl":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateObjectAcl(&object)
+		}
+	case "UpdateObjectAttrs":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateObjectAttrs(&object)
+		}
+	case "UpdateObjectTags":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateObjectTags(&object)
+		}
+	case "UpdateObjectLock":
+		if err = decode(rec, &object); err == nil {
+			err = m.UpdateObjectLock(&object)
+		}
+	case "PutBucket":
+		if err = decode(rec, &bucket); err == nil {
+			err = m.PutBucket(bucket)
+		}
+	case "CheckAndPutBucket":
+		if err = decode(rec, &bucket); err == nil {
+			_, err = m.CheckAndPutBucket(bucket)
+		}
+	case "DeleteBucket":
+		if err = decode(rec, &bucket); err == nil {
+			err = m.DeleteBucket(bucket)
+		}
+	case "UpdateUsage":
+		var size int64
+		if err = decode(rec, &bucketName, &size); err == nil {
+			err = m.UpdateUsage(bucketName, size, nil)
+		}
+	case "CreateMultipart":
+		if err = decode(rec, &multipart); err == nil {
+			err = m.CreateMultipart(multipart)
+		}
+	case "PutObjectPart":
+		if err = decode(rec, &multipart, &part); err == nil {
+			err = m.PutObjectPart(&multipart, &part, nil)
+		}
+	case "DeleteMultipart":
+		if err = decode(rec, &multipart); err == nil {
+			err = m.DeleteMultipart(&multipart, nil)
+		}
+	case "PutObjectMap":
+		if err = decode(rec, &objMap); err == nil {
+			err = m.PutObjectMap(&objMap, nil)
+		}
+	case "DeleteObjectMap":
+		if err = decode(rec, &objMap); err == nil {
+			err = m.DeleteObjectMap(&objMap, nil)
+		}
+	case "PutBucketToLifeCycle":
+		var lifeCycle LifeCycle
+		if err = decode(rec, &lifeCycle); err == nil {
+			err = m.PutBucketToLifeCycle(lifeCycle)
+		}
+	case "RemoveBucketFromLifeCycle":
+		if err = decode(rec, &bucket); err == nil {
+			err = m.RemoveBucketFromLifeCycle(bucket)
+		}
+	case "AddBucketForUser":
+		if err = decode(rec, &bucketName, &name); err == nil {
+			err = m.AddBucketForUser(bucketName, name)
+		}
+	case "RemoveBucketForUser":
+		if err = decode(rec, &bucketName, &name); err == nil {
+			err = m.RemoveBucketForUser(bucketName, name)
+		}
+	case "PutObjectToGarbageCollection":
+		if err = decode(rec, &object); err == nil {
+			err = m.PutObjectToGarbageCollection(&object, nil)
+		}
+	case "PutFreezerToGarbageCollection":
+		if err = decode(rec, &freezer); err == nil {
+			err = m.PutFreezerToGarbageCollection(&freezer, nil)
+		}
+	case "RemoveGarbageCollection":
+		var gc GarbageCollection
+		if err = decode(rec, &gc); err == nil {
+			err = m.RemoveGarbageCollection(gc)
+		}
+	case "PutObjectToReplication":
+		if err = decode(rec, &object); err == nil {
+			err = m.PutObjectToReplication(&object, nil)
+		}
+	case "UpdateReplicationTriedTimes":
+		var replication Replication
+		if err = decode(rec, &replication); err == nil {
+			err = m.UpdateReplicationTriedTimes(replication)
+		}
+	case "RemoveReplication":
+		var replication Replication
+		if err = decode(rec, &replication, &name); err == nil {
+			err = m.RemoveReplication(replication, name)
+		}
+	case "CreateFreezer":
+		if err = decode(rec, &freezer); err == nil {
+			err = m.CreateFreezer(&freezer)
+		}
+	case "UploadFreezerDate":
+		var lifetime int
+		if err = decode(rec, &bucketName, &objectName, &lifetime); err == nil {
+			err = m.UploadFreezerDate(bucketName, objectName, lifetime)
+		}
+	case "DeleteFreezer":
+		if err = decode(rec, &bucketName, &objectName); err == nil {
+			err = m.DeleteFreezer(bucketName, objectName, nil)
+		}
+	case "UpdateFreezerStatus":
+		var status, statusSetTo Status
+		if err = decode(rec, &bucketName, &objectName, &status, &statusSetTo); err == nil {
+			_, err = m.UpdateFreezerStatus(bucketName, objectName, status, statusSetTo)
+		}
+	case "PutFreezer":
+		var status Status
+		if err = decode(rec, &freezer, &status); err == nil {
+			err = m.PutFreezer(&freezer, status, nil)
+		}
+	default:
+		err = fmt.Errorf("unknown operation")
+	}
+	return
+}
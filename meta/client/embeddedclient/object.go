@@ -0,0 +1,120 @@
+package embeddedclient
+
+import (
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// Changes are applied at once and tx is ignored, see MemoryClient.NewTrans
+
+func (e *EmbeddedClient) PutObject(object *Object, tx DB) error {
+	return e.change("PutObject", func() error {
+		return e.MemoryClient.PutObject(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateAppendObject(object *Object, tx DB) error {
+	return e.change("UpdateAppendObject", func() error {
+		return e.MemoryClient.UpdateAppendObject(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) RenameObject(object *Object, sourceObject string, tx DB) error {
+	return e.change("RenameObject", func() error {
+		return e.MemoryClient.RenameObject(object, sourceObject, tx)
+	}, object, sourceObject)
+}
+
+func (e *EmbeddedClient) ReplaceObjectMetas(object *Object, tx DB) error {
+	return e.change("ReplaceObjectMetas", func() error {
+		return e.MemoryClient.ReplaceObjectMetas(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) DeleteObject(object *Object, tx DB) error {
+	return e.change("DeleteObject", func() error {
+		return e.MemoryClient.DeleteObject(object, tx)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateObject(object *Object, tx DB) error {
+	return e.change("UpdateObject", func() error {
+		return e.MemoryClient.UpdateObject(object, tx)
+	}, object)
+}
+
+// TransitObject journals the object only if transited
+func (e *EmbeddedClient) TransitObject(object, sourceObject *Object, tx DB) (transited bool, err error) {
+	err = e.change("TransitObject", func() (err error) {
+		transited, err = e.MemoryClient.TransitObject(object, sourceObject, tx)
+		if err == nil && !transited {
+			return errUnchanged
+		}
+		return err
+	}, object, sourceObject)
+	return transited, err
+}
+
+func (e *EmbeddedClient) UpdateObjectAcl(object *Object) error {
+	return e.change("UpdateObjectAcl", func() error {
+		return e.MemoryClient.UpdateObjectAcl(object)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateObjectAttrs(object *Object) error {
+	return e.change("UpdateObjectAttrs", func() error {
+		return e.MemoryClient.UpdateObjectAttrs(object)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateObjectTags(object *Object) error {
+	return e.change("UpdateObjectTags", func() error {
+		return e.MemoryClient.UpdateObjectTags(object)
+	}, object)
+}
+
+func (e *EmbeddedClient) UpdateObjectLock(object *Object) error {
+	return e.change("UpdateObjectLock", func() error {
+		return e.MemoryClient.UpdateObjectLock(object)
+	}, object)
+}
+
+//objmap
+func (e *EmbeddedClient) PutObjectMap(objMap *ObjMap, tx DB) error {
+	return e.change("PutObjectMap", func() error {
+		return e.MemoryClient.PutObjectMap(objMap, tx)
+	}, objMap)
+}
+
+func (e *EmbeddedClient) DeleteObjectMap(objMap *ObjMap, tx DB) error {
+	return e.change("DeleteObjectMap", func() error {
+		return e.MemoryClient.DeleteObjectMap(objMap, tx)
+	}, objMap)
+}
+
+//multipart
+func (e *EmbeddedClient) CreateMultipart(multipart Multipart) error {
+	return e.change("CreateMultipart", func() error {
+		return e.MemoryClient.CreateMultipart(multipart)
+	}, multipart)
+}
+
+func (e *EmbeddedClient) PutObjectPart(multipart *Multipart, part *Part, tx DB) error {
+	return e.change("PutObjectPart", func() error {
+		return e.MemoryClient.PutObjectPart(multipart, part, tx)
+	}, uploadOf(multipart), part)
+}
+
+func (e *EmbeddedClient) DeleteMultipart(multipart *Multipart, tx DB) error {
+	return e.change("DeleteMultipart", func() error {
+		return e.MemoryClient.DeleteMultipart(multipart, tx)
+	}, uploadOf(multipart))
+}
+
+// uploadOf returns the key fields of multipart, parts are not journaled again for every part
+func uploadOf(multipart *Multipart) Multipart {
+	return Multipart{
+		BucketName:  multipart.BucketName,
+		ObjectName:  multipart.ObjectName,
+		InitialTime: multipart.InitialTime,
+	}
+}
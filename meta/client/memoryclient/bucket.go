@@ -0,0 +1,248 @@
+package memoryclient
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+func (m *MemoryClient) GetBucket(bucketName string) (bucket *Bucket, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stored, ok := m.buckets[bucketName]
+	if !ok {
+		return nil, ErrNoSuchBucket
+	}
+	bucket = new(Bucket)
+	err = deepCopy(bucket, stored)
+	return
+}
+
+func (m *MemoryClient) GetBuckets() (buckets []Bucket, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	names := make([]string, 0, len(m.buckets))
+	for name := range m.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var bucket Bucket
+		err = deepCopy(&bucket, m.buckets[name])
+		if err != nil {
+			return
+		}
+		buckets = append(buckets, bucket)
+	}
+	return
+}
+
+// Actually this method is used to update bucket, create time and usage are kept
+func (m *MemoryClient) PutBucket(bucket Bucket) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stored, ok := m.buckets[bucket.Name]
+	if !ok {
+		return nil
+	}
+	updated := new(Bucket)
+	err := deepCopy(updated, bucket)
+	if err != nil {
+		return err
+	}
+	updated.CreateTime = stored.CreateTime
+	updated.Usage = stored.Usage
+	m.buckets[bucket.Name] = updated
+	return nil
+}
+
+func (m *MemoryClient) CheckAndPutBucket(bucket Bucket) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.buckets[bucket.Name]; ok {
+		return false, nil
+	}
+	stored := new(Bucket)
+	err := deepCopy(stored, bucket)
+	if err != nil {
+		return false, err
+	}
+	m.buckets[bucket.Name] = stored
+	return true, nil
+}
+
+func (m *MemoryClient) DeleteBucket(bucket Bucket) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.buckets, bucket.Name)
+	return nil
+}
+
+func (m *MemoryClient) UpdateUsage(bucketName string, size int64, tx DB) error {
+	if !helper.CONFIG.PiggybackUpdateUsage {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if bucket, ok := m.buckets[bucketName]; ok {
+		bucket.Usage += size
+	}
+	return nil
+}
+
+// bucketObjects returns keys of all objects in the bucket, sorted as in tidb
+func (m *MemoryClient) bucketObjects(bucketName string) (keys []objectKey) {
+	for key := range m.objects {
+		if key.bucketName == bucketName {
+			keys = append(keys, key)
+		}
+	}
+	return sortKeys(keys)
+}
+
+// ListObjects follows the semantic of tidb client: the latest versions are listed if not versioned,
+// objects whose latest version is a delete marker are skipped.
+func (m *MemoryClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimiter string, versioned bool,
+	maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if versioned {
+		return m.listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter, maxKeys)
+	}
+	var count int
+	var lastName string
+	commonPrefixes := make(map[string]struct{})
+	for _, key := range m.bucketObjects(bucketName) {
+		name := key.objectName
+		// versions are sorted from the latest one
+		if name == lastName {
+			continue
+		}
+		lastName = name
+		if name <= marker || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if m.objects[key].DeleteMarker {
+			continue
+		}
+		if len(delimiter) != 0 {
+			subStr := strings.TrimPrefix(name, prefix)
+			n := strings.Index(subStr, delimiter)
+			if n != -1 {
+				prefixKey := prefix + subStr[:n+len(delimiter)]
+				if prefixKey == marker {
+					continue
+				}
+				if _, ok := commonPrefixes[prefixKey]; !ok {
+					if count == maxKeys {
+						truncated = true
+						break
+					}
+					commonPrefixes[prefixKey] = struct{}{}
+					nextMarker = prefixKey
+					count += 1
+				}
+				continue
+			}
+		}
+		if count == maxKeys {
+			truncated = true
+			break
+		}
+		var o *Object
+		o, err = m.getObject(bucketName, name, strconv.FormatUint(key.version, 10))
+		if err != nil {
+			return
+		}
+		retObjects = append(retObjects, o)
+		nextMarker = name
+		count += 1
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
+
+// List all versions and delete markers of objects, in order of object name
+// and from the latest version to the oldest one for each object.
+// nextVerIdMarker is the encrypted last modified time of the last version returned.
+func (m *MemoryClient) listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter string,
+	maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
+
+	var version uint64
+	if marker != "" {
+		// key marker only, skip all versions of it
+		version = math.MaxUint64
+		if verIdMarker != "" {
+			var decrypted string
+			var timestamp uint64
+			decrypted, err = util.Decrypt(verIdMarker)
+			if err == nil {
+				timestamp, err = strconv.ParseUint(decrypted, 10, 64)
+			}
+			if err != nil {
+				return nil, nil, false, "", "", ErrNoSuchVersion
+			}
+			version = math.MaxUint64 - timestamp
+		}
+	}
+	if marker < prefix {
+		marker = prefix
+		version = 0
+	}
+	start := objectKey{bucketName, marker, version}
+	var count int
+	commonPrefixes := make(map[string]struct{})
+	for _, key := range m.bucketObjects(bucketName) {
+		if !start.less(key) {
+			continue
+		}
+		name := key.objectName
+		if !strings.HasPrefix(name, prefix) {
+			// names are sorted, no more objects with the prefix
+			break
+		}
+		if len(delimiter) != 0 {
+			subStr := strings.TrimPrefix(name, prefix)
+			n := strings.Index(subStr, delimiter)
+			if n != -1 {
+				prefixKey := prefix + subStr[:n+len(delimiter)]
+				if prefixKey == start.objectName {
+					continue
+				}
+				if _, ok := commonPrefixes[prefixKey]; !ok {
+					if count == maxKeys {
+						truncated = true
+						break
+					}
+					commonPrefixes[prefixKey] = struct{}{}
+					nextMarker = prefixKey
+					nextVerIdMarker = ""
+					count += 1
+				}
+				continue
+			}
+		}
+		if count == maxKeys {
+			truncated = true
+			break
+		}
+		var o *Object
+		o, err = m.getObject(bucketName, name, strconv.FormatUint(key.version, 10))
+		if err != nil {
+			return
+		}
+		retObjects = append(retObjects, o)
+		nextMarker = name
+		nextVerIdMarker = util.Encrypt(strconv.FormatUint(math.MaxUint64-key.version, 10))
+		count += 1
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
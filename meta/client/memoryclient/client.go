@@ -0,0 +1,130 @@
+package memoryclient
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+// errDuplicateEntry is returned where tidb would fail on a unique key
+var errDuplicateEntry = errors.New("duplicate entry")
+
+type objectKey struct {
+	bucketName string
+	objectName string
+	version    uint64 // math.MaxUint64 - LastModifiedTime.UnixNano(), as in tidb
+}
+
+type nameKey struct {
+	bucketName string
+	objectName string
+}
+
+// MemoryClient keeps all metadata in memory, tables of tidb are maps here.
+// Values are deep copied in and out, so callers could not modify stored entries
+// without calling the client, as with a real database.
+// It is meant for unit tests, data is lost when the process exits unless
+// saved by Dump, as embeddedclient does.
+type MemoryClient struct {
+	// Clusters is returned by GetClusters, set it to weight data clusters
+	Clusters []Cluster
+
+	lock         sync.RWMutex
+	buckets      map[string]*Bucket
+	objects      map[objectKey]*Object
+	multiparts   map[objectKey]*Multipart
+	objMaps      map[nameKey]*ObjMap
+	lifeCycles   map[string]LifeCycle
+	users        map[string]map[string]struct{}
+	gcs          map[objectKey]GarbageCollection
+	replications map[objectKey]Replication
+	freezers     map[nameKey]*Freezer
+}
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		buckets:      make(map[string]*Bucket),
+		objects:      make(map[objectKey]*Object),
+		multiparts:   make(map[objectKey]*Multipart),
+		objMaps:      make(map[nameKey]*ObjMap),
+		lifeCycles:   make(map[string]LifeCycle),
+		users:        make(map[string]map[string]struct{}),
+		gcs:          make(map[objectKey]GarbageCollection),
+		replications: make(map[objectKey]Replication),
+		freezers:     make(map[nameKey]*Freezer),
+	}
+}
+
+// deepCopy copies src to dst through json, the same way tidb client
+// stores most of the fields
+func deepCopy(dst, src interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func versionOf(object *Object) uint64 {
+	return math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+}
+
+// versionOfId returns the version of object with version id from requests,
+// which is the encrypted timestamp
+func versionOfId(versionId string) (uint64, error) {
+	decrypted, err := util.Decrypt(versionId)
+	if err != nil {
+		return 0, err
+	}
+	timestamp, err := strconv.ParseUint(decrypted, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return math.MaxUint64 - timestamp, nil
+}
+
+func keyOf(object *Object) objectKey {
+	return objectKey{object.BucketName, object.Name, versionOf(object)}
+}
+
+func (k objectKey) less(o objectKey) bool {
+	if k.bucketName != o.bucketName {
+		return k.bucketName < o.bucketName
+	}
+	if k.objectName != o.objectName {
+		return k.objectName < o.objectName
+	}
+	return k.version < o.version
+}
+
+// sortKeys sorts keys in order of bucket, object and version,
+// which is the order of `rowkey` index in tidb
+func sortKeys(keys []objectKey) []objectKey {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	return keys
+}
+
+func (k objectKey) rowkey() string {
+	return k.bucketName + ObjectNameSeparator + k.objectName +
+		ObjectNameSeparator + strconv.FormatUint(k.version, 10)
+}
+
+func parseRowkey(rowkey string) (key objectKey, ok bool) {
+	bucketName, objectName, v, ok := ParseReplicationRowkey(rowkey)
+	if !ok {
+		return key, false
+	}
+	version, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return key, false
+	}
+	return objectKey{bucketName, objectName, version}, true
+}
@@ -0,0 +1,196 @@
+package memoryclient
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	. "github.com/journeymidnight/yig/error"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func newObject(name string, t time.Time) *Object {
+	return &Object{
+		BucketName:       "bucket",
+		Name:             name,
+		LastModifiedTime: t,
+		CustomAttributes: map[string]string{"k": "v"},
+	}
+}
+
+func TestMemoryClient_Object(t *testing.T) {
+	m := NewMemoryClient()
+	now := time.Now().UTC()
+	v1 := newObject("a", now)
+	v2 := newObject("a", now.Add(time.Second))
+	for _, o := range []*Object{v1, v2} {
+		if err := m.PutObject(o, nil); err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+	if err := m.PutObject(v1, nil); err == nil {
+		t.Fatal("PutObject duplicate version, expected error")
+	}
+	// stored objects are copies
+	v2.CustomAttributes["k"] = "changed"
+
+	latest, err := m.GetObject("bucket", "a", "")
+	if err != nil {
+		t.Fatal("GetObject error:", err)
+	}
+	if !latest.LastModifiedTime.Equal(v2.LastModifiedTime) || latest.CustomAttributes["k"] != "v" {
+		t.Fatal("GetObject returns", latest)
+	}
+	if latest.GetVersionId() != newObject("a", v2.LastModifiedTime).GetVersionId() {
+		t.Fatal("GetObject returns version id", latest.VersionId)
+	}
+	all, err := m.GetAllObject("bucket", "a", "")
+	if err != nil || len(all) != 2 {
+		t.Fatal("GetAllObject returns", all, err)
+	}
+
+	v1.Tags = map[string]string{"t": "1"}
+	if err = m.UpdateObjectTags(v1); err != nil {
+		t.Fatal("UpdateObjectTags error:", err)
+	}
+	if err = m.DeleteObject(v2, nil); err != nil {
+		t.Fatal("DeleteObject error:", err)
+	}
+	latest, err = m.GetObject("bucket", "a", "")
+	if err != nil || latest.Tags["t"] != "1" {
+		t.Fatal("GetObject returns", latest, err)
+	}
+	if _, err = m.GetObject("bucket", "b", ""); err != ErrNoSuchKey {
+		t.Fatal("GetObject not existing object, expected ErrNoSuchKey, got", err)
+	}
+	if _, err = m.GetObjectMap("bucket", "a"); err != sql.ErrNoRows {
+		t.Fatal("GetObjectMap not existing map, expected sql.ErrNoRows, got", err)
+	}
+}
+
+func TestMemoryClient_ListObjects(t *testing.T) {
+	m := NewMemoryClient()
+	now := time.Now().UTC()
+	for i, name := range []string{"a", "b/1", "b/2", "c", "c", "d"} {
+		o := newObject(name, now.Add(time.Duration(i)*time.Second))
+		// the latest version of d is a delete marker
+		o.DeleteMarker = name == "d"
+		if err := m.PutObject(o, nil); err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+
+	objects, prefixes, truncated, nextMarker, _, err := m.ListObjects("bucket", "", "", "", "/", false, 2)
+	if err != nil || len(objects) != 1 || objects[0].Name != "a" ||
+		len(prefixes) != 1 || prefixes[0] != "b/" || !truncated || nextMarker != "b/" {
+		t.Fatal("ListObjects returns", objects, prefixes, truncated, nextMarker, err)
+	}
+	objects, _, truncated, _, _, err = m.ListObjects("bucket", nextMarker, "", "", "/", false, 2)
+	if err != nil || len(objects) != 1 || objects[0].Name != "c" || truncated {
+		t.Fatal("ListObjects returns", objects, truncated, err)
+	}
+
+	objects, _, truncated, nextMarker, nextVerIdMarker, err := m.ListObjects("bucket", "", "", "c", "", true, 1)
+	if err != nil || len(objects) != 1 || !truncated || nextMarker != "c" {
+		t.Fatal("ListObjects versioned returns", objects, truncated, nextMarker, err)
+	}
+	latestC := objects[0]
+	objects, _, truncated, _, _, err = m.ListObjects("bucket", nextMarker, nextVerIdMarker, "c", "", true, 5)
+	if err != nil || len(objects) != 1 || truncated ||
+		!objects[0].LastModifiedTime.Before(latestC.LastModifiedTime) {
+		t.Fatal("ListObjects versioned returns", objects, truncated, err)
+	}
+}
+
+func TestMemoryClient_Multipart(t *testing.T) {
+	m := NewMemoryClient()
+	multipart := Multipart{
+		BucketName:  "bucket",
+		ObjectName:  "a",
+		InitialTime: time.Now().UTC(),
+		Metadata:    MultipartMetadata{OwnerId: "user", InitiatorId: "user"},
+	}
+	uploadId, err := multipart.GetUploadId()
+	if err != nil {
+		t.Fatal("GetUploadId error:", err)
+	}
+	if err = m.CreateMultipart(multipart); err != nil {
+		t.Fatal("CreateMultipart error:", err)
+	}
+	for _, etag := range []string{"old", "new"} {
+		part := &Part{PartNumber: 1, Size: 1, Etag: etag,
+			LastModified: time.Now().UTC().Format(CREATE_TIME_LAYOUT)}
+		if err = m.PutObjectPart(&multipart, part, nil); err != nil {
+			t.Fatal("PutObjectPart error:", err)
+		}
+	}
+	stored, err := m.GetMultipart("bucket", "a", uploadId)
+	if err != nil || len(stored.Parts) != 1 || stored.Parts[1].Etag != "new" {
+		t.Fatal("GetMultipart returns", stored, err)
+	}
+	uploads, _, _, _, _, err := m.ListMultipartUploads("bucket", "", "", "", "", "", 10)
+	if err != nil || len(uploads) != 1 || uploads[0].UploadId != uploadId || uploads[0].Owner.ID != "user" {
+		t.Fatal("ListMultipartUploads returns", uploads, err)
+	}
+	if err = m.DeleteMultipart(&stored, nil); err != nil {
+		t.Fatal("DeleteMultipart error:", err)
+	}
+	if _, err = m.GetMultipart("bucket", "a", uploadId); err != ErrNoSuchUpload {
+		t.Fatal("GetMultipart deleted upload, expected ErrNoSuchUpload, got", err)
+	}
+}
+
+func TestMemoryClient_Scan(t *testing.T) {
+	m := NewMemoryClient()
+	now := time.Now().UTC()
+	var objects []*Object
+	for i, name := range []string{"a", "b", "c"} {
+		o := newObject(name, now.Add(time.Duration(i)*time.Second))
+		objects = append(objects, o)
+		if err := m.PutObjectToGarbageCollection(o, nil); err != nil {
+			t.Fatal("PutObjectToGarbageCollection error:", err)
+		}
+		if err := m.PutObjectToReplication(o, nil); err != nil {
+			t.Fatal("PutObjectToReplication error:", err)
+		}
+	}
+
+	gcs, err := m.ScanGarbageCollection(2, "")
+	if err != nil || len(gcs) != 2 || gcs[0].ObjectName != "a" || gcs[1].ObjectName != "b" {
+		t.Fatal("ScanGarbageCollection returns", gcs, err)
+	}
+	// start row key is inclusive
+	gcs, err = m.ScanGarbageCollection(2, gcs[1].Rowkey)
+	if err != nil || len(gcs) != 2 || gcs[0].ObjectName != "b" {
+		t.Fatal("ScanGarbageCollection returns", gcs, err)
+	}
+	if err = m.RemoveGarbageCollection(gcs[0]); err != nil {
+		t.Fatal("RemoveGarbageCollection error:", err)
+	}
+	if gcs, _ = m.ScanGarbageCollection(10, ""); len(gcs) != 2 {
+		t.Fatal("ScanGarbageCollection after remove returns", gcs)
+	}
+
+	replications, err := m.ScanReplication(2, "")
+	if err != nil || len(replications) != 2 {
+		t.Fatal("ScanReplication returns", replications, err)
+	}
+	// start row key is exclusive
+	replications, err = m.ScanReplication(2, replications[1].Rowkey())
+	if err != nil || len(replications) != 1 || replications[0].ObjectName != "c" {
+		t.Fatal("ScanReplication returns", replications, err)
+	}
+	if err = m.PutObject(objects[2], nil); err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	if err = m.RemoveReplication(replications[0], "COMPLETED"); err != nil {
+		t.Fatal("RemoveReplication error:", err)
+	}
+	o, err := m.GetObject("bucket", "c", "")
+	if err != nil || o.ReplicationStatus != "COMPLETED" {
+		t.Fatal("GetObject returns", o, err)
+	}
+	if replications, _ = m.ScanReplication(10, ""); len(replications) != 2 {
+		t.Fatal("ScanReplication after remove returns", replications)
+	}
+}
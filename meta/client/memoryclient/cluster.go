@@ -0,0 +1,11 @@
+package memoryclient
+
+import (
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (m *MemoryClient) GetClusters() (cluster []Cluster, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return append(cluster, m.Clusters...), nil
+}
@@ -0,0 +1,155 @@
+package memoryclient
+
+import (
+	"sort"
+	"strings"
+
+	. "github.com/journeymidnight/yig/error"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (m *MemoryClient) CreateFreezer(freezer *Freezer) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := nameKey{freezer.BucketName, freezer.Name}
+	if _, ok := m.freezers[key]; ok {
+		return errDuplicateEntry
+	}
+	stored := &Freezer{}
+	err = deepCopy(stored, freezer)
+	if err != nil {
+		return
+	}
+	m.freezers[key] = stored
+	return nil
+}
+
+func (m *MemoryClient) GetFreezer(bucketName, objectName, version string) (freezer *Freezer, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stored, ok := m.freezers[nameKey{bucketName, objectName}]
+	if !ok {
+		return nil, ErrNoSuchKey
+	}
+	freezer = &Freezer{}
+	err = deepCopy(freezer, stored)
+	if err != nil {
+		return nil, err
+	}
+	//build simple index for multipart
+	if len(freezer.Parts) != 0 {
+		var sortedPartNum = make([]int64, len(freezer.Parts))
+		for k, v := range freezer.Parts {
+			sortedPartNum[k-1] = v.Offset
+		}
+		freezer.PartsIndex = &SimpleIndex{Index: sortedPartNum}
+	}
+	return freezer, nil
+}
+
+func (m *MemoryClient) GetFreezerStatus(bucketName, objectName, version string) (freezer *Freezer, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stored, ok := m.freezers[nameKey{bucketName, objectName}]
+	if !ok {
+		return nil, ErrNoSuchKey
+	}
+	freezer = &Freezer{
+		BucketName: stored.BucketName,
+		Name:       stored.Name,
+		VersionId:  stored.VersionId,
+		Status:     stored.Status,
+	}
+	return freezer, nil
+}
+
+func (m *MemoryClient) UploadFreezerDate(bucketName, objectName string, lifetime int) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if stored, ok := m.freezers[nameKey{bucketName, objectName}]; ok {
+		stored.LifeTime = lifetime
+	}
+	return nil
+}
+
+func (m *MemoryClient) DeleteFreezer(bucketName, objectName string, tx DB) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.freezers, nameKey{bucketName, objectName})
+	return nil
+}
+
+func (m *MemoryClient) ScanFreezers(limit int, startRowKey string, status Status) (freezers []Freezer, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	less := func(k, o nameKey) bool {
+		if k.bucketName != o.bucketName {
+			return k.bucketName < o.bucketName
+		}
+		return k.objectName < o.objectName
+	}
+	var start nameKey
+	if startRowKey != "" {
+		s := strings.SplitN(startRowKey, ObjectNameSeparator, 2)
+		if len(s) != 2 {
+			return nil, nil
+		}
+		start = nameKey{s[0], s[1]}
+	}
+	var keys []nameKey
+	for key, f := range m.freezers {
+		if f.Status == status && (startRowKey == "" || less(start, key)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	for _, key := range keys {
+		if len(freezers) >= limit {
+			break
+		}
+		stored := m.freezers[key]
+		freezers = append(freezers, Freezer{
+			Rowkey:           []byte(key.bucketName + ObjectNameSeparator + key.objectName),
+			BucketName:       stored.BucketName,
+			Name:             stored.Name,
+			Status:           stored.Status,
+			LifeTime:         stored.LifeTime,
+			LastModifiedTime: stored.LastModifiedTime,
+		})
+	}
+	return
+}
+
+func (m *MemoryClient) UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo Status) (updated bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stored, ok := m.freezers[nameKey{bucketName, objectName}]
+	if !ok || stored.Status != status {
+		return false, nil
+	}
+	stored.Status = statusSetTo
+	return true, nil
+}
+
+func (m *MemoryClient) PutFreezer(freezer *Freezer, status Status, tx DB) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := nameKey{freezer.BucketName, freezer.Name}
+	old, ok := m.freezers[key]
+	if !ok {
+		return nil
+	}
+	stored := &Freezer{}
+	err = deepCopy(stored, freezer)
+	if err != nil {
+		return
+	}
+	// lifetime is requested by users, not saved with data
+	stored.LifeTime = old.LifeTime
+	stored.Status = status
+	stored.PartsIndex = nil
+	m.freezers[key] = stored
+	return nil
+}
@@ -0,0 +1,80 @@
+package memoryclient
+
+import (
+	"math"
+
+	. "github.com/journeymidnight/yig/meta/client/tidbclient"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// gc
+func (m *MemoryClient) PutObjectToGarbageCollection(object *Object, tx DB) (err error) {
+	version := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+	return m.putGarbageCollection(GarbageCollectionFromObject(object), version)
+}
+
+func (m *MemoryClient) PutFreezerToGarbageCollection(object *Freezer, tx DB) (err error) {
+	version := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+	return m.putGarbageCollection(GarbageCollectionFromFreeze(object), version)
+}
+
+// putGarbageCollection ignores existing entries, as `insert ignore` of tidb client
+func (m *MemoryClient) putGarbageCollection(gc GarbageCollection, version uint64) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := objectKey{gc.BucketName, gc.ObjectName, version}
+	if _, ok := m.gcs[key]; ok {
+		return nil
+	}
+	var stored GarbageCollection
+	err = deepCopy(&stored, gc)
+	if err != nil {
+		return
+	}
+	stored.Rowkey = key.rowkey()
+	m.gcs[key] = stored
+	return nil
+}
+
+// ScanGarbageCollection returns entries from startRowKey, inclusive
+func (m *MemoryClient) ScanGarbageCollection(limit int, startRowKey string) (gcs []GarbageCollection, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var start objectKey
+	if startRowKey != "" {
+		var ok bool
+		start, ok = parseRowkey(startRowKey)
+		if !ok {
+			return nil, nil
+		}
+	}
+	var keys []objectKey
+	for key := range m.gcs {
+		if startRowKey == "" || !key.less(start) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range sortKeys(keys) {
+		if len(gcs) >= limit {
+			break
+		}
+		var gc GarbageCollection
+		err = deepCopy(&gc, m.gcs[key])
+		if err != nil {
+			return
+		}
+		gcs = append(gcs, gc)
+	}
+	return
+}
+
+func (m *MemoryClient) RemoveGarbageCollection(garbage GarbageCollection) (err error) {
+	key, ok := parseRowkey(garbage.Rowkey)
+	if !ok {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.gcs, key)
+	return nil
+}
@@ -0,0 +1,45 @@
+package memoryclient
+
+import (
+	"sort"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (m *MemoryClient) PutBucketToLifeCycle(lifeCycle LifeCycle) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lifeCycles[lifeCycle.BucketName] = lifeCycle
+	return nil
+}
+
+func (m *MemoryClient) RemoveBucketFromLifeCycle(bucket Bucket) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.lifeCycles, bucket.Name)
+	return nil
+}
+
+func (m *MemoryClient) ScanLifeCycle(limit int, marker string) (result ScanLifeCycleResult, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var names []string
+	for name := range m.lifeCycles {
+		if name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	result.Lcs = make([]LifeCycle, 0, limit)
+	for _, name := range names {
+		result.Lcs = append(result.Lcs, m.lifeCycles[name])
+		result.NextMarker = name
+	}
+	if len(result.Lcs) == limit {
+		result.Truncated = true
+	}
+	return result, nil
+}
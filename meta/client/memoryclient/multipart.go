@@ -0,0 +1,167 @@
+package memoryclient
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam"
+	"github.com/journeymidnight/yig/iam/common"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+func multipartKey(multipart *Multipart) objectKey {
+	uploadTime := math.MaxUint64 - uint64(multipart.InitialTime.UnixNano())
+	return objectKey{multipart.BucketName, multipart.ObjectName, uploadTime}
+}
+
+func (m *MemoryClient) GetMultipart(bucketName, objectName, uploadId string) (multipart Multipart, err error) {
+	timestampString, err := util.Decrypt(uploadId)
+	if err != nil {
+		return
+	}
+	uploadTime, err := strconv.ParseUint(timestampString, 10, 64)
+	if err != nil {
+		return
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stored, ok := m.multiparts[objectKey{bucketName, objectName, math.MaxUint64 - uploadTime}]
+	if !ok {
+		err = ErrNoSuchUpload
+		return
+	}
+	err = deepCopy(&multipart, stored)
+	if multipart.Parts == nil {
+		multipart.Parts = make(map[int]*Part)
+	}
+	return
+}
+
+func (m *MemoryClient) CreateMultipart(multipart Multipart) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := multipartKey(&multipart)
+	if _, ok := m.multiparts[key]; ok {
+		return errDuplicateEntry
+	}
+	stored := &Multipart{}
+	err = deepCopy(stored, multipart)
+	if err != nil {
+		return
+	}
+	// parts are added by PutObjectPart
+	stored.Parts = make(map[int]*Part)
+	m.multiparts[key] = stored
+	return nil
+}
+
+func (m *MemoryClient) PutObjectPart(multipart *Multipart, part *Part, tx DB) (err error) {
+	_, err = time.Parse(CREATE_TIME_LAYOUT, part.LastModified)
+	if err != nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stored, ok := m.multiparts[multipartKey(multipart)]
+	if !ok {
+		return ErrNoSuchUpload
+	}
+	p := &Part{}
+	err = deepCopy(p, part)
+	if err != nil {
+		return
+	}
+	stored.Parts[p.PartNumber] = p
+	return nil
+}
+
+func (m *MemoryClient) DeleteMultipart(multipart *Multipart, tx DB) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.multiparts, multipartKey(multipart))
+	return nil
+}
+
+func (m *MemoryClient) ListMultipartUploads(bucketName, keyMarker, uploadIdMarker, prefix, delimiter, encodingType string,
+	maxUploads int) (uploads []datatype.Upload, prefixs []string, isTruncated bool, nextKeyMarker, nextUploadIdMarker string, err error) {
+
+	var uploadNum uint64
+	if uploadIdMarker != "" {
+		var timestampString string
+		var uploadTime uint64
+		timestampString, err = util.Decrypt(uploadIdMarker)
+		if err != nil {
+			return
+		}
+		uploadTime, err = strconv.ParseUint(timestampString, 10, 64)
+		if err != nil {
+			return
+		}
+		uploadNum = math.MaxUint64 - uploadTime
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var keys []objectKey
+	for key := range m.multiparts {
+		if key.bucketName == bucketName {
+			keys = append(keys, key)
+		}
+	}
+	commonPrefixes := make(map[string]struct{})
+	for _, key := range sortKeys(keys) {
+		name, uploadtime := key.objectName, key.version
+		if name < keyMarker || (name == keyMarker && uploadtime < uploadNum) {
+			continue
+		}
+		//filte by prefix
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		//filte by delimiter
+		if len(delimiter) != 0 {
+			subStr := strings.TrimPrefix(name, prefix)
+			n := strings.Index(subStr, delimiter)
+			if n != -1 {
+				commonPrefixes[prefix+subStr[:n+len(delimiter)]] = struct{}{}
+				continue
+			}
+		}
+		if len(uploads) >= maxUploads {
+			isTruncated = true
+			nextKeyMarker = name
+			nextUploadIdMarker = GetMultipartUploadIdForTidb(uploadtime)
+			break
+		}
+		multipart := m.multiparts[key]
+		upload := datatype.Upload{StorageClass: multipart.Metadata.StorageClass.ToString()}
+		upload.UploadId = GetMultipartUploadIdForTidb(uploadtime)
+		upload.Key = name
+		if encodingType != "" {
+			upload.Key = url.QueryEscape(upload.Key)
+		}
+		var user common.Credential
+		user, err = iam.GetCredentialByUserId(multipart.Metadata.OwnerId)
+		if err != nil {
+			return
+		}
+		upload.Owner.ID = user.UserId
+		upload.Owner.DisplayName = user.DisplayName
+		user, err = iam.GetCredentialByUserId(multipart.Metadata.InitiatorId)
+		if err != nil {
+			return
+		}
+		upload.Initiator.ID = user.UserId
+		upload.Initiator.DisplayName = user.DisplayName
+		upload.Initiated = multipart.InitialTime.UTC().Format(CREATE_TIME_LAYOUT)
+		uploads = append(uploads, upload)
+	}
+	prefixs = helper.Keys(commonPrefixes)
+	return
+}
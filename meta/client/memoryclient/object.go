@@ -0,0 +1,239 @@
+package memoryclient
+
+import (
+	"strconv"
+
+	. "github.com/journeymidnight/yig/error"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+func (m *MemoryClient) GetObject(bucketName, objectName, version string) (object *Object, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.getObject(bucketName, objectName, version)
+}
+
+func (m *MemoryClient) getObject(bucketName, objectName, version string) (object *Object, err error) {
+	var stored *Object
+	if version == "" {
+		keys := m.objectVersions(bucketName, objectName)
+		if len(keys) != 0 {
+			stored = m.objects[keys[0]]
+		}
+	} else {
+		v, e := strconv.ParseUint(version, 10, 64)
+		if e != nil {
+			v, e = versionOfId(version)
+		}
+		if e == nil {
+			stored = m.objects[objectKey{bucketName, objectName, v}]
+		}
+	}
+	if stored == nil {
+		return nil, ErrNoSuchKey
+	}
+	object = &Object{}
+	err = deepCopy(object, stored)
+	if err != nil {
+		return nil, err
+	}
+	if object.Parts == nil {
+		object.Parts = make(map[int]*Part)
+	}
+	//build simple index for multipart
+	if len(object.Parts) != 0 {
+		var sortedPartNum = make([]int64, len(object.Parts))
+		for k, v := range object.Parts {
+			sortedPartNum[k-1] = v.Offset
+		}
+		object.PartsIndex = &SimpleIndex{Index: sortedPartNum}
+	}
+	object.VersionId = util.Encrypt(strconv.FormatUint(uint64(object.LastModifiedTime.UnixNano()), 10))
+	return object, nil
+}
+
+// objectVersions returns keys of all versions of the object, from the latest to the oldest
+func (m *MemoryClient) objectVersions(bucketName, objectName string) (keys []objectKey) {
+	for key := range m.objects {
+		if key.bucketName == bucketName && key.objectName == objectName {
+			keys = append(keys, key)
+		}
+	}
+	return sortKeys(keys)
+}
+
+func (m *MemoryClient) GetAllObject(bucketName, objectName, version string) (objects []*Object, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, key := range m.objectVersions(bucketName, objectName) {
+		var object *Object
+		object, err = m.getObject(bucketName, objectName, strconv.FormatUint(key.version, 10))
+		if err != nil {
+			return
+		}
+		objects = append(objects, object)
+	}
+	return
+}
+
+func (m *MemoryClient) PutObject(object *Object, tx DB) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := keyOf(object)
+	if _, ok := m.objects[key]; ok {
+		return errDuplicateEntry
+	}
+	stored := &Object{}
+	err := deepCopy(stored, object)
+	if err != nil {
+		return err
+	}
+	stored.Rowkey = nil
+	stored.VersionId = ""
+	m.objects[key] = stored
+	return nil
+}
+
+// UpdateAppendObject updates size and last modified time of the object,
+// version of the object changes with last modified time
+func (m *MemoryClient) UpdateAppendObject(object *Object, tx DB) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, key := range m.objectVersions(object.BucketName, object.Name) {
+		stored := m.objects[key]
+		delete(m.objects, key)
+		stored.Size = object.Size
+		stored.LastModifiedTime = object.LastModifiedTime
+		m.objects[keyOf(stored)] = stored
+	}
+	return nil
+}
+
+// Parts are saved along with objects, renaming the object renames its parts
+func (m *MemoryClient) RenameObjectPart(object *Object, sourceObject string, tx DB) (err error) {
+	return nil
+}
+
+func (m *MemoryClient) RenameObject(object *Object, sourceObject string, tx DB) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := objectKey{object.BucketName, sourceObject, versionOf(object)}
+	stored, ok := m.objects[key]
+	if !ok {
+		return nil
+	}
+	target := key
+	target.objectName = object.Name
+	if _, ok := m.objects[target]; ok {
+		return errDuplicateEntry
+	}
+	delete(m.objects, key)
+	stored.Name = object.Name
+	m.objects[target] = stored
+	return nil
+}
+
+func (m *MemoryClient) ReplaceObjectMetas(object *Object, tx DB) (err error) {
+	return m.updateObjects(object, false, func(stored *Object, object *Object) error {
+		stored.ContentType = object.ContentType
+		stored.StorageClass = object.StorageClass
+		stored.CustomAttributes, stored.Tags = nil, nil
+		err := deepCopy(&stored.CustomAttributes, object.CustomAttributes)
+		if err != nil {
+			return err
+		}
+		return deepCopy(&stored.Tags, object.Tags)
+	})
+}
+
+func (m *MemoryClient) DeleteObject(object *Object, tx DB) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.objects, keyOf(object))
+	return nil
+}
+
+func (m *MemoryClient) UpdateObject(object *Object, tx DB) (err error) {
+	return m.updateObjects(object, false, func(stored *Object, object *Object) error {
+		stored.Location = object.Location
+		stored.Pool = object.Pool
+		stored.Size = object.Size
+		stored.ObjectId = object.ObjectId
+		stored.Etag = object.Etag
+		stored.InitializationVector = append([]byte(nil), object.InitializationVector...)
+		stored.StorageClass = object.StorageClass
+		stored.Parts = nil
+		return deepCopy(&stored.Parts, object.Parts)
+	})
+}
+
+func (m *MemoryClient) TransitObject(object, sourceObject *Object, tx DB) (transited bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stored, ok := m.objects[keyOf(object)]
+	if !ok || stored.Location != sourceObject.Location || stored.Pool != sourceObject.Pool ||
+		stored.ObjectId != sourceObject.ObjectId || stored.Size != sourceObject.Size {
+		return false, nil
+	}
+	stored.Location = object.Location
+	stored.Pool = object.Pool
+	stored.Size = object.Size
+	stored.ObjectId = object.ObjectId
+	stored.Etag = object.Etag
+	stored.InitializationVector = append([]byte(nil), object.InitializationVector...)
+	stored.StorageClass = object.StorageClass
+	stored.Parts = nil
+	return true, deepCopy(&stored.Parts, object.Parts)
+}
+
+func (m *MemoryClient) UpdateObjectAcl(object *Object) error {
+	return m.updateObjects(object, false, func(stored *Object, object *Object) error {
+		stored.ACL = object.ACL
+		return nil
+	})
+}
+
+func (m *MemoryClient) UpdateObjectAttrs(object *Object) error {
+	return m.updateObjects(object, true, func(stored *Object, object *Object) error {
+		stored.CustomAttributes = nil
+		return deepCopy(&stored.CustomAttributes, object.CustomAttributes)
+	})
+}
+
+func (m *MemoryClient) UpdateObjectTags(object *Object) error {
+	return m.updateObjects(object, false, func(stored *Object, object *Object) error {
+		stored.Tags = nil
+		return deepCopy(&stored.Tags, object.Tags)
+	})
+}
+
+func (m *MemoryClient) UpdateObjectLock(object *Object) error {
+	return m.updateObjects(object, false, func(stored *Object, object *Object) error {
+		stored.ObjectLock = object.ObjectLock
+		return nil
+	})
+}
+
+// updateObjects applies update to the version of object, or to all versions of it
+// if allVersions is set, the same as the `where` clauses of tidb client.
+// Missing objects are ignored as an `update` statement matching no rows.
+func (m *MemoryClient) updateObjects(object *Object, allVersions bool,
+	update func(stored *Object, object *Object) error) error {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var keys []objectKey
+	if allVersions {
+		keys = m.objectVersions(object.BucketName, object.Name)
+	} else if _, ok := m.objects[keyOf(object)]; ok {
+		keys = []objectKey{keyOf(object)}
+	}
+	for _, key := range keys {
+		err := update(m.objects[key], object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
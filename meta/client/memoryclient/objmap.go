@@ -0,0 +1,47 @@
+package memoryclient
+
+import (
+	"database/sql"
+	"strconv"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// GetObjectMap returns sql.ErrNoRows if not found, as tidb client does
+func (m *MemoryClient) GetObjectMap(bucketName, objectName string) (objMap *ObjMap, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	stored, ok := m.objMaps[nameKey{bucketName, objectName}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	objMap = &ObjMap{
+		BucketName: stored.BucketName,
+		Name:       stored.Name,
+		NullVerNum: stored.NullVerNum,
+		NullVerId:  strconv.FormatUint(stored.NullVerNum, 10),
+	}
+	return objMap, nil
+}
+
+func (m *MemoryClient) PutObjectMap(objMap *ObjMap, tx DB) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := nameKey{objMap.BucketName, objMap.Name}
+	if _, ok := m.objMaps[key]; ok {
+		return errDuplicateEntry
+	}
+	m.objMaps[key] = &ObjMap{
+		BucketName: objMap.BucketName,
+		Name:       objMap.Name,
+		NullVerNum: objMap.NullVerNum,
+	}
+	return nil
+}
+
+func (m *MemoryClient) DeleteObjectMap(objMap *ObjMap, tx DB) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.objMaps, nameKey{objMap.BucketName, objMap.Name})
+	return nil
+}
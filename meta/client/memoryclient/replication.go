@@ -0,0 +1,82 @@
+package memoryclient
+
+import (
+	"strconv"
+	"time"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// PutObjectToReplication ignores existing entries, as `insert ignore` of tidb client
+func (m *MemoryClient) PutObjectToReplication(object *Object, tx DB) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := keyOf(object)
+	if _, ok := m.replications[key]; ok {
+		return nil
+	}
+	m.replications[key] = Replication{
+		BucketName: key.bucketName,
+		ObjectName: key.objectName,
+		Version:    strconv.FormatUint(key.version, 10),
+		MTime:      time.Now().UTC(),
+	}
+	return nil
+}
+
+// ScanReplication returns entries after startRowKey, in order of bucket, object and version
+func (m *MemoryClient) ScanReplication(limit int, startRowKey string) (replications []Replication, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var start objectKey
+	if startRowKey != "" {
+		var ok bool
+		start, ok = parseRowkey(startRowKey)
+		if !ok {
+			return nil, nil
+		}
+	}
+	var keys []objectKey
+	for key := range m.replications {
+		if startRowKey == "" || start.less(key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range sortKeys(keys) {
+		if len(replications) >= limit {
+			break
+		}
+		replications = append(replications, m.replications[key])
+	}
+	return
+}
+
+func (m *MemoryClient) UpdateReplicationTriedTimes(replication Replication) error {
+	key, ok := parseRowkey(replication.Rowkey())
+	if !ok {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if stored, ok := m.replications[key]; ok {
+		stored.TriedTimes = replication.TriedTimes
+		stored.MTime = time.Now().UTC()
+		m.replications[key] = stored
+	}
+	return nil
+}
+
+// RemoveReplication removes the entry and records the final replication status of object version
+func (m *MemoryClient) RemoveReplication(replication Replication, status string) (err error) {
+	key, ok := parseRowkey(replication.Rowkey())
+	if !ok {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if object, ok := m.objects[key]; ok {
+		object.ReplicationStatus = status
+	}
+	delete(m.replications, key)
+	return nil
+}
@@ -0,0 +1,118 @@
+package memoryclient
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+// tables holds all metadata of a MemoryClient, in the format of Dump
+type tables struct {
+	Buckets      []*Bucket
+	Objects      []*Object
+	Multiparts   []*Multipart
+	ObjMaps      []*ObjMap
+	LifeCycles   []LifeCycle
+	Users        map[string][]string // user id -> bucket names
+	Gcs          []GarbageCollection
+	Replications []Replication
+	Freezers     []*Freezer
+}
+
+// Dump writes all metadata to w in json, which could be read back by Load
+func (m *MemoryClient) Dump(w io.Writer) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	t := &tables{Users: make(map[string][]string)}
+	for _, bucket := range m.buckets {
+		t.Buckets = append(t.Buckets, bucket)
+	}
+	for _, object := range m.objects {
+		t.Objects = append(t.Objects, object)
+	}
+	for _, multipart := range m.multiparts {
+		t.Multiparts = append(t.Multiparts, multipart)
+	}
+	for _, objMap := range m.objMaps {
+		t.ObjMaps = append(t.ObjMaps, objMap)
+	}
+	for _, lifeCycle := range m.lifeCycles {
+		t.LifeCycles = append(t.LifeCycles, lifeCycle)
+	}
+	for userId, buckets := range m.users {
+		for bucketName := range buckets {
+			t.Users[userId] = append(t.Users[userId], bucketName)
+		}
+	}
+	for _, gc := range m.gcs {
+		t.Gcs = append(t.Gcs, gc)
+	}
+	for _, replication := range m.replications {
+		t.Replications = append(t.Replications, replication)
+	}
+	for _, freezer := range m.freezers {
+		t.Freezers = append(t.Freezers, freezer)
+	}
+	return json.NewEncoder(w).Encode(t)
+}
+
+// Load replaces all metadata of the client with those written by Dump
+func (m *MemoryClient) Load(r io.Reader) error {
+	t := &tables{}
+	err := json.NewDecoder(r).Decode(t)
+	if err != nil {
+		return err
+	}
+	loaded := NewMemoryClient()
+	for _, bucket := range t.Buckets {
+		loaded.buckets[bucket.Name] = bucket
+	}
+	for _, object := range t.Objects {
+		loaded.objects[keyOf(object)] = object
+	}
+	for _, multipart := range t.Multiparts {
+		loaded.multiparts[multipartKey(multipart)] = multipart
+	}
+	for _, objMap := range t.ObjMaps {
+		loaded.objMaps[nameKey{objMap.BucketName, objMap.Name}] = objMap
+	}
+	for _, lifeCycle := range t.LifeCycles {
+		loaded.lifeCycles[lifeCycle.BucketName] = lifeCycle
+	}
+	for userId, buckets := range t.Users {
+		loaded.users[userId] = make(map[string]struct{})
+		for _, bucketName := range buckets {
+			loaded.users[userId][bucketName] = struct{}{}
+		}
+	}
+	for _, gc := range t.Gcs {
+		if key, ok := parseRowkey(gc.Rowkey); ok {
+			loaded.gcs[key] = gc
+		}
+	}
+	for _, replication := range t.Replications {
+		version, err := strconv.ParseUint(replication.Version, 10, 64)
+		if err != nil {
+			continue
+		}
+		loaded.replications[objectKey{replication.BucketName, replication.ObjectName, version}] = replication
+	}
+	for _, freezer := range t.Freezers {
+		loaded.freezers[nameKey{freezer.BucketName, freezer.Name}] = freezer
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.buckets = loaded.buckets
+	m.objects = loaded.objects
+	m.multiparts = loaded.multiparts
+	m.objMaps = loaded.objMaps
+	m.lifeCycles = loaded.lifeCycles
+	m.users = loaded.users
+	m.gcs = loaded.gcs
+	m.replications = loaded.replications
+	m.freezers = loaded.freezers
+	return nil
+}
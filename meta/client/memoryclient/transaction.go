@@ -0,0 +1,18 @@
+package memoryclient
+
+import "database/sql"
+
+// Transactions are not supported, every change is applied immediately
+// and nothing is rolled back on AbortTrans.
+// NewTrans returns a nil *sql.Tx, which is ignored by all methods taking a tx.
+func (m *MemoryClient) NewTrans() (tx *sql.Tx, err error) {
+	return nil, nil
+}
+
+func (m *MemoryClient) AbortTrans(tx *sql.Tx) (err error) {
+	return nil
+}
+
+func (m *MemoryClient) CommitTrans(tx *sql.Tx) (err error) {
+	return nil
+}
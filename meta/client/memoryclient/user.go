@@ -0,0 +1,32 @@
+package memoryclient
+
+import (
+	"sort"
+)
+
+func (m *MemoryClient) GetUserBuckets(userId string) (buckets []string, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for bucketName := range m.users[userId] {
+		buckets = append(buckets, bucketName)
+	}
+	sort.Strings(buckets)
+	return
+}
+
+func (m *MemoryClient) AddBucketForUser(bucketName, userId string) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.users[userId] == nil {
+		m.users[userId] = make(map[string]struct{})
+	}
+	m.users[userId][bucketName] = struct{}{}
+	return nil
+}
+
+func (m *MemoryClient) RemoveBucketForUser(bucketName string, userId string) (err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.users[userId], bucketName)
+	return nil
+}
@@ -0,0 +1,28 @@
+package mysqlclient
+
+import (
+	"database/sql"
+	"os"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+)
+
+// MysqlClient stores metadata in MySQL 8. MySQL understands all SQL of TidbClient,
+// only the connection and schema (integrate/yig_mysql.sql) are different.
+type MysqlClient struct {
+	*tidbclient.TidbClient
+}
+
+func NewMysqlClient() *MysqlClient {
+	conn, err := sql.Open("mysql", helper.CONFIG.MysqlInfo)
+	if err != nil {
+		os.Exit(1)
+	}
+	conn.SetMaxIdleConns(helper.CONFIG.DbMaxIdleConns)
+	conn.SetMaxOpenConns(helper.CONFIG.DbMaxOpenConns)
+	conn.SetConnMaxLifetime(time.Duration(helper.CONFIG.DbConnMaxLifeSeconds) * time.Second)
+	return &MysqlClient{TidbClient: &tidbclient.TidbClient{Client: conn}}
+}
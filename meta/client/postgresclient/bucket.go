@@ -0,0 +1,358 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+const bucketColumns = "bucketname,acl,cors,COALESCE(logging,'{}'),lc,uid,policy,website,COALESCE(encryption,'{}')," +
+	"createtime,usages,versioning,COALESCE(tags,'{}'),COALESCE(objectlock,'{}'),COALESCE(notification,'{}')," +
+	"COALESCE(replication,'{}')"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBucket(row scanner) (bucket Bucket, err error) {
+	var acl, cors, logging, lc, policy, website, encryption, tags, objectLock, notification, replication string
+	err = row.Scan(
+		&bucket.Name,
+		&acl,
+		&cors,
+		&logging,
+		&lc,
+		&bucket.OwnerId,
+		&policy,
+		&website,
+		&encryption,
+		&bucket.CreateTime,
+		&bucket.Usage,
+		&bucket.Versioning,
+		&tags,
+		&objectLock,
+		&notification,
+		&replication,
+	)
+	if err != nil {
+		return
+	}
+	fields := []struct {
+		data  string
+		value interface{}
+	}{
+		{acl, &bucket.ACL},
+		{cors, &bucket.CORS},
+		{logging, &bucket.BucketLogging},
+		{lc, &bucket.Lifecycle},
+		{policy, &bucket.Policy},
+		{website, &bucket.Website},
+		{encryption, &bucket.Encryption},
+		{tags, &bucket.Tags},
+		{objectLock, &bucket.ObjectLock},
+		{notification, &bucket.Notification},
+		{replication, &bucket.Replication},
+	}
+	for _, field := range fields {
+		err = json.Unmarshal([]byte(field.data), field.value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (t *PostgresClient) GetBucket(bucketName string) (bucket *Bucket, err error) {
+	sqltext := "select " + bucketColumns + " from buckets where bucketname=$1;"
+	b, err := scanBucket(t.Client.QueryRow(sqltext, bucketName))
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchBucket
+		return
+	} else if err != nil {
+		return
+	}
+	return &b, nil
+}
+
+func (t *PostgresClient) GetBuckets() (buckets []Bucket, err error) {
+	sqltext := "select " + bucketColumns + " from buckets;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket Bucket
+		bucket, err = scanBucket(rows)
+		if err != nil {
+			return
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+//Actually this method is used to update bucket
+func (t *PostgresClient) PutBucket(bucket Bucket) error {
+	sqltext := "update buckets set bucketname=$1,acl=$2,policy=$3,cors=$4,logging=$5,lc=$6,website=$7,encryption=$8," +
+		"uid=$9,versioning=$10,tags=$11,objectlock=$12,notification=$13,replication=$14 where bucketname=$15;"
+	_, err := t.Client.Exec(sqltext, bucket.Name, toJson(bucket.ACL), toJson(bucket.Policy), toJson(bucket.CORS),
+		toJson(bucket.BucketLogging), toJson(bucket.Lifecycle), toJson(bucket.Website), toJson(bucket.Encryption),
+		bucket.OwnerId, bucket.Versioning, toJson(bucket.Tags), toJson(bucket.ObjectLock),
+		toJson(bucket.Notification), toJson(bucket.Replication), bucket.Name)
+	return err
+}
+
+// CheckAndPutBucket creates the bucket and returns true if it does not exist
+func (t *PostgresClient) CheckAndPutBucket(bucket Bucket) (bool, error) {
+	sqltext := "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages," +
+		"versioning,tags,objectlock,notification,replication) " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16) on conflict do nothing;"
+	result, err := t.Client.Exec(sqltext, bucket.Name, toJson(bucket.ACL), toJson(bucket.CORS),
+		toJson(bucket.BucketLogging), toJson(bucket.Lifecycle), bucket.OwnerId, toJson(bucket.Policy),
+		toJson(bucket.Website), toJson(bucket.Encryption), bucket.CreateTime.Format(TIME_LAYOUT_TIDB),
+		bucket.Usage, bucket.Versioning, toJson(bucket.Tags), toJson(bucket.ObjectLock),
+		toJson(bucket.Notification), toJson(bucket.Replication))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (t *PostgresClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimiter string, versioned bool,
+	maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
+
+	if versioned {
+		return t.listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter, maxKeys)
+	}
+	var count int
+	var exit bool
+	commonPrefixes := make(map[string]struct{})
+	omarker := marker
+	if marker < prefix {
+		marker = prefix
+	}
+	// only the latest version of every object is scanned, i.e. the smallest version
+	for !exit {
+		var names []string
+		var versions []string
+		var deleteMarkers []bool
+		sqltext := "select distinct on (name) name,version,deletemarker from objects where bucketname=$1 and name>$2 " +
+			"order by name,version limit $3;"
+		if marker == omarker || marker == "" {
+			// marker itself should be listed if marker is the prefix
+			sqltext = strings.Replace(sqltext, "name>$2", "name>=$2", 1)
+			if marker == omarker && omarker != "" {
+				sqltext = strings.Replace(sqltext, "name>=$2", "name>$2", 1)
+			}
+		}
+		var rows *sql.Rows
+		rows, err = t.Client.Query(sqltext, bucketName, marker, maxKeys)
+		if err != nil {
+			return
+		}
+		for rows.Next() {
+			var name, version string
+			var deleteMarker bool
+			err = rows.Scan(&name, &version, &deleteMarker)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			names = append(names, name)
+			versions = append(versions, version)
+			deleteMarkers = append(deleteMarkers, deleteMarker)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return
+		}
+		if len(names) == 0 {
+			break
+		}
+		for i, name := range names {
+			marker = name
+			if !strings.HasPrefix(name, prefix) {
+				// names are sorted, no more objects with the prefix
+				exit = true
+				break
+			}
+			if deleteMarkers[i] {
+				continue
+			}
+			if len(delimiter) != 0 {
+				subStr := strings.TrimPrefix(name, prefix)
+				n := strings.Index(subStr, delimiter)
+				if n != -1 {
+					prefixKey := prefix + subStr[:n+len(delimiter)]
+					if prefixKey == omarker {
+						continue
+					}
+					if _, ok := commonPrefixes[prefixKey]; !ok {
+						if count == maxKeys {
+							truncated = true
+							exit = true
+							break
+						}
+						commonPrefixes[prefixKey] = struct{}{}
+						nextMarker = prefixKey
+						count += 1
+					}
+					continue
+				}
+			}
+			if count == maxKeys {
+				truncated = true
+				exit = true
+				break
+			}
+			var o *Object
+			o, err = t.GetObject(bucketName, name, versions[i])
+			if err != nil {
+				return
+			}
+			retObjects = append(retObjects, o)
+			nextMarker = name
+			count += 1
+		}
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
+
+// List all versions and delete markers of objects, in order of object name
+// and from the latest version to the oldest one for each object.
+// nextVerIdMarker is the encrypted last modified time of the last version returned.
+func (t *PostgresClient) listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter string,
+	maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
+
+	var version uint64
+	if marker != "" {
+		// key marker only, skip all versions of it
+		version = math.MaxUint64
+		if verIdMarker != "" {
+			var decrypted string
+			var timestamp uint64
+			decrypted, err = util.Decrypt(verIdMarker)
+			if err == nil {
+				timestamp, err = strconv.ParseUint(decrypted, 10, 64)
+			}
+			if err != nil {
+				return nil, nil, false, "", "", ErrNoSuchVersion
+			}
+			version = math.MaxUint64 - timestamp
+		}
+	}
+	if marker < prefix {
+		marker = prefix
+		version = 0
+	}
+	omarker := marker
+	var count int
+	var exit bool
+	commonPrefixes := make(map[string]struct{})
+	for !exit {
+		var rows *sql.Rows
+		var names []string
+		var versions []uint64
+		sqltext := "select name,version from objects where bucketname=$1 and ((name=$2 and version>$3) or name>$2) " +
+			"order by name,version limit $4;"
+		rows, err = t.Client.Query(sqltext, bucketName, marker, strconv.FormatUint(version, 10), maxKeys)
+		if err != nil {
+			return
+		}
+		for rows.Next() {
+			var name string
+			var v uint64
+			err = rows.Scan(&name, &v)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			names = append(names, name)
+			versions = append(versions, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return
+		}
+		if len(names) == 0 {
+			break
+		}
+		for i, name := range names {
+			marker, version = name, versions[i]
+			if !strings.HasPrefix(name, prefix) {
+				// names are sorted, no more objects with the prefix
+				exit = true
+				break
+			}
+			if len(delimiter) != 0 {
+				subStr := strings.TrimPrefix(name, prefix)
+				n := strings.Index(subStr, delimiter)
+				if n != -1 {
+					prefixKey := prefix + subStr[:n+len(delimiter)]
+					if prefixKey == omarker {
+						continue
+					}
+					if _, ok := commonPrefixes[prefixKey]; !ok {
+						if count == maxKeys {
+							truncated = true
+							exit = true
+							break
+						}
+						commonPrefixes[prefixKey] = struct{}{}
+						nextMarker = prefixKey
+						nextVerIdMarker = ""
+						count += 1
+					}
+					continue
+				}
+			}
+			if count == maxKeys {
+				truncated = true
+				exit = true
+				break
+			}
+			var o *Object
+			o, err = t.GetObject(bucketName, name, strconv.FormatUint(versions[i], 10))
+			if err != nil {
+				return
+			}
+			retObjects = append(retObjects, o)
+			nextMarker = name
+			nextVerIdMarker = util.Encrypt(strconv.FormatUint(math.MaxUint64-versions[i], 10))
+			count += 1
+		}
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
+
+func (t *PostgresClient) DeleteBucket(bucket Bucket) error {
+	sqltext := "delete from buckets where bucketname=$1;"
+	_, err := t.Client.Exec(sqltext, bucket.Name)
+	return err
+}
+
+func (t *PostgresClient) UpdateUsage(bucketName string, size int64, tx DB) (err error) {
+	if !helper.CONFIG.PiggybackUpdateUsage {
+		return nil
+	}
+
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update buckets set usages=usages+$1 where bucketname=$2;"
+	_, err = tx.Exec(sqltext, size, bucketName)
+	return
+}
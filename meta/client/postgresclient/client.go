@@ -0,0 +1,52 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+)
+
+// PostgresClient stores metadata in PostgreSQL, schema is integrate/yig_postgres.sql.
+// The driver is registered in driver.go, which is only built with `-tags postgres`.
+//
+// Versions and upload times are numeric(20) since PostgreSQL has no unsigned bigint,
+// they are passed to and scanned from the database as decimal strings.
+type PostgresClient struct {
+	Client *sql.DB
+}
+
+func NewPostgresClient() *PostgresClient {
+	cli := &PostgresClient{}
+	conn, err := sql.Open("postgres", helper.CONFIG.PostgresInfo)
+	if err != nil {
+		helper.Logger.Error("Failed to open postgres:", err,
+			"yig must be built with `-tags postgres` to use postgres as meta store")
+		os.Exit(1)
+	}
+	conn.SetMaxIdleConns(helper.CONFIG.DbMaxIdleConns)
+	conn.SetMaxOpenConns(helper.CONFIG.DbMaxOpenConns)
+	conn.SetConnMaxLifetime(time.Duration(helper.CONFIG.DbConnMaxLifeSeconds) * time.Second)
+	cli.Client = conn
+	return cli
+}
+
+// version of the object or multipart upload created at t, in decimal
+func versionOf(t time.Time) string {
+	return strconv.FormatUint(math.MaxUint64-uint64(t.UnixNano()), 10)
+}
+
+// timeOf is the reverse of versionOf
+func timeOf(version uint64) time.Time {
+	timestamp := int64(math.MaxUint64 - version)
+	return time.Unix(timestamp/1e9, timestamp%1e9)
+}
+
+func toJson(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,134 @@
+package postgresclient_test
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/meta/client/postgresclient"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClient(t *testing.T) (*postgresclient.PostgresClient, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	return &postgresclient.PostgresClient{Client: db}, mock
+}
+
+func TestPostgresClient_GetBucket(t *testing.T) {
+	client, mock := newClient(t)
+	defer client.Client.Close()
+
+	createTime := time.Date(2010, 4, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("select (.+) from buckets where bucketname=\\$1").
+		WithArgs("hehe").
+		WillReturnRows(sqlmock.NewRows([]string{"bucketname", "acl", "cors", "logging", "lc", "uid", "policy",
+			"website", "encryption", "createtime", "usages", "versioning", "tags", "objectlock", "notification",
+			"replication"}).
+			AddRow("hehe", `{"CannedAcl":"private"}`, "{}", "{}", "{}", "haha", "{}", "{}", "{}",
+				createTime, 4, "Enabled", "{}", "{}", "{}", "{}"))
+	mock.ExpectQuery("select (.+) from buckets where bucketname=\\$1").
+		WithArgs("none").
+		WillReturnError(sql.ErrNoRows)
+
+	bucket, err := client.GetBucket("hehe")
+	assert.Nil(t, err)
+	assert.Equal(t, "hehe", bucket.Name)
+	assert.Equal(t, "haha", bucket.OwnerId)
+	assert.Equal(t, "private", bucket.ACL.CannedAcl)
+	assert.Equal(t, createTime, bucket.CreateTime)
+	assert.Equal(t, int64(4), bucket.Usage)
+	assert.Equal(t, "Enabled", bucket.Versioning)
+
+	_, err = client.GetBucket("none")
+	assert.Equal(t, ErrNoSuchBucket, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresClient_CheckAndPutBucket(t *testing.T) {
+	client, mock := newClient(t)
+	defer client.Client.Close()
+
+	mock.ExpectExec("insert into buckets(.+) on conflict do nothing").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("insert into buckets(.+) on conflict do nothing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	bucket := Bucket{Name: "hehe", OwnerId: "haha", CreateTime: time.Now().UTC()}
+	created, err := client.CheckAndPutBucket(bucket)
+	assert.Nil(t, err)
+	assert.True(t, created)
+	created, err = client.CheckAndPutBucket(bucket)
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresClient_PutAndGetObject(t *testing.T) {
+	client, mock := newClient(t)
+	defer client.Client.Close()
+
+	lastModified := time.Unix(1500000000, 123456789).UTC()
+	version := strconv.FormatUint(math.MaxUint64-uint64(lastModified.UnixNano()), 10)
+	object := &Object{
+		BucketName:       "hehe",
+		Name:             "obj",
+		Location:         "cluster",
+		Pool:             "rabbit",
+		OwnerId:          "haha",
+		Size:             10,
+		ObjectId:         "oid",
+		LastModifiedTime: lastModified,
+		Etag:             "etag",
+		Parts: map[int]*Part{
+			1: {PartNumber: 1, Size: 5, ObjectId: "oid1", Offset: 0, Etag: "e1"},
+			2: {PartNumber: 2, Size: 5, ObjectId: "oid2", Offset: 5, Etag: "e2"},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("insert into objects(.+)").
+		WithArgs("hehe", "obj", version, "cluster", "rabbit", "haha", int64(10), "oid",
+			sqlmock.AnyArg(), "etag", "", "null", sqlmock.AnyArg(), false, false, "", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			int64(ObjectTypeNormal), int64(ObjectStorageClassStandard), "null", sqlmock.AnyArg(), "", "", "null").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("insert into objectpart(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("insert into objectpart(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	assert.Nil(t, client.PutObject(object, nil))
+
+	mock.ExpectQuery("select (.+) from objects where bucketname=\\$1 and name=\\$2 order by version limit 1").
+		WithArgs("hehe", "obj").
+		WillReturnRows(sqlmock.NewRows([]string{"bucketname", "name", "version", "location", "pool", "ownerid",
+			"size", "objectid", "etag", "contenttype", "customattributes", "acl", "nullversion", "deletemarker",
+			"ssetype", "encryptionkey", "initializationvector", "type", "storageclass", "tags", "objectlock",
+			"replicationstatus", "ssekmskeyid", "ssecontext"}).
+			AddRow("hehe", "obj", version, "cluster", "rabbit", "haha", 10, "oid", "etag", "", "{}",
+				`{"CannedAcl":"private"}`, false, false, "", nil, nil, 0, 0, "{}", "{}", "", "", "{}"))
+	mock.ExpectQuery("select (.+) from objectpart where bucketname=\\$1 and objectname=\\$2 and version=\\$3").
+		WithArgs("hehe", "obj", version).
+		WillReturnRows(sqlmock.NewRows([]string{"partnumber", "size", "objectid", "offset", "etag",
+			"lastmodified", "initializationvector"}).
+			AddRow(2, 5, "oid2", 5, "e2", "", nil).
+			AddRow(1, 5, "oid1", 0, "e1", "", nil))
+
+	got, err := client.GetObject("hehe", "obj", "")
+	assert.Nil(t, err)
+	assert.Equal(t, lastModified.UnixNano(), got.LastModifiedTime.UnixNano())
+	assert.Equal(t, "private", got.ACL.CannedAcl)
+	assert.Equal(t, 2, len(got.Parts))
+	assert.Equal(t, []int64{0, 5}, got.PartsIndex.Index)
+	assert.Equal(t, "oid2", got.Parts[2].ObjectId)
+
+	mock.ExpectQuery("select (.+) from objects").WillReturnError(sql.ErrNoRows)
+	_, err = client.GetObject("hehe", "none", "")
+	assert.Equal(t, ErrNoSuchKey, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
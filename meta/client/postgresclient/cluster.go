@@ -0,0 +1,23 @@
+package postgresclient
+
+import (
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *PostgresClient) GetClusters() (cluster []Cluster, err error) {
+	sqltext := "select fsid,pool,weight from cluster;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := Cluster{}
+		err = rows.Scan(&c.Fsid, &c.Pool, &c.Weight)
+		if err != nil {
+			return nil, err
+		}
+		cluster = append(cluster, c)
+	}
+	return cluster, rows.Err()
+}
@@ -0,0 +1,5 @@
+// +build postgres
+
+package postgresclient
+
+import _ "github.com/lib/pq"
@@ -0,0 +1,197 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/journeymidnight/yig/error"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *PostgresClient) CreateFreezer(freezer *Freezer) (err error) {
+	// TODO Multi-version control
+	sqltext := "insert into restoreobjects(bucketname,objectname,status,lifetime,lastmodifiedtime) " +
+		"values($1,$2,$3,$4,$5);"
+	_, err = t.Client.Exec(sqltext, freezer.BucketName, freezer.Name, freezer.Status, freezer.LifeTime,
+		freezer.LastModifiedTime.Format(TIME_LAYOUT_TIDB))
+	return
+}
+
+func (t *PostgresClient) GetFreezer(bucketName, objectName, version string) (freezer *Freezer, err error) {
+	sqltext := "select bucketname,objectname,COALESCE(version::text,''),status,lifetime,lastmodifiedtime," +
+		"COALESCE(location,''),COALESCE(pool,''),COALESCE(ownerid,''),COALESCE(size,0),COALESCE(objectid,'')," +
+		"COALESCE(etag,'') from restoreobjects where bucketname=$1 and objectname=$2;"
+	var lastModifiedTime time.Time
+	freezer = &Freezer{}
+	err = t.Client.QueryRow(sqltext, bucketName, objectName).Scan(
+		&freezer.BucketName,
+		&freezer.Name,
+		&freezer.VersionId,
+		&freezer.Status,
+		&freezer.LifeTime,
+		&lastModifiedTime,
+		&freezer.Location,
+		&freezer.Pool,
+		&freezer.OwnerId,
+		&freezer.Size,
+		&freezer.ObjectId,
+		&freezer.Etag,
+	)
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchKey
+		return
+	} else if err != nil {
+		return
+	}
+	// lastmodifiedtime is a timestamp without time zone written in local time, as TidbClient does
+	freezer.LastModifiedTime = time.Date(lastModifiedTime.Year(), lastModifiedTime.Month(), lastModifiedTime.Day(),
+		lastModifiedTime.Hour(), lastModifiedTime.Minute(), lastModifiedTime.Second(), lastModifiedTime.Nanosecond(),
+		time.Local)
+	freezer.Parts, err = getParts(t.Client, "select "+partColumns+
+		" from restoreobjectpart where bucketname=$1 and objectname=$2;", bucketName, objectName)
+	if err != nil {
+		return
+	}
+	freezer.PartsIndex = partsIndex(freezer.Parts)
+	return
+}
+
+func (t *PostgresClient) GetFreezerStatus(bucketName, objectName, version string) (freezer *Freezer, err error) {
+	sqltext := "select bucketname,objectname,COALESCE(version::text,''),status from restoreobjects " +
+		"where bucketname=$1 and objectname=$2;"
+	freezer = &Freezer{}
+	err = t.Client.QueryRow(sqltext, bucketName, objectName).Scan(
+		&freezer.BucketName,
+		&freezer.Name,
+		&freezer.VersionId,
+		&freezer.Status,
+	)
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchKey
+	}
+	return
+}
+
+func (t *PostgresClient) UploadFreezerDate(bucketName, objectName string, lifetime int) (err error) {
+	sqltext := "update restoreobjects set lifetime=$1 where bucketname=$2 and objectname=$3;"
+	_, err = t.Client.Exec(sqltext, lifetime, bucketName, objectName)
+	return
+}
+
+func (t *PostgresClient) DeleteFreezer(bucketName, objectName string, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	sqltext := "delete from restoreobjects where bucketname=$1 and objectname=$2;"
+	_, err = tx.Exec(sqltext, bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from restoreobjectpart where bucketname=$1 and objectname=$2;"
+	_, err = tx.Exec(sqltext, bucketName, objectName)
+	return err
+}
+
+// ScanFreezers returns freezers in status after startRowKey, in order of bucket and object.
+// Rowkey of freezers returned is bucket name and object name joined by ObjectNameSeparator.
+func (t *PostgresClient) ScanFreezers(limit int, startRowKey string, status Status) (freezers []Freezer, err error) {
+	sqltext := "select bucketname,objectname,status,lifetime,lastmodifiedtime from restoreobjects where status=$1 "
+	args := []interface{}{status}
+	if startRowKey != "" {
+		s := strings.Split(startRowKey, ObjectNameSeparator)
+		sqltext += "and (bucketname>$2 or (bucketname=$2 and objectname>$3)) "
+		args = append(args, s[0], s[1])
+	}
+	sqltext += "order by bucketname,objectname limit $" + strconv.Itoa(len(args)+1) + ";"
+	args = append(args, limit)
+	rows, err := t.Client.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f Freezer
+		var lastModifiedTime time.Time
+		err = rows.Scan(
+			&f.BucketName,
+			&f.Name,
+			&f.Status,
+			&f.LifeTime,
+			&lastModifiedTime,
+		)
+		if err != nil {
+			return
+		}
+		f.LastModifiedTime = time.Date(lastModifiedTime.Year(), lastModifiedTime.Month(), lastModifiedTime.Day(),
+			lastModifiedTime.Hour(), lastModifiedTime.Minute(), lastModifiedTime.Second(), lastModifiedTime.Nanosecond(),
+			time.Local)
+		f.Rowkey = []byte(f.BucketName + ObjectNameSeparator + f.Name)
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
+}
+
+// UpdateFreezerStatus sets status of the freezer to statusSetTo only if it is in status,
+// so one freezer is restored by one worker
+func (t *PostgresClient) UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo Status) (updated bool, err error) {
+	sqltext := "update restoreobjects set status=$1 where bucketname=$2 and objectname=$3 and status=$4;"
+	result, err := t.Client.Exec(sqltext, statusSetTo, bucketName, objectName, status)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// PutFreezer saves data of the restored copy into the freezer, and sets it to status
+func (t *PostgresClient) PutFreezer(freezer *Freezer, status Status, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	sqltext := "update restoreobjects set status=$1,lastmodifiedtime=$2,location=$3,pool=$4,ownerid=$5,size=$6," +
+		"objectid=$7,etag=$8 where bucketname=$9 and objectname=$10;"
+	_, err = tx.Exec(sqltext, status, freezer.LastModifiedTime.Format(TIME_LAYOUT_TIDB), freezer.Location,
+		freezer.Pool, freezer.OwnerId, freezer.Size, freezer.ObjectId, freezer.Etag, freezer.BucketName, freezer.Name)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from restoreobjectpart where bucketname=$1 and objectname=$2;"
+	_, err = tx.Exec(sqltext, freezer.BucketName, freezer.Name)
+	if err != nil {
+		return err
+	}
+	for _, p := range freezer.Parts {
+		sqltext = "insert into restoreobjectpart(" + partColumns + ",bucketname,objectname) " +
+			"values($1,$2,$3,$4,$5,$6,$7,$8,$9);"
+		_, err = tx.Exec(sqltext, p.PartNumber, p.Size, p.ObjectId, p.Offset, p.Etag, p.LastModified,
+			p.InitializationVector, freezer.BucketName, freezer.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
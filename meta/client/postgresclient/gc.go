@@ -0,0 +1,160 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+//gc
+func (t *PostgresClient) PutObjectToGarbageCollection(object *Object, tx DB) (err error) {
+	return t.putGarbageCollection(tidbclient.GarbageCollectionFromObject(object),
+		versionOf(object.LastModifiedTime), tx)
+}
+
+func (t *PostgresClient) PutFreezerToGarbageCollection(object *Freezer, tx DB) (err error) {
+	return t.putGarbageCollection(tidbclient.GarbageCollectionFromFreeze(object),
+		versionOf(object.LastModifiedTime), tx)
+}
+
+func (t *PostgresClient) putGarbageCollection(o GarbageCollection, version string, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	sqltext := "insert into gc(bucketname,objectname,version,location,pool,objectid,status,mtime,part,triedtimes) " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) on conflict do nothing;"
+	result, err := tx.Exec(sqltext, o.BucketName, o.ObjectName, version, o.Location, o.Pool, o.ObjectId, o.Status,
+		o.MTime.Format(TIME_LAYOUT_TIDB), len(o.Parts) > 0, o.TriedTimes)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// already in gc, so are the parts
+		return nil
+	}
+	for _, p := range o.Parts {
+		err = putPart(tx, "gcpart", "version", o.BucketName, o.ObjectName, version, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *PostgresClient) ScanGarbageCollection(limit int, startRowKey string) (gcs []GarbageCollection, err error) {
+	sqltext := "select bucketname,objectname,version from gc "
+	args := []interface{}{}
+	if startRowKey != "" {
+		s := strings.Split(startRowKey, ObjectNameSeparator)
+		sqltext += "where bucketname>$1 or (bucketname=$1 and objectname>$2) or " +
+			"(bucketname=$1 and objectname=$2 and version>=$3) "
+		args = append(args, s[0], s[1], s[2])
+	}
+	sqltext += "order by bucketname,objectname,version limit $" + strconv.Itoa(len(args)+1) + ";"
+	args = append(args, limit)
+	rows, err := t.Client.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	var keys [][3]string
+	for rows.Next() {
+		var b, o, v string
+		err = rows.Scan(&b, &o, &v)
+		if err != nil {
+			rows.Close()
+			return
+		}
+		keys = append(keys, [3]string{b, o, v})
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		var gc GarbageCollection
+		gc, err = t.GetGarbageCollection(key[0], key[1], key[2])
+		if err != nil {
+			return
+		}
+		gcs = append(gcs, gc)
+	}
+	return
+}
+
+func (t *PostgresClient) RemoveGarbageCollection(garbage GarbageCollection) (err error) {
+	var tx *sql.Tx
+	tx, err = t.Client.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	version := strings.Split(garbage.Rowkey, ObjectNameSeparator)[2]
+	sqltext := "delete from gc where bucketname=$1 and objectname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, garbage.BucketName, garbage.ObjectName, version)
+	if err != nil {
+		return err
+	}
+	if len(garbage.Parts) > 0 {
+		sqltext = "delete from gcpart where bucketname=$1 and objectname=$2 and version=$3;"
+		_, err = tx.Exec(sqltext, garbage.BucketName, garbage.ObjectName, version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//util func
+func (t *PostgresClient) GetGarbageCollection(bucketName, objectName, version string) (gc GarbageCollection, err error) {
+	sqltext := "select bucketname,objectname,version,location,pool,objectid,status,mtime,part,triedtimes " +
+		"from gc where bucketname=$1 and objectname=$2 and version=$3;"
+	var hasPart bool
+	var v string
+	var mtime time.Time
+	err = t.Client.QueryRow(sqltext, bucketName, objectName, version).Scan(
+		&gc.BucketName,
+		&gc.ObjectName,
+		&v,
+		&gc.Location,
+		&gc.Pool,
+		&gc.ObjectId,
+		&gc.Status,
+		&mtime,
+		&hasPart,
+		&gc.TriedTimes,
+	)
+	if err != nil {
+		return
+	}
+	gc.MTime = mtime
+	gc.Rowkey = gc.BucketName + ObjectNameSeparator + gc.ObjectName + ObjectNameSeparator + v
+	if hasPart {
+		gc.Parts, err = getParts(t.Client, "select "+partColumns+
+			" from gcpart where bucketname=$1 and objectname=$2 and version=$3;", bucketName, objectName, version)
+	}
+	return
+}
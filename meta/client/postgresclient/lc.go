@@ -0,0 +1,48 @@
+package postgresclient
+
+import (
+	"github.com/journeymidnight/yig/helper"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *PostgresClient) PutBucketToLifeCycle(lifeCycle LifeCycle) error {
+	sqltext := "insert into lifecycle(bucketname,status) values($1,$2);"
+	_, err := t.Client.Exec(sqltext, lifeCycle.BucketName, lifeCycle.Status)
+	if err != nil {
+		helper.Logger.Error("Failed to execute:", sqltext, "err:", err)
+	}
+	return nil
+}
+
+func (t *PostgresClient) RemoveBucketFromLifeCycle(bucket Bucket) error {
+	sqltext := "delete from lifecycle where bucketname=$1;"
+	_, err := t.Client.Exec(sqltext, bucket.Name)
+	if err != nil {
+		helper.Logger.Error("Failed to execute:", sqltext, "err:", err)
+	}
+	return nil
+}
+
+func (t *PostgresClient) ScanLifeCycle(limit int, marker string) (result ScanLifeCycleResult, err error) {
+	sqltext := "select bucketname,status from lifecycle where bucketname>$1 order by bucketname limit $2;"
+	rows, err := t.Client.Query(sqltext, marker, limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	result.Lcs = make([]LifeCycle, 0, limit)
+	var lc LifeCycle
+	for rows.Next() {
+		err = rows.Scan(
+			&lc.BucketName,
+			&lc.Status)
+		if err != nil {
+			helper.Logger.Error("Failed in scan LifeCycle:", err)
+			return
+		}
+		result.Lcs = append(result.Lcs, lc)
+	}
+	result.NextMarker = lc.BucketName
+	result.Truncated = len(result.Lcs) == limit
+	return result, rows.Err()
+}
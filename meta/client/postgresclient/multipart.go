@@ -0,0 +1,260 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam"
+	"github.com/journeymidnight/yig/iam/common"
+	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
+)
+
+func (t *PostgresClient) GetMultipart(bucketName, objectName, uploadId string) (multipart Multipart, err error) {
+	timestampString, err := util.Decrypt(uploadId)
+	if err != nil {
+		return
+	}
+	uploadTime, err := strconv.ParseUint(timestampString, 10, 64)
+	if err != nil {
+		return
+	}
+	uploadTime = math.MaxUint64 - uploadTime
+	sqltext := "select bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest," +
+		"encryption,COALESCE(cipher,''::bytea),attrs,storageclass,COALESCE(tags,'{}'),COALESCE(objectlock,'{}') " +
+		"from multiparts where bucketname=$1 and objectname=$2 and uploadtime=$3;"
+	var initialTime uint64
+	var acl, sseRequest, attrs, tags, objectLock string
+	err = t.Client.QueryRow(sqltext, bucketName, objectName, strconv.FormatUint(uploadTime, 10)).Scan(
+		&multipart.BucketName,
+		&multipart.ObjectName,
+		&initialTime,
+		&multipart.Metadata.InitiatorId,
+		&multipart.Metadata.OwnerId,
+		&multipart.Metadata.ContentType,
+		&multipart.Metadata.Location,
+		&multipart.Metadata.Pool,
+		&acl,
+		&sseRequest,
+		&multipart.Metadata.EncryptionKey,
+		&multipart.Metadata.CipherKey,
+		&attrs,
+		&multipart.Metadata.StorageClass,
+		&tags,
+		&objectLock,
+	)
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchUpload
+		return
+	} else if err != nil {
+		return
+	}
+	multipart.InitialTime = timeOf(initialTime)
+	fields := []struct {
+		data  string
+		value interface{}
+	}{
+		{acl, &multipart.Metadata.Acl},
+		{sseRequest, &multipart.Metadata.SseRequest},
+		{attrs, &multipart.Metadata.Attrs},
+		{tags, &multipart.Metadata.Tags},
+		{objectLock, &multipart.Metadata.ObjectLock},
+	}
+	for _, field := range fields {
+		err = json.Unmarshal([]byte(field.data), field.value)
+		if err != nil {
+			return
+		}
+	}
+	multipart.Parts, err = getParts(t.Client, "select "+partColumns+
+		" from multipartpart where bucketname=$1 and objectname=$2 and uploadtime=$3;",
+		bucketName, objectName, strconv.FormatUint(uploadTime, 10))
+	return
+}
+
+func (t *PostgresClient) CreateMultipart(multipart Multipart) (err error) {
+	m := multipart.Metadata
+	sqltext := "insert into multiparts(bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool," +
+		"acl,sserequest,encryption,cipher,attrs,storageclass,tags,objectlock) " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16);"
+	_, err = t.Client.Exec(sqltext, multipart.BucketName, multipart.ObjectName, versionOf(multipart.InitialTime),
+		m.InitiatorId, m.OwnerId, m.ContentType, m.Location, m.Pool, toJson(m.Acl), toJson(m.SseRequest),
+		m.EncryptionKey, m.CipherKey, toJson(m.Attrs), m.StorageClass, toJson(m.Tags), toJson(m.ObjectLock))
+	return
+}
+
+func (t *PostgresClient) PutObjectPart(multipart *Multipart, part *Part, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	// lastmodified is stored as is, in CREATE_TIME_LAYOUT
+	_, err = time.Parse(CREATE_TIME_LAYOUT, part.LastModified)
+	if err != nil {
+		return
+	}
+	return putPart(tx, "multipartpart", "uploadtime", multipart.BucketName, multipart.ObjectName,
+		versionOf(multipart.InitialTime), part)
+}
+
+func (t *PostgresClient) DeleteMultipart(multipart *Multipart, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	uploadtime := versionOf(multipart.InitialTime)
+	sqltext := "delete from multiparts where bucketname=$1 and objectname=$2 and uploadtime=$3;"
+	_, err = tx.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime)
+	if err != nil {
+		return
+	}
+	sqltext = "delete from multipartpart where bucketname=$1 and objectname=$2 and uploadtime=$3;"
+	_, err = tx.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime)
+	return err
+}
+
+func (t *PostgresClient) ListMultipartUploads(bucketName, keyMarker, uploadIdMarker, prefix, delimiter,
+	encodingType string, maxUploads int) (uploads []datatype.Upload, prefixs []string, isTruncated bool,
+	nextKeyMarker, nextUploadIdMarker string, err error) {
+
+	var count int
+	var exit bool
+	commonPrefixes := make(map[string]struct{})
+	var uploadNum uint64
+	if uploadIdMarker != "" {
+		var timestampString string
+		var uploadTime uint64
+		timestampString, err = util.Decrypt(uploadIdMarker)
+		if err != nil {
+			return
+		}
+		uploadTime, err = strconv.ParseUint(timestampString, 10, 64)
+		if err != nil {
+			return
+		}
+		uploadNum = math.MaxUint64 - uploadTime
+	}
+	objnum := make(map[string]int)
+	currentMarker := keyMarker
+
+	for !exit {
+		var loopnum int
+		var rows *sql.Rows
+		sqltext := "select objectname,uploadtime,initiatorid,ownerid,storageclass from multiparts " +
+			"where bucketname=$1 and objectname>=$2 order by objectname,uploadtime limit $3 offset $4;"
+		rows, err = t.Client.Query(sqltext, bucketName, currentMarker, maxUploads+1, objnum[currentMarker])
+		if err != nil {
+			return
+		}
+		for rows.Next() {
+			loopnum += 1
+			var name, initiatorid, ownerid string
+			var uploadtime uint64
+			var storageClass StorageClass
+			err = rows.Scan(
+				&name,
+				&uploadtime,
+				&initiatorid,
+				&ownerid,
+				&storageClass,
+			)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			if name != currentMarker {
+				currentMarker = name
+				objnum[name] = 0
+			}
+			objnum[name] += 1
+			//filte by uploadtime and key
+			if uploadNum != 0 && name == keyMarker && uploadtime <= uploadNum {
+				continue
+			}
+			//filte by prefix
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			//filte by delimiter
+			if len(delimiter) != 0 {
+				subStr := strings.TrimPrefix(name, prefix)
+				n := strings.Index(subStr, delimiter)
+				if n != -1 {
+					prefixKey := prefix + subStr[:n+len(delimiter)]
+					commonPrefixes[prefixKey] = struct{}{}
+					continue
+				}
+			}
+			if count >= maxUploads {
+				isTruncated = true
+				exit = true
+				break
+			}
+			upload := datatype.Upload{StorageClass: storageClass.ToString()}
+			upload.UploadId = GetMultipartUploadIdForTidb(uploadtime)
+			upload.Key = name
+			if encodingType != "" {
+				upload.Key = url.QueryEscape(upload.Key)
+			}
+			var user common.Credential
+			user, err = iam.GetCredentialByUserId(ownerid)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			upload.Owner.ID = user.UserId
+			upload.Owner.DisplayName = user.DisplayName
+			user, err = iam.GetCredentialByUserId(initiatorid)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			upload.Initiator.ID = user.UserId
+			upload.Initiator.DisplayName = user.DisplayName
+			upload.Initiated = timeOf(uploadtime).UTC().Format(CREATE_TIME_LAYOUT)
+			uploads = append(uploads, upload)
+			nextKeyMarker = name
+			nextUploadIdMarker = upload.UploadId
+			count += 1
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return
+		}
+		if loopnum == 0 {
+			exit = true
+		}
+	}
+	if !isTruncated {
+		nextKeyMarker, nextUploadIdMarker = "", ""
+	}
+	prefixs = helper.Keys(commonPrefixes)
+	return
+}
+
+func (t *PostgresClient) RenameObjectPart(object *Object, sourceObject string, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update objectpart set objectname=$1 where bucketname=$2 and objectname=$3 and version=$4;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, sourceObject, versionOf(object.LastModifiedTime))
+	return err
+}
@@ -0,0 +1,384 @@
+package postgresclient
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	. "github.com/journeymidnight/yig/error"
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+const objectColumns = "bucketname,name,version,location,pool,ownerid,size,objectid,etag,contenttype," +
+	"customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass," +
+	"COALESCE(tags,'{}'),COALESCE(objectlock,'{}'),COALESCE(replicationstatus,''),COALESCE(ssekmskeyid,'')," +
+	"COALESCE(ssecontext,'{}')"
+
+// columns of objectpart, gcpart, multipartpart and restoreobjectpart, offset is a keyword of postgres
+const partColumns = "partnumber,size,objectid,\"offset\",etag,lastmodified,initializationvector"
+
+func (t *PostgresClient) GetObject(bucketName, objectName, version string) (object *Object, err error) {
+	var row *sql.Row
+	sqltext := "select " + objectColumns + " from objects where bucketname=$1 and name=$2 "
+	if version == "" {
+		sqltext += "order by version limit 1;"
+		row = t.Client.QueryRow(sqltext, bucketName, objectName)
+	} else {
+		sqltext += "and version=$3;"
+		row = t.Client.QueryRow(sqltext, bucketName, objectName, version)
+	}
+	object, iversion, err := scanObject(row)
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchKey
+		return
+	} else if err != nil {
+		return
+	}
+	object.Parts, err = getParts(t.Client, "select "+partColumns+
+		" from objectpart where bucketname=$1 and objectname=$2 and version=$3;",
+		bucketName, objectName, strconv.FormatUint(iversion, 10))
+	if err != nil {
+		return
+	}
+	object.PartsIndex = partsIndex(object.Parts)
+	object.GetVersionId()
+	return
+}
+
+func scanObject(row *sql.Row) (object *Object, version uint64, err error) {
+	var customAttributes, acl, tags, objectLock, sseContext string
+	object = &Object{}
+	err = row.Scan(
+		&object.BucketName,
+		&object.Name,
+		&version,
+		&object.Location,
+		&object.Pool,
+		&object.OwnerId,
+		&object.Size,
+		&object.ObjectId,
+		&object.Etag,
+		&object.ContentType,
+		&customAttributes,
+		&acl,
+		&object.NullVersion,
+		&object.DeleteMarker,
+		&object.SseType,
+		&object.EncryptionKey,
+		&object.InitializationVector,
+		&object.Type,
+		&object.StorageClass,
+		&tags,
+		&objectLock,
+		&object.ReplicationStatus,
+		&object.SseKmsKeyId,
+		&sseContext,
+	)
+	if err != nil {
+		return
+	}
+	object.LastModifiedTime = timeOf(version)
+	err = json.Unmarshal([]byte(customAttributes), &object.CustomAttributes)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(acl), &object.ACL)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(tags), &object.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &object.ObjectLock)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(sseContext), &object.SseContext)
+	return
+}
+
+func (t *PostgresClient) GetAllObject(bucketName, objectName, version string) (object []*Object, err error) {
+	sqltext := "select version from objects where bucketname=$1 and name=$2 order by version;"
+	var versions []string
+	rows, err := t.Client.Query(sqltext, bucketName, objectName)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sversion string
+		err = rows.Scan(&sversion)
+		if err != nil {
+			return
+		}
+		versions = append(versions, sversion)
+	}
+	err = rows.Err()
+	if err != nil {
+		return
+	}
+	for _, v := range versions {
+		var obj *Object
+		obj, err = t.GetObject(bucketName, objectName, v)
+		if err != nil {
+			return
+		}
+		object = append(object, obj)
+	}
+	return
+}
+
+func (t *PostgresClient) UpdateObjectAttrs(object *Object) error {
+	sqltext := "update objects set customattributes=$1 where bucketname=$2 and name=$3;"
+	_, err := t.Client.Exec(sqltext, toJson(object.CustomAttributes), object.BucketName, object.Name)
+	return err
+}
+
+func (t *PostgresClient) UpdateObjectTags(object *Object) error {
+	sqltext := "update objects set tags=$1 where bucketname=$2 and name=$3 and version=$4;"
+	_, err := t.Client.Exec(sqltext, toJson(object.Tags), object.BucketName, object.Name,
+		versionOf(object.LastModifiedTime))
+	return err
+}
+
+func (t *PostgresClient) UpdateObjectLock(object *Object) error {
+	sqltext := "update objects set objectlock=$1 where bucketname=$2 and name=$3 and version=$4;"
+	_, err := t.Client.Exec(sqltext, toJson(object.ObjectLock), object.BucketName, object.Name,
+		versionOf(object.LastModifiedTime))
+	return err
+}
+
+func (t *PostgresClient) UpdateObjectAcl(object *Object) error {
+	sqltext := "update objects set acl=$1 where bucketname=$2 and name=$3 and version=$4;"
+	_, err := t.Client.Exec(sqltext, toJson(object.ACL), object.BucketName, object.Name,
+		versionOf(object.LastModifiedTime))
+	return err
+}
+
+func (t *PostgresClient) RenameObject(object *Object, sourceObject string, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update objects set name=$1 where bucketname=$2 and name=$3 and version=$4;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, sourceObject, versionOf(object.LastModifiedTime))
+	return
+}
+
+func (t *PostgresClient) ReplaceObjectMetas(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update objects set contenttype=$1,customattributes=$2,storageclass=$3,tags=$4 " +
+		"where bucketname=$5 and name=$6 and version=$7;"
+	_, err = tx.Exec(sqltext, object.ContentType, toJson(object.CustomAttributes), object.StorageClass,
+		toJson(object.Tags), object.BucketName, object.Name, versionOf(object.LastModifiedTime))
+	return
+}
+
+func (t *PostgresClient) UpdateAppendObject(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update objects set lastmodifiedtime=$1,size=$2,version=$3 where bucketname=$4 and name=$5;"
+	_, err = tx.Exec(sqltext, object.LastModifiedTime.Format(TIME_LAYOUT_TIDB), object.Size,
+		versionOf(object.LastModifiedTime), object.BucketName, object.Name)
+	return err
+}
+
+func (t *PostgresClient) PutObject(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	version := versionOf(object.LastModifiedTime)
+	sqltext := "insert into objects(bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag," +
+		"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type," +
+		"storageclass,tags,objectlock,replicationstatus,ssekmskeyid,ssecontext) " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25);"
+	_, err = tx.Exec(sqltext, object.BucketName, object.Name, version, object.Location, object.Pool,
+		object.OwnerId, object.Size, object.ObjectId, object.LastModifiedTime.Format(TIME_LAYOUT_TIDB),
+		object.Etag, object.ContentType, toJson(object.CustomAttributes), toJson(object.ACL),
+		object.NullVersion, object.DeleteMarker, object.SseType, object.EncryptionKey,
+		object.InitializationVector, object.Type, object.StorageClass, toJson(object.Tags),
+		toJson(object.ObjectLock), object.ReplicationStatus, object.SseKmsKeyId, toJson(object.SseContext))
+	if err != nil {
+		return err
+	}
+	for _, p := range object.Parts {
+		err = putPart(tx, "objectpart", "version", object.BucketName, object.Name, version, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *PostgresClient) UpdateObject(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+
+	version := versionOf(object.LastModifiedTime)
+	sqltext := "delete from objectpart where objectname=$1 and bucketname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, version)
+	if err != nil {
+		return err
+	}
+	sqltext = "update objects set location=$1,pool=$2,size=$3,objectid=$4,etag=$5,initializationvector=$6," +
+		"storageclass=$7 where bucketname=$8 and name=$9 and version=$10;"
+	_, err = tx.Exec(sqltext, object.Location, object.Pool, object.Size, object.ObjectId, object.Etag,
+		object.InitializationVector, object.StorageClass, object.BucketName, object.Name, version)
+	if err != nil {
+		return err
+	}
+	for _, p := range object.Parts {
+		err = putPart(tx, "objectpart", "version", object.BucketName, object.Name, version, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *PostgresClient) TransitObject(object, sourceObject *Object, tx DB) (transited bool, err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return false, err
+		}
+		defer func() {
+			if err == nil && transited {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil || !transited {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+
+	version := versionOf(object.LastModifiedTime)
+	sqltext := "update objects set location=$1,pool=$2,size=$3,objectid=$4,etag=$5,initializationvector=$6," +
+		"storageclass=$7 where bucketname=$8 and name=$9 and version=$10 " +
+		"and location=$11 and pool=$12 and objectid=$13 and size=$14;"
+	result, err := tx.Exec(sqltext, object.Location, object.Pool, object.Size, object.ObjectId, object.Etag,
+		object.InitializationVector, object.StorageClass, object.BucketName, object.Name, version,
+		sourceObject.Location, sourceObject.Pool, sourceObject.ObjectId, sourceObject.Size)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n != 1 {
+		return false, err
+	}
+	sqltext = "delete from objectpart where objectname=$1 and bucketname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, version)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range object.Parts {
+		err = putPart(tx, "objectpart", "version", object.BucketName, object.Name, version, p)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (t *PostgresClient) DeleteObject(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+
+	version := versionOf(object.LastModifiedTime)
+	sqltext := "delete from objects where name=$1 and bucketname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, version)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from objectpart where objectname=$1 and bucketname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, version)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+//util function
+func putPart(tx DB, table, keyColumn, bucketName, objectName, key string, p *Part) error {
+	sqltext := "insert into " + table + "(" + partColumns + ",bucketname,objectname," + keyColumn + ") " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10);"
+	_, err := tx.Exec(sqltext, p.PartNumber, p.Size, p.ObjectId, p.Offset, p.Etag, p.LastModified,
+		p.InitializationVector, bucketName, objectName, key)
+	return err
+}
+
+func getParts(cli *sql.DB, sqltext string, args ...interface{}) (parts map[int]*Part, err error) {
+	parts = make(map[int]*Part)
+	rows, err := cli.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p := &Part{}
+		err = rows.Scan(
+			&p.PartNumber,
+			&p.Size,
+			&p.ObjectId,
+			&p.Offset,
+			&p.Etag,
+			&p.LastModified,
+			&p.InitializationVector,
+		)
+		if err != nil {
+			return
+		}
+		parts[p.PartNumber] = p
+	}
+	return parts, rows.Err()
+}
+
+// build simple index for multipart
+func partsIndex(parts map[int]*Part) *SimpleIndex {
+	if len(parts) == 0 {
+		return nil
+	}
+	var sortedPartNum = make([]int64, len(parts))
+	for k, v := range parts {
+		sortedPartNum[k-1] = v.Offset
+	}
+	return &SimpleIndex{Index: sortedPartNum}
+}
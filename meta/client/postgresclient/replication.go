@@ -0,0 +1,88 @@
+package postgresclient
+
+import (
+	"strconv"
+	"time"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *PostgresClient) PutObjectToReplication(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	mtime := time.Now().UTC().Format(TIME_LAYOUT_TIDB)
+	sqltext := "insert into replication(bucketname,objectname,version,mtime,triedtimes) values($1,$2,$3,$4,$5) " +
+		"on conflict do nothing;"
+	_, err = tx.Exec(sqltext, object.BucketName, object.Name, versionOf(object.LastModifiedTime), mtime, 0)
+	return err
+}
+
+// ScanReplication returns entries after startRowKey, in order of bucket, object and version
+func (t *PostgresClient) ScanReplication(limit int, startRowKey string) (replications []Replication, err error) {
+	sqltext := "select bucketname,objectname,version,mtime,triedtimes from replication "
+	args := []interface{}{}
+	if startRowKey != "" {
+		bucketName, objectName, version, ok := ParseReplicationRowkey(startRowKey)
+		if !ok {
+			return nil, nil
+		}
+		sqltext += "where bucketname>$1 or (bucketname=$1 and objectname>$2) or " +
+			"(bucketname=$1 and objectname=$2 and version>$3) "
+		args = append(args, bucketName, objectName, version)
+	}
+	sqltext += "order by bucketname,objectname,version limit $" + strconv.Itoa(len(args)+1) + ";"
+	args = append(args, limit)
+	rows, err := t.Client.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Replication
+		err = rows.Scan(
+			&r.BucketName,
+			&r.ObjectName,
+			&r.Version,
+			&r.MTime,
+			&r.TriedTimes,
+		)
+		if err != nil {
+			return
+		}
+		replications = append(replications, r)
+	}
+	return replications, rows.Err()
+}
+
+func (t *PostgresClient) UpdateReplicationTriedTimes(replication Replication) error {
+	mtime := time.Now().UTC().Format(TIME_LAYOUT_TIDB)
+	sqltext := "update replication set triedtimes=$1,mtime=$2 where bucketname=$3 and objectname=$4 and version=$5;"
+	_, err := t.Client.Exec(sqltext, replication.TriedTimes, mtime,
+		replication.BucketName, replication.ObjectName, replication.Version)
+	return err
+}
+
+// RemoveReplication removes the entry and records the final replication status of object version
+func (t *PostgresClient) RemoveReplication(replication Replication, status string) (err error) {
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	sqltext := "update objects set replicationstatus=$1 where bucketname=$2 and name=$3 and version=$4;"
+	_, err = tx.Exec(sqltext, status, replication.BucketName, replication.ObjectName, replication.Version)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from replication where bucketname=$1 and objectname=$2 and version=$3;"
+	_, err = tx.Exec(sqltext, replication.BucketName, replication.ObjectName, replication.Version)
+	return err
+}
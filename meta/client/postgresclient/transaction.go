@@ -0,0 +1,18 @@
+package postgresclient
+
+import "database/sql"
+
+func (t *PostgresClient) NewTrans() (tx *sql.Tx, err error) {
+	tx, err = t.Client.Begin()
+	return
+}
+
+func (t *PostgresClient) AbortTrans(tx *sql.Tx) (err error) {
+	err = tx.Rollback()
+	return
+}
+
+func (t *PostgresClient) CommitTrans(tx *sql.Tx) (err error) {
+	err = tx.Commit()
+	return
+}
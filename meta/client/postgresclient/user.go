@@ -0,0 +1,32 @@
+package postgresclient
+
+func (t *PostgresClient) GetUserBuckets(userId string) (buckets []string, err error) {
+	sqltext := "select bucketname from users where userid=$1;"
+	rows, err := t.Client.Query(sqltext, userId)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmp string
+		err = rows.Scan(&tmp)
+		if err != nil {
+			return
+		}
+		buckets = append(buckets, tmp)
+	}
+	return buckets, rows.Err()
+}
+
+func (t *PostgresClient) AddBucketForUser(bucketName, userId string) (err error) {
+	sqltext := "insert into users(userid,bucketname) values($1,$2);"
+	_, err = t.Client.Exec(sqltext, userId, bucketName)
+	return
+}
+
+func (t *PostgresClient) RemoveBucketForUser(bucketName string, userId string) (err error) {
+	sqltext := "delete from users where userid=$1 and bucketname=$2;"
+	_, err = t.Client.Exec(sqltext, userId, bucketName)
+	return
+}
@@ -3,6 +3,7 @@ package tidbclient
 import (
 	"database/sql"
 	"encoding/json"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -11,11 +12,12 @@ import (
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	. "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/meta/util"
 )
 
 func (t *TidbClient) GetBucket(bucketName string) (bucket *Bucket, err error) {
-	var acl, cors, logging, lc, policy, website, encryption, createTime string
-	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning from buckets where bucketname=?;"
+	var acl, cors, logging, lc, policy, website, encryption, createTime, tags, objectLock, notification, replication string
+	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning,COALESCE(tags,\"{}\"),COALESCE(objectlock,\"{}\"),COALESCE(notification,\"{}\"),COALESCE(replication,\"{}\") from buckets where bucketname=?;"
 	bucket = new(Bucket)
 	err = t.Client.QueryRow(sqltext, bucketName).Scan(
 		&bucket.Name,
@@ -30,6 +32,10 @@ func (t *TidbClient) GetBucket(bucketName string) (bucket *Bucket, err error) {
 		&createTime,
 		&bucket.Usage,
 		&bucket.Versioning,
+		&tags,
+		&objectLock,
+		&notification,
+		&replication,
 	)
 	if err != nil && err == sql.ErrNoRows {
 		err = ErrNoSuchBucket
@@ -69,11 +75,27 @@ func (t *TidbClient) GetBucket(bucketName string) (bucket *Bucket, err error) {
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &bucket.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &bucket.ObjectLock)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(notification), &bucket.Notification)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(replication), &bucket.Replication)
+	if err != nil {
+		return
+	}
 	return
 }
 
 func (t *TidbClient) GetBuckets() (buckets []Bucket, err error) {
-	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning from buckets;"
+	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning,COALESCE(tags,\"{}\"),COALESCE(objectlock,\"{}\"),COALESCE(notification,\"{}\"),COALESCE(replication,\"{}\") from buckets;"
 	rows, err := t.Client.Query(sqltext)
 	if err == sql.ErrNoRows {
 		err = nil
@@ -85,7 +107,7 @@ func (t *TidbClient) GetBuckets() (buckets []Bucket, err error) {
 
 	for rows.Next() {
 		var tmp Bucket
-		var acl, cors, logging, lc, policy, website,encryption, createTime string
+		var acl, cors, logging, lc, policy, website, encryption, createTime, tags, objectLock, notification, replication string
 		err = rows.Scan(
 			&tmp.Name,
 			&acl,
@@ -98,7 +120,11 @@ func (t *TidbClient) GetBuckets() (buckets []Bucket, err error) {
 			&encryption,
 			&createTime,
 			&tmp.Usage,
-			&tmp.Versioning)
+			&tmp.Versioning,
+			&tags,
+			&objectLock,
+			&notification,
+			&replication)
 		if err != nil {
 			return
 		}
@@ -134,6 +160,22 @@ func (t *TidbClient) GetBuckets() (buckets []Bucket, err error) {
 		if err != nil {
 			return
 		}
+		err = json.Unmarshal([]byte(tags), &tmp.Tags)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(objectLock), &tmp.ObjectLock)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(notification), &tmp.Notification)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(replication), &tmp.Replication)
+		if err != nil {
+			return
+		}
 		buckets = append(buckets, tmp)
 	}
 	return
@@ -168,7 +210,7 @@ func (t *TidbClient) CheckAndPutBucket(bucket Bucket) (bool, error) {
 
 func (t *TidbClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimiter string, versioned bool, maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
 	if versioned {
-		return
+		return t.listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter, maxKeys)
 	}
 	var count int
 	var exit bool
@@ -286,6 +328,115 @@ func (t *TidbClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimi
 	return
 }
 
+// List all versions and delete markers of objects, in order of object name
+// and from the latest version to the oldest one for each object.
+// nextVerIdMarker is the encrypted last modified time of the last version returned.
+func (t *TidbClient) listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter string,
+	maxKeys int) (retObjects []*Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
+
+	var version uint64
+	if marker != "" {
+		// key marker only, skip all versions of it
+		version = math.MaxUint64
+		if verIdMarker != "" {
+			var decrypted string
+			var timestamp uint64
+			decrypted, err = util.Decrypt(verIdMarker)
+			if err == nil {
+				timestamp, err = strconv.ParseUint(decrypted, 10, 64)
+			}
+			if err != nil {
+				return nil, nil, false, "", "", ErrNoSuchVersion
+			}
+			version = math.MaxUint64 - timestamp
+		}
+	}
+	if marker < prefix {
+		marker = prefix
+		version = 0
+	}
+	omarker := marker
+	var count int
+	var exit bool
+	commonPrefixes := make(map[string]struct{})
+	for !exit {
+		var rows *sql.Rows
+		var names []string
+		var versions []uint64
+		sqltext := "select name,version from objects where bucketname=? and ((name=? and version>?) or name>?) " +
+			"order by bucketname,name,version limit ?;"
+		rows, err = t.Client.Query(sqltext, bucketName, marker, version, marker, maxKeys)
+		if err != nil {
+			return
+		}
+		for rows.Next() {
+			var name string
+			var v uint64
+			err = rows.Scan(&name, &v)
+			if err != nil {
+				rows.Close()
+				return
+			}
+			names = append(names, name)
+			versions = append(versions, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return
+		}
+		if len(names) == 0 {
+			break
+		}
+		for i, name := range names {
+			marker, version = name, versions[i]
+			if !strings.HasPrefix(name, prefix) {
+				// names are sorted, no more objects with the prefix
+				exit = true
+				break
+			}
+			if len(delimiter) != 0 {
+				subStr := strings.TrimPrefix(name, prefix)
+				n := strings.Index(subStr, delimiter)
+				if n != -1 {
+					prefixKey := prefix + subStr[:n+len(delimiter)]
+					if prefixKey == omarker {
+						continue
+					}
+					if _, ok := commonPrefixes[prefixKey]; !ok {
+						if count == maxKeys {
+							truncated = true
+							exit = true
+							break
+						}
+						commonPrefixes[prefixKey] = struct{}{}
+						nextMarker = prefixKey
+						nextVerIdMarker = ""
+						count += 1
+					}
+					continue
+				}
+			}
+			if count == maxKeys {
+				truncated = true
+				exit = true
+				break
+			}
+			var o *Object
+			o, err = t.GetObject(bucketName, name, strconv.FormatUint(versions[i], 10))
+			if err != nil {
+				return
+			}
+			retObjects = append(retObjects, o)
+			nextMarker = name
+			nextVerIdMarker = util.Encrypt(strconv.FormatUint(math.MaxUint64-versions[i], 10))
+			count += 1
+		}
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
+
 func (t *TidbClient) DeleteBucket(bucket Bucket) error {
 	sqltext := "delete from buckets where bucketname=?;"
 	_, err := t.Client.Exec(sqltext, bucket.Name)
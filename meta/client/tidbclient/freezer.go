@@ -2,9 +2,11 @@ package tidbclient
 
 import (
 	"database/sql"
+	"strings"
+	"time"
+
 	. "github.com/journeymidnight/yig/error"
 	. "github.com/journeymidnight/yig/meta/types"
-	"time"
 )
 
 func (t *TidbClient) CreateFreezer(freezer *Freezer) (err error) {
@@ -98,7 +100,7 @@ func (t *TidbClient) DeleteFreezer(bucketName, objectName string, tx DB) (err er
 	if err != nil {
 		return err
 	}
-	sqltext = "delete from restoreobjectpart where objectname=? and bucketname=?;"
+	sqltext = "delete from restoreobjectpart where bucketname=? and objectname=?;"
 	_, err = tx.Exec(sqltext, bucketName, objectName)
 	if err != nil {
 		return err
@@ -106,6 +108,92 @@ func (t *TidbClient) DeleteFreezer(bucketName, objectName string, tx DB) (err er
 	return nil
 }
 
+// ScanFreezers returns freezers in status after startRowKey, in order of bucket and object.
+// Rowkey of freezers returned is bucket name and object name joined by ObjectNameSeparator.
+func (t *TidbClient) ScanFreezers(limit int, startRowKey string, status Status) (freezers []Freezer, err error) {
+	sqltext := "select bucketname,objectname,status,lifetime,lastmodifiedtime from restoreobjects where status=? "
+	args := []interface{}{status}
+	if startRowKey != "" {
+		s := strings.Split(startRowKey, ObjectNameSeparator)
+		sqltext += "and (bucketname>? or (bucketname=? and objectname>?)) "
+		args = append(args, s[0], s[0], s[1])
+	}
+	sqltext += "order by bucketname,objectname limit ?;"
+	args = append(args, limit)
+	rows, err := t.Client.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	local, _ := time.LoadLocation("Local")
+	for rows.Next() {
+		var f Freezer
+		var lastModifiedTime string
+		err = rows.Scan(
+			&f.BucketName,
+			&f.Name,
+			&f.Status,
+			&f.LifeTime,
+			&lastModifiedTime,
+		)
+		if err != nil {
+			return
+		}
+		f.LastModifiedTime, _ = time.ParseInLocation(TIME_LAYOUT_TIDB, lastModifiedTime, local)
+		f.Rowkey = []byte(f.BucketName + ObjectNameSeparator + f.Name)
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
+}
+
+// UpdateFreezerStatus sets status of the freezer to statusSetTo only if it is in status,
+// so one freezer is restored by one worker
+func (t *TidbClient) UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo Status) (updated bool, err error) {
+	sqltext := "update restoreobjects set status=? where bucketname=? and objectname=? and status=?;"
+	result, err := t.Client.Exec(sqltext, statusSetTo, bucketName, objectName, status)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// PutFreezer saves data of the restored copy into the freezer, and sets it to status
+func (t *TidbClient) PutFreezer(freezer *Freezer, status Status, tx DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	sqltext, args := freezer.GetUpdateSql(status)
+	_, err = tx.Exec(sqltext, args...)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from restoreobjectpart where bucketname=? and objectname=?;"
+	_, err = tx.Exec(sqltext, freezer.BucketName, freezer.Name)
+	if err != nil {
+		return err
+	}
+	for _, p := range freezer.Parts {
+		sqltext, args = p.GetCreateFreezerSql(freezer.BucketName, freezer.Name)
+		_, err = tx.Exec(sqltext, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //util function
 func getFreezerParts(bucketName, objectName string, cli *sql.DB) (parts map[int]*Part, err error) {
 	parts = make(map[int]*Part)
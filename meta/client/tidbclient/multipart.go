@@ -30,9 +30,9 @@ func (t *TidbClient) GetMultipart(bucketName, objectName, uploadId string) (mult
 	}
 	uploadTime = math.MaxUint64 - uploadTime
 	sqltext := "select bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest," +
-		"encryption,COALESCE(cipher,\"\"),attrs,storageclass from multiparts where bucketname=? and objectname=? and uploadtime=?;"
+		"encryption,COALESCE(cipher,\"\"),attrs,storageclass,COALESCE(tags,\"{}\"),COALESCE(objectlock,\"{}\") from multiparts where bucketname=? and objectname=? and uploadtime=?;"
 	var initialTime uint64
-	var acl, sseRequest, attrs string
+	var acl, sseRequest, attrs, tags, objectLock string
 	err = t.Client.QueryRow(sqltext, bucketName, objectName, uploadTime).Scan(
 		&multipart.BucketName,
 		&multipart.ObjectName,
@@ -48,6 +48,8 @@ func (t *TidbClient) GetMultipart(bucketName, objectName, uploadId string) (mult
 		&multipart.Metadata.CipherKey,
 		&attrs,
 		&multipart.Metadata.StorageClass,
+		&tags,
+		&objectLock,
 	)
 	if err != nil && err == sql.ErrNoRows {
 		err = ErrNoSuchUpload
@@ -71,6 +73,14 @@ func (t *TidbClient) GetMultipart(bucketName, objectName, uploadId string) (mult
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &multipart.Metadata.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &multipart.Metadata.ObjectLock)
+	if err != nil {
+		return
+	}
 
 	sqltext = "select partnumber,size,objectid,offset,etag,lastmodified,initializationvector from multipartpart where bucketname=? and objectname=? and uploadtime=?;"
 	rows, err := t.Client.Query(sqltext, bucketName, objectName, uploadTime)
@@ -108,9 +118,11 @@ func (t *TidbClient) CreateMultipart(multipart Multipart) (err error) {
 	acl, _ := json.Marshal(m.Acl)
 	sseRequest, _ := json.Marshal(m.SseRequest)
 	attrs, _ := json.Marshal(m.Attrs)
-	sqltext := "insert into multiparts(bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest,encryption,cipher,attrs,storageclass) " +
-		"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
-	_, err = t.Client.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime, m.InitiatorId, m.OwnerId, m.ContentType, m.Location, m.Pool, acl, sseRequest, m.EncryptionKey,m.CipherKey, attrs, m.StorageClass)
+	tags, _ := json.Marshal(m.Tags)
+	objectLock, _ := json.Marshal(m.ObjectLock)
+	sqltext := "insert into multiparts(bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest,encryption,cipher,attrs,storageclass,tags,objectlock) " +
+		"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+	_, err = t.Client.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime, m.InitiatorId, m.OwnerId, m.ContentType, m.Location, m.Pool, acl, sseRequest, m.EncryptionKey, m.CipherKey, attrs, m.StorageClass, tags, objectLock)
 	return
 }
 
@@ -163,10 +175,17 @@ func (t *TidbClient) ListMultipartUploads(bucketName, keyMarker, uploadIdMarker,
 	commonPrefixes := make(map[string]struct{})
 	var uploadNum uint64
 	if uploadIdMarker != "" {
-		uploadNum, err = strconv.ParseUint(uploadIdMarker, 10, 64)
-	}
-	if err != nil {
-		return
+		var timestampString string
+		var uploadTime uint64
+		timestampString, err = util.Decrypt(uploadIdMarker)
+		if err != nil {
+			return
+		}
+		uploadTime, err = strconv.ParseUint(timestampString, 10, 64)
+		if err != nil {
+			return
+		}
+		uploadNum = math.MaxUint64 - uploadTime
 	}
 	var objnum map[string]int = make(map[string]int)
 	var currentMarker string = keyMarker
@@ -214,7 +233,7 @@ func (t *TidbClient) ListMultipartUploads(bucketName, keyMarker, uploadIdMarker,
 			//filte by uploadtime and key
 			if first {
 				if uploadNum != 0 {
-					if name == currentMarker && uploadtime < uploadNum {
+					if name == keyMarker && uploadtime < uploadNum {
 						continue
 					}
 				}
@@ -14,12 +14,13 @@ import (
 )
 
 func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *Object, err error) {
-	var ibucketname, iname, customattributes, acl, lastModifiedTime string
+	var ibucketname, iname, customattributes, acl, tags, objectLock, sseContext, lastModifiedTime string
 	var iversion uint64
 
 	var row *sql.Row
 	sqltext := "select bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag,contenttype," +
-		"customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass from objects where bucketname=? and name=? "
+		"customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass,COALESCE(tags,\"{}\"),COALESCE(objectlock,\"{}\"),COALESCE(replicationstatus,\"\")," +
+		"COALESCE(ssekmskeyid,\"\"),COALESCE(ssecontext,\"{}\") from objects where bucketname=? and name=? "
 	if version == "" {
 		sqltext += "order by bucketname,name,version limit 1;"
 		row = t.Client.QueryRow(sqltext, bucketName, objectName)
@@ -49,6 +50,11 @@ func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *
 		&object.InitializationVector,
 		&object.Type,
 		&object.StorageClass,
+		&tags,
+		&objectLock,
+		&object.ReplicationStatus,
+		&object.SseKmsKeyId,
+		&sseContext,
 	)
 	if err == sql.ErrNoRows {
 		err = ErrNoSuchKey
@@ -70,6 +76,18 @@ func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &object.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &object.ObjectLock)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(sseContext), &object.SseContext)
+	if err != nil {
+		return
+	}
 	object.Parts, err = getParts(object.BucketName, object.Name, iversion, t.Client)
 	//build simple index for multipart
 	if len(object.Parts) != 0 {
@@ -79,8 +97,7 @@ func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *
 		}
 		object.PartsIndex = &SimpleIndex{Index: sortedPartNum}
 	}
-	var reversedTime uint64
-	timestamp := math.MaxUint64 - reversedTime
+	timestamp := math.MaxUint64 - iversion
 	timeData := []byte(strconv.FormatUint(timestamp, 10))
 	object.VersionId = hex.EncodeToString(xxtea.Encrypt(timeData, XXTEA_KEY))
 	return
@@ -119,6 +136,18 @@ func (t *TidbClient) UpdateObjectAttrs(object *Object) error {
 	return err
 }
 
+func (t *TidbClient) UpdateObjectTags(object *Object) error {
+	sql, args := object.GetUpdateTagsSql()
+	_, err := t.Client.Exec(sql, args...)
+	return err
+}
+
+func (t *TidbClient) UpdateObjectLock(object *Object) error {
+	sql, args := object.GetUpdateObjectLockSql()
+	_, err := t.Client.Exec(sql, args...)
+	return err
+}
+
 func (t *TidbClient) UpdateObjectAcl(object *Object) error {
 	sql, args := object.GetUpdateAclSql()
 	_, err := t.Client.Exec(sql, args...)
@@ -209,6 +238,9 @@ func (t *TidbClient) UpdateObject(object *Object, tx DB) (err error) {
 
 	sql, args := object.GetUpdateSql()
 	_, err = tx.Exec(sql, args...)
+	if err != nil {
+		return err
+	}
 	if object.Parts != nil {
 		for _, p := range object.Parts {
 			psql, args := p.GetCreateSql(object.BucketName, object.Name, version)
@@ -221,6 +253,49 @@ func (t *TidbClient) UpdateObject(object *Object, tx DB) (err error) {
 	return nil
 }
 
+func (t *TidbClient) TransitObject(object, sourceObject *Object, tx DB) (transited bool, err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return false, err
+		}
+		defer func() {
+			if err == nil && transited {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil || !transited {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+
+	sql, args := object.GetTransitSql(sourceObject)
+	result, err := tx.Exec(sql, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n != 1 {
+		return false, err
+	}
+
+	v := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+	version := strconv.FormatUint(v, 10)
+	sqltext := "delete from objectpart where objectname=? and bucketname=? and version=?;"
+	_, err = tx.Exec(sqltext, object.Name, object.BucketName, version)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range object.Parts {
+		psql, args := p.GetCreateSql(object.BucketName, object.Name, version)
+		_, err = tx.Exec(psql, args...)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 func (t *TidbClient) DeleteObject(object *Object, tx DB) (err error) {
 	if tx == nil {
 		tx, err = t.Client.Begin()
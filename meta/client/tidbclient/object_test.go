@@ -0,0 +1,74 @@
+package tidbclient_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var objectColumns = []string{
+	"bucketname", "name", "version", "location", "pool", "ownerid", "size", "objectid",
+	"lastmodifiedtime", "etag", "contenttype", "customattributes", "acl", "nullversion",
+	"deletemarker", "ssetype", "encryptionkey", "initializationvector", "type", "storageclass",
+	"tags", "objectlock", "replicationstatus", "ssekmskeyid", "ssecontext",
+}
+
+// versionConverter passes versions as uint64 like the mysql driver does,
+// which database/sql rejects if the high bit is set
+type versionConverter struct{}
+
+func (versionConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if u, ok := v.(uint64); ok {
+		return strconv.FormatUint(u, 10), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func newVersionClient() (client *tidbclient.TidbClient, mock sqlmock.Sqlmock, err error) {
+	var db *sql.DB
+	db, mock, err = sqlmock.New(sqlmock.ValueConverterOption(versionConverter{}))
+	if err != nil {
+		return
+	}
+	client = &tidbclient.TidbClient{Client: db}
+	return
+}
+
+func TestTidbClient_GetObjectVersionId(t *testing.T) {
+	client, mock, err := newVersionClient()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	defer client.Client.Close()
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	version := math.MaxUint64 - uint64(modified.UnixNano())
+	values := []driver.Value{
+		"bucket", "a", strconv.FormatUint(version, 10), "cluster", "pool", "owner", 4, "oid",
+		modified.Format(types.TIME_LAYOUT_TIDB), "etag", "text/plain", "{}", "{}", false,
+		false, "", []byte{}, []byte{}, 0, 0,
+		"{}", "{}", "", "", "{}",
+	}
+	mock.ExpectQuery("select (.+) from objects where bucketname=(.+) and name=(.+) and version=(.+)").
+		WithArgs("bucket", "a", strconv.FormatUint(version, 10)).
+		WillReturnRows(sqlmock.NewRows(objectColumns).AddRow(values...))
+	mock.ExpectQuery("select (.+) from objectpart where (.+)").
+		WithArgs("bucket", "a", strconv.FormatUint(version, 10)).
+		WillReturnRows(sqlmock.NewRows([]string{"partnumber", "size", "objectid", "offset", "etag",
+			"lastmodified", "initializationvector"}))
+
+	object, err := client.GetObject("bucket", "a", strconv.FormatUint(version, 10))
+	assert.Nil(t, err)
+	// the version ID is of the version read, the same as ID of the object written
+	expected := &types.Object{LastModifiedTime: modified}
+	assert.Equal(t, expected.GetVersionId(), object.VersionId)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,92 @@
+package tidbclient
+
+import (
+	"math"
+	"time"
+
+	. "github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *TidbClient) PutObjectToReplication(object *Object, tx DB) (err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	version := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+	mtime := time.Now().UTC().Format(TIME_LAYOUT_TIDB)
+	sqltext := "insert ignore into replication(bucketname,objectname,version,mtime,triedtimes) values(?,?,?,?,?);"
+	_, err = tx.Exec(sqltext, object.BucketName, object.Name, version, mtime, 0)
+	return err
+}
+
+// ScanReplication returns entries after startRowKey, in order of bucket, object and version
+func (t *TidbClient) ScanReplication(limit int, startRowKey string) (replications []Replication, err error) {
+	sqltext := "select bucketname,objectname,version,mtime,triedtimes from replication "
+	args := []interface{}{}
+	if startRowKey != "" {
+		bucketName, objectName, version, ok := ParseReplicationRowkey(startRowKey)
+		if !ok {
+			return nil, nil
+		}
+		sqltext += "where bucketname>? or (bucketname=? and objectname>?) or (bucketname=? and objectname=? and version>?) "
+		args = append(args, bucketName, bucketName, objectName, bucketName, objectName, version)
+	}
+	sqltext += "order by bucketname,objectname,version limit ?;"
+	args = append(args, limit)
+	rows, err := t.Client.Query(sqltext, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Replication
+		var mtime string
+		err = rows.Scan(
+			&r.BucketName,
+			&r.ObjectName,
+			&r.Version,
+			&mtime,
+			&r.TriedTimes,
+		)
+		if err != nil {
+			return
+		}
+		r.MTime, err = time.Parse(TIME_LAYOUT_TIDB, mtime)
+		if err != nil {
+			return
+		}
+		replications = append(replications, r)
+	}
+	return replications, rows.Err()
+}
+
+func (t *TidbClient) UpdateReplicationTriedTimes(replication Replication) error {
+	mtime := time.Now().UTC().Format(TIME_LAYOUT_TIDB)
+	sqltext := "update replication set triedtimes=?,mtime=? where bucketname=? and objectname=? and version=?;"
+	_, err := t.Client.Exec(sqltext, replication.TriedTimes, mtime,
+		replication.BucketName, replication.ObjectName, replication.Version)
+	return err
+}
+
+// RemoveReplication removes the entry and records the final replication status of object version
+func (t *TidbClient) RemoveReplication(replication Replication, status string) (err error) {
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	sqltext := "update objects set replicationstatus=? where bucketname=? and name=? and version=?;"
+	_, err = tx.Exec(sqltext, status, replication.BucketName, replication.ObjectName, replication.Version)
+	if err != nil {
+		return err
+	}
+	sqltext = "delete from replication where bucketname=? and objectname=? and version=?;"
+	_, err = tx.Exec(sqltext, replication.BucketName, replication.ObjectName, replication.Version)
+	return err
+}
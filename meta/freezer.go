@@ -48,3 +48,15 @@ func (m *Meta) DeleteFreezer(freezer *types.Freezer) (err error) {
 
 	return err
 }
+
+func (m *Meta) ScanFreezers(limit int, startRowKey string, status types.Status) ([]types.Freezer, error) {
+	return m.Client.ScanFreezers(limit, startRowKey, status)
+}
+
+func (m *Meta) UpdateFreezerStatus(bucketName, objectName string, status, statusSetTo types.Status) (bool, error) {
+	return m.Client.UpdateFreezerStatus(bucketName, objectName, status, statusSetTo)
+}
+
+func (m *Meta) PutFreezer(freezer *types.Freezer, status types.Status) error {
+	return m.Client.PutFreezer(freezer, status, nil)
+}
@@ -1,9 +1,16 @@
 package meta
 
 import (
+	"database/sql"
+
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/meta/client"
+	"github.com/journeymidnight/yig/meta/client/embeddedclient"
+	"github.com/journeymidnight/yig/meta/client/memoryclient"
+	"github.com/journeymidnight/yig/meta/client/mysqlclient"
+	"github.com/journeymidnight/yig/meta/client/postgresclient"
 	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	"github.com/journeymidnight/yig/meta/migrate"
 )
 
 const (
@@ -19,10 +26,34 @@ func New(myCacheType CacheType) *Meta {
 	meta := Meta{
 		Cache:  newMetaCache(myCacheType),
 	}
-	if helper.CONFIG.MetaStore == "tidb" {
-		meta.Client = tidbclient.NewTidbClient()
-	} else {
+	switch helper.CONFIG.MetaStore {
+	case "tidb":
+		c := tidbclient.NewTidbClient()
+		checkSchema(c.Client)
+		meta.Client = c
+	case "mysql":
+		c := mysqlclient.NewMysqlClient()
+		checkSchema(c.Client)
+		meta.Client = c
+	case "postgres":
+		c := postgresclient.NewPostgresClient()
+		checkSchema(c.Client)
+		meta.Client = c
+	case "embedded":
+		meta.Client = embeddedclient.NewEmbeddedClient()
+	case "memory":
+		// for unit tests only, metadata is lost on exit
+		meta.Client = memoryclient.NewMemoryClient()
+	default:
 		panic("unsupport metastore")
 	}
 	return &meta
-}
\ No newline at end of file
+}
+// checkSchema refuses to start if tables in db are older than yig expects
+func checkSchema(db *sql.DB) {
+	err := migrate.New(db, helper.CONFIG.MetaStore).Check()
+	if err != nil {
+		helper.Logger.Error(err)
+		panic(err.Error())
+	}
+}
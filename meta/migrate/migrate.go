@@ -0,0 +1,247 @@
+// Package migrate upgrades and downgrades the schema of SQL meta stores.
+//
+// Migrations are compiled into yig and numbered from 1, the version of a schema
+// is the largest number recorded in table schema_version, 0 if there is none.
+// yig refuses to start on a schema older than Latest, `yig_migrate up` brings
+// it up to date.
+//
+// DDL is not transactional in TiDB and MySQL, a migration could stop halfway,
+// so every migration checks what is there before changing it, and could be run again.
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+)
+
+// meta stores with a schema, named as meta_store in config
+const (
+	TIDB     = "tidb"
+	MYSQL    = "mysql"
+	POSTGRES = "postgres"
+)
+
+type migration struct {
+	version     int
+	description string
+	up          func(m *Migrator) error
+	down        func(m *Migrator) error
+}
+
+type Migrator struct {
+	db    *sql.DB
+	store string
+}
+
+func New(db *sql.DB, store string) *Migrator {
+	return &Migrator{db: db, store: store}
+}
+
+// Latest is the schema version this build of yig works with
+func Latest() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Version returns the version of schema in database
+func (m *Migrator) Version() (version int, err error) {
+	exists, err := m.tableExists("schema_version")
+	if err != nil || !exists {
+		return
+	}
+	var v sql.NullInt64
+	err = m.db.QueryRow("select max(version) from schema_version").Scan(&v)
+	return int(v.Int64), err
+}
+
+// Check returns an error if the schema is older than Latest
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("failed to get schema version of %s meta store: %v", m.store, err)
+	}
+	if version < Latest() {
+		return fmt.Errorf("schema version of %s meta store is %d, but yig requires %d, "+
+			"run `yig_migrate up` to upgrade", m.store, version, Latest())
+	}
+	if version > Latest() {
+		helper.Logger.Warn("Schema version of", m.store, "meta store is", version,
+			"newer than", Latest(), "of this yig")
+	}
+	return nil
+}
+
+// Up applies migrations after the current version up to target
+func (m *Migrator) Up(target int) error {
+	if target > Latest() {
+		return fmt.Errorf("no schema version %d, the latest is %d", target, Latest())
+	}
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	err = m.createVersionTable()
+	if err != nil {
+		return err
+	}
+	for _, mi := range migrations {
+		if mi.version <= version || mi.version > target {
+			continue
+		}
+		helper.Logger.Info("Migrating schema up to", mi.version, mi.description)
+		err = mi.up(m)
+		if err != nil {
+			return fmt.Errorf("migrate up to %d: %v", mi.version, err)
+		}
+		err = m.exec("insert into schema_version(version,description,appliedtime) values(?,?,?)",
+			mi.version, mi.description, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down reverts migrations from the current version down to target
+func (m *Migrator) Down(target int) error {
+	if target < 0 {
+		return fmt.Errorf("invalid schema version %d", target)
+	}
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mi := migrations[i]
+		if mi.version > version || mi.version <= target {
+			continue
+		}
+		helper.Logger.Info("Migrating schema down from", mi.version, mi.description)
+		err = mi.down(m)
+		if err != nil {
+			return fmt.Errorf("migrate down from %d: %v", mi.version, err)
+		}
+		if target == 0 && mi.version == 1 {
+			// schema_version is dropped with all tables
+			return m.dropTable("schema_version")
+		}
+		err = m.exec("delete from schema_version where version=?", mi.version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createVersionTable() error {
+	if m.store == POSTGRES {
+		return m.exec("CREATE TABLE IF NOT EXISTS schema_version (" +
+			"version integer NOT NULL, description varchar(255) DEFAULT NULL, " +
+			"appliedtime timestamp DEFAULT NULL, PRIMARY KEY (version))")
+	}
+	return m.exec(m.ddl("CREATE TABLE IF NOT EXISTS `schema_version` (" +
+		"`version` int(11) NOT NULL, `description` varchar(255) DEFAULT NULL, " +
+		"`appliedtime` datetime DEFAULT NULL, PRIMARY KEY (`version`)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin"))
+}
+
+// exec runs query with `?` as placeholders
+func (m *Migrator) exec(query string, args ...interface{}) error {
+	_, err := m.db.Exec(m.rebind(query), args...)
+	return err
+}
+
+func (m *Migrator) rebind(query string) string {
+	return Rebind(m.store, query)
+}
+
+// Rebind replaces `?` in query with `$n` if store is PostgreSQL
+func Rebind(store, query string) string {
+	if store != POSTGRES {
+		return query
+	}
+	parts := strings.Split(query, "?")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = "$" + strconv.Itoa(i) + parts[i]
+	}
+	return strings.Join(parts, "")
+}
+
+// mysqlDDL converts DDL of TiDB for MySQL 8, as integrate/yig_mysql.sql differs from integrate/yig.sql
+var mysqlDDL = strings.NewReplacer(
+	"JSON NOT NULL DEFAULT ''", "text DEFAULT NULL",
+	"JSON", "text",
+	"CHARSET=utf8 COLLATE=utf8_bin", "CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+)
+
+// ddl converts DDL written for TiDB to the store
+func (m *Migrator) ddl(query string) string {
+	if m.store == MYSQL {
+		return mysqlDDL.Replace(query)
+	}
+	return query
+}
+
+func (m *Migrator) schema() string {
+	if m.store == POSTGRES {
+		return "current_schema()"
+	}
+	return "database()"
+}
+
+func (m *Migrator) tableExists(table string) (bool, error) {
+	var n int
+	err := m.db.QueryRow(m.rebind("select count(*) from information_schema.tables "+
+		"where table_schema="+m.schema()+" and table_name=?"), table).Scan(&n)
+	return n > 0, err
+}
+
+func (m *Migrator) columnExists(table, column string) (bool, error) {
+	var n int
+	err := m.db.QueryRow(m.rebind("select count(*) from information_schema.columns "+
+		"where table_schema="+m.schema()+" and table_name=? and column_name=?"), table, column).Scan(&n)
+	return n > 0, err
+}
+
+// createTable creates table with DDL of TiDB or PostgreSQL, indexes of PostgreSQL follow the table
+func (m *Migrator) createTable(t table) error {
+	exists, err := m.tableExists(t.name)
+	if err != nil || exists {
+		return err
+	}
+	if m.store != POSTGRES {
+		return m.exec(m.ddl(t.tidb))
+	}
+	for _, query := range t.postgres {
+		err = m.exec(query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) dropTable(table string) error {
+	return m.exec("DROP TABLE IF EXISTS " + table)
+}
+
+// addColumn adds column of definition, in TiDB, to table if it's not there
+func (m *Migrator) addColumn(table, column, definition string) error {
+	exists, err := m.columnExists(table, column)
+	if err != nil || exists {
+		return err
+	}
+	return m.exec(m.ddl("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition))
+}
+
+func (m *Migrator) dropColumn(table, column string) error {
+	exists, err := m.columnExists(table, column)
+	if err != nil || !exists {
+		return err
+	}
+	return m.exec("ALTER TABLE " + table + " DROP COLUMN " + column)
+}
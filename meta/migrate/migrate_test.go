@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+func newMigrator(t *testing.T, store string) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	return New(db, store), mock
+}
+
+func expectVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectQuery("select count\\(\\*\\) from information_schema.tables").
+		WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("select max\\(version\\) from schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(version))
+}
+
+func TestMigrator_Check(t *testing.T) {
+	m, mock := newMigrator(t, TIDB)
+	defer m.db.Close()
+
+	// created before schema_version
+	mock.ExpectQuery("select count\\(\\*\\) from information_schema.tables where table_schema=database\\(\\)").
+		WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	err := m.Check()
+	if err == nil || !strings.Contains(err.Error(), "yig_migrate up") {
+		t.Fatal("Check returns", err)
+	}
+
+	expectVersion(mock, Latest()-1)
+	if err = m.Check(); err == nil {
+		t.Fatal("Check older schema, expected error")
+	}
+	expectVersion(mock, Latest())
+	if err = m.Check(); err != nil {
+		t.Fatal("Check error:", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("Unfulfilled expectations:", err)
+	}
+}
+
+func TestMigrator_Up(t *testing.T) {
+	m, mock := newMigrator(t, POSTGRES)
+	defer m.db.Close()
+
+	expectVersion(mock, 1)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for i, c := range columnsV2 {
+		mock.ExpectQuery("select count\\(\\*\\) from information_schema.columns where table_schema=current_schema\\(\\)").
+			WithArgs(c.table, c.name).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(i % 2))
+		if i%2 == 0 {
+			// added only if not there
+			mock.ExpectExec("ALTER TABLE " + c.table + " ADD COLUMN " + c.name).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+	}
+	mock.ExpectQuery("select count\\(\\*\\) from information_schema.tables").
+		WithArgs("replication").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("CREATE TABLE replication").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("insert into schema_version\\(version,description,appliedtime\\) values\\(\\$1,\\$2,\\$3\\)").
+		WithArgs(2, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, c := range columnsV3 {
+		mock.ExpectQuery("select count\\(\\*\\) from information_schema.columns").
+			WithArgs(c.table, c.name).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("ALTER TABLE " + c.table + " ADD COLUMN " + c.name).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec("insert into schema_version\\(version,description,appliedtime\\) values\\(\\$1,\\$2,\\$3\\)").
+		WithArgs(3, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := m.Up(Latest()); err != nil {
+		t.Fatal("Up error:", err)
+	}
+	if err := m.Up(Latest() + 1); err == nil {
+		t.Fatal("Up to unknown version, expected error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("Unfulfilled expectations:", err)
+	}
+}
+
+func TestMigrator_ddl(t *testing.T) {
+	m := New(nil, MYSQL)
+	for _, t1 := range tables {
+		query := m.ddl(t1.tidb)
+		if strings.Contains(query, "JSON") || !strings.Contains(query, "utf8mb4_bin") {
+			t.Fatal("DDL of", t1.name, "for MySQL:", query)
+		}
+	}
+}
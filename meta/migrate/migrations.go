@@ -0,0 +1,414 @@
+package migrate
+
+// migrations in order of version, append new ones to the end and never change applied ones
+var migrations = []migration{
+	{
+		version:     1,
+		description: "tables of yig",
+		up: func(m *Migrator) error {
+			for _, t := range tables {
+				err := m.createTable(t)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(m *Migrator) error {
+			for _, t := range tables {
+				err := m.dropTable(t.name)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version:     2,
+		description: "tagging, object lock, notification and replication",
+		up: func(m *Migrator) error {
+			for _, c := range columnsV2 {
+				err := m.addColumn(c.table, c.name, c.definition)
+				if err != nil {
+					return err
+				}
+			}
+			return m.createTable(replicationTable)
+		},
+		down: func(m *Migrator) error {
+			for _, c := range columnsV2 {
+				err := m.dropColumn(c.table, c.name)
+				if err != nil {
+					return err
+				}
+			}
+			return m.dropTable(replicationTable.name)
+		},
+	},
+	{
+		version:     3,
+		description: "sse-kms key id and encryption context",
+		up: func(m *Migrator) error {
+			for _, c := range columnsV3 {
+				err := m.addColumn(c.table, c.name, c.definition)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		down: func(m *Migrator) error {
+			for _, c := range columnsV3 {
+				err := m.dropColumn(c.table, c.name)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// table is created with DDL tidb, converted for MySQL, or statements in postgres
+type table struct {
+	name     string
+	tidb     string
+	postgres []string
+}
+
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+var columnsV2 = []column{
+	{"objects", "tags", "JSON DEFAULT NULL"},
+	{"multiparts", "tags", "JSON DEFAULT NULL"},
+	{"buckets", "tags", "JSON DEFAULT NULL"},
+	{"buckets", "objectlock", "JSON DEFAULT NULL"},
+	{"objects", "objectlock", "JSON DEFAULT NULL"},
+	{"multiparts", "objectlock", "JSON DEFAULT NULL"},
+	{"buckets", "notification", "JSON DEFAULT NULL"},
+	{"buckets", "replication", "JSON DEFAULT NULL"},
+	{"objects", "replicationstatus", "varchar(255) DEFAULT NULL"},
+}
+
+var columnsV3 = []column{
+	{"objects", "ssekmskeyid", "varchar(255) DEFAULT NULL"},
+	{"objects", "ssecontext", "JSON DEFAULT NULL"},
+}
+
+var replicationTable = table{
+	name: "replication",
+	tidb: "CREATE TABLE `replication` (" + `
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  version bigint(20) UNSIGNED DEFAULT NULL,
+  mtime datetime DEFAULT NULL,
+  triedtimes int(11) DEFAULT NULL,
+  UNIQUE KEY rowkey (bucketname,objectname,version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+	postgres: []string{`CREATE TABLE replication (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  mtime timestamp DEFAULT NULL,
+  triedtimes integer DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+)`},
+}
+
+// tables of version 1, as integrate/yig.sql was before schema_version
+var tables = []table{
+	{
+		name: "buckets",
+		tidb: `CREATE TABLE buckets (
+  bucketname varchar(255) NOT NULL DEFAULT '',
+  acl JSON DEFAULT NULL,
+  cors JSON DEFAULT NULL,
+  logging JSON NOT NULL DEFAULT '',
+  lc JSON DEFAULT NULL,
+  uid varchar(255) DEFAULT NULL,
+  policy JSON DEFAULT NULL,
+  website JSON DEFAULT NULL,
+  encryption JSON DEFAULT NULL,
+  createtime datetime DEFAULT NULL,
+  usages bigint(20) DEFAULT NULL,
+  versioning varchar(255) DEFAULT NULL,
+  PRIMARY KEY (bucketname)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE buckets (
+  bucketname varchar(255) COLLATE "C" NOT NULL DEFAULT '',
+  acl json DEFAULT NULL,
+  cors json DEFAULT NULL,
+  logging json DEFAULT NULL,
+  lc json DEFAULT NULL,
+  uid varchar(255) DEFAULT NULL,
+  policy json DEFAULT NULL,
+  website json DEFAULT NULL,
+  encryption json DEFAULT NULL,
+  createtime timestamp DEFAULT NULL,
+  usages bigint DEFAULT NULL,
+  versioning varchar(255) DEFAULT NULL,
+  PRIMARY KEY (bucketname)
+)`},
+	},
+	{
+		name: "cluster",
+		tidb: `CREATE TABLE cluster (
+  fsid varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  weight int(11) DEFAULT NULL,
+  UNIQUE KEY rowkey (fsid,pool)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE cluster (
+  fsid varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  weight integer DEFAULT NULL,
+  UNIQUE (fsid, pool)
+)`},
+	},
+	{
+		name: "gc",
+		tidb: `CREATE TABLE gc (
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  version bigint(20) UNSIGNED DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  status varchar(255) DEFAULT NULL,
+  mtime datetime DEFAULT NULL,
+  part tinyint(1) DEFAULT NULL,
+  triedtimes int(11) DEFAULT NULL,
+  UNIQUE KEY rowkey (bucketname,objectname,version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE gc (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  status varchar(255) DEFAULT NULL,
+  mtime timestamp DEFAULT NULL,
+  part boolean DEFAULT NULL,
+  triedtimes integer DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+)`},
+	},
+	partTable("gcpart", "version"),
+	partTable("multipartpart", "uploadtime"),
+	{
+		name: "multiparts",
+		tidb: `CREATE TABLE multiparts (
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  uploadtime bigint(20) UNSIGNED DEFAULT NULL,
+  initiatorid varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  acl JSON DEFAULT NULL,
+  sserequest JSON DEFAULT NULL,
+  encryption blob DEFAULT NULL,
+  cipher blob DEFAULT NULL,
+  attrs JSON DEFAULT NULL,
+  storageclass tinyint(1) DEFAULT 0,
+  UNIQUE KEY rowkey (bucketname,objectname,uploadtime)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE multiparts (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  uploadtime numeric(20) DEFAULT NULL,
+  initiatorid varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  acl json DEFAULT NULL,
+  sserequest json DEFAULT NULL,
+  encryption bytea DEFAULT NULL,
+  cipher bytea DEFAULT NULL,
+  attrs json DEFAULT NULL,
+  storageclass smallint DEFAULT 0,
+  UNIQUE (bucketname, objectname, uploadtime)
+)`},
+	},
+	// version of objectpart is a varchar in TiDB
+	{
+		name: "objectpart",
+		tidb: `CREATE TABLE objectpart (
+  partnumber int(11) DEFAULT NULL,
+  size bigint(20) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  ` + "`offset`" + ` bigint(20) DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified datetime DEFAULT NULL,
+  initializationvector blob DEFAULT NULL,
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  version varchar(255) DEFAULT NULL,
+  KEY rowkey (bucketname,objectname,version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: partTable("objectpart", "version").postgres,
+	},
+	{
+		name: "objects",
+		tidb: `CREATE TABLE objects (
+  bucketname varchar(255) DEFAULT NULL,
+  name varchar(255) DEFAULT NULL,
+  version bigint(20) UNSIGNED DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint(20) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  lastmodifiedtime datetime DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  customattributes JSON DEFAULT NULL,
+  acl JSON DEFAULT NULL,
+  nullversion tinyint(1) DEFAULT NULL,
+  deletemarker tinyint(1) DEFAULT NULL,
+  ssetype varchar(255) DEFAULT NULL,
+  encryptionkey blob DEFAULT NULL,
+  initializationvector blob DEFAULT NULL,
+  type tinyint(1) DEFAULT 0,
+  storageclass tinyint(1) DEFAULT 0,
+  UNIQUE KEY rowkey (bucketname,name,version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE objects (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  name varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  lastmodifiedtime timestamp DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  contenttype varchar(255) DEFAULT NULL,
+  customattributes json DEFAULT NULL,
+  acl json DEFAULT NULL,
+  nullversion boolean DEFAULT NULL,
+  deletemarker boolean DEFAULT NULL,
+  ssetype varchar(255) DEFAULT NULL,
+  encryptionkey bytea DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  type smallint DEFAULT 0,
+  storageclass smallint DEFAULT 0,
+  UNIQUE (bucketname, name, version)
+)`},
+	},
+	partTable("restoreobjectpart", "version"),
+	{
+		name: "restoreobjects",
+		tidb: `CREATE TABLE restoreobjects (
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  version bigint(20) UNSIGNED DEFAULT NULL,
+  status tinyint(1) DEFAULT '0',
+  lifetime tinyint(2) DEFAULT '1',
+  lastmodifiedtime datetime DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint(20) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  UNIQUE KEY rowkey (bucketname,objectname,version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE restoreobjects (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  version numeric(20) DEFAULT NULL,
+  status smallint DEFAULT 0,
+  lifetime smallint DEFAULT 1,
+  lastmodifiedtime timestamp DEFAULT NULL,
+  location varchar(255) DEFAULT NULL,
+  pool varchar(255) DEFAULT NULL,
+  ownerid varchar(255) DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  UNIQUE (bucketname, objectname, version)
+)`},
+	},
+	{
+		name: "objmap",
+		tidb: `CREATE TABLE objmap (
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  nullvernum bigint(20) DEFAULT NULL,
+  UNIQUE KEY objmap (bucketname,objectname)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE objmap (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  nullvernum numeric(20) DEFAULT NULL,
+  UNIQUE (bucketname, objectname)
+)`},
+	},
+	{
+		name: "users",
+		tidb: `CREATE TABLE users (
+  userid varchar(255) DEFAULT NULL,
+  bucketname varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE users (
+  userid varchar(255) DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL
+)`},
+	},
+	{
+		name: "lifecycle",
+		tidb: `CREATE TABLE lifecycle (
+  bucketname varchar(255) DEFAULT NULL,
+  status varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{`CREATE TABLE lifecycle (
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  status varchar(255) DEFAULT NULL
+)`},
+	},
+}
+
+// partTable is a table of parts, keyed by bucketname, objectname and key
+func partTable(name, key string) table {
+	return table{
+		name: name,
+		tidb: "CREATE TABLE " + name + ` (
+  partnumber int(11) DEFAULT NULL,
+  size bigint(20) DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  ` + "`offset`" + ` bigint(20) DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified datetime DEFAULT NULL,
+  initializationvector blob DEFAULT NULL,
+  bucketname varchar(255) DEFAULT NULL,
+  objectname varchar(255) DEFAULT NULL,
+  ` + key + ` bigint(20) UNSIGNED DEFAULT NULL,
+  KEY rowkey (bucketname,objectname,` + key + `)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin`,
+		postgres: []string{
+			"CREATE TABLE " + name + ` (
+  partnumber integer DEFAULT NULL,
+  size bigint DEFAULT NULL,
+  objectid varchar(255) DEFAULT NULL,
+  "offset" bigint DEFAULT NULL,
+  etag varchar(255) DEFAULT NULL,
+  lastmodified varchar(255) DEFAULT NULL,
+  initializationvector bytea DEFAULT NULL,
+  bucketname varchar(255) COLLATE "C" DEFAULT NULL,
+  objectname varchar(255) COLLATE "C" DEFAULT NULL,
+  ` + key + ` numeric(20) DEFAULT NULL
+)`,
+			"CREATE INDEX " + name + "_rowkey ON " + name + " (bucketname, objectname, " + key + ")",
+		},
+	}
+}
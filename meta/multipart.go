@@ -23,6 +23,21 @@ func (m *Meta) DeleteMultipart(multipart Multipart) (err error) {
 	if err != nil {
 		return
 	}
+	// put uploaded parts into gc
+	if len(multipart.Parts) != 0 {
+		object := &Object{
+			BucketName:       multipart.BucketName,
+			Name:             multipart.ObjectName,
+			Location:         multipart.Metadata.Location,
+			Pool:             multipart.Metadata.Pool,
+			LastModifiedTime: multipart.InitialTime,
+			Parts:            multipart.Parts,
+		}
+		err = m.Client.PutObjectToGarbageCollection(object, tx)
+		if err != nil {
+			return
+		}
+	}
 	var removedSize int64 = 0
 	for _, p := range multipart.Parts {
 		removedSize += p.Size
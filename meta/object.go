@@ -2,6 +2,7 @@ package meta
 
 import (
 	"database/sql"
+	"github.com/journeymidnight/yig/api/datatype"
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	. "github.com/journeymidnight/yig/meta/types"
@@ -47,7 +48,10 @@ func (m *Meta) GetAllObject(bucketName string, objectName string) (object []*Obj
 }
 
 func (m *Meta) GetObjectMap(bucketName, objectName string) (objMap *ObjMap, err error) {
-	m.Client.GetObjectMap(bucketName, objectName)
+	objMap, err = m.Client.GetObjectMap(bucketName, objectName)
+	if err == sql.ErrNoRows {
+		err = ErrNoSuchKey
+	}
 	return
 }
 
@@ -97,6 +101,13 @@ func (m *Meta) PutObject(object *Object, multipart *Multipart, objMap *ObjMap, u
 		return err
 	}
 
+	if object.ReplicationStatus == datatype.ReplicationStatusPending {
+		err = m.Client.PutObjectToReplication(object, tx)
+		if err != nil {
+			return err
+		}
+	}
+
 	if objMap != nil {
 		err = m.Client.PutObjectMap(objMap, tx)
 		if err != nil {
@@ -130,6 +141,16 @@ func (m *Meta) UpdateObjectAcl(object *Object) error {
 	return err
 }
 
+func (m *Meta) UpdateObjectTags(object *Object) error {
+	err := m.Client.UpdateObjectTags(object)
+	return err
+}
+
+func (m *Meta) UpdateObjectLock(object *Object) error {
+	err := m.Client.UpdateObjectLock(object)
+	return err
+}
+
 func (m *Meta) UpdateObjectAttrs(object *Object) error {
 	err := m.Client.UpdateObjectAttrs(object)
 	return err
@@ -229,6 +250,35 @@ func (m *Meta) UpdateGlacierObject(targetObject, sourceObject *Object, isFreezer
 	return err
 }
 
+// Replace the data location of sourceObject with targetObject's, and put the
+// old data into gc, in one transaction
+// TransitObject moves sourceObject to the location of targetObject, and puts the old data
+// into gc in the same transaction. Nothing is changed and transited is false if the object
+// is not located as sourceObject any more, e.g. appended or transited by others.
+func (m *Meta) TransitObject(targetObject, sourceObject *Object) (transited bool, err error) {
+	var tx *sql.Tx
+	tx, err = m.Client.NewTrans()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil && transited {
+			err = m.Client.CommitTrans(tx)
+		}
+		if err != nil || !transited {
+			m.Client.AbortTrans(tx)
+		}
+	}()
+
+	transited, err = m.Client.TransitObject(targetObject, sourceObject, tx)
+	if err != nil || !transited {
+		return
+	}
+
+	err = m.Client.PutObjectToGarbageCollection(sourceObject, tx)
+	return
+}
+
 func (m *Meta) AppendObject(object *Object, isExist bool) error {
 	tx, err := m.Client.NewTrans()
 	if err != nil {
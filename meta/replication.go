@@ -0,0 +1,15 @@
+package meta
+
+import . "github.com/journeymidnight/yig/meta/types"
+
+func (m *Meta) ScanReplication(limit int, startRowKey string) ([]Replication, error) {
+	return m.Client.ScanReplication(limit, startRowKey)
+}
+
+func (m *Meta) UpdateReplicationTriedTimes(replication Replication) error {
+	return m.Client.UpdateReplicationTriedTimes(replication)
+}
+
+func (m *Meta) RemoveReplication(replication Replication, status string) error {
+	return m.Client.RemoveReplication(replication, status)
+}
@@ -30,6 +30,10 @@ type Bucket struct {
 	Encryption datatype.EncryptionConfiguration
 	Versioning string // actually enum: Disabled/Enabled/Suspended
 	Usage      int64
+	Tags       map[string]string
+	ObjectLock datatype.ObjectLockConfiguration
+	Notification datatype.NotificationConfiguration
+	Replication datatype.ReplicationConfiguration
 }
 
 func (b *Bucket) String() (s string) {
@@ -45,6 +49,10 @@ func (b *Bucket) String() (s string) {
 	s += "Encryption" + fmt.Sprintf("%+v", b.Encryption) + "\t"
 	s += "Version: " + b.Versioning + "\t"
 	s += "Usage: " + humanize.Bytes(uint64(b.Usage)) + "\t"
+	s += "Tags: " + fmt.Sprintf("%+v", b.Tags) + "\t"
+	s += "ObjectLock: " + fmt.Sprintf("%+v", b.ObjectLock) + "\t"
+	s += "Notification: " + fmt.Sprintf("%+v", b.Notification) + "\t"
+	s += "Replication: " + fmt.Sprintf("%+v", b.Replication) + "\t"
 	return
 }
 
@@ -57,8 +65,12 @@ func (b Bucket) GetUpdateSql() (string, []interface{}) {
 	bucket_policy, _ := json.Marshal(b.Policy)
 	website, _ := json.Marshal(b.Website)
 	encryption,_ := json.Marshal(b.Encryption)
-	sql := "update buckets set bucketname=?,acl=?,policy=?,cors=?,logging=?,lc=?,website=?,encryption=?,uid=?,versioning=? where bucketname=?"
-	args := []interface{}{b.Name, acl, bucket_policy, cors, logging, lc, website, encryption, b.OwnerId, b.Versioning, b.Name}
+	tags, _ := json.Marshal(b.Tags)
+	objectLock, _ := json.Marshal(b.ObjectLock)
+	notification, _ := json.Marshal(b.Notification)
+	replication, _ := json.Marshal(b.Replication)
+	sql := "update buckets set bucketname=?,acl=?,policy=?,cors=?,logging=?,lc=?,website=?,encryption=?,uid=?,versioning=?,tags=?,objectlock=?,notification=?,replication=? where bucketname=?"
+	args := []interface{}{b.Name, acl, bucket_policy, cors, logging, lc, website, encryption, b.OwnerId, b.Versioning, tags, objectLock, notification, replication, b.Name}
 	return sql, args
 }
 
@@ -70,9 +82,13 @@ func (b Bucket) GetCreateSql() (string, []interface{}) {
 	bucket_policy, _ := json.Marshal(b.Policy)
 	website, _ := json.Marshal(b.Website)
 	encryption,_ := json.Marshal(b.Encryption)
+	tags, _ := json.Marshal(b.Tags)
+	objectLock, _ := json.Marshal(b.ObjectLock)
+	notification, _ := json.Marshal(b.Notification)
+	replication, _ := json.Marshal(b.Replication)
 	createTime := b.CreateTime.Format(TIME_LAYOUT_TIDB)
-	sql := "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning) " +
-		"values(?,?,?,?,?,?,?,?,?,?,?,?);"
-	args := []interface{}{b.Name, acl, cors, logging, lc, b.OwnerId, bucket_policy, website, encryption, createTime, b.Usage, b.Versioning}
+	sql := "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning,tags,objectlock,notification,replication) " +
+		"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);"
+	args := []interface{}{b.Name, acl, cors, logging, lc, b.OwnerId, bucket_policy, website, encryption, createTime, b.Usage, b.Versioning, tags, objectLock, notification, replication}
 	return sql, args
 }
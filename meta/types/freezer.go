@@ -32,9 +32,15 @@ func (o *Freezer) GetUpdateSql(status Status) (string, []interface{}) {
 	// TODO Multi-version control
 	// version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	lastModifiedTime := o.LastModifiedTime.Format(TIME_LAYOUT_TIDB)
-	sql := "update restoreobjects set status,=?,lastmodifiedtime=?,location=?,pool=?," +
-		"ownerid=?,size=?,etag=? where bucketname=? and objectname=? and status=?"
-	args := []interface{}{status, lastModifiedTime, o.Location, o.Pool, o.OwnerId, o.Size, o.Etag, o.BucketName, o.Name, o.Status}
+	sql := "update restoreobjects set status=?,lastmodifiedtime=?,location=?,pool=?," +
+		"ownerid=?,size=?,objectid=?,etag=? where bucketname=? and objectname=?"
+	args := []interface{}{status, lastModifiedTime, o.Location, o.Pool, o.OwnerId, o.Size, o.ObjectId, o.Etag,
+		o.BucketName, o.Name}
 
 	return sql, args
 }
+
+// ExpiryDate is when the restored copy expires, LifeTime days after restored
+func (o *Freezer) ExpiryDate() time.Time {
+	return o.LastModifiedTime.AddDate(0, 0, o.LifeTime)
+}
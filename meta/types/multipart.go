@@ -37,6 +37,8 @@ type MultipartMetadata struct {
 	CipherKey     []byte
 	Attrs         map[string]string
 	StorageClass  StorageClass
+	Tags          map[string]string
+	ObjectLock    datatype.ObjectLock
 }
 
 type Multipart struct {
@@ -99,6 +101,13 @@ func (p *Part) GetCreateGcSql(bucketname, objectname string, version uint64) (st
 	return sql, args
 }
 
+func (p *Part) GetCreateFreezerSql(bucketname, objectname string) (string, []interface{}) {
+	sql := "insert into restoreobjectpart(partnumber,size,objectid,offset,etag,lastmodified,initializationvector,bucketname,objectname) " +
+		"values(?,?,?,?,?,?,?,?,?)"
+	args := []interface{}{p.PartNumber, p.Size, p.ObjectId, p.Offset, p.Etag, p.LastModified, p.InitializationVector, bucketname, objectname}
+	return sql, args
+}
+
 func (o *Object) GetUpdateObjectPartNameSql(sourceObject string) (string, []interface{}) {
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	sql := "update objectpart set objectname=? where bucketname=? and objectname=? and version=?"
@@ -36,16 +36,24 @@ type Object struct {
 	NullVersion      bool   // if this entry has `null` version
 	DeleteMarker     bool   // if this entry is a delete marker
 	VersionId        string // version cache
-	// type of Server Side Encryption, could be "SSE-KMS", "SSE-S3", "SSE-C"(custom), or ""(none),
-	// KMS is not implemented yet
+	// type of Server Side Encryption, could be "SSE-KMS", "SSE-S3", "SSE-C"(custom), or ""(none)
 	SseType string
-	// encryption key for SSE-S3, the key itself is encrypted with SSE_S3_MASTER_KEY,
-	// in AES256-GCM
+	// encryption key for SSE-S3 and SSE-KMS, the key itself is sealed by KMS
 	EncryptionKey        []byte
 	InitializationVector []byte
+	// KMS key and encryption context EncryptionKey is sealed with, for SSE-KMS
+	SseKmsKeyId string
+	SseContext  map[string]string
 	// ObjectType include `Normal`, `Appendable`, 'Multipart'
 	Type         ObjectType
 	StorageClass StorageClass
+	// object tags set by `x-amz-tagging` header or `?tagging` API
+	Tags map[string]string
+	// retention and legal hold set by `x-amz-object-lock-*` headers,
+	// `?retention` and `?legal-hold` APIs, or bucket default retention
+	ObjectLock datatype.ObjectLock
+	// PENDING/COMPLETED/FAILED if the version is matched by bucket replication rules
+	ReplicationStatus string
 }
 
 type ObjectType int
@@ -144,13 +152,18 @@ func (o *Object) GetCreateSql() (string, []interface{}) {
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
 	acl, _ := json.Marshal(o.ACL)
+	tags, _ := json.Marshal(o.Tags)
+	objectLock, _ := json.Marshal(o.ObjectLock)
+	sseContext, _ := json.Marshal(o.SseContext)
 	lastModifiedTime := o.LastModifiedTime.Format(TIME_LAYOUT_TIDB)
 	sql := "insert into objects(bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag," +
-		"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass) " +
-		"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+		"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass,tags,objectlock,replicationstatus," +
+		"ssekmskeyid,ssecontext) " +
+		"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
 	args := []interface{}{o.BucketName, o.Name, version, o.Location, o.Pool, o.OwnerId, o.Size, o.ObjectId,
 		lastModifiedTime, o.Etag, o.ContentType, customAttributes, acl, o.NullVersion, o.DeleteMarker,
-		o.SseType, o.EncryptionKey, o.InitializationVector, o.Type, o.StorageClass}
+		o.SseType, o.EncryptionKey, o.InitializationVector, o.Type, o.StorageClass, tags, objectLock, o.ReplicationStatus,
+		o.SseKmsKeyId, sseContext}
 	return sql, args
 }
 
@@ -170,6 +183,16 @@ func (o *Object) GetUpdateSql() (string, []interface{}) {
 	return sql, args
 }
 
+// GetTransitSql updates data location of the version, only if it's located as sourceObject
+func (o *Object) GetTransitSql(sourceObject *Object) (string, []interface{}) {
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	sql := "update objects set location=?,pool=?,size=?,objectid=?,etag=?,initializationvector=?,storageclass=? " +
+		"where bucketname=? and name=? and version=? and location=? and pool=? and objectid=? and size=?"
+	args := []interface{}{o.Location, o.Pool, o.Size, o.ObjectId, o.Etag, o.InitializationVector, o.StorageClass,
+		o.BucketName, o.Name, version, sourceObject.Location, sourceObject.Pool, sourceObject.ObjectId, sourceObject.Size}
+	return sql, args
+}
+
 func (o *Object) GetUpdateAclSql() (string, []interface{}) {
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	acl, _ := json.Marshal(o.ACL)
@@ -178,6 +201,22 @@ func (o *Object) GetUpdateAclSql() (string, []interface{}) {
 	return sql, args
 }
 
+func (o *Object) GetUpdateTagsSql() (string, []interface{}) {
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	tags, _ := json.Marshal(o.Tags)
+	sql := "update objects set tags=? where bucketname=? and name=? and version=?"
+	args := []interface{}{tags, o.BucketName, o.Name, version}
+	return sql, args
+}
+
+func (o *Object) GetUpdateObjectLockSql() (string, []interface{}) {
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	objectLock, _ := json.Marshal(o.ObjectLock)
+	sql := "update objects set objectlock=? where bucketname=? and name=? and version=?"
+	args := []interface{}{objectLock, o.BucketName, o.Name, version}
+	return sql, args
+}
+
 func (o *Object) GetUpdateAttrsSql() (string, []interface{}) {
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
 	sql := "update objects set customattributes=? where bucketname=? and name=?"
@@ -204,10 +243,11 @@ func (o *Object) GetSubUsageSql() (string, []interface{}) {
 	return sql, args
 }
 
-// TODO : with Version
 func (o *Object) GetReplaceObjectMetasSql() (string, []interface{}) {
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
-	sql := "update objects set contenttype=?,customattributes=?,storageclass=? where bucketname=? and name=?"
-	args := []interface{}{o.ContentType, customAttributes, o.StorageClass, o.BucketName, o.Name}
+	tags, _ := json.Marshal(o.Tags)
+	sql := "update objects set contenttype=?,customattributes=?,storageclass=?,tags=? where bucketname=? and name=? and version=?"
+	args := []interface{}{o.ContentType, customAttributes, o.StorageClass, tags, o.BucketName, o.Name, version}
 	return sql, args
 }
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Replication is an object version waiting to be replicated by tools/replication
+type Replication struct {
+	BucketName string
+	ObjectName string
+	Version    string    // version in `objects` table
+	MTime      time.Time // time the entry is queued or last tried
+	TriedTimes int
+}
+
+func (r Replication) Rowkey() string {
+	return r.BucketName + ObjectNameSeparator + r.ObjectName + ObjectNameSeparator + r.Version
+}
+
+// ParseReplicationRowkey splits rowkey made by Rowkey, bucket names never contain
+// the separator but object names could, so bucket is taken from the left and version from the right.
+func ParseReplicationRowkey(rowkey string) (bucketName, objectName, version string, ok bool) {
+	s := strings.SplitN(rowkey, ObjectNameSeparator, 2)
+	if len(s) != 2 {
+		return
+	}
+	i := strings.LastIndex(s[1], ObjectNameSeparator)
+	if i < 0 {
+		return
+	}
+	return s[0], s[1][:i], s[1][i+len(ObjectNameSeparator):], true
+}
@@ -0,0 +1,21 @@
+package types
+
+import "testing"
+
+func TestParseReplicationRowkey(t *testing.T) {
+	var testcase = [...]Replication{
+		{BucketName: "bucket", ObjectName: "a", Version: "1"},
+		{BucketName: "bucket", ObjectName: "a\nb\nc", Version: "2"},
+		{BucketName: "bucket", ObjectName: "\n", Version: "3"},
+		{BucketName: "bucket", ObjectName: "", Version: "4"},
+	}
+	for i, r := range testcase {
+		bucketName, objectName, version, ok := ParseReplicationRowkey(r.Rowkey())
+		if !ok || bucketName != r.BucketName || objectName != r.ObjectName || version != r.Version {
+			t.Errorf("Test %d: ParseReplicationRowkey returns %q %q %q %v", i, bucketName, objectName, version, ok)
+		}
+	}
+	if _, _, _, ok := ParseReplicationRowkey("bucket\na"); ok {
+		t.Errorf("ParseReplicationRowkey of invalid rowkey succeeded")
+	}
+}
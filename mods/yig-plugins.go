@@ -33,10 +33,8 @@ const (
 func InitialPlugins() map[string]*YigPlugin {
 
 	globalPlugins := make(map[string]*YigPlugin)
-	var sopath string
 
 	for name, pluginConfig := range helper.CONFIG.Plugins {
-		sopath = pluginConfig.Path
 		helper.Logger.Info("plugins: open for", name)
 		if pluginConfig.Path == "" {
 			helper.Logger.Info("plugin path for", name, "is empty")
@@ -45,46 +43,51 @@ func InitialPlugins() map[string]*YigPlugin {
 
 		//if enable do not exist in toml file, enable's default is false
 		if pluginConfig.Enable == false {
-			helper.Logger.Info(sopath, "is not enabled, continue")
+			helper.Logger.Info(pluginConfig.Path, "is not enabled, continue")
 			continue
 		}
 
-		//open plugin file
-		plug, err := plugin.Open(sopath)
+		yigPlugin, err := OpenPlugin(name)
 		if err != nil {
-			helper.Logger.Error(fmt.Sprintf(
-				"plugins: failed to open %s for %s, err: %v\n",
-				sopath, name, err))
-			continue
-		}
-		exported, err := plug.Lookup(EXPORTED_PLUGIN)
-		if err != nil {
-			helper.Logger.Error(fmt.Sprintf(
-				"plugins: lookup %s in %s failed, err: %v\n",
-				EXPORTED_PLUGIN, sopath, err))
-			continue
-		}
-
-		//check plugin type
-		yigPlugin, ok := exported.(*YigPlugin)
-		if !ok {
-			helper.Logger.Warn(fmt.Sprintf(
-				"plugins: convert %s in %s failed, exported: %v\n",
-				EXPORTED_PLUGIN, sopath, exported))
-			continue
-		}
-
-		//check plugin content
-		if yigPlugin.Name == name && yigPlugin.Create != nil {
-			globalPlugins[yigPlugin.Name] = yigPlugin
-		} else {
-			helper.Logger.Warn(fmt.Sprintf(
-				"plugins: check %s failed, value: %v\n", sopath, yigPlugin))
+			helper.Logger.Error("plugins:", err)
 			continue
 		}
+		globalPlugins[yigPlugin.Name] = yigPlugin
 		helper.Logger.Info(fmt.Sprintf(
-			"plugins: loaded plugin %s from %s\n", yigPlugin.Name, sopath))
+			"plugins: loaded plugin %s from %s\n", yigPlugin.Name, pluginConfig.Path))
 	}
 
 	return globalPlugins
 }
+
+// OpenPlugin opens the plugin configured as name whether it's enabled or not,
+// for plugins used by others only, e.g. the KMS keyring falls back to
+func OpenPlugin(name string) (*YigPlugin, error) {
+	pluginConfig, ok := helper.CONFIG.Plugins[name]
+	if !ok || pluginConfig.Path == "" {
+		return nil, fmt.Errorf("plugin %s is not configured", name)
+	}
+	sopath := pluginConfig.Path
+
+	//open plugin file
+	plug, err := plugin.Open(sopath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s for %s, err: %v", sopath, name, err)
+	}
+	exported, err := plug.Lookup(EXPORTED_PLUGIN)
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s in %s failed, err: %v", EXPORTED_PLUGIN, sopath, err)
+	}
+
+	//check plugin type
+	yigPlugin, ok := exported.(*YigPlugin)
+	if !ok {
+		return nil, fmt.Errorf("convert %s in %s failed, exported: %v", EXPORTED_PLUGIN, sopath, exported)
+	}
+
+	//check plugin content
+	if yigPlugin.Name != name || yigPlugin.Create == nil {
+		return nil, fmt.Errorf("check %s failed, value: %v", sopath, yigPlugin)
+	}
+	return yigPlugin, nil
+}
@@ -16,6 +16,9 @@ type MessageSender interface {
 
 var MsgSender MessageSender
 
+// name of the message queue plugin, notification destinations must be named by it
+var MsgSenderName string
+
 // create the singleton MessageSender
 func InitMessageSender(plugins map[string]*mods.YigPlugin) (MessageSender, error) {
 	for name, p := range plugins {
@@ -27,6 +30,7 @@ func InitMessageSender(plugins map[string]*mods.YigPlugin) (MessageSender, error
 			}
 			helper.Logger.Println("Message Queue plugin is", name)
 			MsgSender = c.(MessageSender)
+			MsgSenderName = name
 			return MsgSender, nil
 		}
 	}
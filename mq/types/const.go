@@ -7,5 +7,15 @@ const (
 )
 
 const (
-	MESSAGEBUS_KAFKA = "kafka"
+	WEBHOOK_CFG_URLS               = "urls"
+	WEBHOOK_CFG_SPOOL_DIR          = "spool_dir"
+	WEBHOOK_CFG_RETRY_INTERVAL_S   = "retry_interval_s"
+	WEBHOOK_CFG_REQUEST_TIMEOUT_MS = "request_timeout_ms"
+	WEBHOOK_CFG_QUEUE_SIZE         = "queue_size"
+	WEBHOOK_CFG_MAX_AGE_S          = "max_age_s"
+)
+
+const (
+	MESSAGEBUS_KAFKA   = "kafka"
+	MESSAGEBUS_WEBHOOK = "webhook"
 )
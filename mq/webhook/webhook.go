@@ -0,0 +1,393 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/journeymidnight/yig/circuitbreak"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	DefaultRetryInterval  = 10 * time.Second
+	DefaultRequestTimeout = 5 * time.Second
+	DefaultQueueSize      = 1024
+	DefaultMaxAge         = 24 * time.Hour
+
+	spoolFileSuffix = ".msg"
+	// sub directory of spool to keep messages dropped, they're never retried
+	deadLetterDir = "dead"
+)
+
+var (
+	ErrWebhookClosed = errors.New("webhook sender has been closed")
+	ErrFlushTimeout  = errors.New("timeout to flush webhook messages")
+)
+
+type Config struct {
+	// endpoints to POST messages to
+	Urls []string
+	// messages failed to deliver are stored under SpoolDir,
+	// in a sub directory named by md5 of the endpoint url
+	SpoolDir       string
+	RetryInterval  time.Duration
+	RequestTimeout time.Duration
+	QueueSize      int
+	// messages spooled longer than MaxAge are dropped into the dead letter directory
+	MaxAge time.Duration
+}
+
+// Webhook delivers messages as HTTP POST requests to every configured endpoint.
+// Messages which could not be delivered are spooled to local disk and retried
+// in order, so they survive endpoint outage and process restart.
+// Messages rejected with 4xx, or not delivered in MaxAge, are dropped into
+// the dead letter directory under the spool, so they don't block later ones.
+type Webhook struct {
+	targets []*target
+	closed  bool
+	lock    sync.RWMutex
+}
+
+type message struct {
+	value []byte
+	// flush marker if not nil
+	done chan struct{}
+}
+
+type target struct {
+	// number of messages in spool, keep 64-bit aligned for atomic operations
+	pending       int64
+	sequence      uint64
+	url           string
+	spoolDir      string
+	client        *circuitbreak.CircuitClient
+	queue         chan message
+	retryInterval time.Duration
+	maxAge        time.Duration
+	doneChan      chan struct{}
+}
+
+// statusError is returned by post if the endpoint responds with non-2xx status
+type statusError struct {
+	url    string
+	status int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("webhook %s responds with status %d", e.url, e.status)
+}
+
+// isPermanent reports whether err is a client error which fails on retry as well,
+// 408 Request Timeout and 429 Too Many Requests are retried
+func isPermanent(err error) bool {
+	e, ok := err.(statusError)
+	return ok && e.status >= 400 && e.status < 500 &&
+		e.status != http.StatusRequestTimeout && e.status != http.StatusTooManyRequests
+}
+
+func NewWebhook(config Config) (*Webhook, error) {
+	if len(config.Urls) == 0 {
+		return nil, errors.New("no webhook url is configured")
+	}
+	if config.SpoolDir == "" {
+		return nil, errors.New("webhook spool directory is not configured")
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = DefaultRequestTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultMaxAge
+	}
+
+	w := new(Webhook)
+	for _, url := range config.Urls {
+		t := &target{
+			url:           url,
+			spoolDir:      filepath.Join(config.SpoolDir, fmt.Sprintf("%x", md5.Sum([]byte(url)))),
+			client:        circuitbreak.NewCircuitClient(),
+			queue:         make(chan message, config.QueueSize),
+			retryInterval: config.RetryInterval,
+			maxAge:        config.MaxAge,
+			doneChan:      make(chan struct{}),
+		}
+		t.client.HttpClient.Timeout = config.RequestTimeout
+		err := os.MkdirAll(filepath.Join(t.spoolDir, deadLetterDir), 0755)
+		if err != nil {
+			return nil, err
+		}
+		files, err := t.spooledFiles()
+		if err != nil {
+			return nil, err
+		}
+		t.pending = int64(len(files))
+		w.targets = append(w.targets, t)
+	}
+	for _, t := range w.targets {
+		go t.run()
+	}
+	return w, nil
+}
+
+func (w *Webhook) AsyncSend(value []byte) error {
+	if len(value) == 0 {
+		return fmt.Errorf("input message[%v] is invalid.", value)
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return ErrWebhookClosed
+	}
+	var lastErr error
+	for _, t := range w.targets {
+		select {
+		case t.queue <- message{value: value}:
+		default:
+			// never block the caller, spool it directly if queue is full
+			if err := t.spool(value); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+// Flush waits until queued messages are handled and spooled messages are
+// retried once, timeout is in ms. The timeout covers waiting for room in
+// full queues too, so Close is never blocked longer than that.
+func (w *Webhook) Flush(timeout int) error {
+	timer := time.NewTimer(time.Duration(timeout) * time.Millisecond)
+	defer timer.Stop()
+
+	w.lock.RLock()
+	if w.closed {
+		w.lock.RUnlock()
+		return ErrWebhookClosed
+	}
+	var dones []chan struct{}
+	for _, t := range w.targets {
+		done := make(chan struct{})
+		select {
+		case t.queue <- message{done: done}:
+		case <-timer.C:
+			w.lock.RUnlock()
+			return ErrFlushTimeout
+		}
+		dones = append(dones, done)
+	}
+	w.lock.RUnlock()
+
+	for _, done := range dones {
+		select {
+		case <-done:
+		case <-timer.C:
+			return ErrFlushTimeout
+		}
+	}
+	return nil
+}
+
+// Close stops all deliveries, messages still in queue are delivered or spooled.
+func (w *Webhook) Close() {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return
+	}
+	w.closed = true
+	for _, t := range w.targets {
+		close(t.queue)
+	}
+	w.lock.Unlock()
+	for _, t := range w.targets {
+		<-t.doneChan
+	}
+}
+
+func (t *target) run() {
+	defer close(t.doneChan)
+	ticker := time.NewTicker(t.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-t.queue:
+			if !ok {
+				return
+			}
+			if msg.done != nil {
+				t.retry()
+				close(msg.done)
+				continue
+			}
+			// keep messages in order, new messages wait behind spooled ones
+			if atomic.LoadInt64(&t.pending) > 0 {
+				t.retry()
+			}
+			if atomic.LoadInt64(&t.pending) == 0 {
+				err := t.post(msg.value)
+				if err == nil {
+					continue
+				}
+				if isPermanent(err) {
+					helper.Logger.Error("Drop webhook message for", t.url, "err:", err)
+					if err = t.write(filepath.Join(t.spoolDir, deadLetterDir), msg.value); err != nil {
+						helper.Logger.Error("Failed to save dropped webhook message for", t.url, "err:", err)
+					}
+					continue
+				}
+			}
+			if err := t.spool(msg.value); err != nil {
+				helper.Logger.Error("Failed to spool webhook message for", t.url, "err:", err)
+			}
+		case <-ticker.C:
+			t.retry()
+		}
+	}
+}
+
+func (t *target) post(value []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(value))
+	if err != nil {
+		return err
+	}
+	// bucket notifications are json, access logs are msgpack
+	if json.Valid(value) {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-msgpack")
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError{url: t.url, status: resp.StatusCode}
+	}
+	return nil
+}
+
+// retry delivers spooled messages in order and stops at the first failure,
+// messages failed permanently or expired are dropped instead
+func (t *target) retry() {
+	files, err := t.spooledFiles()
+	if err != nil {
+		helper.Logger.Error("Failed to list webhook spool", t.spoolDir, "err:", err)
+		return
+	}
+	for _, file := range files {
+		if t.expired(file) {
+			if !t.drop(file, fmt.Errorf("not delivered in %v", t.maxAge)) {
+				return
+			}
+			continue
+		}
+		value, err := ioutil.ReadFile(file)
+		if err != nil {
+			helper.Logger.Error("Failed to read webhook spool file", file, "err:", err)
+			return
+		}
+		err = t.post(value)
+		if isPermanent(err) {
+			if !t.drop(file, err) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			helper.Logger.Warn("Failed to resend webhook message to", t.url, "err:", err)
+			return
+		}
+		if err = os.Remove(file); err != nil {
+			helper.Logger.Error("Failed to remove webhook spool file", file, "err:", err)
+			return
+		}
+		atomic.AddInt64(&t.pending, -1)
+	}
+}
+
+// expired reports whether the spooled file is older than maxAge, by the time in its name
+func (t *target) expired(file string) bool {
+	name := filepath.Base(file)
+	i := strings.Index(name, "-")
+	if i < 0 {
+		return false
+	}
+	spooled, err := strconv.ParseInt(name[:i], 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Since(time.Unix(0, spooled)) > t.maxAge
+}
+
+// drop moves the spooled file into the dead letter directory, false if it fails
+func (t *target) drop(file string, reason error) bool {
+	helper.Logger.Error("Drop webhook message", file, "for", t.url, "err:", reason)
+	err := os.Rename(file, filepath.Join(t.spoolDir, deadLetterDir, filepath.Base(file)))
+	if err != nil {
+		helper.Logger.Error("Failed to drop webhook spool file", file, "err:", err)
+		return false
+	}
+	atomic.AddInt64(&t.pending, -1)
+	return true
+}
+
+func (t *target) spool(value []byte) error {
+	err := t.write(t.spoolDir, value)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&t.pending, 1)
+	return nil
+}
+
+// write saves the message as a file in dir
+func (t *target) write(dir string, value []byte) error {
+	// file names sort in the order of spooling
+	name := fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), atomic.AddUint64(&t.sequence, 1))
+	tmpFile := filepath.Join(dir, name+".tmp")
+	err := ioutil.WriteFile(tmpFile, value, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpFile, filepath.Join(dir, name+spoolFileSuffix))
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+func (t *target) spooledFiles() (files []string, err error) {
+	infos, err := ioutil.ReadDir(t.spoolDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), spoolFileSuffix) {
+			files = append(files, filepath.Join(t.spoolDir, info.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,262 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+type endpoint struct {
+	sync.Mutex
+	down     bool
+	status   int // responded if not 0
+	received []string
+	types    []string
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+	if e.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if e.status != 0 {
+		w.WriteHeader(e.status)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	e.received = append(e.received, string(body))
+	e.types = append(e.types, r.Header.Get("Content-Type"))
+}
+
+func (e *endpoint) setDown(down bool) {
+	e.Lock()
+	e.down = down
+	e.Unlock()
+}
+
+func (e *endpoint) setStatus(status int) {
+	e.Lock()
+	e.status = status
+	e.Unlock()
+}
+
+func (e *endpoint) messages() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string{}, e.received...)
+}
+
+func newTestWebhook(t *testing.T, url string, spoolDir string) *Webhook {
+	w, err := NewWebhook(Config{
+		Urls:          []string{url},
+		SpoolDir:      spoolDir,
+		RetryInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook failed: %v", err)
+	}
+	return w
+}
+
+func checkMessages(t *testing.T, got []string, expected ...string) {
+	if len(got) != len(expected) {
+		t.Fatalf("Expected messages %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected messages %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+func TestWebhookSend(t *testing.T) {
+	e := new(endpoint)
+	server := httptest.NewServer(e)
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w := newTestWebhook(t, server.URL, spoolDir)
+	defer w.Close()
+	if err := w.AsyncSend([]byte(`{"Records":[]}`)); err != nil {
+		t.Fatalf("AsyncSend failed: %v", err)
+	}
+	if err := w.AsyncSend([]byte{0x81, 0xa1, 0x61, 0x01}); err != nil {
+		t.Fatalf("AsyncSend failed: %v", err)
+	}
+	if err := w.AsyncSend(nil); err == nil {
+		t.Fatalf("AsyncSend empty message, expected error")
+	}
+	if err := w.Flush(5000); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	checkMessages(t, e.messages(), `{"Records":[]}`, string([]byte{0x81, 0xa1, 0x61, 0x01}))
+	if e.types[0] != "application/json" || e.types[1] != "application/x-msgpack" {
+		t.Fatalf("Unexpected content types %v", e.types)
+	}
+}
+
+func TestWebhookSpool(t *testing.T) {
+	e := &endpoint{down: true}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w := newTestWebhook(t, server.URL, spoolDir)
+	defer w.Close()
+	for _, msg := range []string{"1", "2", "3"} {
+		w.AsyncSend([]byte(msg))
+	}
+	w.Flush(5000)
+	checkMessages(t, e.messages())
+	files, _ := w.targets[0].spooledFiles()
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 spooled messages, got %d", len(files))
+	}
+
+	e.setDown(false)
+	w.AsyncSend([]byte("4"))
+	w.Flush(5000)
+	checkMessages(t, e.messages(), "1", "2", "3", "4")
+	files, _ = w.targets[0].spooledFiles()
+	if len(files) != 0 {
+		t.Fatalf("Expected empty spool, got %v", files)
+	}
+}
+
+func TestWebhookSpoolAfterRestart(t *testing.T) {
+	e := &endpoint{down: true}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w := newTestWebhook(t, server.URL, spoolDir)
+	w.AsyncSend([]byte("1"))
+	w.AsyncSend([]byte("2"))
+	w.Close()
+	if err := w.AsyncSend([]byte("3")); err != ErrWebhookClosed {
+		t.Fatalf("AsyncSend after Close, expected %v, got %v", ErrWebhookClosed, err)
+	}
+
+	e.setDown(false)
+	w = newTestWebhook(t, server.URL, spoolDir)
+	defer w.Close()
+	w.Flush(5000)
+	checkMessages(t, e.messages(), "1", "2")
+}
+
+func deadLetters(t *testing.T, w *Webhook) int {
+	infos, err := ioutil.ReadDir(filepath.Join(w.targets[0].spoolDir, deadLetterDir))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	return len(infos)
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	e := &endpoint{status: http.StatusBadRequest}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w := newTestWebhook(t, server.URL, spoolDir)
+	defer w.Close()
+	// rejected messages are dropped instead of spooled
+	w.AsyncSend([]byte("1"))
+	w.Flush(5000)
+	if n := deadLetters(t, w); n != 1 {
+		t.Fatalf("Expected 1 dropped message, got %d", n)
+	}
+
+	// spooled messages rejected on retry are dropped, so later ones are delivered
+	e.setDown(true)
+	w.AsyncSend([]byte("2"))
+	w.Flush(5000)
+	e.setDown(false)
+	w.AsyncSend([]byte("3"))
+	w.Flush(5000)
+	e.setStatus(0)
+	w.AsyncSend([]byte("4"))
+	w.Flush(5000)
+	checkMessages(t, e.messages(), "4")
+	if n := deadLetters(t, w); n != 3 {
+		t.Fatalf("Expected 3 dropped messages, got %d", n)
+	}
+	if files, _ := w.targets[0].spooledFiles(); len(files) != 0 {
+		t.Fatalf("Expected empty spool, got %v", files)
+	}
+}
+
+func TestWebhookMaxAge(t *testing.T) {
+	e := &endpoint{down: true}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w, err := NewWebhook(Config{
+		Urls:          []string{server.URL},
+		SpoolDir:      spoolDir,
+		RetryInterval: time.Hour,
+		MaxAge:        time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook failed: %v", err)
+	}
+	defer w.Close()
+	w.AsyncSend([]byte("1"))
+	w.Flush(5000)
+	if files, _ := w.targets[0].spooledFiles(); len(files) != 0 {
+		t.Fatalf("Expected expired messages dropped, got %v", files)
+	}
+	if n := deadLetters(t, w); n != 1 {
+		t.Fatalf("Expected 1 dropped message, got %d", n)
+	}
+}
+
+func TestWebhookFlushFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	spoolDir, _ := ioutil.TempDir("", "webhook")
+	defer os.RemoveAll(spoolDir)
+
+	w, err := NewWebhook(Config{
+		Urls:          []string{server.URL},
+		SpoolDir:      spoolDir,
+		RetryInterval: time.Hour,
+		QueueSize:     1,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook failed: %v", err)
+	}
+	defer w.Close()
+	defer close(release)
+	// the first is being posted, the second fills the queue
+	w.AsyncSend([]byte("1"))
+	time.Sleep(100 * time.Millisecond)
+	w.AsyncSend([]byte("2"))
+	if err := w.Flush(100); err != ErrFlushTimeout {
+		t.Fatalf("Flush with full queue, expected %v, got %v", ErrFlushTimeout, err)
+	}
+}
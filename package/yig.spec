@@ -34,16 +34,27 @@ make build_internal
 rm -rf %{buildroot}
 install -D -m 755 admin %{buildroot}%{_bindir}/yig_admin
 install -D -m 755 delete %{buildroot}%{_bindir}/yig_delete_daemon
+install -D -m 755 fsck %{buildroot}%{_bindir}/yig_fsck
 install -D -m 755 getrediskeys %{buildroot}%{_bindir}/yig_getrediskeys
 install -D -m 755 lc     %{buildroot}%{_bindir}/yig_lifecyle_daemon
+install -D -m 755 migrate %{buildroot}%{_bindir}/yig_migrate
+install -D -m 755 rekey %{buildroot}%{_bindir}/yig_rekey
+install -D -m 755 replication %{buildroot}%{_bindir}/yig_replication_daemon
+install -D -m 755 restore %{buildroot}%{_bindir}/yig_restore_daemon
 install -D -m 755 %{_builddir}/yig/yig %{buildroot}%{_bindir}/yig
 install -D -m 644 package/yig.logrotate %{buildroot}/etc/logrotate.d/yig.logrotate
 install -D -m 644 package/access.logrotate %{buildroot}/etc/logrotate.d/access.logrotate
 install -D -m 644 package/yig_delete.logrotate %{buildroot}/etc/logrotate.d/yig_delete.logrotate
 install -D -m 644 package/yig_lc.logrotate %{buildroot}/etc/logrotate.d/yig_lc.logrotate
+install -D -m 644 package/yig_replication.logrotate %{buildroot}/etc/logrotate.d/yig_replication.logrotate
+/etc/logrotate.d/yig_restore.logrotate
+install -D -m 644 package/yig_restore.logrotate %{buildroot}/etc/logrotate.d/yig_restore.logrotate
 install -D -m 644 package/yig.service   %{buildroot}/usr/lib/systemd/system/yig.service
 install -D -m 644 package/yig_delete.service   %{buildroot}/usr/lib/systemd/system/yig_delete.service
 install -D -m 644 package/yig_lc.service   %{buildroot}/usr/lib/systemd/system/yig_lc.service
+install -D -m 644 package/yig_replication.service   %{buildroot}/usr/lib/systemd/system/yig_replication.service
+/usr/lib/systemd/system/yig_restore.service
+install -D -m 644 package/yig_restore.service   %{buildroot}/usr/lib/systemd/system/yig_restore.service
 install -D -m 644 conf/yig.toml %{buildroot}%{_sysconfdir}/yig/yig.toml
 install -d %{buildroot}/var/log/yig/
 
@@ -66,16 +77,25 @@ rm -rf %{buildroot}
 /usr/bin/yig_admin
 /usr/bin/yig
 /usr/bin/yig_delete_daemon
+/usr/bin/yig_fsck
 /usr/bin/yig_getrediskeys
 /usr/bin/yig_lifecyle_daemon
+/usr/bin/yig_migrate
+/usr/bin/yig_rekey
+/usr/bin/yig_replication_daemon
+/usr/bin/yig_restore_daemon
 /etc/logrotate.d/yig.logrotate
 /etc/logrotate.d/access.logrotate
 /etc/logrotate.d/yig_delete.logrotate
 /etc/logrotate.d/yig_lc.logrotate
+/etc/logrotate.d/yig_replication.logrotate
+/etc/logrotate.d/yig_restore.logrotate
 %dir /var/log/yig/
 /usr/lib/systemd/system/yig.service
 /usr/lib/systemd/system/yig_delete.service
 /usr/lib/systemd/system/yig_lc.service
+/usr/lib/systemd/system/yig_replication.service
+/usr/lib/systemd/system/yig_restore.service
 
 
 %changelog
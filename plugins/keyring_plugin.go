@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/mods"
+)
+
+const pluginName = "encryption_keyring"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.KMS_PLUGIN,
+	Create:     GetKeyringClient,
+}
+
+// GetKeyringClient returns keyring with master keys from the oldest to the newest,
+// the newest one seals new keys, and older ones are rewrapped by tools/rekey.
+// There is no default master key, it must be generated for each deployment.
+// Keys sealed by the KMS used before are unsealed by the KMS plugin named by fallback,
+// and rewrapped by tools/rekey too, see crypto.Keyring.
+func GetKeyringClient(config map[string]interface{}) (interface{}, error) {
+	keyName, _ := config["keyName"].(string)
+	if keyName == "" {
+		return nil, errors.New("keyName of keyring is not configured")
+	}
+	values, _ := config["masterKeys"].([]interface{})
+	if len(values) == 0 {
+		return nil, errors.New("masterKeys of keyring is not configured, " +
+			"generate one as \"<ID>:$(head -c 32 /dev/urandom | base64)\"")
+	}
+	var masterKeys []crypto.MasterKey
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid master key %v", v)
+		}
+		masterKey, err := crypto.ParseMasterKey(s)
+		if err != nil {
+			return nil, err
+		}
+		masterKeys = append(masterKeys, masterKey)
+	}
+	k, err := crypto.NewKeyring(keyName, masterKeys...)
+	if err != nil {
+		return nil, err
+	}
+	if name, _ := config["fallback"].(string); name != "" {
+		fallback, err := openFallback(name)
+		if err != nil {
+			return nil, err
+		}
+		k.SetFallback(fallback)
+		helper.Logger.Info("Keyring falls back to KMS plugin", name)
+	}
+	helper.Logger.Info("Get keyring plugin config, key:", keyName, "master keys:", len(masterKeys))
+	return interface{}(k), nil
+}
+
+// openFallback creates the KMS plugin configured as name, it needs not be enabled
+func openFallback(name string) (crypto.KMS, error) {
+	if name == pluginName {
+		return nil, errors.New("keyring could not fall back to itself")
+	}
+	p, err := mods.OpenPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.PluginType != mods.KMS_PLUGIN {
+		return nil, fmt.Errorf("fallback plugin %s is not a KMS plugin", name)
+	}
+	c, err := p.Create(helper.CONFIG.Plugins[name].Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fallback KMS plugin %s: %v", name, err)
+	}
+	kms, ok := c.(crypto.KMS)
+	if !ok {
+		return nil, fmt.Errorf("fallback plugin %s is not a KMS", name)
+	}
+	return kms, nil
+}
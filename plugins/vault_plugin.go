@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/journeymidnight/yig/crypto/vault"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/mods"
+)
+
+const pluginName = "encryption_vault"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.KMS_PLUGIN,
+	Create:     GetVaultClient,
+}
+
+func GetVaultClient(config map[string]interface{}) (interface{}, error) {
+	if nil == config || len(config) == 0 {
+		return nil, errors.New("input vault params is invalid")
+	}
+	endpoint, _ := config["endpoint"].(string)
+	keyName, _ := config["keyName"].(string)
+	roleId, _ := config["kms_id"].(string)
+	secretId, _ := config["kms_secret"].(string)
+	token, _ := config["token"].(string)
+	helper.Logger.Info("Get vault plugin config, endpoint:", endpoint, "key:", keyName)
+
+	var version int
+	switch v := config["version"].(type) {
+	case nil:
+	case int64:
+		version = int(v)
+	case int:
+		version = v
+	case string:
+		var err error
+		if version, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid vault param version: %v", v)
+		}
+	default:
+		return nil, fmt.Errorf("invalid vault param version: %v", v)
+	}
+
+	v, err := vault.NewVault(vault.Config{
+		Endpoint:   endpoint,
+		KeyName:    keyName,
+		KeyVersion: version,
+		RoleId:     roleId,
+		SecretId:   secretId,
+		Token:      token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return interface{}(v), nil
+}
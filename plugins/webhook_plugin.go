@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/mq/types"
+	"github.com/journeymidnight/yig/mq/webhook"
+)
+
+const pluginName = "webhook"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.MQ_PLUGIN,
+	Create:     GetWebhookClient,
+}
+
+func GetWebhookClient(config map[string]interface{}) (interface{}, error) {
+	helper.Logger.Info("Get webhook plugin config:", config)
+	if nil == config || len(config) == 0 {
+		return nil, errors.New("input webhook params is invalid")
+	}
+
+	var urls []string
+	switch v := config[types.WEBHOOK_CFG_URLS].(type) {
+	case string:
+		for _, url := range strings.Split(v, ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				urls = append(urls, url)
+			}
+		}
+	case []interface{}:
+		for _, url := range v {
+			urls = append(urls, fmt.Sprint(url))
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("params doesn't contain validate webhook urls")
+	}
+	spoolDir, _ := config[types.WEBHOOK_CFG_SPOOL_DIR].(string)
+
+	retryInterval, err := getInt(config, types.WEBHOOK_CFG_RETRY_INTERVAL_S)
+	if err != nil {
+		return nil, err
+	}
+	requestTimeout, err := getInt(config, types.WEBHOOK_CFG_REQUEST_TIMEOUT_MS)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := getInt(config, types.WEBHOOK_CFG_QUEUE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := getInt(config, types.WEBHOOK_CFG_MAX_AGE_S)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := webhook.NewWebhook(webhook.Config{
+		Urls:           urls,
+		SpoolDir:       spoolDir,
+		RetryInterval:  time.Duration(retryInterval) * time.Second,
+		RequestTimeout: time.Duration(requestTimeout) * time.Millisecond,
+		QueueSize:      queueSize,
+		MaxAge:         time.Duration(maxAge) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	helper.Logger.Info("start webhook, urls:", urls)
+	return interface{}(w), nil
+}
+
+// getInt accepts both integer and string values, 0 for absent keys
+func getInt(config map[string]interface{}, key string) (int, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("invalid webhook param %s: %v", key, v)
+	}
+}
@@ -0,0 +1,206 @@
+package s3gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/aws/credentials"
+	"github.com/journeymidnight/aws-sdk-go/aws/session"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	"github.com/journeymidnight/aws-sdk-go/service/s3/s3manager"
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+)
+
+// cluster ID of a gateway is CLUSTER_ID_PREFIX + its name in config
+const CLUSTER_ID_PREFIX = "s3:"
+
+// Initialize returns a cluster for every gateway in `s3_gateways`,
+// panic on errors as ceph.Initialize does
+func Initialize(config helper.Config) map[string]backend.Cluster {
+	clusters := make(map[string]backend.Cluster)
+	for name, c := range config.S3Gateways {
+		if c.Region == "" {
+			c.Region = config.Region
+		}
+		cluster, err := NewGatewayCluster(name, c)
+		if err != nil {
+			panic("Failed to initialize s3 gateway " + name + ": " + err.Error())
+		}
+		clusters[cluster.Name] = cluster
+	}
+	return clusters
+}
+
+// GatewayCluster stores objects in a bucket of another S3-compatible service,
+// every object is stored as <prefix><pool>/<oid>
+type GatewayCluster struct {
+	Name           string
+	Bucket         string
+	Prefix         string
+	StorageClasses []string // storage classes of objects stored in this gateway
+
+	client     *s3.S3
+	uploader   *s3manager.Uploader
+	instanceId string
+	counter    uint64
+}
+
+func NewGatewayCluster(name string, config helper.S3GatewayConfig) (*GatewayCluster, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("endpoint and bucket must be set")
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""),
+		Endpoint:         aws.String(config.Endpoint),
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := s3.New(sess)
+	cluster := &GatewayCluster{
+		Name:           CLUSTER_ID_PREFIX + name,
+		Bucket:         config.Bucket,
+		Prefix:         config.Prefix,
+		StorageClasses: config.StorageClasses,
+		client:         client,
+		uploader:       s3manager.NewUploaderWithClient(client),
+		// unique among processes sharing the same bucket, e.g. yig and tools/lc
+		instanceId: fmt.Sprintf("%x%x", os.Getpid(), time.Now().UnixNano()),
+	}
+	helper.Logger.Info("S3 gateway Cluster", cluster.Name, "is ready, endpoint is",
+		config.Endpoint, "bucket is", config.Bucket)
+	return cluster, nil
+}
+
+func (cluster *GatewayCluster) ID() string {
+	return cluster.Name
+}
+
+func (cluster *GatewayCluster) getUniqUploadName() string {
+	v := atomic.AddUint64(&cluster.counter, 1)
+	return fmt.Sprintf("%s:%d", cluster.instanceId, v)
+}
+
+func (cluster *GatewayCluster) objectKey(poolName, oid string) string {
+	return cluster.Prefix + poolName + "/" + oid
+}
+
+// GetUsage always returns 0, space of remote services is not limited
+func (cluster *GatewayCluster) GetUsage() (usage backend.Usage, err error) {
+	return usage, nil
+}
+
+type countingReader struct {
+	io.Reader
+	n uint64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.n += uint64(n)
+	return
+}
+
+func (cluster *GatewayCluster) upload(poolname, oid string, data io.Reader) (size uint64, err error) {
+	reader := &countingReader{Reader: data}
+	_, err = cluster.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(cluster.objectKey(poolname, oid)),
+		Body:   reader,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Write failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	return reader.n, nil
+}
+
+func (cluster *GatewayCluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	oid = cluster.getUniqUploadName()
+	size, err = cluster.upload(poolname, oid, data)
+	return
+}
+
+// Append rewrites the whole remote object since S3 has no append operation,
+// first `offset` bytes of existing object are copied from the remote service.
+func (cluster *GatewayCluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	oid = existName
+	if len(oid) == 0 {
+		oid = cluster.getUniqUploadName()
+	}
+	if poolname != backend.BIG_FILE_POOLNAME {
+		return oid, 0,
+			errors.New("specified pool must be used for storing big file.")
+	}
+	if offset == 0 {
+		size, err = cluster.upload(poolname, oid, data)
+		return
+	}
+	existing, err := cluster.GetReader(poolname, oid, 0, uint64(offset))
+	if err != nil {
+		return oid, 0, err
+	}
+	defer existing.Close()
+	existingReader := &countingReader{Reader: existing}
+	chunk := &countingReader{Reader: data}
+	_, err = cluster.upload(poolname, oid, io.MultiReader(existingReader,
+		&offsetChecker{reader: existingReader, offset: offset}, chunk))
+	if err != nil {
+		return oid, 0, err
+	}
+	return oid, chunk.n, nil
+}
+
+// offsetChecker fails the upload if existing object is shorter than offset
+type offsetChecker struct {
+	reader *countingReader
+	offset int64
+}
+
+func (c *offsetChecker) Read(p []byte) (int, error) {
+	if c.reader.n != uint64(c.offset) {
+		return 0, fmt.Errorf("Bad offset %d, existing object size is %d", c.offset, c.reader.n)
+	}
+	return 0, io.EOF
+}
+
+func (cluster *GatewayCluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(cluster.objectKey(poolName, oid)),
+	}
+	if length != 0 {
+		input.Range = aws.String("bytes=" + strconv.FormatInt(startOffset, 10) + "-" +
+			strconv.FormatInt(startOffset+int64(length)-1, 10))
+	} else if startOffset != 0 {
+		input.Range = aws.String("bytes=" + strconv.FormatInt(startOffset, 10) + "-")
+	}
+	output, err := cluster.client.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+// Remove succeeds if the object does not exist, as DeleteObject of S3 does
+func (cluster *GatewayCluster) Remove(poolname string, oid string) error {
+	_, err := cluster.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(cluster.objectKey(poolname, oid)),
+	})
+	return err
+}
@@ -0,0 +1,173 @@
+package s3gateway
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// fakeS3 serves PUT, GET with range and DELETE of objects in path style
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = data
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet:
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>no such key</Message></Error>"))
+			return
+		}
+		status := http.StatusOK
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			bounds := strings.SplitN(strings.TrimPrefix(rangeHeader, "bytes="), "-", 2)
+			start, _ := strconv.Atoi(bounds[0])
+			end := len(data) - 1
+			if bounds[1] != "" {
+				end, _ = strconv.Atoi(bounds[1])
+			}
+			if end >= len(data) {
+				end = len(data) - 1
+			}
+			w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(end)+
+				"/"+strconv.Itoa(len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestCluster(t *testing.T) (*GatewayCluster, *fakeS3, func()) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(s3)
+	cluster, err := NewGatewayCluster("test", helper.S3GatewayConfig{
+		Endpoint:       server.URL,
+		Region:         "us-east-1",
+		AccessKey:      "ak",
+		SecretKey:      "sk",
+		Bucket:         "bucket",
+		Prefix:         "yig/",
+		StorageClasses: []string{"GLACIER"},
+	})
+	if err != nil {
+		server.Close()
+		t.Fatal("NewGatewayCluster error:", err)
+	}
+	return cluster, s3, server.Close
+}
+
+func readAll(t *testing.T, cluster *GatewayCluster, pool, oid string, offset int64, length uint64) []byte {
+	reader, err := cluster.GetReader(pool, oid, offset, length)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Read error:", err)
+	}
+	return data
+}
+
+func TestGatewayCluster_PutAndGet(t *testing.T) {
+	cluster, s3, closeServer := newTestCluster(t)
+	defer closeServer()
+	if cluster.ID() != "s3:test" {
+		t.Fatal("Cluster ID is", cluster.ID())
+	}
+
+	data := []byte("hello gateway backend")
+	oid, size, err := cluster.Put(backend.GLACIER_FILE_POOLNAME, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	if size != uint64(len(data)) {
+		t.Fatalf("Put size %d, expected %d", size, len(data))
+	}
+	if _, ok := s3.objects["/bucket/yig/turtle/"+oid]; !ok {
+		t.Fatal("Object not found in remote bucket, objects:", s3.objects)
+	}
+	if got := readAll(t, cluster, backend.GLACIER_FILE_POOLNAME, oid, 0, 0); !bytes.Equal(got, data) {
+		t.Fatalf("Read %q, expected %q", got, data)
+	}
+	if got := readAll(t, cluster, backend.GLACIER_FILE_POOLNAME, oid, 6, 7); string(got) != "gateway" {
+		t.Fatalf("Read range %q, expected %q", got, "gateway")
+	}
+	if got := readAll(t, cluster, backend.GLACIER_FILE_POOLNAME, oid, 6, 0); !bytes.Equal(got, data[6:]) {
+		t.Fatalf("Read to end %q, expected %q", got, data[6:])
+	}
+
+	if err = cluster.Remove(backend.GLACIER_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	if _, err = cluster.GetReader(backend.GLACIER_FILE_POOLNAME, oid, 0, 0); err == nil {
+		t.Fatal("GetReader removed object, expected error")
+	}
+	if err = cluster.Remove(backend.GLACIER_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove removed object error:", err)
+	}
+}
+
+func TestGatewayCluster_Append(t *testing.T) {
+	cluster, _, closeServer := newTestCluster(t)
+	defer closeServer()
+
+	if _, _, err := cluster.Append(backend.SMALL_FILE_POOLNAME, "", bytes.NewReader([]byte("a")), 0); err == nil {
+		t.Fatal("Append to small file pool, expected error")
+	}
+	oid, size, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("hello")), 0)
+	if err != nil || size != 5 {
+		t.Fatal("Append error:", err, size)
+	}
+	_, size, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte(" world")), 5)
+	if err != nil || size != 6 {
+		t.Fatal("Append error:", err, size)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 0); string(got) != "hello world" {
+		t.Fatalf("Read %q, expected %q", got, "hello world")
+	}
+	// existing object is shorter than offset
+	if _, _, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte("!")), 20); err == nil {
+		t.Fatal("Append with bad offset, expected error")
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 0); string(got) != "hello world" {
+		t.Fatalf("Read %q after failed append, expected %q", got, "hello world")
+	}
+}
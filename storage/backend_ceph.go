@@ -5,20 +5,30 @@ import (
 	"encoding/hex"
 	"github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/backend"
-	"github.com/journeymidnight/yig/ceph"
 	"github.com/journeymidnight/yig/crypto"
 	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/filesystem"
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/iam/common"
+	"github.com/journeymidnight/yig/memory"
 	"github.com/journeymidnight/yig/meta"
 	"github.com/journeymidnight/yig/meta/types"
 	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/s3gateway"
 	"github.com/journeymidnight/yig/signature"
 	"io"
 	"sync"
 	"time"
 )
 
+// dataStores initialize clusters of each data store, ceph is registered in ceph.go
+// unless built with tag `noceph`, so the storage could be built and tested without librados
+var dataStores = map[string]func(config helper.Config) map[string]backend.Cluster{
+	"filesystem": filesystem.Initialize,
+	// for unit tests only, objects are lost on exit
+	"memory": memory.Initialize,
+}
+
 func New(metaCacheType int, enableDataCache bool, kms crypto.KMS) *YigStorage {
 	yig := YigStorage{
 		DataStorage: make(map[string]backend.Cluster),
@@ -29,30 +39,48 @@ func New(metaCacheType int, enableDataCache bool, kms crypto.KMS) *YigStorage {
 		WaitGroup:   new(sync.WaitGroup),
 	}
 
-	yig.DataStorage = ceph.Initialize(helper.CONFIG)
+	initialize, ok := dataStores[helper.CONFIG.DataStore]
+	if !ok {
+		panic("unsupport datastore")
+	}
+	yig.DataStorage = initialize(helper.CONFIG)
 	if len(yig.DataStorage) == 0 {
 		panic("No data storage can be used!")
 	}
+	for id, cluster := range s3gateway.Initialize(helper.CONFIG) {
+		yig.DataStorage[id] = cluster
+	}
 
 	initializeRecycler(&yig)
 	return &yig
 }
 
-//TODO: Append Support Encryption
+// AppendObject appends data to the object at offset. Data appended is encrypted the same way as
+// the object, with the key and initialization vector of the object, see wrapEncryptionReaderAt
 func (yig *YigStorage) AppendObject(bucketName string, objectName string, credential common.Credential,
 	offset uint64, size int64, data io.ReadCloser, metadata map[string]string, acl datatype.Acl,
 	sseRequest datatype.SseRequest, storageClass types.StorageClass, objInfo *types.Object) (result datatype.AppendObjectResult, err error) {
 
 	defer data.Close()
-	encryptionKey, cipherKey, err := yig.encryptionKeyFromSseRequest(sseRequest, bucketName, objectName)
-	helper.Logger.Println(10, "get encryptionKey:", encryptionKey, "cipherKey:", cipherKey, "err:", err)
-	if err != nil {
-		return
+	var encryptionKey, cipherKey []byte
+	var sseContext crypto.Context
+	if objInfo != nil {
+		sseRequest = datatype.SseRequest{Type: objInfo.SseType, SseAwsKmsKeyId: objInfo.SseKmsKeyId}
+		// objects appended before encryption was supported are stored unencrypted without key
+		if objInfo.SseType != "" && len(objInfo.EncryptionKey) != 0 {
+			encryptionKey, err = yig.unsealObjectKey(objInfo)
+			if err != nil {
+				return
+			}
+			cipherKey, sseContext = objInfo.EncryptionKey, objInfo.SseContext
+		}
+	} else {
+		encryptionKey, cipherKey, sseContext, err = yig.encryptionKeyFromSseRequest(&sseRequest, bucketName, objectName)
+		if err != nil {
+			return
+		}
 	}
 
-	//TODO: Append Support Encryption
-	encryptionKey = nil
-
 	md5Writer := md5.New()
 
 	// Limit the reader to its provided size if specified.
@@ -94,7 +122,7 @@ func (yig *YigStorage) AppendObject(bucketName string, objectName string, creden
 
 	dataReader := io.TeeReader(limitedDataReader, md5Writer)
 
-	storageReader, err := wrapEncryptionReader(dataReader, encryptionKey, initializationVector)
+	storageReader, err := wrapEncryptionReaderAt(dataReader, encryptionKey, initializationVector, int64(offset))
 	if err != nil {
 		return
 	}
@@ -139,15 +167,16 @@ func (yig *YigStorage) AppendObject(bucketName string, objectName string, creden
 		ACL:                  acl,
 		NullVersion:          true,
 		DeleteMarker:         false,
-		SseType:              sseRequest.Type,
-		EncryptionKey:        []byte(""),
 		InitializationVector: initializationVector,
 		CustomAttributes:     metadata,
 		Type:                 types.ObjectTypeAppendable,
 		StorageClass:         storageClass,
 	}
+	setObjectKey(object, sseRequest, cipherKey, sseContext)
 
 	result.LastModified = object.LastModifiedTime
+	result.SseType = object.SseType
+	result.SseAwsKmsKeyId = object.SseKmsKeyId
 	result.NextPosition = object.Size
 	helper.Logger.Println(20, "Append info.", "bucket:", bucketName, "objName:", objectName, "oid:", oid,
 		"objSize:", object.Size, "bytesWritten:", bytesWritten, "storageClass:", storageClass)
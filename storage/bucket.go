@@ -19,7 +19,7 @@ import (
 )
 
 func (yig *YigStorage) MakeBucket(bucketName string, acl datatype.Acl,
-	credential common.Credential) error {
+	credential common.Credential, objectLockEnabled bool) error {
 	// Input validation.
 	if err := api.CheckValidBucketName(bucketName); err != nil {
 		return err
@@ -33,6 +33,14 @@ func (yig *YigStorage) MakeBucket(bucketName string, acl datatype.Acl,
 		ACL:        acl,
 		Versioning: meta.VersionDisabled, // it's the default
 	}
+	// object lock works only on versioned objects, and versioning could not
+	// be suspended afterwards
+	if objectLockEnabled {
+		bucket.Versioning = meta.VersionEnabled
+		bucket.ObjectLock = datatype.ObjectLockConfiguration{
+			ObjectLockEnabled: datatype.ObjectLockEnabled,
+		}
+	}
 	processed, err := yig.MetaStorage.Client.CheckAndPutBucket(bucket)
 	if err != nil {
 		helper.Logger.Error("Error making CheckAndPut:", err)
@@ -256,6 +264,10 @@ func (yig *YigStorage) SetBucketVersioning(bucketName string, versioning datatyp
 	if bucket.OwnerId != credential.UserId {
 		return ErrBucketAccessForbidden
 	}
+	if (bucket.ObjectLock.IsEnabled() || !bucket.Replication.IsEmpty()) &&
+		versioning.Status != meta.VersionEnabled {
+		return ErrInvalidBucketState
+	}
 	bucket.Versioning = versioning.Status
 	err = yig.MetaStorage.Client.PutBucket(*bucket)
 	if err != nil {
@@ -478,6 +490,120 @@ func (yig *YigStorage) DeleteBucketEncryption(bucket *meta.Bucket) error {
 	return nil
 }
 
+func (yig *YigStorage) SetBucketTagging(bucket *meta.Bucket, tags map[string]string) (err error) {
+	bucket.Tags = tags
+	err = yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketTagging(bucketName string) (tags map[string]string, err error) {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if len(bucket.Tags) == 0 {
+		return nil, ErrNoSuchTagSet
+	}
+	return bucket.Tags, nil
+}
+
+func (yig *YigStorage) DeleteBucketTagging(bucket *meta.Bucket) error {
+	bucket.Tags = nil
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) SetBucketObjectLock(bucket *meta.Bucket,
+	config datatype.ObjectLockConfiguration) error {
+
+	if !bucket.ObjectLock.IsEnabled() && bucket.Versioning != meta.VersionEnabled {
+		return ErrInvalidBucketState
+	}
+	bucket.ObjectLock = config
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketObjectLock(bucketName string) (config datatype.ObjectLockConfiguration, err error) {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if !bucket.ObjectLock.IsEnabled() {
+		return config, ErrObjectLockConfigurationNotFound
+	}
+	return bucket.ObjectLock, nil
+}
+
+func (yig *YigStorage) SetBucketNotification(bucket *meta.Bucket,
+	config datatype.NotificationConfiguration) error {
+
+	bucket.Notification = config
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketNotification(bucketName string) (config datatype.NotificationConfiguration, err error) {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	return bucket.Notification, nil
+}
+
+// SetBucketReplication requires versioning enabled, as S3 does
+func (yig *YigStorage) SetBucketReplication(bucket *meta.Bucket,
+	config datatype.ReplicationConfiguration) error {
+
+	if bucket.Versioning != meta.VersionEnabled {
+		return ErrInvalidBucketState
+	}
+	bucket.Replication = config
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketReplication(bucketName string) (config datatype.ReplicationConfiguration, err error) {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if bucket.Replication.IsEmpty() {
+		return config, ErrReplicationConfigurationNotFound
+	}
+	return bucket.Replication, nil
+}
+
+func (yig *YigStorage) DeleteBucketReplication(bucket *meta.Bucket) error {
+	bucket.Replication = datatype.ReplicationConfiguration{}
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
 func (yig *YigStorage) CheckBucketEncryption(bucketName string) (*datatype.ApplyServerSideEncryptionByDefault, bool) {
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
@@ -491,12 +617,10 @@ func (yig *YigStorage) CheckBucketEncryption(bucketName string) (*datatype.Apply
 		return nil, false
 	}
 	configuration := bucketEncryption.Rules[0].ApplyServerSideEncryptionByDefault
-	if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 {
+	if configuration.SSEAlgorithm == crypto.SSEAlgorithmAES256 ||
+		configuration.SSEAlgorithm == crypto.SSEAlgorithmKMS {
 		return configuration, true
 	}
-	//if bucketEncryption.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm == crypto.SSEAlgorithmKMS {
-	//	return configuration, nil
-	//}
 	return nil, false
 }
 
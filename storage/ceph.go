@@ -0,0 +1,9 @@
+// +build !noceph
+
+package storage
+
+import "github.com/journeymidnight/yig/ceph"
+
+func init() {
+	dataStores["ceph"] = ceph.Initialize
+}
@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"time"
+
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
 	meta "github.com/journeymidnight/yig/meta/types"
 )
 
@@ -36,3 +40,84 @@ func (yig *YigStorage) UpdateFreezerDate(freezer *meta.Freezer, date int, isIncr
 	freezer.LifeTime = lifeTime
 	return yig.MetaStorage.UpdateFreezerDate(freezer)
 }
+
+func (yig *YigStorage) ScanFreezers(limit int, startRowKey string, status meta.Status) ([]meta.Freezer, error) {
+	return yig.MetaStorage.ScanFreezers(limit, startRowKey, status)
+}
+
+// RestoreObject copies data of the GLACIER object requested to restore into a standard pool,
+// and marks the freezer restored. The freezer is skipped if restoring by others.
+func (yig *YigStorage) RestoreObject(freezer *meta.Freezer) (err error) {
+	claimed, err := yig.MetaStorage.UpdateFreezerStatus(freezer.BucketName, freezer.Name,
+		meta.ObjectNeedRestore, meta.ObjectRestoring)
+	if err != nil || !claimed {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// try again later
+			_, e := yig.MetaStorage.UpdateFreezerStatus(freezer.BucketName, freezer.Name,
+				meta.ObjectRestoring, meta.ObjectNeedRestore)
+			if e != nil {
+				helper.Logger.Error("Failed to reset status of freezer", freezer.BucketName, freezer.Name,
+					"error:", e)
+			}
+		}
+	}()
+
+	object, err := yig.MetaStorage.GetObject(freezer.BucketName, freezer.Name, false)
+	if err == ErrNoSuchKey || (err == nil && object.StorageClass != meta.ObjectStorageClassGlacier) {
+		// deleted or transited since requested, nothing to restore
+		helper.Logger.Info("Object", freezer.BucketName, freezer.Name, "not in GLACIER, remove freezer")
+		return yig.MetaStorage.Client.DeleteFreezer(freezer.BucketName, freezer.Name, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	copied, err := yig.copyObjectData(object, meta.ObjectStorageClassStandard)
+	if err != nil {
+		return err
+	}
+	restored := &meta.Freezer{
+		Name:             freezer.Name,
+		BucketName:       freezer.BucketName,
+		Location:         copied.Location,
+		Pool:             copied.Pool,
+		OwnerId:          copied.OwnerId,
+		Size:             copied.Size,
+		ObjectId:         copied.ObjectId,
+		LastModifiedTime: time.Now(),
+		Etag:             copied.Etag,
+		Parts:            copied.Parts,
+	}
+	err = yig.MetaStorage.PutFreezer(restored, meta.ObjectHasRestored)
+	if err != nil {
+		yig.recycleObjectData(&copied)
+		return err
+	}
+	return nil
+}
+
+// ExpireFreezer removes the restored copy of freezer after its LifeTime,
+// returns whether it is expired
+func (yig *YigStorage) ExpireFreezer(freezer *meta.Freezer, now time.Time) (expired bool, err error) {
+	if now.Before(freezer.ExpiryDate()) {
+		return false, nil
+	}
+	// LifeTime is extended if restore requested again
+	stored, err := yig.MetaStorage.GetFreezer(freezer.BucketName, freezer.Name, "")
+	if err == ErrNoSuchKey {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if stored.Status != meta.ObjectHasRestored || now.Before(stored.ExpiryDate()) {
+		return false, nil
+	}
+	err = yig.MetaStorage.DeleteFreezer(stored)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
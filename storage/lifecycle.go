@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/backend"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam/common"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// TransitObject moves data of the object into the pool of storageClass.
+// Data is copied as is, so encrypted objects stay encrypted with the same keys.
+// The old data is put into gc table along with the metadata update.
+// If the object is changed while copying, the copy is recycled and the object is left as is.
+func (yig *YigStorage) TransitObject(object *meta.Object, storageClass meta.StorageClass) (err error) {
+	targetObject, err := yig.copyObjectData(object, storageClass)
+	if err != nil {
+		return err
+	}
+	targetObject.StorageClass = storageClass
+
+	transited, err := yig.MetaStorage.TransitObject(&targetObject, object)
+	if err != nil {
+		helper.Logger.Error("Transit object", object.BucketName, object.Name,
+			object.VersionId, "sql fails:", err)
+		yig.recycleObjectData(&targetObject)
+		return ErrInternalError
+	}
+	if !transited {
+		yig.recycleObjectData(&targetObject)
+		return errors.New("object is changed while transiting")
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	yig.DataCache.Remove(object.BucketName + ":" + object.Name + ":" + object.GetVersionId())
+	return nil
+}
+
+// copyObjectData copies data of the object into a pool of storageClass,
+// and returns the object located at the copy.
+// Data copied is recycled if copying fails.
+func (yig *YigStorage) copyObjectData(object *meta.Object,
+	storageClass meta.StorageClass) (copied meta.Object, err error) {
+
+	sourceCluster, ok := yig.DataStorage[object.Location]
+	if !ok {
+		return copied, errors.New("Cannot find specified ceph cluster: " + object.Location)
+	}
+	targetCluster, poolName := yig.pickClusterAndPool(object.BucketName, object.Name,
+		storageClass, object.Size, false)
+
+	var written []objectToRecycle
+	defer func() {
+		if err != nil {
+			for _, o := range written {
+				yig.recycleObject(o)
+			}
+		}
+	}()
+	transit := func(objectId string, size int64) (oid string, err error) {
+		oid, err = yig.transitData(sourceCluster, object.Pool, objectId, targetCluster, poolName, size)
+		if err != nil {
+			return
+		}
+		written = append(written, objectToRecycle{
+			location: targetCluster.ID(),
+			pool:     poolName,
+			objectId: oid,
+		})
+		return
+	}
+
+	copied = *object
+	if len(object.Parts) == 0 {
+		copied.ObjectId, err = transit(object.ObjectId, object.Size)
+		if err != nil {
+			return
+		}
+	} else {
+		copied.Parts = make(map[int]*meta.Part, len(object.Parts))
+		for partNumber, part := range object.Parts {
+			targetPart := *part
+			targetPart.ObjectId, err = transit(part.ObjectId, part.Size)
+			if err != nil {
+				return
+			}
+			copied.Parts[partNumber] = &targetPart
+		}
+	}
+	copied.Location = targetCluster.ID()
+	copied.Pool = poolName
+	return copied, nil
+}
+
+// recycleObjectData removes data of the object asynchronously
+func (yig *YigStorage) recycleObjectData(object *meta.Object) {
+	if len(object.Parts) == 0 {
+		yig.recycleObject(objectToRecycle{
+			location: object.Location,
+			pool:     object.Pool,
+			objectId: object.ObjectId,
+		})
+		return
+	}
+	for _, part := range object.Parts {
+		yig.recycleObject(objectToRecycle{
+			location: object.Location,
+			pool:     object.Pool,
+			objectId: part.ObjectId,
+		})
+	}
+}
+
+// ExpireObject expires the current version of object the same as DeleteObject without version ID,
+// so it's removed in unversioned buckets, and a delete marker is created in versioned buckets.
+func (yig *YigStorage) ExpireObject(object *meta.Object) (result datatype.DeleteObjectResult, err error) {
+	return yig.DeleteObject(object.BucketName, object.Name, "", common.Credential{}, false)
+}
+
+// ExpireObjectVersion permanently removes the specified version of object
+// or delete marker, regardless of bucket versioning status.
+// Versions protected by object lock are never expired.
+func (yig *YigStorage) ExpireObjectVersion(object *meta.Object) (err error) {
+	if object.ObjectLock.IsLocked(false) {
+		return ErrObjectLocked
+	}
+	if object.StorageClass == meta.ObjectStorageClassGlacier {
+		err = yig.removeCurrentFreezer(object)
+		if err != nil {
+			return err
+		}
+	}
+	var objMap *meta.ObjMap
+	if object.NullVersion {
+		objMap = &meta.ObjMap{
+			Name:       object.Name,
+			BucketName: object.BucketName,
+		}
+	}
+	err = yig.removeByObject(object, objMap)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	yig.DataCache.Remove(object.BucketName + ":" + object.Name + ":" + object.GetVersionId())
+	return nil
+}
+
+// removeCurrentFreezer removes the restore state of object name if the version is the current one,
+// since objects are restored by name, and the restored copy is of the current version
+func (yig *YigStorage) removeCurrentFreezer(object *meta.Object) error {
+	current, err := yig.MetaStorage.Client.GetObject(object.BucketName, object.Name, "")
+	if err != nil && err != ErrNoSuchKey {
+		return err
+	}
+	if err == nil && !current.LastModifiedTime.Equal(object.LastModifiedTime) {
+		return nil
+	}
+	return yig.removeFreezer(object.BucketName, object.Name)
+}
+
+func (yig *YigStorage) transitData(source backend.Cluster, sourcePool string, objectId string,
+	target backend.Cluster, targetPool string, size int64) (oid string, err error) {
+
+	reader, err := source.GetReader(sourcePool, objectId, 0, uint64(size))
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	oid, bytesWritten, err := target.Put(targetPool, reader)
+	if err != nil {
+		return
+	}
+	if int64(bytesWritten) < size {
+		yig.recycleObject(objectToRecycle{
+			location: target.ID(),
+			pool:     targetPool,
+			objectId: oid,
+		})
+		return "", ErrIncompleteBody
+	}
+	return oid, nil
+}
@@ -84,7 +84,8 @@ func (yig *YigStorage) ListMultipartUploads(credential common.Credential, bucket
 
 func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketName, objectName string,
 	metadata map[string]string, acl datatype.Acl,
-	sseRequest datatype.SseRequest, storageClass meta.StorageClass) (uploadId string, err error) {
+	sseRequest datatype.SseRequest, storageClass meta.StorageClass,
+	tags map[string]string, objectLock datatype.ObjectLock) (uploadId string, err error) {
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
@@ -100,6 +101,12 @@ func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketNa
 	}
 	// TODO policy and fancy ACL
 
+	// default retention of bucket is applied when the upload completes
+	_, err = bucket.ObjectLock.LockForNewObject(objectLock, time.Now().UTC())
+	if err != nil {
+		return
+	}
+
 	contentType, ok := metadata["Content-Type"]
 	if !ok {
 		contentType = "application/octet-stream"
@@ -113,18 +120,25 @@ func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketNa
 		Location:     cephCluster.ID(),
 		Pool:         pool,
 		Acl:          acl,
-		SseRequest:   sseRequest,
 		Attrs:        metadata,
 		StorageClass: storageClass,
+		Tags:         tags,
+		ObjectLock:   objectLock,
 	}
-	if sseRequest.Type == crypto.S3.String() {
-		multipartMetadata.EncryptionKey, multipartMetadata.CipherKey, err = yig.encryptionKeyFromSseRequest(sseRequest, bucketName, objectName)
+	switch sseRequest.Type {
+	case crypto.S3.String():
+		multipartMetadata.EncryptionKey, multipartMetadata.CipherKey, _, err = yig.encryptionKeyFromSseRequest(&sseRequest, bucketName, objectName)
+		if err != nil {
+			return
+		}
+	case crypto.S3KMS.String():
+		// only the sealed key is kept, it's unsealed for every part
+		_, multipartMetadata.CipherKey, _, err = yig.encryptionKeyFromSseRequest(&sseRequest, bucketName, objectName)
 		if err != nil {
 			return
 		}
-	} else {
-		multipartMetadata.EncryptionKey = nil
 	}
+	multipartMetadata.SseRequest = sseRequest
 
 	multipart := meta.Multipart{
 		BucketName:  bucketName,
@@ -141,6 +155,22 @@ func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketNa
 	return
 }
 
+// unsealMultipartKey returns the data key of SSE-KMS multipart upload
+func (yig *YigStorage) unsealMultipartKey(multipart meta.Multipart) ([]byte, error) {
+	if yig.KMS == nil {
+		return nil, ErrKMSNotConfigured
+	}
+	keyId, context, err := MultipartKeyContext(yig.KMS, &multipart)
+	if err != nil {
+		return nil, err
+	}
+	key, err := yig.KMS.UnsealKey(keyId, multipart.Metadata.CipherKey, context)
+	if err != nil {
+		return nil, err
+	}
+	return key[:], nil
+}
+
 func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential common.Credential,
 	uploadId string, partId int, size int64, data io.ReadCloser, md5Hex string,
 	sseRequest datatype.SseRequest) (result datatype.PutObjectPartResult, err error) {
@@ -169,8 +199,10 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	case crypto.S3.String():
 		encryptionKey = multipart.Metadata.EncryptionKey
 	case crypto.S3KMS.String():
-		err = ErrNotImplemented
-		return
+		encryptionKey, err = yig.unsealMultipartKey(multipart)
+		if err != nil {
+			return
+		}
 	}
 
 	md5Writer := md5.New()
@@ -198,7 +230,7 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, add `maybeObjectToRecycle` to `recycleQueue`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cluster.ID(),
@@ -206,14 +238,14 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 		objectId: objectId,
 	}
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		err = ErrIncompleteBody
 		return
 	}
 
 	calculatedMd5 := hex.EncodeToString(md5Writer.Sum(nil))
 	if md5Hex != "" && md5Hex != calculatedMd5 {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		err = ErrBadDigest
 		return
 	}
@@ -221,14 +253,14 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	if signVerifyReader, ok := data.(*signature.SignVerifyReadCloser); ok {
 		credential, err = signVerifyReader.Verify()
 		if err != nil {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return
 		}
 	}
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 	switch bucket.ACL.CannedAcl {
@@ -236,7 +268,7 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 		break
 	default:
 		if bucket.OwnerId != credential.UserId {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return result, ErrBucketAccessForbidden
 		}
 	} // TODO policy and fancy ACL
@@ -251,21 +283,21 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	}
 	err = yig.MetaStorage.PutObjectPart(multipart, part)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 	// remove possible old object in Ceph
 	if part, ok := multipart.Parts[partId]; ok {
-		RecycleQueue <- objectToRecycle{
+		yig.recycleObject(objectToRecycle{
 			location: multipart.Metadata.Location,
 			pool:     multipart.Metadata.Pool,
 			objectId: part.ObjectId,
-		}
+		})
 	}
 
 	result.ETag = calculatedMd5
-	result.SseType = sseRequest.Type
-	result.SseAwsKmsKeyIdBase64 = base64.StdEncoding.EncodeToString([]byte(sseRequest.SseAwsKmsKeyId))
+	result.SseType = multipart.Metadata.SseRequest.Type
+	result.SseAwsKmsKeyId = multipart.Metadata.SseRequest.SseAwsKmsKeyId
 	result.SseCustomerAlgorithm = sseRequest.SseCustomerAlgorithm
 	result.SseCustomerKeyMd5Base64 = base64.StdEncoding.EncodeToString(sseRequest.SseCustomerKey)
 	return result, nil
@@ -298,8 +330,10 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 	case crypto.S3.String():
 		encryptionKey = multipart.Metadata.EncryptionKey
 	case crypto.S3KMS.String():
-		err = ErrNotImplemented
-		return
+		encryptionKey, err = yig.unsealMultipartKey(multipart)
+		if err != nil {
+			return
+		}
 	}
 
 	md5Writer := md5.New()
@@ -327,7 +361,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, add `maybeObjectToRecycle` to `recycleQueue`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cephCluster.ID(),
@@ -336,7 +370,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 	}
 
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		err = ErrIncompleteBody
 		return
 	}
@@ -345,7 +379,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 	switch bucket.ACL.CannedAcl {
@@ -353,7 +387,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 		break
 	default:
 		if bucket.OwnerId != credential.UserId {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			err = ErrBucketAccessForbidden
 			return
 		}
@@ -375,17 +409,17 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 
 	err = yig.MetaStorage.PutObjectPart(multipart, part)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 
 	// remove possible old object in Ceph
 	if part, ok := multipart.Parts[partId]; ok {
-		RecycleQueue <- objectToRecycle{
+		yig.recycleObject(objectToRecycle{
 			location: multipart.Metadata.Location,
 			pool:     multipart.Metadata.Pool,
 			objectId: part.ObjectId,
-		}
+		})
 	}
 
 	return result, nil
@@ -496,20 +530,11 @@ func (yig *YigStorage) AbortMultipartUpload(credential common.Credential,
 		return err
 	}
 
+	// parts in Ceph are removed by gc
 	err = yig.MetaStorage.DeleteMultipart(multipart)
 	if err != nil {
 		return err
 	}
-	// remove parts in Ceph
-	var removedSize int64 = 0
-	for _, p := range multipart.Parts {
-		RecycleQueue <- objectToRecycle{
-			location: multipart.Metadata.Location,
-			pool:     multipart.Metadata.Pool,
-			objectId: p.ObjectId,
-		}
-		removedSize += p.Size
-	}
 
 	return nil
 }
@@ -608,6 +633,19 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 		CustomAttributes: multipart.Metadata.Attrs,
 		Type:             meta.ObjectTypeMultipart,
 		StorageClass:     multipart.Metadata.StorageClass,
+		Tags:             multipart.Metadata.Tags,
+	}
+	if multipart.Metadata.SseRequest.Type == crypto.S3KMS.String() {
+		object.SseKmsKeyId = multipart.Metadata.SseRequest.SseAwsKmsKeyId
+		object.SseContext, err = sseKmsContext(multipart.Metadata.SseRequest, bucketName, objectName)
+		if err != nil {
+			return
+		}
+	}
+	object.ObjectLock, err = bucket.ObjectLock.LockForNewObject(multipart.Metadata.ObjectLock,
+		object.LastModifiedTime)
+	if err != nil {
+		return
 	}
 
 	var nullVerNum uint64
@@ -640,6 +678,7 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 		}
 	}
 
+	setReplicationStatus(bucket, object)
 	if nullVerNum != 0 {
 		err = yig.MetaStorage.PutObject(object, &multipart, objMap, false)
 	} else {
@@ -648,7 +687,7 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 
 	sseRequest := multipart.Metadata.SseRequest
 	result.SseType = sseRequest.Type
-	result.SseAwsKmsKeyIdBase64 = base64.StdEncoding.EncodeToString([]byte(sseRequest.SseAwsKmsKeyId))
+	result.SseAwsKmsKeyId = sseRequest.SseAwsKmsKeyId
 	result.SseCustomerAlgorithm = sseRequest.SseCustomerAlgorithm
 	result.SseCustomerKeyMd5Base64 = base64.StdEncoding.EncodeToString(sseRequest.SseCustomerKey)
 
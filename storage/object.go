@@ -6,7 +6,6 @@ import (
 	"errors"
 	"io"
 	"math/rand"
-	"path"
 	"sync"
 	"time"
 
@@ -20,6 +19,7 @@ import (
 	"github.com/journeymidnight/yig/iam/common"
 	meta "github.com/journeymidnight/yig/meta/types"
 	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/s3gateway"
 	"github.com/journeymidnight/yig/signature"
 )
 
@@ -33,12 +33,37 @@ func (yig *YigStorage) pickRandomCluster() (cluster backend.Cluster) {
 	helper.Logger.Warn("Error picking cluster from table cluster in DB, " +
 		"use first cluster in config to write.")
 	for _, c := range yig.DataStorage {
+		if _, ok := c.(*s3gateway.GatewayCluster); ok {
+			continue
+		}
 		cluster = c
 		break
 	}
 	return
 }
 
+// pickGatewayCluster returns a random gateway for the storage class,
+// nil if no gateway is configured for it
+func (yig *YigStorage) pickGatewayCluster(storageClass meta.StorageClass) (cluster backend.Cluster) {
+	var gateways []backend.Cluster
+	for _, c := range yig.DataStorage {
+		gateway, ok := c.(*s3gateway.GatewayCluster)
+		if !ok {
+			continue
+		}
+		for _, class := range gateway.StorageClasses {
+			if class == storageClass.ToString() {
+				gateways = append(gateways, gateway)
+				break
+			}
+		}
+	}
+	if len(gateways) == 0 {
+		return nil
+	}
+	return gateways[rand.Intn(len(gateways))]
+}
+
 func (yig *YigStorage) pickClusterAndPool(bucket string, object string, storageClass meta.StorageClass,
 	size int64, isAppend bool) (cluster backend.Cluster, poolName string) {
 
@@ -61,6 +86,9 @@ func (yig *YigStorage) pickClusterAndPool(bucket string, object string, storageC
 			idx = 1
 		}
 	}
+	if cluster = yig.pickGatewayCluster(storageClass); cluster != nil {
+		return
+	}
 	var needCheck bool
 	queryTime := latestQueryTime[idx]
 	if time.Since(queryTime).Hours() > 24 { // check used space every 24 hours
@@ -186,16 +214,11 @@ func getAlignedReader(cluster backend.Cluster, poolName, objectName string,
 func (yig *YigStorage) GetObject(object *meta.Object, startOffset int64,
 	length int64, writer io.Writer, sseRequest datatype.SseRequest) (err error) {
 	var encryptionKey []byte
-	if object.SseType == crypto.S3.String() {
-		if yig.KMS == nil {
-			return ErrKMSNotConfigured
-		}
-		key, err := yig.KMS.UnsealKey(yig.KMS.GetKeyID(), object.EncryptionKey,
-			crypto.Context{object.BucketName: path.Join(object.BucketName, object.Name)})
+	if object.SseType == crypto.S3.String() || object.SseType == crypto.S3KMS.String() {
+		encryptionKey, err = yig.unsealObjectKey(object)
 		if err != nil {
 			return err
 		}
-		encryptionKey = key[:]
 	} else { // SSE-C
 		if len(sseRequest.CopySourceSseCustomerKey) != 0 {
 			encryptionKey = sseRequest.CopySourceSseCustomerKey
@@ -493,6 +516,154 @@ func (yig *YigStorage) SetObjectAcl(bucketName string, objectName string, versio
 	return nil
 }
 
+func (yig *YigStorage) getObjectForSubresource(bucketName string, objectName string, version string,
+	credential common.Credential) (object *meta.Object, err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if version == "" {
+		object, err = yig.MetaStorage.GetObject(bucketName, objectName, false)
+	} else {
+		object, err = yig.getObjWithVersion(bucketName, objectName, version)
+	}
+	if err != nil {
+		return
+	}
+	if object.DeleteMarker {
+		return nil, ErrNoSuchKey
+	}
+	if !credential.AllowOtherUserAccess {
+		if bucket.OwnerId != credential.UserId && object.OwnerId != credential.UserId {
+			return nil, ErrAccessDenied
+		}
+	}
+	return
+}
+
+func (yig *YigStorage) SetObjectTagging(bucketName string, objectName string, version string,
+	tags map[string]string, credential common.Credential) error {
+
+	object, err := yig.getObjectForSubresource(bucketName, objectName, version, credential)
+	if err != nil {
+		return err
+	}
+	object.Tags = tags
+	err = yig.MetaStorage.UpdateObjectTags(object)
+	if err != nil {
+		helper.Logger.Error("Update Object Tags, sql fails:", err)
+		return ErrInternalError
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":")
+	// the version resolved if not specified is cached by its ID too
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":"+object.GetVersionId())
+	return nil
+}
+
+func (yig *YigStorage) GetObjectTagging(bucketName string, objectName string, version string,
+	credential common.Credential) (tags map[string]string, err error) {
+
+	object, err := yig.getObjectForSubresource(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	return object.Tags, nil
+}
+
+func (yig *YigStorage) DeleteObjectTagging(bucketName string, objectName string, version string,
+	credential common.Credential) error {
+
+	return yig.SetObjectTagging(bucketName, objectName, version, nil, credential)
+}
+
+func (yig *YigStorage) getObjectForLock(bucketName string, objectName string, version string,
+	credential common.Credential) (object *meta.Object, err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if !bucket.ObjectLock.IsEnabled() {
+		return nil, ErrInvalidBucketObjectLockConfiguration
+	}
+	return yig.getObjectForSubresource(bucketName, objectName, version, credential)
+}
+
+func (yig *YigStorage) updateObjectLock(object *meta.Object) error {
+	err := yig.MetaStorage.UpdateObjectLock(object)
+	if err != nil {
+		helper.Logger.Error("Update Object Lock, sql fails:", err)
+		return ErrInternalError
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	// the version resolved if not specified is cached by its ID too
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	return nil
+}
+
+// SetObjectRetention replaces the retention of object version.
+// COMPLIANCE retention could only be extended, GOVERNANCE retention could be
+// shortened or removed only if bypassGovernance is set.
+func (yig *YigStorage) SetObjectRetention(bucketName string, objectName string, version string,
+	retention datatype.ObjectLock, bypassGovernance bool, credential common.Credential) error {
+
+	object, err := yig.getObjectForLock(bucketName, objectName, version, credential)
+	if err != nil {
+		return err
+	}
+	if !object.ObjectLock.CanChangeRetention(retention, bypassGovernance) {
+		return ErrObjectLocked
+	}
+	object.ObjectLock.Mode = retention.Mode
+	object.ObjectLock.RetainUntilDate = retention.RetainUntilDate
+	return yig.updateObjectLock(object)
+}
+
+func (yig *YigStorage) GetObjectRetention(bucketName string, objectName string, version string,
+	credential common.Credential) (retention datatype.ObjectLock, err error) {
+
+	object, err := yig.getObjectForLock(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	if object.ObjectLock.Mode == "" {
+		return retention, ErrNoSuchObjectLockConfiguration
+	}
+	retention.Mode = object.ObjectLock.Mode
+	retention.RetainUntilDate = object.ObjectLock.RetainUntilDate
+	return retention, nil
+}
+
+func (yig *YigStorage) SetObjectLegalHold(bucketName string, objectName string, version string,
+	legalHold bool, credential common.Credential) error {
+
+	object, err := yig.getObjectForLock(bucketName, objectName, version, credential)
+	if err != nil {
+		return err
+	}
+	object.ObjectLock.LegalHold = legalHold
+	return yig.updateObjectLock(object)
+}
+
+func (yig *YigStorage) GetObjectLegalHold(bucketName string, objectName string, version string,
+	credential common.Credential) (legalHold bool, err error) {
+
+	object, err := yig.getObjectForLock(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	return object.ObjectLock.LegalHold, nil
+}
+
+// setReplicationStatus marks new object versions matched by bucket replication rules
+// as pending, MetaStorage.PutObject queues them for tools/replication in the same transaction
+func setReplicationStatus(bucket *meta.Bucket, object *meta.Object) {
+	if _, ok := bucket.Replication.MatchRule(object.Name, object.Tags); ok {
+		object.ReplicationStatus = datatype.ReplicationStatusPending
+	}
+}
+
 // Write path:
 //                                           +-----------+
 // PUT object/part                           |           |   Ceph
@@ -506,10 +677,11 @@ func (yig *YigStorage) SetObjectAcl(bucketName string, objectName string, versio
 // Encryptor is enabled when user set SSE headers
 func (yig *YigStorage) PutObject(bucketName string, objectName string, credential common.Credential,
 	size int64, data io.ReadCloser, metadata map[string]string, acl datatype.Acl,
-	sseRequest datatype.SseRequest, storageClass meta.StorageClass) (result datatype.PutObjectResult, err error) {
+	sseRequest datatype.SseRequest, storageClass meta.StorageClass,
+	tags map[string]string, objectLock datatype.ObjectLock) (result datatype.PutObjectResult, err error) {
 
 	defer data.Close()
-	encryptionKey, cipherKey, err := yig.encryptionKeyFromSseRequest(sseRequest, bucketName, objectName)
+	encryptionKey, cipherKey, sseContext, err := yig.encryptionKeyFromSseRequest(&sseRequest, bucketName, objectName)
 	helper.Logger.Info("get encryptionKey:", encryptionKey, "cipherKey:", cipherKey, "err:", err)
 	if err != nil {
 		return
@@ -530,6 +702,11 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		}
 	}
 
+	objectLock, err = bucket.ObjectLock.LockForNewObject(objectLock, time.Now().UTC())
+	if err != nil {
+		return
+	}
+
 	md5Writer := md5.New()
 
 	// Limit the reader to its provided size if specified.
@@ -563,7 +740,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, add `maybeObjectToRecycle` to `recycleQueue`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cluster.ID(),
@@ -571,7 +748,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		objectId: objectId,
 	}
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		helper.Logger.Error("Failed to write objects, already written",
 			bytesWritten, "total size", size)
 		return result, ErrIncompleteBody
@@ -581,7 +758,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	helper.Logger.Info("CalculatedMd5:", calculatedMd5, "userMd5:", metadata["md5Sum"])
 	if userMd5, ok := metadata["md5Sum"]; ok {
 		if userMd5 != "" && userMd5 != calculatedMd5 {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return result, ErrBadDigest
 		}
 	}
@@ -591,39 +768,41 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	if signVerifyReader, ok := data.(*signature.SignVerifyReadCloser); ok {
 		credential, err = signVerifyReader.Verify()
 		if err != nil {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return
 		}
 	}
 	// TODO validate bucket policy and fancy ACL
 	object := &meta.Object{
-		Name:             objectName,
-		BucketName:       bucketName,
-		Location:         cluster.ID(),
-		Pool:             poolName,
-		OwnerId:          credential.UserId,
-		Size:             int64(bytesWritten),
-		ObjectId:         objectId,
-		LastModifiedTime: time.Now().UTC(),
-		Etag:             calculatedMd5,
-		ContentType:      metadata["Content-Type"],
-		ACL:              acl,
-		NullVersion:      helper.Ternary(bucket.Versioning == "Enabled", false, true).(bool),
-		DeleteMarker:     false,
-		SseType:          sseRequest.Type,
-		EncryptionKey: helper.Ternary(sseRequest.Type == crypto.S3.String(),
-			cipherKey, []byte("")).([]byte),
+		Name:                 objectName,
+		BucketName:           bucketName,
+		Location:             cluster.ID(),
+		Pool:                 poolName,
+		OwnerId:              credential.UserId,
+		Size:                 int64(bytesWritten),
+		ObjectId:             objectId,
+		LastModifiedTime:     time.Now().UTC(),
+		Etag:                 calculatedMd5,
+		ContentType:          metadata["Content-Type"],
+		ACL:                  acl,
+		NullVersion:          helper.Ternary(bucket.Versioning == "Enabled", false, true).(bool),
+		DeleteMarker:         false,
 		InitializationVector: initializationVector,
 		CustomAttributes:     metadata,
 		Type:                 meta.ObjectTypeNormal,
 		StorageClass:         storageClass,
+		Tags:                 tags,
+		ObjectLock:           objectLock,
 	}
+	setObjectKey(object, sseRequest, cipherKey, sseContext)
+	result.SseAwsKmsKeyId = object.SseKmsKeyId
 
 	result.LastModified = object.LastModifiedTime
+	result.Size = object.Size
 	var nullVerNum uint64
 	nullVerNum, err = yig.checkOldObject(bucketName, objectName, bucket.Versioning)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 	if bucket.Versioning == meta.VersionEnabled {
@@ -646,6 +825,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		}
 	}
 
+	setReplicationStatus(bucket, object)
 	if nullVerNum != 0 {
 		objMap := &meta.ObjMap{
 			Name:       objectName,
@@ -657,7 +837,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	}
 
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 
@@ -704,6 +884,9 @@ func (yig *YigStorage) RenameObject(targetObject *meta.Object, sourceObject stri
 			return result, ErrBucketAccessForbidden
 		}
 	}
+	if targetObject.ObjectLock.IsLocked(false) {
+		return result, ErrObjectLocked
+	}
 
 	if len(targetObject.Parts) != 0 {
 		err = yig.MetaStorage.RenameObjectPart(targetObject, sourceObject)
@@ -732,7 +915,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 	var oid string
 	var maybeObjectToRecycle objectToRecycle
 	var encryptionKey []byte
-	encryptionKey, cipherKey, err := yig.encryptionKeyFromSseRequest(sseRequest, targetObject.BucketName, targetObject.Name)
+	encryptionKey, cipherKey, sseContext, err := yig.encryptionKeyFromSseRequest(&sseRequest, targetObject.BucketName, targetObject.Name)
 	if err != nil {
 		return
 	}
@@ -751,6 +934,14 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		}
 	}
 
+	if !isMetadataOnly {
+		targetObject.ObjectLock, err = bucket.ObjectLock.LockForNewObject(targetObject.ObjectLock,
+			time.Now().UTC())
+		if err != nil {
+			return
+		}
+	}
+
 	if isMetadataOnly {
 		if sourceObject.StorageClass == meta.ObjectStorageClassGlacier {
 			targetObject.LastModifiedTime = sourceObject.LastModifiedTime
@@ -767,6 +958,8 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 			yig.DataCache.Remove(targetObject.BucketName + ":" + targetObject.Name + ":" + targetObject.GetVersionId())
 			return result, nil
 		}
+		// metas of the source version are replaced, other versions are untouched
+		targetObject.LastModifiedTime = sourceObject.LastModifiedTime
 		err = yig.MetaStorage.ReplaceObjectMetas(targetObject)
 		if err != nil {
 			helper.Logger.Error("Copy Object with same source and target, sql fails:", err)
@@ -825,7 +1018,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 					objectId: oid,
 				}
 				if bytesW < uint64(part.Size) {
-					RecycleQueue <- maybeObjectToRecycle
+					yig.recycleObject(maybeObjectToRecycle)
 					return result, ErrIncompleteBody
 				}
 				if err != nil {
@@ -835,7 +1028,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 				//we will only chack part etag,overall etag will be same if each part of etag is same
 				if calculatedMd5 != part.Etag {
 					err = ErrInternalError
-					RecycleQueue <- maybeObjectToRecycle
+					yig.recycleObject(maybeObjectToRecycle)
 					return result, err
 				}
 				part.LastModified = time.Now().UTC().Format(meta.CREATE_TIME_LAYOUT)
@@ -873,7 +1066,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		if err != nil {
 			return
 		}
-		// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+		// Should metadata update failed, add `maybeObjectToRecycle` to `recycleQueue`,
 		// so the object in Ceph could be removed asynchronously
 		maybeObjectToRecycle = objectToRecycle{
 			location: cephCluster.ID(),
@@ -881,13 +1074,13 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 			objectId: oid,
 		}
 		if int64(bytesWritten) < targetObject.Size {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return result, ErrIncompleteBody
 		}
 
 		calculatedMd5 := hex.EncodeToString(md5Writer.Sum(nil))
 		if calculatedMd5 != targetObject.Etag {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycleObject(maybeObjectToRecycle)
 			return result, ErrBadDigest
 		}
 		result.Md5 = calculatedMd5
@@ -904,16 +1097,15 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 	targetObject.LastModifiedTime = time.Now().UTC()
 	targetObject.NullVersion = helper.Ternary(bucket.Versioning == "Enabled", false, true).(bool)
 	targetObject.DeleteMarker = false
-	targetObject.SseType = sseRequest.Type
-	targetObject.EncryptionKey = helper.Ternary(sseRequest.Type == crypto.S3.String(),
-		cipherKey, []byte("")).([]byte)
+	setObjectKey(targetObject, sseRequest, cipherKey, sseContext)
+	result.SseAwsKmsKeyId = targetObject.SseKmsKeyId
 
 	result.LastModified = targetObject.LastModifiedTime
 
 	var nullVerNum uint64
 	nullVerNum, err = yig.checkOldObject(targetObject.BucketName, targetObject.Name, bucket.Versioning)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 	if bucket.Versioning == "Enabled" {
@@ -934,6 +1126,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		result.LastModified = targetObject.LastModifiedTime
 		err = yig.MetaStorage.UpdateGlacierObject(targetObject, sourceObject, false)
 	} else {
+		setReplicationStatus(bucket, targetObject)
 		if nullVerNum != 0 {
 			objMap.NullVerNum = nullVerNum
 			err = yig.MetaStorage.PutObject(targetObject, nil, objMap, true)
@@ -943,7 +1136,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 	}
 
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycleObject(maybeObjectToRecycle)
 		return
 	}
 
@@ -983,17 +1176,15 @@ func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string
 	if err != nil {
 		return err
 	}
+	for _, obj := range objs {
+		if obj.ObjectLock.IsLocked(false) {
+			return ErrObjectLocked
+		}
+	}
 	for _, obj := range objs {
 		if obj.StorageClass == meta.ObjectStorageClassGlacier {
-			freezer, err := yig.GetFreezer(bucketName, objectName, "")
-			if err == nil {
-				if freezer.Name == objectName {
-					err = yig.MetaStorage.DeleteFreezer(freezer)
-					if err != nil {
-						return err
-					}
-				}
-			} else if err != ErrNoSuchKey {
+			err = yig.removeFreezer(bucketName, objectName)
+			if err != nil {
 				return err
 			}
 		}
@@ -1005,6 +1196,18 @@ func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string
 	return
 }
 
+func (yig *YigStorage) removeFreezer(bucketName, objectName string) error {
+	freezer, err := yig.GetFreezer(bucketName, objectName, "")
+	if err == nil {
+		if freezer.Name == objectName {
+			return yig.MetaStorage.DeleteFreezer(freezer)
+		}
+	} else if err != ErrNoSuchKey {
+		return err
+	}
+	return nil
+}
+
 func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string) (version uint64, err error) {
 
 	if versioning == meta.VersionDisabled {
@@ -1056,6 +1259,9 @@ func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string)
 		} else {
 			helper.Logger.Info("object.NullVersion:", object.NullVersion)
 			if objectExist && object.NullVersion {
+				if object.ObjectLock.IsLocked(false) {
+					return 0, ErrObjectLocked
+				}
 				err = yig.MetaStorage.DeleteObject(object, object.DeleteMarker, nil)
 				if err != nil {
 					return
@@ -1068,7 +1274,9 @@ func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string)
 	return 0, errors.New("No Such versioning status!")
 }
 
-func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version string) error {
+func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version string,
+	bypassGovernance bool) error {
+
 	object, err := yig.getObjWithVersion(bucketName, objectName, version)
 	if err == ErrNoSuchKey {
 		return nil
@@ -1076,6 +1284,9 @@ func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version strin
 	if err != nil {
 		return err
 	}
+	if object.ObjectLock.IsLocked(bypassGovernance) {
+		return ErrObjectLocked
+	}
 
 	if version == "null" {
 		objMap := &meta.ObjMap{
@@ -1127,8 +1338,12 @@ func (yig *YigStorage) addDeleteMarker(bucket meta.Bucket, objectName string,
 // |           |                              | null version delete marker                             |
 //
 // See http://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html
+//
+// Object versions protected by legal hold or retention could not be removed,
+// unless bypassGovernance is set and the retention is in GOVERNANCE mode.
+// Adding delete markers is always allowed.
 func (yig *YigStorage) DeleteObject(bucketName string, objectName string, version string,
-	credential common.Credential) (result datatype.DeleteObjectResult, err error) {
+	credential common.Credential, bypassGovernance bool) (result datatype.DeleteObjectResult, err error) {
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
@@ -1160,7 +1375,7 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 			}
 			result.DeleteMarker = true
 		} else {
-			err = yig.removeObjectVersion(bucketName, objectName, version)
+			err = yig.removeObjectVersion(bucketName, objectName, version, bypassGovernance)
 			if err != nil {
 				return
 			}
@@ -1168,7 +1383,7 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 		}
 	case meta.VersionSuspended:
 		if version == "" {
-			err = yig.removeObjectVersion(bucketName, objectName, "null")
+			err = yig.removeObjectVersion(bucketName, objectName, "null", bypassGovernance)
 			if err != nil {
 				return
 			}
@@ -1178,7 +1393,7 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 			}
 			result.DeleteMarker = true
 		} else {
-			err = yig.removeObjectVersion(bucketName, objectName, version)
+			err = yig.removeObjectVersion(bucketName, objectName, version, bypassGovernance)
 			if err != nil {
 				return
 			}
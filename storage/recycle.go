@@ -1,18 +1,29 @@
 package storage
 
 import (
-	"github.com/journeymidnight/yig/helper"
+	"math"
+	"strconv"
 	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
 )
 
 // Remove
 // 1. deleted objects
 // 2. objects that already stored to Ceph but failed to update metadata
 // asynchronously
+//
+// Objects are saved into gc table when queued, or on the first failure to remove
+// if that fails, and the entry is deleted once the object is removed. So objects
+// failed to remove for MAX_TRY_TIMES, or left in queue when storage stops or crashes,
+// are removed by tools/delete eventually.
 
 const (
 	RECYCLE_QUEUE_SIZE = 100
 	MAX_TRY_TIMES      = 3
+	// interval between two tries of removing an object
+	RECYCLE_RETRY_INTERVAL = 1 * time.Second
 )
 
 type objectToRecycle struct {
@@ -20,47 +31,123 @@ type objectToRecycle struct {
 	pool       string
 	objectId   string
 	triedTimes int
+	queuedTime time.Time // version of the gc entry
+	saved      bool      // saved into gc table
 }
 
-var RecycleQueue chan objectToRecycle
+// garbage returns the gc entry of object, bucket names are never empty, so the entry
+// does not conflict with deleted objects, and object IDs are unique in a cluster
+func (object *objectToRecycle) garbage() *meta.Object {
+	return &meta.Object{
+		Name:             object.location + "/" + object.pool + "/" + object.objectId,
+		Location:         object.location,
+		Pool:             object.pool,
+		ObjectId:         object.objectId,
+		LastModifiedTime: object.queuedTime,
+	}
+}
 
-func initializeRecycler(yig *YigStorage) {
-	if RecycleQueue == nil {
-		RecycleQueue = make(chan objectToRecycle, RECYCLE_QUEUE_SIZE)
+// recycleObject saves the object into gc table and queues it to remove,
+// it's saved later on failure to remove if saving fails here
+func (yig *YigStorage) recycleObject(object objectToRecycle) {
+	object.queuedTime = time.Now().UTC()
+	yig.saveGarbage(&object)
+	yig.recycleQueue <- object
+}
+
+func (yig *YigStorage) saveGarbage(object *objectToRecycle) error {
+	if object.queuedTime.IsZero() {
+		object.queuedTime = time.Now().UTC()
+	}
+	err := yig.MetaStorage.PutObjectToGarbageCollection(object.garbage())
+	if err != nil {
+		helper.Logger.Error("Failed to put object to recycle into gc:",
+			object.location, object.pool, object.objectId, "error:", err)
+		return err
+	}
+	object.saved = true
+	return nil
+}
+
+// removeGarbage deletes the gc entry of object removed, tools/delete removes it otherwise
+func (yig *YigStorage) removeGarbage(object *objectToRecycle) {
+	gc := object.garbage()
+	version := math.MaxUint64 - uint64(gc.LastModifiedTime.UnixNano())
+	err := yig.MetaStorage.RemoveGarbageCollection(meta.GarbageCollection{
+		Rowkey: gc.BucketName + meta.ObjectNameSeparator + gc.Name +
+			meta.ObjectNameSeparator + strconv.FormatUint(version, 10),
+		BucketName: gc.BucketName,
+		ObjectName: gc.Name,
+	})
+	if err != nil {
+		helper.Logger.Warn("Failed to remove gc entry of object recycled:",
+			object.location, object.pool, object.objectId, "error:", err)
 	}
+}
+
+func initializeRecycler(yig *YigStorage) {
+	yig.recycleQueue = make(chan objectToRecycle, RECYCLE_QUEUE_SIZE)
+	yig.recycleStop = make(chan struct{})
 	// TODO: move this part of code to an isolated daemon
+	yig.WaitGroup.Add(1)
 	go removeFailed(yig)
 }
 
 func removeFailed(yig *YigStorage) {
-	yig.WaitGroup.Add(1)
 	defer yig.WaitGroup.Done()
+	var retrying []objectToRecycle
+	var retry <-chan time.Time // fires when there are objects to retry
 	for {
 		select {
-		case object := <-RecycleQueue:
-			err := yig.DataStorage[object.location].Remove(object.pool, object.objectId)
-			if err != nil {
-				object.triedTimes += 1
-				if object.triedTimes > MAX_TRY_TIMES {
-					helper.Logger.Warn("Failed to remove object in Ceph:",
-						object.location, object.pool, object.objectId,
-						"with error", err)
-					continue
-				}
-				RecycleQueue <- object
-				time.Sleep(1 * time.Second)
+		case object := <-yig.recycleQueue:
+			if !yig.recycle(&object) {
+				retrying = append(retrying, object)
 			}
-		default:
-			if yig.Stopping {
-				helper.Logger.Info(
-					"Service shutting down, recycle queue length:",
-					len(RecycleQueue))
-				if len(RecycleQueue) == 0 {
-					return
+		case <-retry:
+			var failed []objectToRecycle
+			for _, object := range retrying {
+				if !yig.recycle(&object) {
+					failed = append(failed, object)
 				}
 			}
-			time.Sleep(5 * time.Second)
+			retrying = failed
+		case <-yig.recycleStop:
+			for len(yig.recycleQueue) > 0 {
+				retrying = append(retrying, <-yig.recycleQueue)
+			}
+			helper.Logger.Info("Service shutting down, objects to recycle:", len(retrying))
+			for _, object := range retrying {
+				object.triedTimes = MAX_TRY_TIMES
+				yig.recycle(&object)
+			}
+			return
+		}
+		retry = nil
+		if len(retrying) > 0 {
+			retry = time.After(RECYCLE_RETRY_INTERVAL)
 		}
 	}
 }
 
+// recycle removes the object and its gc entry, the entry is saved on failure if not yet.
+// Returns false if the object should be tried again.
+func (yig *YigStorage) recycle(object *objectToRecycle) bool {
+	err := yig.DataStorage[object.location].Remove(object.pool, object.objectId)
+	if err == nil {
+		if object.saved {
+			yig.removeGarbage(object)
+		}
+		return true
+	}
+	object.triedTimes += 1
+	if !object.saved {
+		yig.saveGarbage(object)
+	}
+	if object.triedTimes < MAX_TRY_TIMES || !object.saved {
+		return false
+	}
+	helper.Logger.Warn("Failed to remove object in Ceph:",
+		object.location, object.pool, object.objectId,
+		"with error", err, "left it to gc")
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/json"
 	"github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/backend"
 	"github.com/journeymidnight/yig/circuitbreak"
@@ -12,6 +13,7 @@ import (
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/meta"
+	"github.com/journeymidnight/yig/meta/types"
 	"github.com/journeymidnight/yig/redis"
 	"io"
 	"path"
@@ -33,11 +35,15 @@ type YigStorage struct {
 	KMS         crypto.KMS
 	Stopping    bool
 	WaitGroup   *sync.WaitGroup
+
+	recycleQueue chan objectToRecycle
+	recycleStop  chan struct{} // closed to drain the recycle queue
 }
 
 func (y *YigStorage) Stop() {
 	y.Stopping = true
 	helper.Logger.Info("Stopping storage...")
+	close(y.recycleStop)
 	y.WaitGroup.Wait()
 	helper.Logger.Info("done")
 }
@@ -72,30 +78,113 @@ func (y *YigStorage) PingCache(interval time.Duration) {
 	}
 }
 
-func (yig *YigStorage) encryptionKeyFromSseRequest(sseRequest datatype.SseRequest, bucket, object string) (key []byte, encKey []byte, err error) {
+// encryptionKeyFromSseRequest returns the data key and the key sealed by KMS.
+// For SSE-KMS, default key of KMS is set to sseRequest if no key is requested,
+// and encryption context the key is sealed with is returned
+func (yig *YigStorage) encryptionKeyFromSseRequest(sseRequest *datatype.SseRequest, bucket, object string) (key []byte,
+	encKey []byte, context crypto.Context, err error) {
+
 	switch sseRequest.Type {
 	case "": // no encryption
-		return nil, nil, nil
-	// not implemented yet
+		return nil, nil, nil, nil
 	case crypto.S3KMS.String():
-		return nil, nil, ErrNotImplemented
+		if yig.KMS == nil {
+			return nil, nil, nil, ErrKMSNotConfigured
+		}
+		if sseRequest.SseAwsKmsKeyId == "" {
+			sseRequest.SseAwsKmsKeyId = yig.KMS.GetKeyID()
+		}
+		context, err = sseKmsContext(*sseRequest, bucket, object)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		key, encKey, err := yig.KMS.GenerateKey(sseRequest.SseAwsKmsKeyId, context)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return key[:], encKey, context, nil
 	case crypto.S3.String():
 		if yig.KMS == nil {
-			return nil, nil, ErrKMSNotConfigured
+			return nil, nil, nil, ErrKMSNotConfigured
 		}
 		key, encKey, err := yig.KMS.GenerateKey(yig.KMS.GetKeyID(), crypto.Context{bucket: path.Join(bucket, object)})
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
-		return key[:], encKey, nil
+		return key[:], encKey, nil, nil
 	case crypto.SSEC.String():
-		return sseRequest.SseCustomerKey, nil, nil
+		return sseRequest.SseCustomerKey, nil, nil, nil
 	default:
 		err = ErrInvalidSseHeader
 		return
 	}
 }
 
+// sseKmsContext returns encryption context of SSE-KMS, which binds the key to the object like SSE-S3,
+// besides the context requested
+func sseKmsContext(sseRequest datatype.SseRequest, bucket, object string) (crypto.Context, error) {
+	context := crypto.Context{}
+	if sseRequest.SseContext != "" {
+		err := json.Unmarshal([]byte(sseRequest.SseContext), &context)
+		if err != nil {
+			return nil, ErrInvalidSseHeader
+		}
+	}
+	context[bucket] = path.Join(bucket, object)
+	return context, nil
+}
+
+// setObjectKey keeps the key sealed by KMS in object for SSE-S3 and SSE-KMS,
+// along with the key ID and encryption context for SSE-KMS
+func setObjectKey(object *types.Object, sseRequest datatype.SseRequest, sealedKey []byte, context crypto.Context) {
+	object.SseType = sseRequest.Type
+	object.EncryptionKey = []byte("")
+	object.SseKmsKeyId = ""
+	object.SseContext = nil
+	switch sseRequest.Type {
+	case crypto.S3.String():
+		object.EncryptionKey = sealedKey
+	case crypto.S3KMS.String():
+		object.EncryptionKey = sealedKey
+		object.SseKmsKeyId = sseRequest.SseAwsKmsKeyId
+		object.SseContext = context
+	}
+}
+
+// ObjectKeyContext returns the KMS key ID and encryption context the data key of
+// SSE-S3 or SSE-KMS object is sealed with
+func ObjectKeyContext(kms crypto.KMS, object *types.Object) (keyId string, context crypto.Context) {
+	if object.SseType == crypto.S3KMS.String() {
+		return object.SseKmsKeyId, object.SseContext
+	}
+	return kms.GetKeyID(), crypto.Context{object.BucketName: path.Join(object.BucketName, object.Name)}
+}
+
+// MultipartKeyContext returns the KMS key ID and encryption context the data key of
+// SSE-S3 or SSE-KMS multipart upload is sealed with
+func MultipartKeyContext(kms crypto.KMS, multipart *types.Multipart) (keyId string, context crypto.Context, err error) {
+	sseRequest := multipart.Metadata.SseRequest
+	keyId = kms.GetKeyID()
+	if sseRequest.Type == crypto.S3KMS.String() {
+		keyId = sseRequest.SseAwsKmsKeyId
+	}
+	context, err = sseKmsContext(sseRequest, multipart.BucketName, multipart.ObjectName)
+	return
+}
+
+// unsealObjectKey returns the data key of SSE-S3 and SSE-KMS objects
+func (yig *YigStorage) unsealObjectKey(object *types.Object) ([]byte, error) {
+	if yig.KMS == nil {
+		return nil, ErrKMSNotConfigured
+	}
+	keyId, context := ObjectKeyContext(yig.KMS, object)
+	key, err := yig.KMS.UnsealKey(keyId, object.EncryptionKey, context)
+	if err != nil {
+		return nil, err
+	}
+	return key[:], nil
+}
+
 func newInitializationVector() (initializationVector []byte, err error) {
 
 	initializationVector = make([]byte, INITIALIZATION_VECTOR_LENGTH)
@@ -107,6 +196,15 @@ func newInitializationVector() (initializationVector []byte, err error) {
 func wrapEncryptionReader(reader io.Reader, encryptionKey []byte,
 	initializationVector []byte) (wrappedReader io.Reader, err error) {
 
+	return wrapEncryptionReaderAt(reader, encryptionKey, initializationVector, 0)
+}
+
+// Wraps reader with encryption if encryptionKey is not empty, for data starting at offset
+// of the object. The counter of CTR mode is derived from the initialization vector and offset,
+// so data appended to an object is encrypted independently, and ranges are decrypted alone.
+func wrapEncryptionReaderAt(reader io.Reader, encryptionKey []byte,
+	initializationVector []byte, offset int64) (wrappedReader io.Reader, err error) {
+
 	if len(encryptionKey) == 0 {
 		return reader, nil
 	}
@@ -116,7 +214,10 @@ func wrapEncryptionReader(reader io.Reader, encryptionKey []byte,
 	if err != nil {
 		return
 	}
-	stream := cipher.NewCTR(block, initializationVector)
+	stream := cipher.NewCTR(block, counterAt(initializationVector, offset))
+	// skip the key stream of bytes before offset in its block
+	skipped := make([]byte, offset%AES_BLOCK_SIZE)
+	stream.XORKeyStream(skipped, skipped)
 	wrappedReader = cipher.StreamReader{
 		S: stream,
 		R: reader,
@@ -124,6 +225,20 @@ func wrapEncryptionReader(reader io.Reader, encryptionKey []byte,
 	return
 }
 
+// counterAt returns the counter of the block containing offset, i.e. the initialization vector
+// plus the number of blocks before, as a big-endian integer like the counter of cipher.NewCTR
+func counterAt(initializationVector []byte, offset int64) []byte {
+	counter := make([]byte, len(initializationVector))
+	copy(counter, initializationVector)
+	carry := uint64(offset / AES_BLOCK_SIZE)
+	for i := len(counter) - 1; i >= 0 && carry > 0; i-- {
+		sum := uint64(counter[i]) + carry&0xff
+		counter[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+	return counter
+}
+
 type alignedReader struct {
 	aligned bool // indicate whether alignment has already been done
 	offset  int64
@@ -167,7 +282,7 @@ func wrapAlignedEncryptionReader(reader io.Reader, startOffset int64, encryption
 	}
 
 	alignedOffset := startOffset / AES_BLOCK_SIZE * AES_BLOCK_SIZE
-	newReader, err := wrapEncryptionReader(reader, encryptionKey, initializationVector)
+	newReader, err := wrapEncryptionReaderAt(reader, encryptionKey, initializationVector, alignedOffset)
 	if err != nil {
 		return
 	}
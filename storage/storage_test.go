@@ -0,0 +1,966 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/crypto"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam/common"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/memory"
+	"github.com/journeymidnight/yig/meta"
+	"github.com/journeymidnight/yig/meta/client/memoryclient"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/s3gateway"
+)
+
+var credential = common.Credential{UserId: "user", DisplayName: "user"}
+
+// run with `-tags noceph` if librados is not installed
+func TestMain(m *testing.M) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	helper.CONFIG.MetaStore = "memory"
+	helper.CONFIG.DataStore = "memory"
+	helper.CONFIG.DownloadBufPoolSize = 1 << 20
+	helper.CONFIG.PiggybackUpdateUsage = true
+	os.Exit(m.Run())
+}
+
+// newStorage returns a storage with empty in-memory meta and data store,
+// so each test starts from scratch. Stop it when the test ends.
+func newStorage() *YigStorage {
+	return New(int(meta.NoCache), false, nil)
+}
+
+// makeBucket creates the bucket and returns the in-memory meta client and data cluster
+func makeBucket(t *testing.T, yig *YigStorage, bucketName string) (*memoryclient.MemoryClient, *memory.MemoryCluster) {
+	err := yig.MakeBucket(bucketName, datatype.Acl{CannedAcl: "private"}, credential, false)
+	if err != nil {
+		t.Fatal("MakeBucket error:", err)
+	}
+	return yig.MetaStorage.Client.(*memoryclient.MemoryClient),
+		yig.DataStorage[memory.CLUSTER_NAME].(*memory.MemoryCluster)
+}
+
+func contains(oids []string, oid string) bool {
+	for _, o := range oids {
+		if o == oid {
+			return true
+		}
+	}
+	return false
+}
+
+func putObject(yig *YigStorage, bucketName, objectName string, data []byte) (datatype.PutObjectResult, error) {
+	return yig.PutObject(bucketName, objectName, credential, int64(len(data)),
+		ioutil.NopCloser(bytes.NewReader(data)), map[string]string{}, datatype.Acl{CannedAcl: "private"},
+		datatype.SseRequest{}, types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+}
+
+func getObject(t *testing.T, yig *YigStorage, bucketName, objectName string, start, length int64) []byte {
+	object, err := yig.GetObjectInfo(bucketName, objectName, "", credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	if length < 0 {
+		length = object.Size - start
+	}
+	var buffer bytes.Buffer
+	err = yig.GetObject(object, start, length, &buffer, datatype.SseRequest{})
+	if err != nil {
+		t.Fatal("GetObject error:", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestPutAndGetObject(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	client, cluster := makeBucket(t, yig, "put")
+
+	data := []byte("hello yig")
+	result, err := putObject(yig, "put", "a", data)
+	if err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	sum := md5.Sum(data)
+	if result.Md5 != hex.EncodeToString(sum[:]) {
+		t.Fatal("PutObject returns md5", result.Md5)
+	}
+	if got := getObject(t, yig, "put", "a", 0, -1); !bytes.Equal(got, data) {
+		t.Fatalf("GetObject %q, expected %q", got, data)
+	}
+	if got := getObject(t, yig, "put", "a", 6, 3); string(got) != "yig" {
+		t.Fatalf("GetObject range %q, expected %q", got, "yig")
+	}
+	bucket, err := client.GetBucket("put")
+	if err != nil || bucket.Usage != int64(len(data)) {
+		t.Fatal("GetBucket returns", bucket, err)
+	}
+
+	// the overwritten object is left to garbage collection
+	if _, err = putObject(yig, "put", "a", []byte("overwritten")); err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	if got := getObject(t, yig, "put", "a", 0, -1); string(got) != "overwritten" {
+		t.Fatalf("GetObject %q, expected %q", got, "overwritten")
+	}
+	list, err := yig.ListObjects(credential, "put", datatype.ListObjectsRequest{Version: 1, MaxKeys: 1000})
+	if err != nil || len(list.Objects) != 1 || list.Objects[0].Key != "a" {
+		t.Fatal("ListObjects returns", list, err)
+	}
+
+	if _, err = yig.DeleteObject("put", "a", "", credential, false); err != nil {
+		t.Fatal("DeleteObject error:", err)
+	}
+	if _, err = yig.GetObjectInfo("put", "a", "", credential); err != ErrNoSuchKey {
+		t.Fatal("GetObjectInfo deleted object, expected ErrNoSuchKey, got", err)
+	}
+	gcs, err := client.ScanGarbageCollection(1000, "")
+	if err != nil {
+		t.Fatal("ScanGarbageCollection error:", err)
+	}
+	var count int
+	oids := cluster.Objects(backend.SMALL_FILE_POOLNAME)
+	for _, gc := range gcs {
+		if gc.BucketName != "put" {
+			continue
+		}
+		count += 1
+		// data is removed by the gc tool
+		if !contains(oids, gc.ObjectId) {
+			t.Fatal("Object in garbage collection is removed:", gc.ObjectId)
+		}
+	}
+	if count != 2 {
+		t.Fatal("ScanGarbageCollection returns", count, "objects, expected 2")
+	}
+
+	// size of chunked uploads is known after stored
+	result, err = yig.PutObject("put", "chunked", credential, -1, ioutil.NopCloser(bytes.NewReader(data)),
+		map[string]string{}, datatype.Acl{CannedAcl: "private"}, datatype.SseRequest{},
+		types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+	if err != nil || result.Size != int64(len(data)) {
+		t.Fatal("PutObject of unknown size returns", result, err)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "multipart")
+
+	uploadId, err := yig.NewMultipartUpload(credential, "multipart", "a", map[string]string{},
+		datatype.Acl{CannedAcl: "private"}, datatype.SseRequest{}, types.ObjectStorageClassStandard,
+		nil, datatype.ObjectLock{})
+	if err != nil {
+		t.Fatal("NewMultipartUpload error:", err)
+	}
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 5<<20),
+		[]byte("the last part"),
+	}
+	var completeParts []types.CompletePart
+	for i, part := range parts {
+		result, err := yig.PutObjectPart("multipart", "a", credential, uploadId, i+1, int64(len(part)),
+			ioutil.NopCloser(bytes.NewReader(part)), "", datatype.SseRequest{})
+		if err != nil {
+			t.Fatal("PutObjectPart error:", err)
+		}
+		completeParts = append(completeParts, types.CompletePart{PartNumber: i + 1, ETag: result.ETag})
+	}
+	uploads, err := yig.ListMultipartUploads(credential, "multipart", datatype.ListUploadsRequest{MaxUploads: 1000})
+	if err != nil || len(uploads.Uploads) != 1 || uploads.Uploads[0].UploadId != uploadId {
+		t.Fatal("ListMultipartUploads returns", uploads, err)
+	}
+
+	_, err = yig.CompleteMultipartUpload(credential, "multipart", "a", uploadId, completeParts)
+	if err != nil {
+		t.Fatal("CompleteMultipartUpload error:", err)
+	}
+	data := bytes.Join(parts, nil)
+	if got := getObject(t, yig, "multipart", "a", 0, -1); !bytes.Equal(got, data) {
+		t.Fatalf("GetObject returns %d bytes, expected %d", len(got), len(data))
+	}
+	// range across parts
+	start := int64(len(parts[0]) - 3)
+	if got := getObject(t, yig, "multipart", "a", start, 7); !bytes.Equal(got, data[start:start+7]) {
+		t.Fatalf("GetObject range %q, expected %q", got, data[start:start+7])
+	}
+	if _, err = yig.MetaStorage.GetMultipart("multipart", "a", uploadId); err != ErrNoSuchUpload {
+		t.Fatal("GetMultipart completed upload, expected ErrNoSuchUpload, got", err)
+	}
+}
+
+// testKMS seals data keys with the key ID and context, unsealing fails if either differs
+type testKMS struct{}
+
+func (testKMS) seal(keyID string, context crypto.Context) []byte {
+	var b bytes.Buffer
+	b.WriteString(keyID)
+	context.WriteTo(&b)
+	sum := sha256.Sum256(b.Bytes())
+	return sum[:]
+}
+
+func (k testKMS) GenerateKey(keyID string, context crypto.Context) (key [32]byte, sealedKey []byte, err error) {
+	_, err = rand.Read(key[:])
+	return key, append(k.seal(keyID, context), key[:]...), err
+}
+
+func (k testKMS) UnsealKey(keyID string, sealedKey []byte, context crypto.Context) (key [32]byte, err error) {
+	if len(sealedKey) != 64 || !bytes.Equal(sealedKey[:32], k.seal(keyID, context)) {
+		return key, errors.New("key ID or context mismatch")
+	}
+	copy(key[:], sealedKey[32:])
+	return key, nil
+}
+
+func (testKMS) GetKeyID() string {
+	return "default"
+}
+
+func TestSseKms(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "kms")
+	yig.KMS = testKMS{}
+
+	data := []byte("hello kms")
+	sseRequest := datatype.SseRequest{
+		Type:           crypto.S3KMS.String(),
+		SseAwsKmsKeyId: "key",
+		SseContext:     `{"project":"yig"}`,
+	}
+	result, err := yig.PutObject("kms", "a", credential, int64(len(data)),
+		ioutil.NopCloser(bytes.NewReader(data)), map[string]string{}, datatype.Acl{CannedAcl: "private"},
+		sseRequest, types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+	if err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	if result.SseAwsKmsKeyId != "key" {
+		t.Fatal("PutObject returns key ID", result.SseAwsKmsKeyId)
+	}
+	object, err := yig.GetObjectInfo("kms", "a", "", credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	if object.SseKmsKeyId != "key" || object.SseContext["project"] != "yig" || object.SseContext["kms"] != "kms/a" {
+		t.Fatal("Object encrypted with key", object.SseKmsKeyId, "and context", object.SseContext)
+	}
+	reader, err := yig.DataStorage[object.Location].GetReader(object.Pool, object.ObjectId, 0, uint64(object.Size))
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	stored, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if bytes.Equal(stored, data) {
+		t.Fatal("Object is stored unencrypted")
+	}
+	if got := getObject(t, yig, "kms", "a", 0, -1); !bytes.Equal(got, data) {
+		t.Fatalf("GetObject %q, expected %q", got, data)
+	}
+	object.SseContext["project"] = "other"
+	var buffer bytes.Buffer
+	if err = yig.GetObject(object, 0, object.Size, &buffer, datatype.SseRequest{}); err == nil {
+		t.Fatal("GetObject with another context, expected error")
+	}
+
+	// default key of KMS
+	uploadId, err := yig.NewMultipartUpload(credential, "kms", "b", map[string]string{},
+		datatype.Acl{CannedAcl: "private"}, datatype.SseRequest{Type: crypto.S3KMS.String()},
+		types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+	if err != nil {
+		t.Fatal("NewMultipartUpload error:", err)
+	}
+	partResult, err := yig.PutObjectPart("kms", "b", credential, uploadId, 1, int64(len(data)),
+		ioutil.NopCloser(bytes.NewReader(data)), "", datatype.SseRequest{})
+	if err != nil {
+		t.Fatal("PutObjectPart error:", err)
+	}
+	if partResult.SseType != crypto.S3KMS.String() || partResult.SseAwsKmsKeyId != "default" {
+		t.Fatal("PutObjectPart returns", partResult.SseType, partResult.SseAwsKmsKeyId)
+	}
+	_, err = yig.CompleteMultipartUpload(credential, "kms", "b", uploadId,
+		[]types.CompletePart{{PartNumber: 1, ETag: partResult.ETag}})
+	if err != nil {
+		t.Fatal("CompleteMultipartUpload error:", err)
+	}
+	if got := getObject(t, yig, "kms", "b", 0, -1); !bytes.Equal(got, data) {
+		t.Fatalf("GetObject %q, expected %q", got, data)
+	}
+}
+
+func newKeyring(t *testing.T, ids ...string) *crypto.Keyring {
+	var masterKeys []crypto.MasterKey
+	for _, id := range ids {
+		masterKey := crypto.MasterKey{ID: id}
+		copy(masterKey.Key[:], id)
+		masterKeys = append(masterKeys, masterKey)
+	}
+	keyring, err := crypto.NewKeyring("yig", masterKeys...)
+	if err != nil {
+		t.Fatal("NewKeyring error:", err)
+	}
+	return keyring
+}
+
+// keys rewrapped with ObjectKeyContext and MultipartKeyContext, like tools/rekey, are unsealed by storage
+func TestRewrapKeys(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "rewrap")
+	yig.KMS = newKeyring(t, "v1")
+
+	data := []byte("hello keyring")
+	for _, sseRequest := range []datatype.SseRequest{
+		{Type: crypto.S3.String()},
+		{Type: crypto.S3KMS.String(), SseAwsKmsKeyId: "key", SseContext: `{"project":"yig"}`},
+	} {
+		_, err := yig.PutObject("rewrap", sseRequest.Type, credential, int64(len(data)),
+			ioutil.NopCloser(bytes.NewReader(data)), map[string]string{}, datatype.Acl{CannedAcl: "private"},
+			sseRequest, types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+		if err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+	uploadId, err := yig.NewMultipartUpload(credential, "rewrap", "multipart", map[string]string{},
+		datatype.Acl{CannedAcl: "private"}, datatype.SseRequest{Type: crypto.S3KMS.String()},
+		types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+	if err != nil {
+		t.Fatal("NewMultipartUpload error:", err)
+	}
+
+	rotated := newKeyring(t, "v1", "v2")
+	yig.KMS = rotated
+	for _, objectName := range []string{crypto.S3.String(), crypto.S3KMS.String()} {
+		object, err := yig.GetObjectInfo("rewrap", objectName, "", credential)
+		if err != nil {
+			t.Fatal("GetObjectInfo error:", err)
+		}
+		keyId, context := ObjectKeyContext(rotated, object)
+		object.EncryptionKey, _, err = rotated.RewrapKey(keyId, object.EncryptionKey, context)
+		if err != nil {
+			t.Fatal("RewrapKey error:", err)
+		}
+		yig.KMS = newKeyring(t, "v2")
+		var buffer bytes.Buffer
+		err = yig.GetObject(object, 0, object.Size, &buffer, datatype.SseRequest{})
+		if err != nil || !bytes.Equal(buffer.Bytes(), data) {
+			t.Fatalf("GetObject rewrapped %q, error %v", buffer.Bytes(), err)
+		}
+		yig.KMS = rotated
+	}
+	multipart, err := yig.MetaStorage.GetMultipart("rewrap", "multipart", uploadId)
+	if err != nil {
+		t.Fatal("GetMultipart error:", err)
+	}
+	keyId, context, err := MultipartKeyContext(rotated, &multipart)
+	if err != nil {
+		t.Fatal("MultipartKeyContext error:", err)
+	}
+	multipart.Metadata.CipherKey, _, err = rotated.RewrapKey(keyId, multipart.Metadata.CipherKey, context)
+	if err != nil {
+		t.Fatal("RewrapKey error:", err)
+	}
+	yig.KMS = newKeyring(t, "v2")
+	if _, err = yig.unsealMultipartKey(multipart); err != nil {
+		t.Fatal("unsealMultipartKey rewrapped error:", err)
+	}
+}
+
+func appendObject(t *testing.T, yig *YigStorage, bucketName, objectName string, data []byte,
+	sseRequest datatype.SseRequest) datatype.AppendObjectResult {
+
+	object, err := yig.GetObjectInfo(bucketName, objectName, "", credential)
+	if err == ErrNoSuchKey {
+		object = nil
+	} else if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	var position uint64
+	if object != nil {
+		position = uint64(object.Size)
+	}
+	result, err := yig.AppendObject(bucketName, objectName, credential, position, int64(len(data)),
+		ioutil.NopCloser(bytes.NewReader(data)), map[string]string{}, datatype.Acl{CannedAcl: "private"},
+		sseRequest, types.ObjectStorageClassStandard, object)
+	if err != nil {
+		t.Fatal("AppendObject error:", err)
+	}
+	return result
+}
+
+func TestAppendEncryptedObject(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "append")
+	yig.KMS = testKMS{}
+
+	// appends start and end in the middle of AES blocks
+	chunks := []string{"hello", " appendable object", " encrypted by yig,", " blocks are unaligned"}
+	for _, sseRequest := range []datatype.SseRequest{
+		{Type: crypto.S3.String()},
+		{Type: crypto.S3KMS.String(), SseAwsKmsKeyId: "key", SseContext: `{"project":"yig"}`},
+	} {
+		objectName := sseRequest.Type
+		var data []byte
+		for i, chunk := range chunks {
+			request := sseRequest
+			if i > 0 {
+				// encryption of later appends follows the object
+				request = datatype.SseRequest{}
+			}
+			result := appendObject(t, yig, "append", objectName, []byte(chunk), request)
+			data = append(data, chunk...)
+			if result.NextPosition != int64(len(data)) || result.SseType != sseRequest.Type {
+				t.Fatal("AppendObject returns", result.NextPosition, result.SseType)
+			}
+		}
+		object, err := yig.GetObjectInfo("append", objectName, "", credential)
+		if err != nil {
+			t.Fatal("GetObjectInfo error:", err)
+		}
+		if object.SseType != sseRequest.Type || len(object.EncryptionKey) == 0 {
+			t.Fatal("Object encrypted with", object.SseType, object.EncryptionKey)
+		}
+		reader, err := yig.DataStorage[object.Location].GetReader(object.Pool, object.ObjectId, 0, uint64(object.Size))
+		if err != nil {
+			t.Fatal("GetReader error:", err)
+		}
+		stored, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		if bytes.Contains(stored, []byte("yig")) {
+			t.Fatal("Object is stored unencrypted")
+		}
+		if got := getObject(t, yig, "append", objectName, 0, -1); !bytes.Equal(got, data) {
+			t.Fatalf("GetObject %q, expected %q", got, data)
+		}
+		if got := getObject(t, yig, "append", objectName, int64(bytes.Index(data, []byte("yig"))), 3); string(got) != "yig" {
+			t.Fatalf("GetObject range %q, expected %q", got, "yig")
+		}
+	}
+}
+
+// data encrypted at any offset is the same as encrypted as a whole,
+// even if the counter overflows bytes of the initialization vector
+func TestWrapEncryptionReaderAt(t *testing.T) {
+	key := make([]byte, ENCRYPTION_KEY_LENGTH)
+	rand.Read(key)
+	initializationVector := bytes.Repeat([]byte{0xff}, INITIALIZATION_VECTOR_LENGTH)
+	initializationVector[0] = 0
+	data := make([]byte, 5000)
+	rand.Read(data)
+
+	reader, err := wrapEncryptionReader(bytes.NewReader(data), key, initializationVector)
+	if err != nil {
+		t.Fatal("wrapEncryptionReader error:", err)
+	}
+	encrypted, _ := ioutil.ReadAll(reader)
+	for _, offset := range []int64{0, 1, 15, 16, 17, 256*16 + 1, 4999} {
+		reader, err = wrapEncryptionReaderAt(bytes.NewReader(data[offset:]), key, initializationVector, offset)
+		if err != nil {
+			t.Fatal("wrapEncryptionReaderAt error:", err)
+		}
+		got, _ := ioutil.ReadAll(reader)
+		if !bytes.Equal(got, encrypted[offset:]) {
+			t.Fatal("Data encrypted at", offset, "differs")
+		}
+		reader, err = wrapAlignedEncryptionReader(bytes.NewReader(encrypted[offset/16*16:]), offset,
+			key, initializationVector)
+		if err != nil {
+			t.Fatal("wrapAlignedEncryptionReader error:", err)
+		}
+		got, _ = ioutil.ReadAll(reader)
+		if !bytes.Equal(got, data[offset:]) {
+			t.Fatal("Data decrypted at", offset, "differs")
+		}
+	}
+}
+
+func TestVersioning(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "versioning")
+	err := yig.SetBucketVersioning("versioning", datatype.Versioning{Status: types.VersionEnabled}, credential)
+	if err != nil {
+		t.Fatal("SetBucketVersioning error:", err)
+	}
+
+	var versionIds []string
+	for _, data := range []string{"one", "two"} {
+		result, err := putObject(yig, "versioning", "a", []byte(data))
+		if err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+		versionIds = append(versionIds, result.VersionId)
+	}
+	if versionIds[0] == "" || versionIds[0] == versionIds[1] {
+		t.Fatal("PutObject returns version ids", versionIds)
+	}
+	if got := getObject(t, yig, "versioning", "a", 0, -1); string(got) != "two" {
+		t.Fatalf("GetObject %q, expected %q", got, "two")
+	}
+
+	result, err := yig.DeleteObject("versioning", "a", "", credential, false)
+	if err != nil || !result.DeleteMarker {
+		t.Fatal("DeleteObject returns", result, err)
+	}
+	list, err := yig.ListObjects(credential, "versioning", datatype.ListObjectsRequest{Version: 1, MaxKeys: 1000})
+	if err != nil || len(list.Objects) != 0 {
+		t.Fatal("ListObjects returns", list, err)
+	}
+	versions, err := yig.ListVersionedObjects(credential, "versioning",
+		datatype.ListObjectsRequest{Versioned: true, MaxKeys: 1000})
+	if err != nil || len(versions.Objects) != 3 {
+		t.Fatal("ListVersionedObjects returns", versions, err)
+	}
+	expected := []string{result.VersionId, versionIds[1], versionIds[0]}
+	for i, v := range versions.Objects {
+		if v.VersionId != expected[i] {
+			t.Fatalf("Version %d is %s, expected %s", i, v.VersionId, expected[i])
+		}
+	}
+	if versions.Objects[0].XMLName.Local != "DeleteMarker" {
+		t.Fatal("The latest version is", versions.Objects[0].XMLName.Local)
+	}
+}
+
+// versionTags returns tags of every version of object, from the newest to the oldest
+func versionTags(t *testing.T, yig *YigStorage, bucketName, objectName string) (tags []string) {
+	objects, err := yig.MetaStorage.GetAllObject(bucketName, objectName)
+	if err != nil {
+		t.Fatal("GetAllObject error:", err)
+	}
+	for _, object := range objects {
+		tags = append(tags, object.Tags["k"])
+	}
+	return tags
+}
+
+func TestObjectTaggingVersions(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "tagging")
+	err := yig.SetBucketVersioning("tagging", datatype.Versioning{Status: types.VersionEnabled}, credential)
+	if err != nil {
+		t.Fatal("SetBucketVersioning error:", err)
+	}
+	for _, data := range []string{"one", "two"} {
+		_, err := putObject(yig, "tagging", "a", []byte(data))
+		if err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+
+	err = yig.SetObjectTagging("tagging", "a", "", map[string]string{"k": "new"}, credential)
+	if err != nil {
+		t.Fatal("SetObjectTagging error:", err)
+	}
+	if tags := versionTags(t, yig, "tagging", "a"); len(tags) != 2 || tags[0] != "new" || tags[1] != "" {
+		t.Fatal("Tags of versions are", tags)
+	}
+
+	// metas replaced by copying to itself are of the source version only
+	source, err := yig.GetObjectInfo("tagging", "a", "", credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	target := &types.Object{
+		BucketName: "tagging",
+		Name:       "a",
+		Tags:       map[string]string{"k": "copied"},
+	}
+	_, err = yig.CopyObject(target, source, nil, credential, datatype.SseRequest{}, true)
+	if err != nil {
+		t.Fatal("CopyObject error:", err)
+	}
+	if tags := versionTags(t, yig, "tagging", "a"); len(tags) != 2 || tags[0] != "copied" || tags[1] != "" {
+		t.Fatal("Tags of versions are", tags)
+	}
+}
+
+// objectCache caches objects marshalled as redis does, so cached entries
+// are not changed by updates to the objects read from them
+type objectCache struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func (c *objectCache) Get(table redis.RedisDatabase, key string,
+	onCacheMiss func() (interface{}, error),
+	unmarshaller func([]byte) (interface{}, error), willNeed bool) (interface{}, error) {
+
+	if table != redis.ObjectTable {
+		return onCacheMiss()
+	}
+	c.lock.Lock()
+	data, ok := c.objects[key]
+	c.lock.Unlock()
+	if ok {
+		return unmarshaller(data)
+	}
+	value, err := onCacheMiss()
+	if err != nil {
+		return nil, err
+	}
+	data, err = helper.MsgPackMarshal(value)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.objects[key] = data
+	c.lock.Unlock()
+	return value, nil
+}
+
+func (c *objectCache) Remove(table redis.RedisDatabase, key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.objects, key)
+}
+
+func (c *objectCache) GetCacheHitRatio() float64 {
+	return -1
+}
+
+func TestObjectLockCache(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	yig.MetaStorage.Cache = &objectCache{objects: make(map[string][]byte)}
+	err := yig.MakeBucket("lock", datatype.Acl{CannedAcl: "private"}, credential, true)
+	if err != nil {
+		t.Fatal("MakeBucket error:", err)
+	}
+	result, err := putObject(yig, "lock", "a", []byte("held"))
+	if err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	// cached by version id
+	_, err = yig.GetObjectInfo("lock", "a", result.VersionId, credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+
+	err = yig.SetObjectLegalHold("lock", "a", "", true, credential)
+	if err != nil {
+		t.Fatal("SetObjectLegalHold error:", err)
+	}
+	_, err = yig.DeleteObject("lock", "a", result.VersionId, credential, false)
+	if err != ErrObjectLocked {
+		t.Fatal("DeleteObject of held version returns", err)
+	}
+}
+
+func TestExpireVersions(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	makeBucket(t, yig, "expire")
+	err := yig.SetBucketVersioning("expire", datatype.Versioning{Status: types.VersionEnabled}, credential)
+	if err != nil {
+		t.Fatal("SetBucketVersioning error:", err)
+	}
+	for _, data := range []string{"one", "two"} {
+		_, err := putObject(yig, "expire", "a", []byte(data))
+		if err != nil {
+			t.Fatal("PutObject error:", err)
+		}
+	}
+	listVersions := func() []*types.Object {
+		objects, _, _, _, _, err := yig.ListObjectsInternal("expire",
+			datatype.ListObjectsRequest{Versioned: true, MaxKeys: 1000})
+		if err != nil {
+			t.Fatal("ListObjectsInternal error:", err)
+		}
+		return objects
+	}
+	versions := listVersions()
+	if len(versions) != 2 || !versions[0].LastModifiedTime.After(versions[1].LastModifiedTime) {
+		t.Fatal("ListObjectsInternal returns", versions)
+	}
+
+	// the current version is expired by a delete marker, versions are kept
+	result, err := yig.ExpireObject(versions[0])
+	if err != nil || !result.DeleteMarker {
+		t.Fatal("ExpireObject returns", result, err)
+	}
+	versions = listVersions()
+	if len(versions) != 3 || !versions[0].DeleteMarker {
+		t.Fatal("Versions after ExpireObject are", versions)
+	}
+
+	// restore state belongs to the current version, kept if a noncurrent version expires
+	err = yig.CreateFreezer(&types.Freezer{BucketName: "expire", Name: "a",
+		Status: types.ObjectNeedRestore, LifeTime: 1})
+	if err != nil {
+		t.Fatal("CreateFreezer error:", err)
+	}
+	noncurrent := versions[2]
+	noncurrent.StorageClass = types.ObjectStorageClassGlacier
+	if err = yig.ExpireObjectVersion(noncurrent); err != nil {
+		t.Fatal("ExpireObjectVersion error:", err)
+	}
+	if _, err = yig.GetFreezer("expire", "a", ""); err != nil {
+		t.Fatal("GetFreezer after noncurrent version expired returns", err)
+	}
+	current := versions[0]
+	current.StorageClass = types.ObjectStorageClassGlacier
+	if err = yig.ExpireObjectVersion(current); err != nil {
+		t.Fatal("ExpireObjectVersion error:", err)
+	}
+	if _, err = yig.GetFreezer("expire", "a", ""); err != ErrNoSuchKey {
+		t.Fatal("GetFreezer after current version expired returns", err)
+	}
+	if versions = listVersions(); len(versions) != 1 {
+		t.Fatal("Versions after ExpireObjectVersion are", versions)
+	}
+}
+
+func TestPutObjectFailures(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	_, cluster := makeBucket(t, yig, "failure")
+	injected := errors.New("injected")
+
+	cluster.SetPutHook(func(poolName string) error { return injected })
+	if _, err := putObject(yig, "failure", "a", []byte("data")); err != injected {
+		t.Fatal("PutObject expected injected error, got", err)
+	}
+	if _, err := yig.GetObjectInfo("failure", "a", "", credential); err != ErrNoSuchKey {
+		t.Fatal("GetObjectInfo failed object, expected ErrNoSuchKey, got", err)
+	}
+	cluster.SetPutHook(nil)
+
+	if testing.Short() {
+		t.Skip("Skip waiting for recycler in short mode")
+	}
+	// data written is recycled if metadata could not be saved, the first removal fails
+	removed := make(chan string, 2)
+	var tried int32
+	cluster.SetRemoveHook(func(poolName, oid string) error {
+		removed <- oid
+		if atomic.AddInt32(&tried, 1) == 1 {
+			return injected
+		}
+		return nil
+	})
+	defer cluster.SetRemoveHook(nil)
+	_, err := yig.PutObject("failure", "a", credential, 4, ioutil.NopCloser(bytes.NewReader([]byte("data"))),
+		map[string]string{"md5Sum": "bad"}, datatype.Acl{CannedAcl: "private"}, datatype.SseRequest{},
+		types.ObjectStorageClassStandard, nil, datatype.ObjectLock{})
+	if err != ErrBadDigest {
+		t.Fatal("PutObject expected ErrBadDigest, got", err)
+	}
+	var oid string
+	for i := 0; i < 2; i++ {
+		select {
+		case oid = <-removed:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timeout waiting for recycler")
+		}
+	}
+	if contains(cluster.Objects(backend.SMALL_FILE_POOLNAME), oid) {
+		t.Fatal("Object not recycled:", oid)
+	}
+}
+
+func TestPickGatewayCluster(t *testing.T) {
+	gateway, err := s3gateway.NewGatewayCluster("cold", helper.S3GatewayConfig{
+		Endpoint:       "http://127.0.0.1:1",
+		Region:         "us-east-1",
+		Bucket:         "bucket",
+		StorageClasses: []string{"GLACIER"},
+	})
+	if err != nil {
+		t.Fatal("NewGatewayCluster error:", err)
+	}
+	local := memory.NewMemoryCluster("local")
+	y := &YigStorage{
+		DataStorage: map[string]backend.Cluster{local.ID(): local, gateway.ID(): gateway},
+		MetaStorage: meta.New(meta.NoCache),
+	}
+
+	cluster, pool := y.pickClusterAndPool("bucket", "a", types.ObjectStorageClassGlacier, 1, false)
+	if cluster != gateway || pool != backend.GLACIER_FILE_POOLNAME {
+		t.Fatal("Picked", cluster.ID(), pool, "for GLACIER object")
+	}
+	// gateways are never picked for other storage classes
+	for i := 0; i < 10; i++ {
+		cluster, _ = y.pickClusterAndPool("bucket", "a", types.ObjectStorageClassStandard, 1, false)
+		if cluster != local {
+			t.Fatal("Picked", cluster.ID(), "for STANDARD object")
+		}
+	}
+}
+
+func TestRecycleToGarbageCollection(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	client, cluster := makeBucket(t, yig, "recycle")
+	injected := errors.New("injected")
+	cluster.SetRemoveHook(func(poolName, oid string) error { return injected })
+	defer cluster.SetRemoveHook(nil)
+
+	inGc := func(objectId string) bool {
+		gcs, err := client.ScanGarbageCollection(100, "")
+		if err != nil {
+			t.Fatal("ScanGarbageCollection error:", err)
+		}
+		for _, gc := range gcs {
+			if gc.Location == cluster.ID() && gc.Pool == backend.BIG_FILE_POOLNAME && gc.ObjectId == objectId {
+				return true
+			}
+		}
+		return false
+	}
+
+	// objects are saved into gc on the first failure, and left to gc after MAX_TRY_TIMES failures
+	object := objectToRecycle{location: cluster.ID(), pool: backend.BIG_FILE_POOLNAME, objectId: "oid"}
+	for i := 1; i < MAX_TRY_TIMES; i++ {
+		if yig.recycle(&object) {
+			t.Fatal("Recycled on failure", i)
+		}
+		if !inGc("oid") {
+			t.Fatal("Not saved into gc after failure", i)
+		}
+	}
+	if !yig.recycle(&object) {
+		t.Fatal("Not left to gc after", MAX_TRY_TIMES, "failures")
+	}
+
+	// objects queued are saved into gc, and removed from gc once removed
+	cluster.SetRemoveHook(nil)
+	queued := objectToRecycle{location: cluster.ID(), pool: backend.BIG_FILE_POOLNAME, objectId: "queued"}
+	yig.saveGarbage(&queued)
+	if !inGc("queued") {
+		t.Fatal("Not saved into gc when queued")
+	}
+	if !yig.recycle(&queued) || inGc("queued") {
+		t.Fatal("Not removed from gc when recycled")
+	}
+}
+
+func TestTransitChangedObject(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	client, _ := makeBucket(t, yig, "transit")
+	if _, err := putObject(yig, "transit", "a", []byte("data")); err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	stale, err := yig.GetObjectInfo("transit", "a", "", credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	if err = yig.TransitObject(stale, types.ObjectStorageClassStandardIa); err != nil {
+		t.Fatal("TransitObject error:", err)
+	}
+	transited, err := yig.GetObjectInfo("transit", "a", "", credential)
+	if err != nil || transited.ObjectId == stale.ObjectId ||
+		transited.StorageClass != types.ObjectStorageClassStandardIa {
+		t.Fatal("GetObjectInfo after transited returns", transited, err)
+	}
+
+	// transited already, the object is not located as read
+	if err = yig.TransitObject(stale, types.ObjectStorageClassGlacier); err == nil {
+		t.Fatal("TransitObject changed object, expected error")
+	}
+	object, err := yig.GetObjectInfo("transit", "a", "", credential)
+	if err != nil || object.ObjectId != transited.ObjectId || object.StorageClass != transited.StorageClass {
+		t.Fatal("GetObjectInfo after transiting changed object returns", object, err)
+	}
+	gcs, err := client.ScanGarbageCollection(100, "")
+	if err != nil {
+		t.Fatal("ScanGarbageCollection error:", err)
+	}
+	for _, gc := range gcs {
+		if gc.ObjectId == object.ObjectId {
+			t.Fatal("Data of the object is put into gc")
+		}
+	}
+}
+
+func TestRestoreObject(t *testing.T) {
+	yig := newStorage()
+	defer yig.Stop()
+	client, cluster := makeBucket(t, yig, "restore")
+	data := []byte("frozen")
+	_, err := yig.PutObject("restore", "a", credential, int64(len(data)),
+		ioutil.NopCloser(bytes.NewReader(data)), map[string]string{}, datatype.Acl{CannedAcl: "private"},
+		datatype.SseRequest{}, types.ObjectStorageClassGlacier, nil, datatype.ObjectLock{})
+	if err != nil {
+		t.Fatal("PutObject error:", err)
+	}
+	err = yig.CreateFreezer(&types.Freezer{BucketName: "restore", Name: "a",
+		Status: types.ObjectNeedRestore, LifeTime: 1})
+	if err != nil {
+		t.Fatal("CreateFreezer error:", err)
+	}
+	freezers, err := yig.ScanFreezers(10, "", types.ObjectNeedRestore)
+	if err != nil || len(freezers) != 1 {
+		t.Fatal("ScanFreezers returns", freezers, err)
+	}
+	if err = yig.RestoreObject(&freezers[0]); err != nil {
+		t.Fatal("RestoreObject error:", err)
+	}
+	// restored already
+	if err = yig.RestoreObject(&freezers[0]); err != nil {
+		t.Fatal("RestoreObject again error:", err)
+	}
+
+	freezer, err := yig.GetFreezer("restore", "a", "")
+	if err != nil || freezer.Status != types.ObjectHasRestored || freezer.Pool != backend.SMALL_FILE_POOLNAME {
+		t.Fatal("GetFreezer returns", freezer, err)
+	}
+	object, err := yig.GetObjectInfo("restore", "a", "", credential)
+	if err != nil {
+		t.Fatal("GetObjectInfo error:", err)
+	}
+	object.Pool = freezer.Pool
+	object.Location = freezer.Location
+	object.ObjectId = freezer.ObjectId
+	var buffer bytes.Buffer
+	err = yig.GetObject(object, 0, object.Size, &buffer, datatype.SseRequest{})
+	if err != nil || !bytes.Equal(buffer.Bytes(), data) {
+		t.Fatalf("GetObject restored copy %q, error %v", buffer.Bytes(), err)
+	}
+
+	now := time.Now()
+	if expired, err := yig.ExpireFreezer(freezer, now); expired || err != nil {
+		t.Fatal("ExpireFreezer before LifeTime returns", expired, err)
+	}
+	if expired, err := yig.ExpireFreezer(freezer, now.AddDate(0, 0, 2)); !expired || err != nil {
+		t.Fatal("ExpireFreezer after LifeTime returns", expired, err)
+	}
+	if _, err = yig.GetFreezer("restore", "a", ""); err != ErrNoSuchKey {
+		t.Fatal("GetFreezer expired, expected ErrNoSuchKey, got", err)
+	}
+	// the copy is removed by the gc tool
+	gcs, err := client.ScanGarbageCollection(1000, "")
+	if err != nil {
+		t.Fatal("ScanGarbageCollection error:", err)
+	}
+	for _, gc := range gcs {
+		if gc.ObjectId == freezer.ObjectId && gc.Pool == freezer.Pool {
+			if !contains(cluster.Objects(freezer.Pool), gc.ObjectId) {
+				t.Fatal("Restored copy in garbage collection is removed:", gc.ObjectId)
+			}
+			return
+		}
+	}
+	t.Fatal("Restored copy not found in gc:", gcs)
+}
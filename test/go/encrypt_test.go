@@ -171,6 +171,30 @@ func TestS3Client_GetEncryptObjectWithSSES3(t *testing.T) {
 	t.Log("GetEncryptObjectWithSSES3 Success value:", v)
 }
 
+func Test_PutEncryptObjectWithSSEKMS(t *testing.T) {
+	sc := NewS3()
+	err := sc.PutEncryptObjectWithSSEKMS(TEST_BUCKET, TEST_KEY, TEST_VALUE, "yig-test-key")
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSEKMS err:", err)
+	}
+	t.Log("PutEncryptObjectWithSSEKMS Success!")
+}
+
+func TestS3Client_GetEncryptObjectWithSSEKMS(t *testing.T) {
+	sc := NewS3()
+	v, keyId, err := sc.GetEncryptObjectWithSSEKMS(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSEKMS err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSEKMS err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+	if keyId != "yig-test-key" {
+		t.Fatal("GetEncryptObjectWithSSEKMS err: key id is:", keyId, ", but should be: yig-test-key")
+	}
+	t.Log("GetEncryptObjectWithSSEKMS Success value:", v)
+}
+
 func Test_Encrypt_End(t *testing.T) {
 	sc := NewS3()
 	err := sc.DeleteObject(TEST_BUCKET, TEST_KEY)
@@ -636,3 +660,356 @@ func Test_CopyObjectPartWithSSES3(t *testing.T) {
 		panic(err)
 	}
 }
+
+func Test_AppendObjectWithSSES3(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+		panic(err)
+	}
+	nextPos, err := sc.AppendEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY, TEST_VALUE, 0)
+	if err != nil {
+		t.Fatal("AppendEncryptObjectWithSSES3 err:", err)
+	}
+	// later appends are encrypted with the key of the object
+	nextPos, err = sc.AppendObject(TEST_BUCKET, TEST_KEY, TEST_VALUE+"APPEND", nextPos)
+	if err != nil {
+		t.Fatal("AppendObject err:", err)
+	}
+	v, err := sc.GetEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSES3 err:", err)
+	}
+	if v != TEST_VALUE+TEST_VALUE+"APPEND" {
+		t.Fatal("GetEncryptObjectWithSSES3 err: value is:", v, ", but should be:", TEST_VALUE+TEST_VALUE+"APPEND")
+	}
+	t.Log("GetEncryptObjectWithSSES3 Success value:", v)
+}
+
+func Test_AppendObjectWithBucketEncryption(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+		panic(err)
+	}
+	var config = &datatype.EncryptionConfiguration{}
+	err = xml.Unmarshal([]byte(EncryptionSSES3XML), config)
+	if err != nil {
+		t.Fatal("Unmarshal encryption configuration err:", err)
+	}
+	err = sc.PutBucketEncryptionWithXml(TEST_BUCKET, TransferToS3AccessEncryptionConfiguration(config))
+	if err != nil {
+		t.Fatal("PutBucketEncryptionWithXml err:", err)
+	}
+
+	nextPos, err := sc.AppendObject(TEST_BUCKET, TEST_KEY, TEST_VALUE, 0)
+	if err != nil {
+		t.Fatal("AppendObject err:", err)
+	}
+	_, err = sc.AppendObject(TEST_BUCKET, TEST_KEY, TEST_VALUE, nextPos)
+	if err != nil {
+		t.Fatal("AppendObject err:", err)
+	}
+	out, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(TEST_BUCKET),
+		Key:    aws.String(TEST_KEY),
+	})
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if aws.StringValue(out.ServerSideEncryption) != "AES256" {
+		t.Fatal("HeadObject err: encryption is:", aws.StringValue(out.ServerSideEncryption), ", but should be: AES256")
+	}
+	v, err := sc.GetEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSES3 err:", err)
+	}
+	if v != TEST_VALUE+TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSES3 err: value is:", v, ", but should be:", TEST_VALUE+TEST_VALUE)
+	}
+
+	_, err = sc.DeleteBucketEncryption(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("DeleteBucketEncryption err:", err)
+	}
+}
+
+func Test_CopyObjectFromSSECToSSES3(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutEncryptObjectWithSSEC(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSEC err:", err)
+	}
+	defer sc.DeleteObject(TEST_BUCKET, TEST_KEY+"-copy")
+
+	// customer key of the source is required
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(TEST_BUCKET),
+		CopySource:           aws.String(TEST_BUCKET + "/" + TEST_KEY),
+		Key:                  aws.String(TEST_KEY + "-copy"),
+		ServerSideEncryption: aws.String("AES256"),
+	}
+	_, err = sc.Client.CopyObject(input)
+	if err == nil {
+		t.Fatal("Copy Object without customer key of the source should fail")
+	}
+
+	input.CopySourceSSECustomerAlgorithm = aws.String("AES256")
+	input.CopySourceSSECustomerKey = aws.String(TEST_SSEC_KEY)
+	input.CopySourceSSECustomerKeyMD5 = aws.String(SSECKeyMD5(TEST_SSEC_KEY))
+	out, err := sc.Client.CopyObject(input)
+	if err != nil {
+		t.Fatal("Copy Object err:", err)
+	}
+	if aws.StringValue(out.ServerSideEncryption) != "AES256" {
+		t.Fatal("Copy Object err: encryption is:", aws.StringValue(out.ServerSideEncryption), ", but should be: AES256")
+	}
+	v, err := sc.GetEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY+"-copy")
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSES3 err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSES3 err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
+
+func Test_CopyObjectFromSSES3ToSSEC(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSES3 err:", err)
+	}
+	defer sc.DeleteObject(TEST_BUCKET, TEST_KEY+"-copy")
+
+	// the source is not encrypted with SSE-C
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(TEST_BUCKET),
+		CopySource:                     aws.String(TEST_BUCKET + "/" + TEST_KEY),
+		Key:                            aws.String(TEST_KEY + "-copy"),
+		CopySourceSSECustomerAlgorithm: aws.String("AES256"),
+		CopySourceSSECustomerKey:       aws.String(TEST_SSEC_KEY),
+		CopySourceSSECustomerKeyMD5:    aws.String(SSECKeyMD5(TEST_SSEC_KEY)),
+		SSECustomerAlgorithm:           aws.String("AES256"),
+		SSECustomerKey:                 aws.String(TEST_SSEC_COPY_KEY),
+		SSECustomerKeyMD5:              aws.String(SSECKeyMD5(TEST_SSEC_COPY_KEY)),
+	}
+	_, err = sc.Client.CopyObject(input)
+	if err == nil {
+		t.Fatal("Copy Object with customer key of SSE-S3 source should fail")
+	}
+
+	input.CopySourceSSECustomerAlgorithm = nil
+	input.CopySourceSSECustomerKey = nil
+	input.CopySourceSSECustomerKeyMD5 = nil
+	out, err := sc.Client.CopyObject(input)
+	if err != nil {
+		t.Fatal("Copy Object err:", err)
+	}
+	if aws.StringValue(out.SSECustomerKeyMD5) != SSECKeyMD5(TEST_SSEC_COPY_KEY) {
+		t.Fatal("Copy Object err: customer key MD5 is:", aws.StringValue(out.SSECustomerKeyMD5))
+	}
+	v, err := sc.GetEncryptObjectWithSSECKey(TEST_BUCKET, TEST_KEY+"-copy", TEST_SSEC_COPY_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSECKey err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSECKey err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
+
+func Test_CopyObjectFromPlainToSSEC(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutObject(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	defer sc.DeleteObject(TEST_BUCKET, TEST_KEY+"-copy")
+
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(TEST_BUCKET),
+		CopySource:           aws.String(TEST_BUCKET + "/" + TEST_KEY),
+		Key:                  aws.String(TEST_KEY + "-copy"),
+		SSECustomerAlgorithm: aws.String("AES256"),
+		SSECustomerKey:       aws.String(TEST_SSEC_KEY),
+		SSECustomerKeyMD5:    aws.String(SSECKeyMD5(TEST_SSEC_KEY)),
+	}
+	_, err = sc.Client.CopyObject(input)
+	if err != nil {
+		t.Fatal("Copy Object err:", err)
+	}
+	v, err := sc.GetEncryptObjectWithSSEC(TEST_BUCKET, TEST_KEY+"-copy")
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSEC err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSEC err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
+
+func Test_CopyObjectWithAnotherSSECKey(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutEncryptObjectWithSSEC(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSEC err:", err)
+	}
+
+	// copy to itself, the object is encrypted again with the new customer key
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(TEST_BUCKET),
+		CopySource:                     aws.String(TEST_BUCKET + "/" + TEST_KEY),
+		Key:                            aws.String(TEST_KEY),
+		CopySourceSSECustomerAlgorithm: aws.String("AES256"),
+		CopySourceSSECustomerKey:       aws.String(TEST_SSEC_KEY),
+		CopySourceSSECustomerKeyMD5:    aws.String(SSECKeyMD5(TEST_SSEC_KEY)),
+		SSECustomerAlgorithm:           aws.String("AES256"),
+		SSECustomerKey:                 aws.String(TEST_SSEC_COPY_KEY),
+		SSECustomerKeyMD5:              aws.String(SSECKeyMD5(TEST_SSEC_COPY_KEY)),
+	}
+	_, err = sc.Client.CopyObject(input)
+	if err != nil {
+		t.Fatal("Copy Object err:", err)
+	}
+	v, err := sc.GetEncryptObjectWithSSECKey(TEST_BUCKET, TEST_KEY, TEST_SSEC_COPY_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSECKey err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSECKey err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
+
+func Test_CopyObjectPartFromSSECToSSES3(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutEncryptObjectWithSSEC(TEST_BUCKET, TEST_KEY+"-source", TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSEC err:", err)
+	}
+	defer sc.DeleteObject(TEST_BUCKET, TEST_KEY+"-source")
+
+	uploadId, err := sc.CreateMultiPartUploadWithSSES3(TEST_BUCKET, TEST_KEY, s3.ObjectStorageClassStandard)
+	if err != nil {
+		t.Fatal("CreateMultiPartUploadWithSSES3 err:", err)
+	}
+	input := &s3.UploadPartCopyInput{
+		Bucket:                         aws.String(TEST_BUCKET),
+		CopySource:                     aws.String(TEST_BUCKET + "/" + TEST_KEY + "-source"),
+		Key:                            aws.String(TEST_KEY),
+		PartNumber:                     aws.Int64(1),
+		UploadId:                       aws.String(uploadId),
+		CopySourceSSECustomerAlgorithm: aws.String("AES256"),
+		CopySourceSSECustomerKey:       aws.String(TEST_SSEC_KEY),
+		CopySourceSSECustomerKeyMD5:    aws.String(SSECKeyMD5(TEST_SSEC_KEY)),
+	}
+	out, err := sc.Client.UploadPartCopy(input)
+	if err != nil {
+		sc.AbortMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId)
+		t.Fatal("UploadPartCopy err:", err)
+	}
+	completedUpload := &s3.CompletedMultipartUpload{
+		Parts: []*s3.CompletedPart{{
+			ETag:       out.CopyPartResult.ETag,
+			PartNumber: aws.Int64(1),
+		}},
+	}
+	err = sc.CompleteMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId, completedUpload)
+	if err != nil {
+		sc.AbortMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId)
+		t.Fatal("CompleteMultiPartUpload err:", err)
+	}
+	v, err := sc.GetEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSES3 err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSES3 err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
+
+func Test_CopyObjectPartFromSSES3ToSSEC(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutEncryptObjectWithSSES3(TEST_BUCKET, TEST_KEY+"-source", TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutEncryptObjectWithSSES3 err:", err)
+	}
+	defer sc.DeleteObject(TEST_BUCKET, TEST_KEY+"-source")
+
+	uploadId, err := sc.CreateMultiPartUploadWithSSEC(TEST_BUCKET, TEST_KEY, s3.ObjectStorageClassStandard)
+	if err != nil {
+		t.Fatal("CreateMultiPartUploadWithSSEC err:", err)
+	}
+	input := &s3.UploadPartCopyInput{
+		Bucket:               aws.String(TEST_BUCKET),
+		CopySource:           aws.String(TEST_BUCKET + "/" + TEST_KEY + "-source"),
+		Key:                  aws.String(TEST_KEY),
+		PartNumber:           aws.Int64(1),
+		UploadId:             aws.String(uploadId),
+		SSECustomerAlgorithm: aws.String("AES256"),
+		SSECustomerKey:       aws.String(TEST_SSEC_KEY),
+		SSECustomerKeyMD5:    aws.String(SSECKeyMD5(TEST_SSEC_KEY)),
+	}
+	out, err := sc.Client.UploadPartCopy(input)
+	if err != nil {
+		sc.AbortMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId)
+		t.Fatal("UploadPartCopy err:", err)
+	}
+	completedUpload := &s3.CompletedMultipartUpload{
+		Parts: []*s3.CompletedPart{{
+			ETag:       out.CopyPartResult.ETag,
+			PartNumber: aws.Int64(1),
+		}},
+	}
+	err = sc.CompleteMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId, completedUpload)
+	if err != nil {
+		sc.AbortMultiPartUpload(TEST_BUCKET, TEST_KEY, uploadId)
+		t.Fatal("CompleteMultiPartUpload err:", err)
+	}
+	v, err := sc.GetEncryptObjectWithSSEC(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetEncryptObjectWithSSEC err:", err)
+	}
+	if v != TEST_VALUE {
+		t.Fatal("GetEncryptObjectWithSSEC err: value is:", v, ", but should be:", TEST_VALUE)
+	}
+}
@@ -99,6 +99,48 @@ func (s3client *S3Client) GetEncryptObjectWithSSES3(bucketName, key string) (val
 	return string(data), err
 }
 
+func (s3client *S3Client) AppendEncryptObjectWithSSES3(bucketName, key, value string, position int64) (nextPos int64, err error) {
+	var out *s3.AppendObjectOutput
+	params := &s3.AppendObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader([]byte(value)),
+		Position:             aws.Int64(position),
+		ServerSideEncryption: aws.String("AES256"),
+	}
+	if out, err = s3client.Client.AppendObject(params); err != nil {
+		return 0, err
+	}
+	return *out.NextPosition, nil
+}
+
+func (s3client *S3Client) PutEncryptObjectWithSSEKMS(bucketName, key, value, kmsKeyId string) (err error) {
+	params := &s3.PutObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader([]byte(value)),
+		ServerSideEncryption: aws.String("aws:kms"),
+		SSEKMSKeyId:          aws.String(kmsKeyId),
+	}
+	if _, err := s3client.Client.PutObject(params); err != nil {
+		return err
+	}
+	return err
+}
+
+func (s3client *S3Client) GetEncryptObjectWithSSEKMS(bucketName, key string) (value, kmsKeyId string, err error) {
+	params := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	out, err := s3client.Client.GetObject(params)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := ioutil.ReadAll(out.Body)
+	return string(data), aws.StringValue(out.SSEKMSKeyId), err
+}
+
 func (s3client *S3Client) CreateMultiPartUploadWithSSEC(bucketName, key, storageClass string) (uploadId string, err error) {
 	ssekey := "qwertyuiopasdfghjklzxcvbnmaaaaaa"
 	hash := md5.New()
@@ -132,3 +174,32 @@ func (s3client *S3Client) CreateMultiPartUploadWithSSES3(bucketName, key, storag
 	}
 	return *out.UploadId, nil
 }
+
+const (
+	// customer key of PutEncryptObjectWithSSEC and GetEncryptObjectWithSSEC
+	TEST_SSEC_KEY = "qwertyuiopasdfghjklzxcvbnmaaaaaa"
+	// another customer key, to encrypt the target of copy
+	TEST_SSEC_COPY_KEY = "mnbvcxzlkjhgfdsapoiuytrewqbbbbbb"
+)
+
+// SSECKeyMD5 returns base64 encoded MD5 of the customer key
+func SSECKeyMD5(ssekey string) string {
+	sum := md5.Sum([]byte(ssekey))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (s3client *S3Client) GetEncryptObjectWithSSECKey(bucketName, key, ssekey string) (value string, err error) {
+	params := &s3.GetObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		SSECustomerAlgorithm: aws.String("AES256"),
+		SSECustomerKey:       aws.String(ssekey),
+		SSECustomerKeyMD5:    aws.String(SSECKeyMD5(ssekey)),
+	}
+	out, err := s3client.Client.GetObject(params)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(out.Body)
+	return string(data), err
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/ceph"
+	"github.com/journeymidnight/yig/filesystem"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/client/mysqlclient"
+	"github.com/journeymidnight/yig/meta/client/postgresclient"
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	"github.com/journeymidnight/yig/meta/migrate"
+)
+
+const DEFAULT_FSCK_LOG_PATH = "/var/log/yig/fsck.log"
+
+// dataRef is an object in a pool of a cluster
+type dataRef struct {
+	cluster string
+	pool    string
+	oid     string
+}
+
+// refs from metadata to data, the value describes the row referring to it
+type refs map[dataRef]string
+
+// queries select bucket name, object name, version, location, pool and object id
+// of every row referring to data. Parts have no location and pool, they are joined with
+// their objects.
+func refQueries(store string) map[string]string {
+	// version of objectpart is a varchar in TiDB and MySQL
+	partVersion := "cast(o.version as char)"
+	if store == migrate.POSTGRES {
+		partVersion = "o.version"
+	}
+	return map[string]string{
+		"objects": "select bucketname,name,version,location,pool,objectid from objects",
+		"objectpart": "select o.bucketname,o.name,o.version,o.location,o.pool,p.objectid " +
+			"from objectpart p join objects o on p.bucketname=o.bucketname and p.objectname=o.name " +
+			"and p.version=" + partVersion,
+		"multipartpart": "select m.bucketname,m.objectname,m.uploadtime,m.location,m.pool,p.objectid " +
+			"from multipartpart p join multiparts m on p.bucketname=m.bucketname and p.objectname=m.objectname " +
+			"and p.uploadtime=m.uploadtime",
+		"gc": "select bucketname,objectname,version,location,pool,objectid from gc",
+		"gcpart": "select g.bucketname,g.objectname,g.version,g.location,g.pool,p.objectid " +
+			"from gcpart p join gc g on p.bucketname=g.bucketname and p.objectname=g.objectname " +
+			"and p.version=g.version",
+		"restoreobjects": "select bucketname,objectname,version,location,pool,objectid from restoreobjects",
+		"restoreobjectpart": "select r.bucketname,r.objectname,r.version,r.location,r.pool,p.objectid " +
+			"from restoreobjectpart p join restoreobjects r on p.bucketname=r.bucketname " +
+			"and p.objectname=r.objectname and p.version=r.version",
+	}
+}
+
+// scanRefs loads refs of all tables into memory
+func scanRefs(db *sql.DB, store string) (refs, error) {
+	all := make(refs)
+	for table, query := range refQueries(store) {
+		rows, err := db.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %v", table, err)
+		}
+		for rows.Next() {
+			var bucketName, objectName, version, location, pool, oid sql.NullString
+			err = rows.Scan(&bucketName, &objectName, &version, &location, &pool, &oid)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan %s: %v", table, err)
+			}
+			if oid.String == "" {
+				// delete markers and multipart objects have no data of their own
+				continue
+			}
+			all[dataRef{location.String, pool.String, oid.String}] = fmt.Sprintf("%s %s/%s version %s",
+				table, bucketName.String, objectName.String, version.String)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %v", table, err)
+		}
+	}
+	return all, nil
+}
+
+// danglingObjMaps returns null versions in objmap without objects
+func danglingObjMaps(db *sql.DB) (dangling []string, err error) {
+	rows, err := db.Query("select m.bucketname,m.objectname,m.nullvernum from objmap m left join objects o " +
+		"on m.bucketname=o.bucketname and m.objectname=o.name and m.nullvernum=o.version where o.name is null")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucketName, objectName, version string
+		err = rows.Scan(&bucketName, &objectName, &version)
+		if err != nil {
+			return
+		}
+		dangling = append(dangling, fmt.Sprintf("objmap %s/%s version %s", bucketName, objectName, version))
+	}
+	return dangling, rows.Err()
+}
+
+func openFsckMetaStore() *sql.DB {
+	switch helper.CONFIG.MetaStore {
+	case migrate.TIDB:
+		return tidbclient.NewTidbClient().Client
+	case migrate.MYSQL:
+		return mysqlclient.NewMysqlClient().Client
+	case migrate.POSTGRES:
+		// needs fsck built with `-tags postgres`
+		return postgresclient.NewPostgresClient().Client
+	default:
+		fmt.Println("fsck does not support meta store", helper.CONFIG.MetaStore)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func openDataStore() map[string]backend.Cluster {
+	switch helper.CONFIG.DataStore {
+	case "ceph":
+		return ceph.Initialize(helper.CONFIG)
+	case "filesystem":
+		return filesystem.Initialize(helper.CONFIG)
+	default:
+		fmt.Println("fsck does not support data store", helper.CONFIG.DataStore)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func main() {
+	reclaim := flag.Bool("reclaim", false, "remove orphaned objects, only report them by default")
+	grace := flag.Duration("grace", 10*time.Minute,
+		"wait before scanning metadata again, objects being uploaded are not orphans if referred by then")
+	poolNames := flag.String("pools", strings.Join([]string{backend.SMALL_FILE_POOLNAME,
+		backend.BIG_FILE_POOLNAME, backend.GLACIER_FILE_POOLNAME}, ","), "pools to check, separated by comma")
+	flag.Parse()
+
+	helper.SetupConfig()
+	helper.Logger = log.NewFileLogger(DEFAULT_FSCK_LOG_PATH, log.ParseLevel(helper.CONFIG.LogLevel))
+	defer helper.Logger.Close()
+
+	db := openFsckMetaStore()
+	defer db.Close()
+	err := migrate.New(db, helper.CONFIG.MetaStore).Check()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	clusters := openDataStore()
+	pools := strings.Split(*poolNames, ",")
+
+	// metadata is scanned before and after listing data, data is orphaned if referred by neither,
+	// and missing if referred by both, so objects put or deleted in the meantime are not reported
+	before, err := scanRefs(db, helper.CONFIG.MetaStore)
+	if err != nil {
+		fmt.Println("Failed to scan metadata:", err)
+		os.Exit(1)
+	}
+	helper.Logger.Info("Scanned metadata,", len(before), "objects referred")
+	listed := make(map[dataRef]bool)
+	checked := make(map[string]bool) // cluster/pool listed
+	for id, cluster := range clusters {
+		lister, ok := cluster.(backend.Lister)
+		if !ok {
+			helper.Logger.Warn("Cluster", id, "could not list objects, skipped")
+			continue
+		}
+		for _, pool := range pools {
+			err = lister.List(pool, func(oid string) error {
+				listed[dataRef{id, pool, oid}] = true
+				return nil
+			})
+			if err != nil {
+				fmt.Println("Failed to list pool", pool, "of cluster", id, ":", err)
+				os.Exit(1)
+			}
+			checked[id+"/"+pool] = true
+		}
+	}
+	helper.Logger.Info("Listed", len(listed), "objects in data store, wait", grace.String())
+	time.Sleep(*grace)
+	after, err := scanRefs(db, helper.CONFIG.MetaStore)
+	if err != nil {
+		fmt.Println("Failed to scan metadata:", err)
+		os.Exit(1)
+	}
+
+	var orphans, reclaimed, missing int
+	for ref := range listed {
+		if _, ok := before[ref]; ok {
+			continue
+		}
+		if _, ok := after[ref]; ok {
+			continue
+		}
+		orphans += 1
+		if !*reclaim {
+			fmt.Println("orphan", ref.cluster, ref.pool, ref.oid)
+			continue
+		}
+		err = clusters[ref.cluster].Remove(ref.pool, ref.oid)
+		if err != nil {
+			fmt.Println("orphan", ref.cluster, ref.pool, ref.oid, "failed to reclaim:", err)
+			continue
+		}
+		helper.Logger.Info("Reclaimed orphan", ref.cluster, ref.pool, ref.oid)
+		fmt.Println("reclaimed", ref.cluster, ref.pool, ref.oid)
+		reclaimed += 1
+	}
+	for ref, row := range before {
+		if _, ok := after[ref]; !ok || listed[ref] || !checked[ref.cluster+"/"+ref.pool] {
+			continue
+		}
+		missing += 1
+		fmt.Println("missing", ref.cluster, ref.pool, ref.oid, "of", row)
+	}
+	objMaps, err := danglingObjMaps(db)
+	if err != nil {
+		fmt.Println("Failed to check objmap:", err)
+		os.Exit(1)
+	}
+	for _, objMap := range objMaps {
+		fmt.Println("dangling", objMap)
+	}
+	fmt.Printf("%d orphans, %d reclaimed, %d objects missing data, %d dangling objmap\n",
+		orphans, reclaimed, missing, len(objMaps))
+}
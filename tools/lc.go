@@ -12,8 +12,6 @@ import (
 	"github.com/journeymidnight/yig/storage"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -72,125 +70,319 @@ func checkIfExpiration(updateTime time.Time, days int) bool {
 	}
 }
 
-// If a rule has an empty prefix, the days in it will be consider as a default days for all objects that not specified in
-// other rules. For this reason, we have two conditions to check if a object has expired and should be deleted
-//  if defaultConfig == true
-//                    for each object           check if object name has a prifix
-//  list all objects --------------->loop rules---------------------------------->
-//                                                                      |     NO
-//                                                                      |--------> days = default days ---
-//                                                                      |     YES                         |->delete object if expired
-//                                                                      |--------> days = specify days ---
-//
-//  if defaultConfig == false
-//                 for each rule get objects by prefix
-//  iterator rules ----------------------------------> loop objects-------->delete object if expired
+func checkIfDate(date string) bool {
+	t, err := datatype.ParseLifecycleDate(date)
+	if err != nil {
+		return false
+	}
+	return !time.Now().Before(t)
+}
+
+var storageClassOrder = map[types.StorageClass]int{
+	types.ObjectStorageClassStandard:   0,
+	types.ObjectStorageClassStandardIa: 1,
+	types.ObjectStorageClassGlacier:    2,
+}
+
+func checkIfExpired(updateTime time.Time, expiration *datatype.LifecycleExpiration) bool {
+	if expiration.Date != "" {
+		return checkIfDate(expiration.Date)
+	}
+	if expiration.Days > 0 {
+		return checkIfExpiration(updateTime, expiration.Days)
+	}
+	return false
+}
+
+// Find the coldest storage class among transitions of the rules which are due,
+// returns false if the object should stay in its current storage class
+func checkIfTransition(object *types.Object, rules []datatype.LifecycleRule) (types.StorageClass, bool) {
+	target := object.StorageClass
+	for _, rule := range rules {
+		for _, transition := range rule.Transitions {
+			due := false
+			if transition.Date != "" {
+				due = checkIfDate(transition.Date)
+			} else {
+				due = checkIfExpiration(object.LastModifiedTime, transition.Days)
+			}
+			if !due {
+				continue
+			}
+			storageClass, err := types.MatchStorageClassIndex(transition.StorageClass)
+			if err != nil {
+				continue
+			}
+			if storageClassOrder[storageClass] > storageClassOrder[target] {
+				target = storageClass
+			}
+		}
+	}
+	return target, target != object.StorageClass
+}
+
+// Report whether expiration of any of the rules is due
+func checkIfRulesExpired(updateTime time.Time, rules []datatype.LifecycleRule) bool {
+	for _, rule := range rules {
+		if rule.Expiration != nil && checkIfExpired(updateTime, rule.Expiration) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete the object if any of the matching rules expires it, or move it to
+// another storage class if any transition of the rules is due
+func processObject(object *types.Object, rules []datatype.LifecycleRule) {
+	if checkIfRulesExpired(object.LastModifiedTime, rules) {
+		// only noncurrent versions are removed by version, see processNoncurrentVersion
+		result, err := yig.ExpireObject(object)
+		if err != nil {
+			helper.Logger.Error(object.BucketName, object.Name, "failed:", err)
+			return
+		}
+		if result.DeleteMarker {
+			helper.Logger.Info("Created delete marker:", object.BucketName, object.Name, result.VersionId)
+		} else {
+			helper.Logger.Info("Deleted:", object.BucketName, object.Name)
+		}
+		return
+	}
+	if _, ok := storageClassOrder[object.StorageClass]; !ok {
+		return
+	}
+	storageClass, ok := checkIfTransition(object, rules)
+	if !ok {
+		return
+	}
+	err := yig.TransitObject(object, storageClass)
+	if err != nil {
+		helper.Logger.Error(object.BucketName, object.Name, object.VersionId,
+			"transit to", storageClass.ToString(), "failed:", err)
+		return
+	}
+	helper.Logger.Info("Transited:", object.BucketName, object.Name, object.VersionId,
+		"to", storageClass.ToString())
+}
+
+// Each object is matched against the rules by bucket.Lifecycle.MatchRules, actions of all
+// matching rules are applied, a rule without filter applies to all objects.
+//                    for each object           collect rules selecting the object
+//  list all objects --------------->loop rules------------------------------------>
+//                                                                                  |
+//                                       delete object if any expiration is due  <--|
+//                                       or transit it to the coldest due class
 func retrieveBucket(lc types.LifeCycle) error {
-	defaultConfig := false
-	defaultDays := 0
 	bucket, err := yig.MetaStorage.GetBucket(lc.BucketName, false)
 	if err != nil {
 		return err
 	}
-	rules := bucket.Lifecycle.Rule
+	err = abortIncompleteMultipartUploads(bucket)
+	if err != nil {
+		return err
+	}
+	if bucket.Versioning != types.VersionDisabled {
+		return retrieveVersionedBucket(bucket)
+	}
+	var request datatype.ListObjectsRequest
+	request.Versioned = false
+	request.MaxKeys = 1000
+	for {
+		retObjects, _, truncated, nextMarker, _, err := yig.ListObjectsInternal(bucket.Name, request)
+		if err != nil {
+			return err
+		}
+		for _, object := range retObjects {
+			rules := bucket.Lifecycle.MatchRules(object.Name, object.Size, object.Tags)
+			if len(rules) == 0 {
+				continue
+			}
+			processObject(object, rules)
+		}
+		if truncated == true {
+			request.Marker = nextMarker
+		} else {
+			break
+		}
+	}
+	return nil
+}
+
+// Remove the noncurrent version if expired, or move it to another storage class if due.
+// A version becomes noncurrent when its successor is created, at noncurrentTime.
+// Returns true if the version is removed.
+func processNoncurrentVersion(object *types.Object, noncurrentTime time.Time, rules []datatype.LifecycleRule) (removed bool) {
+	expired := false
+	for _, rule := range rules {
+		if rule.NoncurrentVersionExpiration != nil &&
+			checkIfExpiration(noncurrentTime, rule.NoncurrentVersionExpiration.NoncurrentDays) {
+			expired = true
+		}
+	}
+	if expired {
+		err := yig.ExpireObjectVersion(object)
+		if err != nil {
+			helper.Logger.Error(object.BucketName, object.Name, object.GetVersionId(), "failed:", err)
+			return
+		}
+		helper.Logger.Info("Deleted noncurrent version:", object.BucketName, object.Name, object.GetVersionId())
+		return true
+	}
+	if object.DeleteMarker {
+		return
+	}
+	if _, ok := storageClassOrder[object.StorageClass]; !ok {
+		return
+	}
+	target := object.StorageClass
 	for _, rule := range rules {
-		if rule.Prefix == "" {
-			defaultConfig = true
-			defaultDays, err = strconv.Atoi(rule.Expiration)
+		for _, transition := range rule.NoncurrentVersionTransitions {
+			if !checkIfExpiration(noncurrentTime, transition.NoncurrentDays) {
+				continue
+			}
+			storageClass, err := types.MatchStorageClassIndex(transition.StorageClass)
 			if err != nil {
-				return err
+				continue
+			}
+			if storageClassOrder[storageClass] > storageClassOrder[target] {
+				target = storageClass
 			}
 		}
 	}
+	if target == object.StorageClass {
+		return
+	}
+	err := yig.TransitObject(object, target)
+	if err != nil {
+		helper.Logger.Error(object.BucketName, object.Name, object.GetVersionId(),
+			"transit to", target.ToString(), "failed:", err)
+		return
+	}
+	helper.Logger.Info("Transited noncurrent version:", object.BucketName, object.Name, object.GetVersionId(),
+		"to", target.ToString())
+	return
+}
+
+// In a versioned bucket, all versions of an object are listed from the latest to the oldest one.
+// The latest version is processed as in a unversioned bucket, the others by noncurrent version rules.
+// A delete marker is removed if ExpiredObjectDeleteMarker is set and it is the only version left.
+func retrieveVersionedBucket(bucket *types.Bucket) error {
 	var request datatype.ListObjectsRequest
-	request.Versioned = false
+	request.Versioned = true
 	request.MaxKeys = 1000
-	if defaultConfig == true {
-		for {
-			retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucket.Name, request)
-			if err != nil {
-				return err
+
+	var current *types.Object
+	var versionsLeft int
+	finishObject := func() {
+		if current == nil || !current.DeleteMarker || versionsLeft != 1 {
+			return
+		}
+		expired := false
+		for _, rule := range bucket.Lifecycle.MatchRules(current.Name, current.Size, current.Tags) {
+			if rule.Expiration != nil && rule.Expiration.ExpiredObjectDeleteMarker {
+				expired = true
 			}
+		}
+		if !expired {
+			return
+		}
+		err := yig.ExpireObjectVersion(current)
+		if err != nil {
+			helper.Logger.Error(current.BucketName, current.Name, current.GetVersionId(), "failed:", err)
+			return
+		}
+		helper.Logger.Info("Deleted expired delete marker:", current.BucketName, current.Name, current.GetVersionId())
+	}
 
-			for _, object := range retObjects {
-				prefixMatch := false
-				matchDays := 0
-				for _, rule := range rules {
-					if rule.Prefix == "" {
-						continue
-					}
-					if strings.HasPrefix(object.Name, rule.Prefix) == false {
-						continue
-					}
-					prefixMatch = true
-					matchDays, err = strconv.Atoi(rule.Expiration)
-					if err != nil {
-						return err
-					}
-				}
-				days := 0
-				if prefixMatch == true {
-					days = matchDays
-				} else {
-					days = defaultDays
-				}
-				helper.Logger.Info("inteval:", time.Since(object.LastModifiedTime).Seconds())
-				if checkIfExpiration(object.LastModifiedTime, days) {
-					helper.Logger.Info("come here")
-					if object.NullVersion {
-						object.VersionId = ""
-					}
-					_, err = yig.DeleteObject(object.BucketName, object.Name, object.VersionId, common.Credential{})
-					if err != nil {
-						helper.Logger.Error(object.BucketName, object.Name, object.VersionId, err)
-						continue
-					}
-					helper.Logger.Info("Deleted:", object.BucketName, object.Name, object.VersionId)
+	var successorTime time.Time
+	for {
+		retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucket.Name, request)
+		if err != nil {
+			return err
+		}
+		for _, object := range retObjects {
+			if current == nil || object.Name != current.Name {
+				finishObject()
+				current = object
+				versionsLeft = 1
+				successorTime = object.LastModifiedTime
+				rules := bucket.Lifecycle.MatchRules(object.Name, object.Size, object.Tags)
+				if len(rules) != 0 && !object.DeleteMarker {
+					processObject(object, rules)
 				}
+				continue
 			}
-			if truncated == true {
-				request.KeyMarker = nextMarker
-				request.VersionIdMarker = nextVerIdMarker
-			} else {
-				break
-			}
-		}
-	} else {
-		for _, rule := range rules {
-			if rule.Prefix == "" {
+			versionsLeft += 1
+			noncurrentTime := successorTime
+			successorTime = object.LastModifiedTime
+			rules := bucket.Lifecycle.MatchRules(object.Name, object.Size, object.Tags)
+			if len(rules) == 0 {
 				continue
 			}
-			days, _ := strconv.Atoi(rule.Expiration)
-			if err != nil {
-				return err
+			if processNoncurrentVersion(object, noncurrentTime, rules) {
+				versionsLeft -= 1
 			}
-			request.Prefix = rule.Prefix
-			for {
+		}
+		if truncated == true {
+			request.KeyMarker = nextMarker
+			request.VersionIdMarker = nextVerIdMarker
+		} else {
+			break
+		}
+	}
+	finishObject()
+	return nil
+}
 
-				retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucket.Name, request)
-				if err != nil {
-					return err
-				}
-				for _, object := range retObjects {
-					if checkIfExpiration(object.LastModifiedTime, days) {
-						_, err = yig.DeleteObject(object.BucketName, object.Name, object.VersionId, common.Credential{})
-						if err != nil {
-							helper.Logger.Error(object.BucketName, object.Name, object.VersionId, "failed:", err)
-							continue
-						}
-						helper.Logger.Info("Deleted:", object.BucketName, object.Name, object.VersionId)
-					}
-				}
-				if truncated == true {
-					request.KeyMarker = nextMarker
-					request.VersionIdMarker = nextVerIdMarker
-				} else {
-					break
+// Abort multipart uploads initiated DaysAfterInitiation days ago, their parts are removed by gc
+func abortIncompleteMultipartUploads(bucket *types.Bucket) error {
+	enabled := false
+	for _, rule := range bucket.Lifecycle.Rule {
+		if rule.AbortIncompleteMultipartUpload != nil {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	credential := common.Credential{UserId: bucket.OwnerId}
+	var request datatype.ListUploadsRequest
+	request.MaxUploads = 1000
+	for {
+		result, err := yig.ListMultipartUploads(credential, bucket.Name, request)
+		if err != nil {
+			return err
+		}
+		for _, upload := range result.Uploads {
+			multipart, err := yig.MetaStorage.GetMultipart(bucket.Name, upload.Key, upload.UploadId)
+			if err != nil {
+				helper.Logger.Error(bucket.Name, upload.Key, upload.UploadId, "get multipart failed:", err)
+				continue
+			}
+			// size of an incomplete upload is unknown
+			expired := false
+			for _, rule := range bucket.Lifecycle.MatchRules(upload.Key, -1, multipart.Metadata.Tags) {
+				if rule.AbortIncompleteMultipartUpload != nil &&
+					checkIfExpiration(multipart.InitialTime, rule.AbortIncompleteMultipartUpload.DaysAfterInitiation) {
+					expired = true
 				}
-
 			}
+			if !expired {
+				continue
+			}
+			err = yig.AbortMultipartUpload(credential, bucket.Name, upload.Key, upload.UploadId)
+			if err != nil {
+				helper.Logger.Error(bucket.Name, upload.Key, upload.UploadId, "abort failed:", err)
+				continue
+			}
+			helper.Logger.Info("Aborted multipart upload:", bucket.Name, upload.Key, upload.UploadId)
+		}
+		if result.IsTruncated {
+			request.KeyMarker = result.NextKeyMarker
+			request.UploadIdMarker = result.NextUploadIdMarker
+		} else {
+			break
 		}
-
 	}
 	return nil
 }
@@ -265,6 +457,8 @@ func main() {
 			// stop YIG server, order matters
 			stop = true
 			waitgroup.Wait()
+			// objects written by transitions failed are recycled
+			yig.Stop()
 			return
 		}
 	}
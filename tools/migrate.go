@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/client/mysqlclient"
+	"github.com/journeymidnight/yig/meta/client/postgresclient"
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	"github.com/journeymidnight/yig/meta/migrate"
+)
+
+func printMigrateHelp() {
+	fmt.Println("Usage: migrate <command> [version]")
+	fmt.Println("Migrates schema of meta_store in /etc/yig/yig.toml")
+	fmt.Println("Commands:")
+	fmt.Println(" version          Show schema version of database and the latest one")
+	fmt.Println(" up [version]     Upgrade schema to version, the latest by default")
+	fmt.Println(" down <version>   Downgrade schema to version, 0 drops all tables")
+}
+
+func openMetaStore() *sql.DB {
+	switch helper.CONFIG.MetaStore {
+	case migrate.TIDB:
+		return tidbclient.NewTidbClient().Client
+	case migrate.MYSQL:
+		return mysqlclient.NewMysqlClient().Client
+	case migrate.POSTGRES:
+		// needs migrate built with `-tags postgres`
+		return postgresclient.NewPostgresClient().Client
+	default:
+		fmt.Println("meta store", helper.CONFIG.MetaStore, "has no schema to migrate")
+		os.Exit(1)
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printMigrateHelp()
+		os.Exit(1)
+	}
+	helper.SetupConfig()
+	helper.Logger = log.NewLogger(os.Stdout, log.ParseLevel(helper.CONFIG.LogLevel))
+	db := openMetaStore()
+	defer db.Close()
+	m := migrate.New(db, helper.CONFIG.MetaStore)
+
+	version, err := m.Version()
+	if err != nil {
+		fmt.Println("Failed to get schema version:", err)
+		os.Exit(1)
+	}
+	switch os.Args[1] {
+	case "version":
+		fmt.Println("Schema version:", version)
+		fmt.Println("Latest version:", migrate.Latest())
+		return
+	case "up":
+		target := migrate.Latest()
+		if len(os.Args) > 2 {
+			target, err = strconv.Atoi(os.Args[2])
+			if err != nil {
+				fmt.Println("Invalid version", os.Args[2])
+				os.Exit(1)
+			}
+		}
+		err = m.Up(target)
+	case "down":
+		if len(os.Args) < 3 {
+			printMigrateHelp()
+			os.Exit(1)
+		}
+		var target int
+		target, err = strconv.Atoi(os.Args[2])
+		if err != nil {
+			fmt.Println("Invalid version", os.Args[2])
+			os.Exit(1)
+		}
+		err = m.Down(target)
+	default:
+		printMigrateHelp()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Println("Migration failed:", err)
+		os.Exit(1)
+	}
+	version, _ = m.Version()
+	fmt.Println("Schema version:", version)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/client/mysqlclient"
+	"github.com/journeymidnight/yig/meta/client/postgresclient"
+	"github.com/journeymidnight/yig/meta/client/tidbclient"
+	"github.com/journeymidnight/yig/meta/migrate"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	DEFAULT_REKEY_LOG_PATH = "/var/log/yig/rekey.log"
+	SCAN_LIMIT             = 1000
+)
+
+// rekeyer rewraps sealed keys of objects and multipart uploads under the newest master key,
+// data in Ceph is encrypted by data keys which are unchanged, so it's not touched.
+// Keys sealed by the fallback KMS of keyring are rewrapped too.
+// Old master keys should be kept until rekey is done and metadata cached by yig expires.
+type rekeyer struct {
+	db        *sql.DB
+	store     string
+	kms       crypto.KMS
+	rewrapper crypto.KeyRewrapper
+	dryRun    bool
+
+	scanned, rewrapped, failed int
+}
+
+// version is an unsigned bigint in TiDB and MySQL, which should not be compared as a float
+func (r *rekeyer) version() string {
+	if r.store == migrate.POSTGRES {
+		return "cast(? as numeric)"
+	}
+	return "cast(? as unsigned)"
+}
+
+func (r *rekeyer) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.Query(migrate.Rebind(r.store, query), args...)
+}
+
+func (r *rekeyer) exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.db.Exec(migrate.Rebind(r.store, query), args...)
+}
+
+// rewrap returns the key sealed under the newest master key, or nil if it's not rewrapped
+func (r *rekeyer) rewrap(row string, keyId string, sealedKey []byte, context crypto.Context) []byte {
+	r.scanned += 1
+	newSealedKey, rewrapped, err := r.rewrapper.RewrapKey(keyId, sealedKey, context)
+	if err != nil {
+		r.failed += 1
+		fmt.Println("failed to rewrap key of", row, ":", err)
+		return nil
+	}
+	if !rewrapped {
+		return nil
+	}
+	if r.dryRun {
+		r.rewrapped += 1
+		fmt.Println("to rewrap", row)
+		return nil
+	}
+	return newSealedKey
+}
+
+// updated counts the row updated, which is changed in the meantime if no row is affected
+func (r *rekeyer) updated(row string, result sql.Result, err error) {
+	if err == nil {
+		var affected int64
+		affected, err = result.RowsAffected()
+		if err == nil && affected == 0 {
+			helper.Logger.Info("Changed while rewrapping", row)
+			return
+		}
+	}
+	if err != nil {
+		r.failed += 1
+		fmt.Println("failed to update", row, ":", err)
+		return
+	}
+	helper.Logger.Info("Rewrapped key of", row)
+	r.rewrapped += 1
+}
+
+type objectKey struct {
+	object     types.Object
+	version    string
+	sseContext string
+}
+
+// rekeyObjects rewraps keys of SSE-S3 and SSE-KMS objects, in batches of SCAN_LIMIT
+func (r *rekeyer) rekeyObjects() error {
+	var marker *objectKey
+	for {
+		query := "select bucketname,name,version,ssetype,encryptionkey,COALESCE(ssekmskeyid,'')," +
+			"COALESCE(ssecontext,'{}') from objects where ssetype in (?,?) "
+		args := []interface{}{crypto.S3.String(), crypto.S3KMS.String()}
+		if marker != nil {
+			query += "and (bucketname,name,version) > (?,?," + r.version() + ") "
+			args = append(args, marker.object.BucketName, marker.object.Name, marker.version)
+		}
+		query += "order by bucketname,name,version limit ?"
+		rows, err := r.query(query, append(args, SCAN_LIMIT)...)
+		if err != nil {
+			return err
+		}
+		var keys []objectKey
+		for rows.Next() {
+			var k objectKey
+			err = rows.Scan(&k.object.BucketName, &k.object.Name, &k.version, &k.object.SseType,
+				&k.object.EncryptionKey, &k.object.SseKmsKeyId, &k.sseContext)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			keys = append(keys, k)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			row := fmt.Sprintf("object %s/%s version %s", k.object.BucketName, k.object.Name, k.version)
+			if len(k.object.EncryptionKey) == 0 {
+				// appended before encryption of appendable objects was supported
+				continue
+			}
+			err = json.Unmarshal([]byte(k.sseContext), &k.object.SseContext)
+			if err != nil {
+				r.failed += 1
+				fmt.Println("invalid encryption context of", row, ":", err)
+				continue
+			}
+			keyId, context := storage.ObjectKeyContext(r.kms, &k.object)
+			newSealedKey := r.rewrap(row, keyId, k.object.EncryptionKey, context)
+			if newSealedKey == nil {
+				continue
+			}
+			result, err := r.exec("update objects set encryptionkey=? where bucketname=? and name=? "+
+				"and version="+r.version()+" and encryptionkey=?", newSealedKey,
+				k.object.BucketName, k.object.Name, k.version, k.object.EncryptionKey)
+			r.updated(row, result, err)
+		}
+		if len(keys) < SCAN_LIMIT {
+			return nil
+		}
+		marker = &keys[len(keys)-1]
+	}
+}
+
+type multipartKey struct {
+	multipart  types.Multipart
+	uploadTime string
+	sseRequest string
+}
+
+// rekeyMultiparts rewraps keys of SSE-S3 and SSE-KMS multipart uploads, in batches of SCAN_LIMIT
+func (r *rekeyer) rekeyMultiparts() error {
+	var marker *multipartKey
+	for {
+		query := "select bucketname,objectname,uploadtime,COALESCE(sserequest,'{}'),cipher from multiparts " +
+			"where cipher is not null "
+		var args []interface{}
+		if marker != nil {
+			query += "and (bucketname,objectname,uploadtime) > (?,?," + r.version() + ") "
+			args = append(args, marker.multipart.BucketName, marker.multipart.ObjectName, marker.uploadTime)
+		}
+		query += "order by bucketname,objectname,uploadtime limit ?"
+		rows, err := r.query(query, append(args, SCAN_LIMIT)...)
+		if err != nil {
+			return err
+		}
+		var keys []multipartKey
+		for rows.Next() {
+			var k multipartKey
+			err = rows.Scan(&k.multipart.BucketName, &k.multipart.ObjectName, &k.uploadTime,
+				&k.sseRequest, &k.multipart.Metadata.CipherKey)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			keys = append(keys, k)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			row := fmt.Sprintf("multipart upload %s/%s at %s", k.multipart.BucketName,
+				k.multipart.ObjectName, k.uploadTime)
+			var sseRequest datatype.SseRequest
+			err = json.Unmarshal([]byte(k.sseRequest), &sseRequest)
+			if err != nil {
+				r.failed += 1
+				fmt.Println("invalid sse request of", row, ":", err)
+				continue
+			}
+			if (sseRequest.Type != crypto.S3.String() && sseRequest.Type != crypto.S3KMS.String()) ||
+				len(k.multipart.Metadata.CipherKey) == 0 {
+				continue
+			}
+			k.multipart.Metadata.SseRequest = sseRequest
+			keyId, context, err := storage.MultipartKeyContext(r.kms, &k.multipart)
+			if err != nil {
+				r.failed += 1
+				fmt.Println("invalid encryption context of", row, ":", err)
+				continue
+			}
+			newSealedKey := r.rewrap(row, keyId, k.multipart.Metadata.CipherKey, context)
+			if newSealedKey == nil {
+				continue
+			}
+			result, err := r.exec("update multiparts set cipher=? where bucketname=? and objectname=? "+
+				"and uploadtime="+r.version()+" and cipher=?", newSealedKey,
+				k.multipart.BucketName, k.multipart.ObjectName, k.uploadTime, k.multipart.Metadata.CipherKey)
+			r.updated(row, result, err)
+		}
+		if len(keys) < SCAN_LIMIT {
+			return nil
+		}
+		marker = &keys[len(keys)-1]
+	}
+}
+
+func openRekeyMetaStore() *sql.DB {
+	switch helper.CONFIG.MetaStore {
+	case migrate.TIDB:
+		return tidbclient.NewTidbClient().Client
+	case migrate.MYSQL:
+		return mysqlclient.NewMysqlClient().Client
+	case migrate.POSTGRES:
+		// needs rekey built with `-tags postgres`
+		return postgresclient.NewPostgresClient().Client
+	default:
+		// the embedded meta store could be opened by yig only, keys there are not rewrapped,
+		// so master keys and fallback KMS of keyring should be kept
+		fmt.Println("rekey does not support meta store", helper.CONFIG.MetaStore)
+		os.Exit(1)
+	}
+	return nil
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report keys to rewrap")
+	flag.Parse()
+
+	helper.SetupConfig()
+	helper.Logger = log.NewFileLogger(DEFAULT_REKEY_LOG_PATH, log.ParseLevel(helper.CONFIG.LogLevel))
+	defer helper.Logger.Close()
+
+	kms := crypto.NewKMS(mods.InitialPlugins())
+	if kms == nil {
+		fmt.Println("Failed to initialize KMS plugin")
+		os.Exit(1)
+	}
+	rewrapper, ok := kms.(crypto.KeyRewrapper)
+	if !ok {
+		fmt.Println("KMS plugin does not support rewrapping keys")
+		os.Exit(1)
+	}
+	db := openRekeyMetaStore()
+	defer db.Close()
+	err := migrate.New(db, helper.CONFIG.MetaStore).Check()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	r := &rekeyer{
+		db:        db,
+		store:     helper.CONFIG.MetaStore,
+		kms:       kms,
+		rewrapper: rewrapper,
+		dryRun:    *dryRun,
+	}
+	err = r.rekeyObjects()
+	if err != nil {
+		fmt.Println("Failed to scan objects:", err)
+		os.Exit(1)
+	}
+	err = r.rekeyMultiparts()
+	if err != nil {
+		fmt.Println("Failed to scan multipart uploads:", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Printf("%d keys scanned, %d to rewrap, %d failed\n", r.scanned, r.rewrapped, r.failed)
+	} else {
+		fmt.Printf("%d keys scanned, %d rewrapped, %d failed\n", r.scanned, r.rewrapped, r.failed)
+	}
+	if r.failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/aws/credentials"
+	"github.com/journeymidnight/aws-sdk-go/aws/session"
+	"github.com/journeymidnight/aws-sdk-go/service/s3/s3manager"
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/crypto"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT                   = 50
+	DEFAULT_REPLICATION_LOG_PATH = "/var/log/yig/replication.log"
+	// interval between two rounds of scanning the pending queue
+	SCAN_INTERVAL = 10 * time.Second
+)
+
+var (
+	yig         *storage.YigStorage
+	uploader    *s3manager.Uploader
+	taskQ       chan types.Replication
+	signalQueue chan os.Signal
+	waitgroup   sync.WaitGroup
+	batch       sync.WaitGroup
+	stop        bool
+)
+
+// countWriter records bytes written, multipart objects may be returned
+// short by yig.GetObject without error
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Entries of the pending queue are scanned in batches, every batch is dispatched to workers
+// and finished before the next one. Entries failed to replicate are left in queue
+// and retried in the next round.
+func scanReplications() {
+	defer waitgroup.Done()
+	defer close(taskQ)
+	var marker string
+	for {
+		if stop {
+			helper.Logger.Info("Shutting down...")
+			return
+		}
+		replications, err := yig.MetaStorage.ScanReplication(SCAN_LIMIT, marker)
+		if err != nil {
+			helper.Logger.Error("ScanReplication failed:", err)
+			time.Sleep(SCAN_INTERVAL)
+			continue
+		}
+		for _, r := range replications {
+			batch.Add(1)
+			taskQ <- r
+		}
+		batch.Wait()
+		if len(replications) < SCAN_LIMIT {
+			marker = ""
+			time.Sleep(SCAN_INTERVAL)
+			continue
+		}
+		marker = replications[len(replications)-1].Rowkey()
+	}
+}
+
+func processReplications() {
+	defer waitgroup.Done()
+	for r := range taskQ {
+		processReplication(r)
+		batch.Done()
+	}
+}
+
+func processReplication(r types.Replication) {
+	status, versionId, err := replicate(r)
+	if err != nil {
+		r.TriedTimes += 1
+		helper.Logger.Error("Replicate", r.BucketName, r.ObjectName, r.Version,
+			"failed", r.TriedTimes, "times:", err)
+		if r.TriedTimes < helper.CONFIG.ReplicationMaxTriedTimes {
+			err = yig.MetaStorage.UpdateReplicationTriedTimes(r)
+			if err != nil {
+				helper.Logger.Error("UpdateReplicationTriedTimes", r.BucketName, r.ObjectName, r.Version,
+					"failed:", err)
+			}
+			return
+		}
+		status = datatype.ReplicationStatusFailed
+	}
+	err = yig.MetaStorage.RemoveReplication(r, status)
+	if err != nil {
+		helper.Logger.Error("RemoveReplication", r.BucketName, r.ObjectName, r.Version, "failed:", err)
+		return
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, r.BucketName+":"+r.ObjectName+":")
+	if versionId != "" {
+		yig.MetaStorage.Cache.Remove(redis.ObjectTable, r.BucketName+":"+r.ObjectName+":"+versionId)
+	}
+	helper.Logger.Info("Replication done:", r.BucketName, r.ObjectName, r.Version, status)
+}
+
+// replicate copies the object version to destination bucket of the matched rule,
+// returns error if it should be retried later. versionId is the ID the version
+// is cached by, empty if the version is not read.
+func replicate(r types.Replication) (status string, versionId string, err error) {
+	object, err := yig.MetaStorage.Client.GetObject(r.BucketName, r.ObjectName, r.Version)
+	if err == ErrNoSuchKey {
+		helper.Logger.Warn("Object version removed before replicated:", r.BucketName, r.ObjectName, r.Version)
+		return datatype.ReplicationStatusFailed, "", nil
+	} else if err != nil {
+		return
+	}
+	versionId = object.GetVersionId()
+	bucket, err := yig.MetaStorage.GetBucket(r.BucketName, false)
+	if err == ErrNoSuchBucket {
+		return datatype.ReplicationStatusFailed, versionId, nil
+	} else if err != nil {
+		return
+	}
+	// rules may be changed after the object is queued
+	rule, ok := bucket.Replication.MatchRule(object.Name, object.Tags)
+	if !ok {
+		helper.Logger.Warn("No replication rule matches:", r.BucketName, r.ObjectName, r.Version)
+		return datatype.ReplicationStatusFailed, versionId, nil
+	}
+	// customer keys are never stored, these objects could not be read
+	if object.SseType == crypto.SSEC.String() {
+		helper.Logger.Warn("Skip SSE-C object:", r.BucketName, r.ObjectName, r.Version)
+		return datatype.ReplicationStatusFailed, versionId, nil
+	}
+
+	input := newUploadInput(object, rule)
+	reader, writer := io.Pipe()
+	go func() {
+		w := &countWriter{w: writer}
+		err := yig.GetObject(object, 0, object.Size, w, datatype.SseRequest{})
+		if err == nil && w.n != object.Size {
+			err = fmt.Errorf("read %d bytes, expected %d", w.n, object.Size)
+		}
+		writer.CloseWithError(err)
+	}()
+	input.Body = reader
+	_, err = uploader.Upload(input)
+	// unblock the reading goroutine if upload aborts
+	reader.CloseWithError(errors.New("upload finished"))
+	if err != nil {
+		return
+	}
+	return datatype.ReplicationStatusCompleted, versionId, nil
+}
+
+func newUploadInput(object *types.Object, rule datatype.ReplicationRule) *s3manager.UploadInput {
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(rule.DestinationBucket()),
+		Key:         aws.String(object.Name),
+		ContentType: aws.String(object.ContentType),
+		Metadata:    make(map[string]*string),
+	}
+	for key, value := range object.CustomAttributes {
+		v := aws.String(value)
+		switch strings.ToLower(key) {
+		case "cache-control":
+			input.CacheControl = v
+		case "content-disposition":
+			input.ContentDisposition = v
+		case "content-encoding":
+			input.ContentEncoding = v
+		case "content-language":
+			input.ContentLanguage = v
+		case "website-redirect-location":
+			input.WebsiteRedirectLocation = v
+		default:
+			if strings.HasPrefix(strings.ToLower(key), "x-amz-meta-") {
+				input.Metadata[key[len("x-amz-meta-"):]] = v
+			}
+		}
+	}
+	if len(object.Tags) != 0 {
+		tags := url.Values{}
+		for key, value := range object.Tags {
+			tags.Set(key, value)
+		}
+		input.Tagging = aws.String(tags.Encode())
+	}
+	if rule.Destination.StorageClass != "" {
+		input.StorageClass = aws.String(rule.Destination.StorageClass)
+	}
+	return input
+}
+
+func newUploader() *s3manager.Uploader {
+	creds := credentials.NewStaticCredentials(helper.CONFIG.ReplicationAccessKey,
+		helper.CONFIG.ReplicationSecretKey, "")
+	sess := session.Must(session.NewSession(
+		&aws.Config{
+			Credentials:      creds,
+			Endpoint:         aws.String(helper.CONFIG.ReplicationEndpoint),
+			Region:           aws.String(helper.CONFIG.ReplicationRegion),
+			S3ForcePathStyle: aws.Bool(true),
+		},
+	))
+	return s3manager.NewUploader(sess)
+}
+
+func main() {
+	stop = false
+
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_REPLICATION_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.ReplicationEndpoint == "" {
+		helper.Logger.Error("replication_endpoint is not configured")
+		return
+	}
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms)
+	uploader = newUploader()
+	taskQ = make(chan types.Replication, SCAN_LIMIT)
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+
+	numOfWorkers := helper.CONFIG.ReplicationThread
+	helper.Logger.Info("start replication thread:", numOfWorkers)
+	for i := 0; i < numOfWorkers; i++ {
+		waitgroup.Add(1)
+		go processReplications()
+	}
+	waitgroup.Add(1)
+	go scanReplications()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// stop after the current batch is done
+			stop = true
+			waitgroup.Wait()
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT               = 50
+	DEFAULT_RESTORE_LOG_PATH = "/var/log/yig/restore.log"
+	// interval between two rounds of scanning freezers
+	SCAN_INTERVAL = 10 * time.Second
+)
+
+var (
+	yig         *storage.YigStorage
+	taskQ       chan types.Freezer
+	signalQueue chan os.Signal
+	waitgroup   sync.WaitGroup
+	batch       sync.WaitGroup
+	stop        bool
+)
+
+// scanFreezers calls fn with all freezers in status, in batches of SCAN_LIMIT
+func scanFreezers(status types.Status, fn func(freezers []types.Freezer)) error {
+	var marker string
+	for !stop {
+		freezers, err := yig.ScanFreezers(SCAN_LIMIT, marker, status)
+		if err != nil {
+			return err
+		}
+		fn(freezers)
+		if len(freezers) < SCAN_LIMIT {
+			return nil
+		}
+		marker = string(freezers[len(freezers)-1].Rowkey)
+	}
+	return nil
+}
+
+// Freezers requested to restore are dispatched to workers in batches,
+// and restored copies are expired by the scanning goroutine.
+// Freezers failed to restore are set back to READY and retried in the next round.
+func scanRestores() {
+	defer waitgroup.Done()
+	defer close(taskQ)
+	for !stop {
+		err := scanFreezers(types.ObjectNeedRestore, func(freezers []types.Freezer) {
+			for _, f := range freezers {
+				batch.Add(1)
+				taskQ <- f
+			}
+			batch.Wait()
+		})
+		if err != nil {
+			helper.Logger.Error("Scan freezers to restore failed:", err)
+		}
+		now := time.Now()
+		err = scanFreezers(types.ObjectHasRestored, func(freezers []types.Freezer) {
+			for _, f := range freezers {
+				expired, err := yig.ExpireFreezer(&f, now)
+				if err != nil {
+					helper.Logger.Error("Expire restored object", f.BucketName, f.Name, "failed:", err)
+				} else if expired {
+					helper.Logger.Info("Restored object expired:", f.BucketName, f.Name)
+				}
+			}
+		})
+		if err != nil {
+			helper.Logger.Error("Scan restored freezers failed:", err)
+		}
+		time.Sleep(SCAN_INTERVAL)
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func processRestores() {
+	defer waitgroup.Done()
+	for f := range taskQ {
+		err := yig.RestoreObject(&f)
+		if err != nil {
+			helper.Logger.Error("Restore object", f.BucketName, f.Name, "failed:", err)
+		} else {
+			helper.Logger.Info("Restore object done:", f.BucketName, f.Name)
+		}
+		batch.Done()
+	}
+}
+
+// resetRestoring sets freezers left RESTORING by last run back to READY,
+// so only one restore daemon should run at a time
+func resetRestoring() error {
+	return scanFreezers(types.ObjectRestoring, func(freezers []types.Freezer) {
+		for _, f := range freezers {
+			_, err := yig.MetaStorage.UpdateFreezerStatus(f.BucketName, f.Name,
+				types.ObjectRestoring, types.ObjectNeedRestore)
+			if err != nil {
+				helper.Logger.Error("Reset freezer", f.BucketName, f.Name, "failed:", err)
+			}
+		}
+	})
+}
+
+func main() {
+	stop = false
+
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_RESTORE_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms)
+	err := resetRestoring()
+	if err != nil {
+		helper.Logger.Error("Reset restoring freezers failed:", err)
+		return
+	}
+	taskQ = make(chan types.Freezer, SCAN_LIMIT)
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+
+	numOfWorkers := helper.CONFIG.RestoreThread
+	helper.Logger.Info("start restore thread:", numOfWorkers)
+	for i := 0; i < numOfWorkers; i++ {
+		waitgroup.Add(1)
+		go processRestores()
+	}
+	waitgroup.Add(1)
+	go scanRestores()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// stop after the current batch is done
+			stop = true
+			waitgroup.Wait()
+			// copies failed to save into freezers are recycled
+			yig.Stop()
+			return
+		}
+	}
+}